@@ -11,6 +11,7 @@ import (
 	"github.com/stashapp/stash/internal/manager"
 	"github.com/stashapp/stash/internal/manager/config"
 	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/session"
 )
 
@@ -33,16 +34,35 @@ func authenticateHandler() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			c := config.GetInstance()
+			mgr := manager.GetInstance()
+			accessConfig := mgr.ExternalAccessConfig(r.Context())
 
 			// error if external access tripwire activated
-			if accessErr := session.CheckExternalAccessTripwire(c); accessErr != nil {
+			if accessErr := session.CheckExternalAccessTripwire(accessConfig); accessErr != nil {
 				http.Error(w, tripwireActivatedErrMsg, http.StatusForbidden)
 				return
 			}
 
-			userID, err := manager.GetInstance().SessionStore.Authenticate(w, r)
+			var (
+				user   *models.User
+				apiKey *models.APIKey
+				err    error
+			)
+
+			// API keys are ignored when authentication is not required, so
+			// that a stale key does not prevent access
+			apiKeyValue := session.GetRequestAPIKey(r)
+			if apiKeyValue != "" && accessConfig.HasCredentials() {
+				user, apiKey, err = mgr.AuthenticateAPIKey(r.Context(), apiKeyValue)
+			} else {
+				user, err = mgr.SessionStore.Authenticate(w, r)
+				if err == nil && user != nil {
+					apiKey = mgr.SessionStore.GetSessionAPIKey(r, user)
+				}
+			}
+
 			if err != nil {
-				if errors.Is(err, session.ErrUnauthorized) {
+				if !errors.Is(err, session.ErrUnauthorized) {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
 				}
@@ -53,7 +73,7 @@ func authenticateHandler() func(http.Handler) http.Handler {
 				return
 			}
 
-			if err := session.CheckAllowPublicWithoutAuth(c, r); err != nil {
+			if err := session.CheckAllowPublicWithoutAuth(accessConfig, r); err != nil {
 				var accessErr session.ExternalAccessError
 				if errors.As(err, &accessErr) {
 					session.LogExternalAccessError(accessErr)
@@ -73,9 +93,9 @@ func authenticateHandler() func(http.Handler) http.Handler {
 
 			ctx := r.Context()
 
-			if c.HasCredentials() {
+			if accessConfig.HasCredentials() {
 				// authentication is required
-				if userID == "" && !allowUnauthenticated(r) {
+				if user == nil && !allowUnauthenticated(r) {
 					// if graphql or a non-webpage was requested, we just return a forbidden error
 					ext := path.Ext(r.URL.Path)
 					if r.URL.Path == gqlEndpoint || (ext != "" && ext != ".html") {
@@ -102,7 +122,20 @@ func authenticateHandler() func(http.Handler) http.Handler {
 				}
 			}
 
-			ctx = session.SetCurrentUserID(ctx, userID)
+			if user != nil {
+				ctx = session.SetCurrentUser(ctx, user)
+			}
+
+			if apiKey != nil {
+				// graphql operations are checked against the scopes of the key
+				// in authorizeField
+				if r.URL.Path != gqlEndpoint && !apiKey.HasScope(models.APIKeyScopeRead) {
+					http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+					return
+				}
+
+				ctx = session.SetCurrentAPIKey(ctx, apiKey, apiKeyValue)
+			}
 
 			r = r.WithContext(ctx)
 
@@ -110,3 +143,41 @@ func authenticateHandler() func(http.Handler) http.Handler {
 		})
 	}
 }
+
+// pluginAuthenticateHandler authenticates the requests which plugins make
+// directly to the graphql handler by their session cookie, since these do
+// not pass through authenticateHandler.
+func pluginAuthenticateHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mgr := manager.GetInstance()
+		ctx := r.Context()
+
+		user, err := mgr.SessionStore.Authenticate(w, r)
+		if err != nil {
+			if !errors.Is(err, session.ErrUnauthorized) {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if user == nil {
+			if mgr.ExternalAccessConfig(ctx).HasCredentials() {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		} else {
+			ctx = session.SetCurrentUser(ctx, user)
+
+			// plugins run by a request authenticated by an API key are
+			// limited to the scopes of the key
+			if apiKey := mgr.SessionStore.GetSessionAPIKey(r, user); apiKey != nil {
+				ctx = session.SetCurrentAPIKey(ctx, apiKey, "")
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+)
+
+var (
+	errForbidden         = errors.New("forbidden: insufficient role")
+	errInsufficientScope = errors.New("forbidden: API key does not have the required scope")
+)
+
+// adminOperations contains the root fields which require the admin role.
+var adminOperations = map[string]map[string]bool{
+	"Query": {
+		"logs":                        true,
+		"directory":                   true,
+		"validateStashBoxCredentials": true,
+		"streamSessions":              true,
+		"users":                       true,
+	},
+	"Mutation": {
+		"setup":                     true,
+		"migrate":                   true,
+		"moveFiles":                 true,
+		"deleteFiles":               true,
+		"configureGeneral":          true,
+		"configureInterface":        true,
+		"configureDLNA":             true,
+		"configureScraping":         true,
+		"configureDefaults":         true,
+		"configureUI":               true,
+		"configureUISetting":        true,
+		"exportObjects":             true,
+		"importObjects":             true,
+		"metadataImport":            true,
+		"metadataExport":            true,
+		"metadataScan":              true,
+		"metadataGenerate":          true,
+		"metadataAutoTag":           true,
+		"metadataClean":             true,
+		"metadataIdentify":          true,
+		"migrateHashNaming":         true,
+		"migrateSceneScreenshots":   true,
+		"migrateBlobs":              true,
+		"anonymiseDatabase":         true,
+		"optimiseDatabase":          true,
+		"reloadScrapers":            true,
+		"runPluginTask":             true,
+		"reloadPlugins":             true,
+		"stopJob":                   true,
+		"stopAllJobs":               true,
+		"purgeTranscodeCache":       true,
+		"stopStreamSession":         true,
+		"backupDatabase":            true,
+		"querySQL":                  true,
+		"execSQL":                   true,
+		"stashBoxBatchPerformerTag": true,
+		"stashBoxBatchStudioTag":    true,
+		"enableDLNA":                true,
+		"disableDLNA":               true,
+		"addTempDLNAIP":             true,
+		"removeTempDLNAIP":          true,
+		"userCreate":                true,
+		"userUpdate":                true,
+		"userDestroy":               true,
+	},
+	"Subscription": {
+		"loggingSubscribe":        true,
+		"streamSessionsSubscribe": true,
+	},
+}
+
+// fileDeletingMutations contains the mutations which delete files from disk
+// if the delete_file field of their input is true. Like deleteFiles, this
+// requires the admin role.
+var fileDeletingMutations = map[string]bool{
+	"sceneDestroy":   true,
+	"scenesDestroy":  true,
+	"imageDestroy":   true,
+	"imagesDestroy":  true,
+	"galleryDestroy": true,
+}
+
+// readOnlyMutations contains the mutations which may be performed by
+// read-only users. These only record the user's own activity or settings.
+var readOnlyMutations = map[string]bool{
+	"sceneIncrementO":         true,
+	"sceneDecrementO":         true,
+	"sceneResetO":             true,
+	"sceneSaveActivity":       true,
+	"sceneIncrementPlayCount": true,
+	"imageIncrementO":         true,
+	"imageDecrementO":         true,
+	"imageResetO":             true,
+	"changePassword":          true,
+	"generateAPIKey":          true,
+	"apiKeyCreate":            true,
+	"apiKeyRevoke":            true,
+}
+
+// taskMutations contains the mutations which require the tasks scope when
+// using an API key.
+var taskMutations = map[string]bool{
+	"metadataImport":            true,
+	"metadataExport":            true,
+	"metadataScan":              true,
+	"metadataGenerate":          true,
+	"metadataAutoTag":           true,
+	"metadataClean":             true,
+	"metadataIdentify":          true,
+	"migrateHashNaming":         true,
+	"migrateSceneScreenshots":   true,
+	"migrateBlobs":              true,
+	"optimiseDatabase":          true,
+	"backupDatabase":            true,
+	"runPluginTask":             true,
+	"stopJob":                   true,
+	"stopAllJobs":               true,
+	"exportSceneClips":          true,
+	"stashBoxBatchPerformerTag": true,
+	"stashBoxBatchStudioTag":    true,
+}
+
+// apiKeyMutations contains the mutations which manage API keys. API keys may
+// only be used for these if they have the admin scope.
+var apiKeyMutations = map[string]bool{
+	"generateAPIKey": true,
+	"apiKeyCreate":   true,
+	"apiKeyRevoke":   true,
+}
+
+// requiredRole returns the role required to resolve the root field name of
+// the object, which is one of Query, Mutation or Subscription.
+func requiredRole(object string, name string) models.UserRole {
+	if adminOperations[object][name] {
+		return models.UserRoleAdmin
+	}
+
+	if object == "Mutation" && !readOnlyMutations[name] {
+		return models.UserRoleEditor
+	}
+
+	return models.UserRoleReadOnly
+}
+
+// deletesFiles returns true if the arguments of the mutation name request
+// that files are deleted from disk.
+func deletesFiles(name string, args map[string]interface{}) bool {
+	if !fileDeletingMutations[name] {
+		return false
+	}
+
+	var deleteFile *bool
+	switch input := args["input"].(type) {
+	case models.SceneDestroyInput:
+		deleteFile = input.DeleteFile
+	case models.ScenesDestroyInput:
+		deleteFile = input.DeleteFile
+	case models.ImageDestroyInput:
+		deleteFile = input.DeleteFile
+	case models.ImagesDestroyInput:
+		deleteFile = input.DeleteFile
+	case models.GalleryDestroyInput:
+		deleteFile = input.DeleteFile
+	}
+
+	return deleteFile != nil && *deleteFile
+}
+
+// requiredScope returns the API key scope required to resolve the root
+// field name of the object.
+func requiredScope(object string, name string) models.APIKeyScope {
+	if adminOperations[object][name] && !taskMutations[name] {
+		return models.APIKeyScopeAdmin
+	}
+
+	if object != "Mutation" {
+		return models.APIKeyScopeRead
+	}
+
+	switch {
+	case apiKeyMutations[name]:
+		return models.APIKeyScopeAdmin
+	case taskMutations[name]:
+		return models.APIKeyScopeTasks
+	default:
+		return models.APIKeyScopeWrite
+	}
+}
+
+// authorizeField is a graphql field middleware which ensures that the
+// current user has the role, and the API key of the request has the scope,
+// required to resolve root fields.
+func authorizeField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil {
+		return next(ctx)
+	}
+
+	switch fc.Object {
+	case "Query", "Mutation", "Subscription":
+		if !session.HasRole(ctx, requiredRole(fc.Object, fc.Field.Name)) {
+			return nil, errForbidden
+		}
+
+		if fc.Object == "Mutation" && deletesFiles(fc.Field.Name, fc.Args) && !session.HasRole(ctx, models.UserRoleAdmin) {
+			return nil, errForbidden
+		}
+
+		if !session.HasScope(ctx, requiredScope(fc.Object, fc.Field.Name)) {
+			return nil, errInsufficientScope
+		}
+	}
+
+	return next(ctx)
+}
+
+// requireRole returns a middleware which responds with forbidden if the
+// current user does not have the given role.
+func requireRole(role models.UserRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !session.HasRole(r.Context(), role) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+func TestRequiredRole(t *testing.T) {
+	tests := []struct {
+		object string
+		name   string
+		want   models.UserRole
+	}{
+		{"Query", "findScenes", models.UserRoleReadOnly},
+		{"Query", "logs", models.UserRoleAdmin},
+		{"Query", "users", models.UserRoleAdmin},
+		{"Mutation", "sceneUpdate", models.UserRoleEditor},
+		{"Mutation", "sceneSaveActivity", models.UserRoleReadOnly},
+		{"Mutation", "changePassword", models.UserRoleReadOnly},
+		{"Mutation", "configureGeneral", models.UserRoleAdmin},
+		{"Mutation", "configureUI", models.UserRoleAdmin},
+		{"Mutation", "configureUISetting", models.UserRoleAdmin},
+		{"Mutation", "sceneDestroy", models.UserRoleEditor},
+		{"Mutation", "userCreate", models.UserRoleAdmin},
+		{"Subscription", "jobsSubscribe", models.UserRoleReadOnly},
+		{"Subscription", "loggingSubscribe", models.UserRoleAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.object+"."+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, requiredRole(tt.object, tt.name))
+		})
+	}
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		object string
+		name   string
+		want   models.APIKeyScope
+	}{
+		{"Query", "findScenes", models.APIKeyScopeRead},
+		{"Query", "logs", models.APIKeyScopeAdmin},
+		{"Query", "apiKeys", models.APIKeyScopeRead},
+		{"Mutation", "sceneUpdate", models.APIKeyScopeWrite},
+		{"Mutation", "sceneSaveActivity", models.APIKeyScopeWrite},
+		{"Mutation", "metadataScan", models.APIKeyScopeTasks},
+		{"Mutation", "stopJob", models.APIKeyScopeTasks},
+		{"Mutation", "configureGeneral", models.APIKeyScopeAdmin},
+		{"Mutation", "apiKeyCreate", models.APIKeyScopeAdmin},
+		{"Mutation", "generateAPIKey", models.APIKeyScopeAdmin},
+		{"Subscription", "jobsSubscribe", models.APIKeyScopeRead},
+		{"Subscription", "loggingSubscribe", models.APIKeyScopeAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.object+"."+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, requiredScope(tt.object, tt.name))
+		})
+	}
+}
+
+func TestDeletesFiles(t *testing.T) {
+	deleteFile := true
+	keepFile := false
+
+	tests := []struct {
+		name  string
+		field string
+		input interface{}
+		want  bool
+	}{
+		{"scene", "sceneDestroy", models.SceneDestroyInput{DeleteFile: &deleteFile}, true},
+		{"scene keep file", "sceneDestroy", models.SceneDestroyInput{DeleteFile: &keepFile}, false},
+		{"scene unset", "sceneDestroy", models.SceneDestroyInput{}, false},
+		{"scenes", "scenesDestroy", models.ScenesDestroyInput{DeleteFile: &deleteFile}, true},
+		{"image", "imageDestroy", models.ImageDestroyInput{DeleteFile: &deleteFile}, true},
+		{"images", "imagesDestroy", models.ImagesDestroyInput{DeleteFile: &deleteFile}, true},
+		{"gallery", "galleryDestroy", models.GalleryDestroyInput{DeleteFile: &deleteFile}, true},
+		{"gallery keep file", "galleryDestroy", models.GalleryDestroyInput{}, false},
+		{"other mutation", "sceneUpdate", models.SceneDestroyInput{DeleteFile: &deleteFile}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, deletesFiles(tt.field, map[string]interface{}{"input": tt.input}))
+		})
+	}
+}
+
+func TestAuthorizeFieldDeleteFile(t *testing.T) {
+	deleteFile := true
+	keepFile := false
+
+	resolver := func(ctx context.Context) (interface{}, error) {
+		return true, nil
+	}
+
+	tests := []struct {
+		name       string
+		role       models.UserRole
+		deleteFile *bool
+		wantErr    bool
+	}{
+		{"admin deletes file", models.UserRoleAdmin, &deleteFile, false},
+		{"editor deletes file", models.UserRoleEditor, &deleteFile, true},
+		{"editor keeps file", models.UserRoleEditor, &keepFile, false},
+		{"read only", models.UserRoleReadOnly, &keepFile, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := session.SetCurrentUser(context.Background(), &models.User{ID: 1, Role: tt.role})
+			ctx = graphql.WithFieldContext(ctx, &graphql.FieldContext{
+				Object: "Mutation",
+				Field: graphql.CollectedField{
+					Field: &ast.Field{Name: "sceneDestroy"},
+				},
+				Args: map[string]interface{}{
+					"input": models.SceneDestroyInput{DeleteFile: tt.deleteFile},
+				},
+			})
+
+			_, err := authorizeField(ctx, resolver)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("authorizeField() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/stashapp/stash/internal/api/urlbuilders"
 	"github.com/stashapp/stash/internal/manager"
 	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
 	"github.com/stashapp/stash/pkg/utils"
 )
 
@@ -140,7 +141,7 @@ func (r *sceneResolver) Paths(ctx context.Context, obj *models.Scene) (*ScenePat
 	builder := urlbuilders.NewSceneURLBuilder(baseURL, obj)
 	screenshotPath := builder.GetScreenshotURL()
 	previewPath := builder.GetStreamPreviewURL()
-	streamPath := builder.GetStreamURL(config.GetAPIKey()).String()
+	streamPath := builder.GetStreamURL(session.GetCurrentAPIKeyValue(ctx)).String()
 	webpPath := builder.GetStreamPreviewImageURL()
 	objHash := obj.GetHash(config.GetVideoFileNamingAlgorithm())
 	vttPath := builder.GetSpriteVTTURL(objHash)
@@ -148,7 +149,10 @@ func (r *sceneResolver) Paths(ctx context.Context, obj *models.Scene) (*ScenePat
 	chaptersVttPath := builder.GetChaptersVTTURL()
 	funscriptPath := builder.GetFunscriptURL()
 	captionBasePath := builder.GetCaptionURL()
+	subtitleBasePath := builder.GetSubtitleURL()
 	interactiveHeatmap := builder.GetInteractiveHeatmapURL()
+	trickplayBIFPath := builder.GetTrickplayBIFURL()
+	trickplayManifestPath := builder.GetTrickplayManifestURL()
 
 	return &ScenePathsType{
 		Screenshot:         &screenshotPath,
@@ -161,6 +165,9 @@ func (r *sceneResolver) Paths(ctx context.Context, obj *models.Scene) (*ScenePat
 		Funscript:          &funscriptPath,
 		InteractiveHeatmap: &interactiveHeatmap,
 		Caption:            &captionBasePath,
+		Subtitle:           &subtitleBasePath,
+		TrickplayBif:       &trickplayBIFPath,
+		TrickplayManifest:  &trickplayManifestPath,
 	}, nil
 }
 
@@ -175,6 +182,23 @@ func (r *sceneResolver) SceneMarkers(ctx context.Context, obj *models.Scene) (re
 	return ret, nil
 }
 
+func (r *sceneResolver) PlayHistory(ctx context.Context, obj *models.Scene) (ret []*time.Time, err error) {
+	var history []time.Time
+	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
+		history, err = r.repository.Scene.GetPlayHistory(ctx, obj.ID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	ret = make([]*time.Time, len(history))
+	for i := range history {
+		ret[i] = &history[i]
+	}
+
+	return ret, nil
+}
+
 func (r *sceneResolver) Captions(ctx context.Context, obj *models.Scene) (ret []*models.VideoCaption, err error) {
 	primaryFile, err := r.getPrimaryFile(ctx, obj)
 	if err != nil {
@@ -194,6 +218,34 @@ func (r *sceneResolver) Captions(ctx context.Context, obj *models.Scene) (ret []
 	return ret, err
 }
 
+func (r *sceneResolver) getStreams(ctx context.Context, obj *models.Scene, streamType models.VideoStreamType) ([]*models.VideoStream, error) {
+	primaryFile, err := r.getPrimaryFile(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	if primaryFile == nil {
+		return nil, nil
+	}
+
+	var streams []*models.VideoStream
+	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
+		streams, err = r.repository.File.GetStreams(ctx, primaryFile.Base().ID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return models.FilterVideoStreams(streams, streamType), nil
+}
+
+func (r *sceneResolver) AudioStreams(ctx context.Context, obj *models.Scene) ([]*models.VideoStream, error) {
+	return r.getStreams(ctx, obj, models.VideoStreamTypeAudio)
+}
+
+func (r *sceneResolver) SubtitleStreams(ctx context.Context, obj *models.Scene) ([]*models.VideoStream, error) {
+	return r.getStreams(ctx, obj, models.VideoStreamTypeSubtitle)
+}
+
 func (r *sceneResolver) Galleries(ctx context.Context, obj *models.Scene) (ret []*models.Gallery, err error) {
 	if !obj.GalleryIDs.Loaded() {
 		if err := r.withReadTxn(ctx, func(ctx context.Context) error {
@@ -309,20 +361,31 @@ func (r *sceneResolver) Phash(ctx context.Context, obj *models.Scene) (*string,
 	return nil, nil
 }
 
-func (r *sceneResolver) SceneStreams(ctx context.Context, obj *models.Scene) ([]*manager.SceneStreamEndpoint, error) {
+func (r *sceneResolver) SceneStreams(ctx context.Context, obj *models.Scene, profile *string) ([]*manager.SceneStreamEndpoint, error) {
 	// load the primary file into the scene
 	_, err := r.getPrimaryFile(ctx, obj)
 	if err != nil {
 		return nil, err
 	}
 
+	audioStreams, err := r.getStreams(ctx, obj, models.VideoStreamTypeAudio)
+	if err != nil {
+		return nil, err
+	}
+
 	config := manager.GetInstance().Config
 
 	baseURL, _ := ctx.Value(BaseURLCtxKey).(string)
 	builder := urlbuilders.NewSceneURLBuilder(baseURL, obj)
-	apiKey := config.GetAPIKey()
+	// external players authenticate streams with the key of the request
+	apiKey := session.GetCurrentAPIKeyValue(ctx)
+
+	deviceProfile, err := getDeviceProfile(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
 
-	return manager.GetSceneStreamPaths(obj, builder.GetStreamURL(apiKey), config.GetMaxStreamingTranscodeSize())
+	return manager.GetSceneStreamPaths(obj, builder.GetStreamURL(apiKey), config.GetMaxStreamingTranscodeSize(), audioStreams, deviceProfile)
 }
 
 func (r *sceneResolver) Interactive(ctx context.Context, obj *models.Scene) (bool, error) {
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"context"
+
+	"github.com/stashapp/stash/internal/api/loaders"
+	"github.com/stashapp/stash/pkg/models"
+)
+
+func (r *contentRestrictionsResolver) ExcludedTags(ctx context.Context, obj *models.ContentRestrictions) (ret []*models.Tag, err error) {
+	var errs []error
+	ret, errs = loaders.From(ctx).TagByID.LoadAll(obj.ExcludedTagIDs)
+	return ret, firstError(errs)
+}
+
+func (r *contentRestrictionsResolver) ExcludedStudios(ctx context.Context, obj *models.ContentRestrictions) (ret []*models.Studio, err error) {
+	var errs []error
+	ret, errs = loaders.From(ctx).StudioByID.LoadAll(obj.ExcludedStudioIDs)
+	return ret, firstError(errs)
+}
+
+func (r *contentRestrictionsResolver) ExcludedPerformers(ctx context.Context, obj *models.ContentRestrictions) (ret []*models.Performer, err error) {
+	var errs []error
+	ret, errs = loaders.From(ctx).PerformerByID.LoadAll(obj.ExcludedPerformerIDs)
+	return ret, firstError(errs)
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+	"github.com/stashapp/stash/pkg/user"
+)
+
+// defaultAPIKeyName is the name of the API key managed by generateAPIKey.
+const defaultAPIKeyName = "default"
+
+func (r *mutationResolver) APIKeyCreate(ctx context.Context, input APIKeyCreateInput) (*APIKeyCreateResult, error) {
+	currentUserID := session.GetCurrentUserID(ctx)
+	if currentUserID == nil {
+		return nil, errNotLoggedIn
+	}
+
+	key, newKey, err := user.NewAPIKey(*currentUserID, input.Name, input.Scopes, input.ExpiresAt, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.withTxn(ctx, func(ctx context.Context) error {
+		return r.repository.APIKey.Create(ctx, newKey)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &APIKeyCreateResult{
+		APIKey: newKey,
+		Key:    key,
+	}, nil
+}
+
+func (r *mutationResolver) APIKeyRevoke(ctx context.Context, id string) (bool, error) {
+	currentUserID := session.GetCurrentUserID(ctx)
+	if currentUserID == nil {
+		return false, errNotLoggedIn
+	}
+
+	keyID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, fmt.Errorf("converting id: %w", err)
+	}
+
+	if err := r.withTxn(ctx, func(ctx context.Context) error {
+		qb := r.repository.APIKey
+
+		k, err := qb.Find(ctx, keyID)
+		if err != nil {
+			return err
+		}
+
+		// don't reveal the keys of other users
+		if k == nil || (k.UserID != *currentUserID && !session.HasRole(ctx, models.UserRoleAdmin)) {
+			return fmt.Errorf("API key with id %d not found", keyID)
+		}
+
+		return qb.Destroy(ctx, keyID)
+	}); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GenerateAPIKey replaces the default API key of the current user with a new
+// key with the admin scope, or removes it if clear is set.
+func (r *mutationResolver) GenerateAPIKey(ctx context.Context, input GenerateAPIKeyInput) (string, error) {
+	currentUserID := session.GetCurrentUserID(ctx)
+	if currentUserID == nil {
+		return "", errNotLoggedIn
+	}
+
+	var key string
+	var newKey *models.APIKey
+	if input.Clear == nil || !*input.Clear {
+		var err error
+		key, newKey, err = user.NewAPIKey(*currentUserID, defaultAPIKeyName, []models.APIKeyScope{models.APIKeyScopeAdmin}, nil, time.Now())
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := r.withTxn(ctx, func(ctx context.Context) error {
+		qb := r.repository.APIKey
+
+		existing, err := qb.FindByUserID(ctx, *currentUserID)
+		if err != nil {
+			return err
+		}
+
+		for _, k := range existing {
+			if k.Name == defaultAPIKeyName {
+				if err := qb.Destroy(ctx, k.ID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if newKey != nil {
+			return qb.Create(ctx, newKey)
+		}
+
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
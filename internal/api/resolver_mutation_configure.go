@@ -203,6 +203,19 @@ func (r *mutationResolver) ConfigureGeneral(ctx context.Context, input ConfigGen
 		c.Set(config.PreviewPreset, input.PreviewPreset.String())
 	}
 
+	if input.TrickplayInterval != nil {
+		if *input.TrickplayInterval <= 0 {
+			return makeConfigGeneralResult(), fmt.Errorf("trickplay interval must be positive")
+		}
+		c.Set(config.TrickplayInterval, *input.TrickplayInterval)
+	}
+	if input.TrickplayWidth != nil {
+		if *input.TrickplayWidth <= 0 {
+			return makeConfigGeneralResult(), fmt.Errorf("trickplay width must be positive")
+		}
+		c.Set(config.TrickplayWidth, *input.TrickplayWidth)
+	}
+
 	if input.TranscodeHardwareAcceleration != nil {
 		c.Set(config.TranscodeHardwareAcceleration, *input.TranscodeHardwareAcceleration)
 	}
@@ -232,27 +245,9 @@ func (r *mutationResolver) ConfigureGeneral(ctx context.Context, input ConfigGen
 		c.Set(config.GalleryCoverRegex, *input.GalleryCoverRegex)
 	}
 
-	if input.Username != nil && *input.Username != c.GetUsername() {
-		c.Set(config.Username, input.Username)
-		if *input.Password == "" {
-			logger.Info("Username cleared")
-		} else {
-			logger.Info("Username changed")
-		}
-	}
-
-	if input.Password != nil {
-		// bit of a hack - check if the passed in password is the same as the stored hash
-		// and only set if they are different
-		currentPWHash := c.GetPasswordHash()
-
-		if *input.Password != currentPWHash {
-			if *input.Password == "" {
-				logger.Info("Password cleared")
-			} else {
-				logger.Info("Password changed")
-			}
-			c.SetPassword(*input.Password)
+	if input.Username != nil || input.Password != nil {
+		if err := r.setConfigCredentials(ctx, input.Username, input.Password); err != nil {
+			return makeConfigGeneralResult(), err
 		}
 	}
 
@@ -356,6 +351,42 @@ func (r *mutationResolver) ConfigureGeneral(ctx context.Context, input ConfigGen
 	if input.LiveTranscodeOutputArgs != nil {
 		c.Set(config.LiveTranscodeOutputArgs, input.LiveTranscodeOutputArgs)
 	}
+	if input.LiveTranscodeCacheSize != nil {
+		if *input.LiveTranscodeCacheSize < 0 {
+			return makeConfigGeneralResult(), fmt.Errorf("live transcode cache size must not be negative")
+		}
+		c.Set(config.LiveTranscodeCacheSize, *input.LiveTranscodeCacheSize)
+	}
+	if input.MaxLiveHardwareTranscodes != nil {
+		if *input.MaxLiveHardwareTranscodes < 0 {
+			return makeConfigGeneralResult(), fmt.Errorf("max live hardware transcodes must not be negative")
+		}
+		c.Set(config.MaxLiveHardwareTranscodes, *input.MaxLiveHardwareTranscodes)
+	}
+	if input.MaxLiveSoftwareTranscodes != nil {
+		if *input.MaxLiveSoftwareTranscodes < 0 {
+			return makeConfigGeneralResult(), fmt.Errorf("max live software transcodes must not be negative")
+		}
+		c.Set(config.MaxLiveSoftwareTranscodes, *input.MaxLiveSoftwareTranscodes)
+	}
+	if input.DeviceProfiles != nil {
+		if err := c.ValidateDeviceProfiles(input.DeviceProfiles); err != nil {
+			return makeConfigGeneralResult(), err
+		}
+		c.Set(config.DeviceProfiles, input.DeviceProfiles)
+	}
+	if input.NormaliseLoudness != nil {
+		c.Set(config.NormaliseLoudness, *input.NormaliseLoudness)
+	}
+	if input.LoudnessTarget != nil {
+		if *input.LoudnessTarget >= 0 {
+			return makeConfigGeneralResult(), fmt.Errorf("loudness target must be negative")
+		}
+		c.Set(config.LoudnessTarget, *input.LoudnessTarget)
+	}
+	if input.CropTranscodes != nil {
+		c.Set(config.CropTranscodes, *input.CropTranscodes)
+	}
 
 	if input.DrawFunscriptHeatmapRange != nil {
 		c.Set(config.DrawFunscriptHeatmapRange, input.DrawFunscriptHeatmapRange)
@@ -366,6 +397,12 @@ func (r *mutationResolver) ConfigureGeneral(ctx context.Context, input ConfigGen
 	}
 
 	manager.GetInstance().RefreshConfig()
+
+	// move any new credentials into an admin user
+	if err := manager.GetInstance().MigrateConfigCredentials(ctx); err != nil {
+		return makeConfigGeneralResult(), err
+	}
+
 	if refreshScraperCache {
 		manager.GetInstance().RefreshScraperCache()
 	}
@@ -606,27 +643,53 @@ func (r *mutationResolver) ConfigureDefaults(ctx context.Context, input ConfigDe
 	return makeConfigDefaultsResult(), nil
 }
 
-func (r *mutationResolver) GenerateAPIKey(ctx context.Context, input GenerateAPIKeyInput) (string, error) {
+// setConfigCredentials sets the username and password in the configuration.
+// These are only used before any users are created, after which the
+// credentials are migrated to an admin user.
+func (r *mutationResolver) setConfigCredentials(ctx context.Context, username *string, password *string) error {
 	c := config.GetInstance()
 
-	var newAPIKey string
-	if input.Clear == nil || !*input.Clear {
-		username := c.GetUsername()
-		if username != "" {
-			var err error
-			newAPIKey, err = manager.GenerateAPIKey(username)
-			if err != nil {
-				return "", err
-			}
+	usernameChanged := username != nil && *username != c.GetUsername()
+	// bit of a hack - check if the passed in password is the same as the stored hash
+	// and only set if they are different
+	passwordChanged := password != nil && *password != c.GetPasswordHash()
+
+	if !usernameChanged && !passwordChanged {
+		return nil
+	}
+
+	var userCount int
+	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
+		var err error
+		userCount, err = r.repository.User.Count(ctx)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if userCount > 0 {
+		return errors.New("credentials are managed by user management once users exist")
+	}
+
+	if usernameChanged {
+		c.Set(config.Username, username)
+		if *username == "" {
+			logger.Info("Username cleared")
+		} else {
+			logger.Info("Username changed")
 		}
 	}
 
-	c.Set(config.ApiKey, newAPIKey)
-	if err := c.Write(); err != nil {
-		return newAPIKey, err
+	if passwordChanged {
+		if *password == "" {
+			logger.Info("Password cleared")
+		} else {
+			logger.Info("Password changed")
+		}
+		c.SetPassword(*password)
 	}
 
-	return newAPIKey, nil
+	return nil
 }
 
 func (r *mutationResolver) ConfigureUI(ctx context.Context, input map[string]interface{}) (map[string]interface{}, error) {
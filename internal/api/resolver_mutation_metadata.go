@@ -74,6 +74,15 @@ func (r *mutationResolver) ExportObjects(ctx context.Context, input manager.Expo
 	return nil, nil
 }
 
+func (r *mutationResolver) ExportSceneClips(ctx context.Context, input manager.ExportSceneClipsInput) (string, error) {
+	jobID, err := manager.GetInstance().ExportSceneClips(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(jobID), nil
+}
+
 func (r *mutationResolver) MetadataGenerate(ctx context.Context, input manager.GenerateMetadataInput) (string, error) {
 	jobID, err := manager.GetInstance().Generate(ctx, input)
 
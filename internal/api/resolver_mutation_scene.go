@@ -637,6 +637,67 @@ func (r *mutationResolver) SceneMarkerCreate(ctx context.Context, input SceneMar
 	return r.getSceneMarker(ctx, newMarker.ID)
 }
 
+func (r *mutationResolver) SceneMarkersAcceptSuggested(ctx context.Context, input SceneMarkersAcceptSuggestedInput) ([]*models.SceneMarker, error) {
+	sceneID, err := strconv.Atoi(input.SceneID)
+	if err != nil {
+		return nil, fmt.Errorf("converting scene id: %w", err)
+	}
+
+	primaryTagID, err := strconv.Atoi(input.PrimaryTagID)
+	if err != nil {
+		return nil, fmt.Errorf("converting primary tag id: %w", err)
+	}
+
+	tagIDs, err := stringslice.StringSliceToIntSlice(input.TagIds)
+	if err != nil {
+		return nil, fmt.Errorf("converting tag ids: %w", err)
+	}
+	// If this tag is the primary tag, then let's not add it.
+	tagIDs = intslice.IntExclude(tagIDs, []int{primaryTagID})
+
+	var ids []int
+	if err := r.withTxn(ctx, func(ctx context.Context) error {
+		qb := r.repository.SceneMarker
+
+		for _, seconds := range input.Seconds {
+			newMarker := models.NewSceneMarker()
+			if input.Title != nil {
+				newMarker.Title = *input.Title
+			}
+			newMarker.Seconds = seconds
+			newMarker.PrimaryTagID = primaryTagID
+			newMarker.SceneID = sceneID
+
+			if err := qb.Create(ctx, &newMarker); err != nil {
+				return err
+			}
+
+			if err := qb.UpdateTags(ctx, newMarker.ID, tagIDs); err != nil {
+				return err
+			}
+
+			ids = append(ids, newMarker.ID)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*models.SceneMarker, 0, len(ids))
+	for _, id := range ids {
+		r.hookExecutor.ExecutePostHooks(ctx, id, plugin.SceneMarkerCreatePost, input, nil)
+
+		m, err := r.getSceneMarker(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, m)
+	}
+
+	return ret, nil
+}
+
 func (r *mutationResolver) SceneMarkerUpdate(ctx context.Context, input SceneMarkerUpdateInput) (*models.SceneMarker, error) {
 	markerID, err := strconv.Atoi(input.ID)
 	if err != nil {
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stashapp/stash/internal/manager"
+	"github.com/stashapp/stash/pkg/sliceutil/stringslice"
+)
+
+func (r *mutationResolver) PurgeTranscodeCache(ctx context.Context, sceneIds []string) (bool, error) {
+	streamManager := manager.GetInstance().StreamManager
+
+	if sceneIds == nil {
+		if err := streamManager.PurgeCache(""); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	ids, err := stringslice.StringSliceToIntSlice(sceneIds)
+	if err != nil {
+		return false, fmt.Errorf("converting ids: %w", err)
+	}
+
+	fileNamingAlgo := manager.GetInstance().Config.GetVideoFileNamingAlgorithm()
+
+	var hashes []string
+	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
+		scenes, err := r.repository.Scene.FindMany(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range scenes {
+			if hash := s.GetHash(fileNamingAlgo); hash != "" {
+				hashes = append(hashes, hash)
+			}
+		}
+		return nil
+	}); err != nil {
+		return false, err
+	}
+
+	for _, hash := range hashes {
+		if err := streamManager.PurgeCache(hash); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) StopStreamSession(ctx context.Context, id string) (bool, error) {
+	return manager.GetInstance().StreamManager.StopSession(id), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"github.com/stashapp/stash/internal/manager"
 	"github.com/stashapp/stash/pkg/logger"
 	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/plugin"
@@ -340,6 +341,9 @@ func (r *mutationResolver) TagsMerge(ctx context.Context, input TagsMergeInput)
 		return nil, err
 	}
 
+	// merging moves the excluded tags of users to the destination
+	manager.GetInstance().InvalidateUserCache()
+
 	r.hookExecutor.ExecutePostHooks(ctx, t.ID, plugin.TagMergePost, input, nil)
 
 	return t, nil
@@ -0,0 +1,247 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stashapp/stash/internal/manager"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+	"github.com/stashapp/stash/pkg/sliceutil/stringslice"
+	"github.com/stashapp/stash/pkg/user"
+)
+
+var (
+	errNotLoggedIn            = errors.New("not logged in")
+	errInvalidCurrentPassword = errors.New("current password is incorrect")
+)
+
+// contentRestrictionsFromInput converts the restrictions input to
+// content restrictions. Returns empty restrictions if input is nil.
+func contentRestrictionsFromInput(input *ContentRestrictionsInput) (models.ContentRestrictions, error) {
+	var ret models.ContentRestrictions
+	if input == nil {
+		return ret, nil
+	}
+
+	var err error
+	ret.ExcludedTagIDs, err = stringslice.StringSliceToIntSlice(input.ExcludedTagIds)
+	if err != nil {
+		return ret, fmt.Errorf("converting excluded tag ids: %w", err)
+	}
+
+	ret.ExcludedStudioIDs, err = stringslice.StringSliceToIntSlice(input.ExcludedStudioIds)
+	if err != nil {
+		return ret, fmt.Errorf("converting excluded studio ids: %w", err)
+	}
+
+	ret.ExcludedPerformerIDs, err = stringslice.StringSliceToIntSlice(input.ExcludedPerformerIds)
+	if err != nil {
+		return ret, fmt.Errorf("converting excluded performer ids: %w", err)
+	}
+
+	for _, p := range input.ExcludedPaths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		if !filepath.IsAbs(p) {
+			return ret, fmt.Errorf("excluded path %q is not absolute", p)
+		}
+
+		ret.ExcludedPaths = stringslice.StrAppendUnique(ret.ExcludedPaths, filepath.Clean(p))
+	}
+
+	return ret, nil
+}
+
+func (r *mutationResolver) UserCreate(ctx context.Context, input UserCreateInput) (*models.User, error) {
+	if !input.Role.IsValid() {
+		return nil, fmt.Errorf("invalid role: %s", input.Role)
+	}
+
+	restrictions, err := contentRestrictionsFromInput(input.Restrictions)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := user.HashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	newUser := models.User{
+		Username:     strings.TrimSpace(input.Username),
+		PasswordHash: passwordHash,
+		Role:         input.Role,
+		Restrictions: restrictions,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := r.withTxn(ctx, func(ctx context.Context) error {
+		qb := r.repository.User
+
+		if err := user.EnsureUsernameUnique(ctx, 0, newUser.Username, qb); err != nil {
+			return err
+		}
+
+		return user.Create(ctx, &newUser, qb, r.repository.Scene)
+	}); err != nil {
+		return nil, err
+	}
+
+	manager.GetInstance().InvalidateUserCache()
+
+	return &newUser, nil
+}
+
+func (r *mutationResolver) UserUpdate(ctx context.Context, input UserUpdateInput) (*models.User, error) {
+	userID, err := strconv.Atoi(input.ID)
+	if err != nil {
+		return nil, fmt.Errorf("converting id: %w", err)
+	}
+
+	if input.Role != nil && !input.Role.IsValid() {
+		return nil, fmt.Errorf("invalid role: %s", *input.Role)
+	}
+
+	restrictions, err := contentRestrictionsFromInput(input.Restrictions)
+	if err != nil {
+		return nil, err
+	}
+
+	var passwordHash string
+	if input.Password != nil {
+		passwordHash, err = user.HashPassword(*input.Password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var ret *models.User
+	if err := r.withTxn(ctx, func(ctx context.Context) error {
+		qb := r.repository.User
+
+		ret, err = qb.Find(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if ret == nil {
+			return fmt.Errorf("user with id %d not found", userID)
+		}
+
+		if input.Username != nil {
+			username := strings.TrimSpace(*input.Username)
+			if err := user.EnsureUsernameUnique(ctx, userID, username, qb); err != nil {
+				return err
+			}
+			ret.Username = username
+		}
+
+		if input.Role != nil && *input.Role != ret.Role {
+			if err := user.EnsureAdminRemains(ctx, ret, qb); err != nil {
+				return err
+			}
+			ret.Role = *input.Role
+		}
+
+		if passwordHash != "" {
+			ret.PasswordHash = passwordHash
+		}
+
+		if input.Restrictions != nil {
+			ret.Restrictions = restrictions
+		}
+
+		ret.UpdatedAt = time.Now()
+
+		return qb.Update(ctx, ret)
+	}); err != nil {
+		return nil, err
+	}
+
+	manager.GetInstance().InvalidateUserCache()
+
+	return ret, nil
+}
+
+func (r *mutationResolver) UserDestroy(ctx context.Context, id string) (bool, error) {
+	userID, err := strconv.Atoi(id)
+	if err != nil {
+		return false, fmt.Errorf("converting id: %w", err)
+	}
+
+	if err := r.withTxn(ctx, func(ctx context.Context) error {
+		qb := r.repository.User
+
+		u, err := qb.Find(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		if u == nil {
+			return fmt.Errorf("user with id %d not found", userID)
+		}
+
+		if err := user.EnsureAdminRemains(ctx, u, qb); err != nil {
+			return err
+		}
+
+		return qb.Destroy(ctx, userID)
+	}); err != nil {
+		return false, err
+	}
+
+	manager.GetInstance().InvalidateUserCache()
+
+	return true, nil
+}
+
+func (r *mutationResolver) ChangePassword(ctx context.Context, input ChangePasswordInput) (bool, error) {
+	currentUserID := session.GetCurrentUserID(ctx)
+	if currentUserID == nil {
+		return false, errNotLoggedIn
+	}
+
+	passwordHash, err := user.HashPassword(input.NewPassword)
+	if err != nil {
+		return false, err
+	}
+
+	if err := r.withTxn(ctx, func(ctx context.Context) error {
+		qb := r.repository.User
+
+		u, err := qb.Find(ctx, *currentUserID)
+		if err != nil {
+			return err
+		}
+
+		if u == nil {
+			return fmt.Errorf("user with id %d not found", *currentUserID)
+		}
+
+		if !user.CheckPassword(u, input.CurrentPassword) {
+			return errInvalidCurrentPassword
+		}
+
+		u.PasswordHash = passwordHash
+		u.UpdatedAt = time.Now()
+
+		return qb.Update(ctx, u)
+	}); err != nil {
+		return false, err
+	}
+
+	manager.GetInstance().InvalidateUserCache()
+
+	return true, nil
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+)
+
+func (r *queryResolver) APIKeys(ctx context.Context, userID *string) (ret []*models.APIKey, err error) {
+	currentUserID := session.GetCurrentUserID(ctx)
+	if currentUserID == nil {
+		return nil, errNotLoggedIn
+	}
+
+	id := *currentUserID
+	if userID != nil {
+		id, err = strconv.Atoi(*userID)
+		if err != nil {
+			return nil, fmt.Errorf("converting user id: %w", err)
+		}
+
+		if id != *currentUserID && !session.HasRole(ctx, models.UserRoleAdmin) {
+			return nil, errForbidden
+		}
+	}
+
+	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
+		ret, err = r.repository.APIKey.FindByUserID(ctx, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
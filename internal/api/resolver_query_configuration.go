@@ -10,11 +10,34 @@ import (
 	"github.com/stashapp/stash/pkg/fsutil"
 	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/scraper/stashbox"
+	"github.com/stashapp/stash/pkg/session"
 	"golang.org/x/text/collate"
 )
 
 func (r *queryResolver) Configuration(ctx context.Context) (*ConfigResult, error) {
-	return makeConfigResult(), nil
+	ret := makeConfigResult()
+
+	if !session.HasRole(ctx, models.UserRoleAdmin) {
+		redactConfigResult(ret)
+	}
+
+	return ret, nil
+}
+
+// redactConfigResult removes credentials from the configuration, for users
+// which may not change it.
+func redactConfigResult(ret *ConfigResult) {
+	general := ret.General
+	general.APIKey = ""
+	general.Password = ""
+
+	var stashBoxes []*models.StashBox
+	for _, box := range general.StashBoxes {
+		redacted := *box
+		redacted.APIKey = ""
+		stashBoxes = append(stashBoxes, &redacted)
+	}
+	general.StashBoxes = stashBoxes
 }
 
 func (r *queryResolver) Directory(ctx context.Context, path, locale *string) (*Directory, error) {
@@ -102,6 +125,8 @@ func makeConfigGeneralResult() *ConfigGeneralResult {
 		PreviewExcludeStart:           config.GetPreviewExcludeStart(),
 		PreviewExcludeEnd:             config.GetPreviewExcludeEnd(),
 		PreviewPreset:                 config.GetPreviewPreset(),
+		TrickplayInterval:             config.GetTrickplayInterval(),
+		TrickplayWidth:                config.GetTrickplayWidth(),
 		TranscodeHardwareAcceleration: config.GetTranscodeHardwareAcceleration(),
 		MaxTranscodeSize:              &maxTranscodeSize,
 		MaxStreamingTranscodeSize:     &maxStreamingTranscodeSize,
@@ -132,6 +157,13 @@ func makeConfigGeneralResult() *ConfigGeneralResult {
 		TranscodeOutputArgs:           config.GetTranscodeOutputArgs(),
 		LiveTranscodeInputArgs:        config.GetLiveTranscodeInputArgs(),
 		LiveTranscodeOutputArgs:       config.GetLiveTranscodeOutputArgs(),
+		LiveTranscodeCacheSize:        config.GetLiveTranscodeCacheSize(),
+		MaxLiveHardwareTranscodes:     config.GetMaxLiveHardwareTranscodes(),
+		MaxLiveSoftwareTranscodes:     config.GetMaxLiveSoftwareTranscodes(),
+		DeviceProfiles:                config.GetDeviceProfiles(),
+		NormaliseLoudness:             config.GetNormaliseLoudness(),
+		LoudnessTarget:                config.GetLoudnessTarget(),
+		CropTranscodes:                config.GetCropTranscodes(),
 		DrawFunscriptHeatmapRange:     config.GetDrawFunscriptHeatmapRange(),
 	}
 }
@@ -8,6 +8,7 @@ import (
 
 	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/scene"
+	"github.com/stashapp/stash/pkg/sliceutil/intslice"
 	"github.com/stashapp/stash/pkg/sliceutil/stringslice"
 )
 
@@ -248,3 +249,44 @@ func (r *queryResolver) AllScenes(ctx context.Context) (ret []*models.Scene, err
 
 	return ret, nil
 }
+
+const defaultPlayHistoryLimit = 25
+
+func (r *queryResolver) PlayHistory(ctx context.Context, limit *int) (ret []*PlayHistoryEntry, err error) {
+	n := defaultPlayHistoryLimit
+	if limit != nil {
+		n = *limit
+	}
+
+	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
+		qb := r.repository.Scene
+		plays, err := qb.FindPlayHistory(ctx, n)
+		if err != nil {
+			return err
+		}
+
+		var sceneIDs []int
+		for _, p := range plays {
+			sceneIDs = intslice.IntAppendUnique(sceneIDs, p.SceneID)
+		}
+
+		scenes, err := qb.FindMany(ctx, sceneIDs)
+		if err != nil {
+			return err
+		}
+
+		ret = make([]*PlayHistoryEntry, len(plays))
+		for i, p := range plays {
+			ret[i] = &PlayHistoryEntry{
+				Scene:    scenes[intslice.IntIndex(sceneIDs, p.SceneID)],
+				PlayedAt: p.PlayedAt,
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -2,8 +2,20 @@ package api
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 
+	"github.com/stashapp/stash/internal/manager"
 	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/scene/generate"
+)
+
+const (
+	suggestedMarkerMinScoreDefault = 0.5
+
+	// suggestedMarkerMinGap is the minimum number of seconds between
+	// suggested markers and existing markers.
+	suggestedMarkerMinGap = 30
 )
 
 func (r *queryResolver) FindSceneMarkers(ctx context.Context, sceneMarkerFilter *models.SceneMarkerFilterType, filter *models.FindFilterType) (ret *FindSceneMarkersResultType, err error) {
@@ -35,3 +47,60 @@ func (r *queryResolver) AllSceneMarkers(ctx context.Context) (ret []*models.Scen
 
 	return ret, nil
 }
+
+func (r *queryResolver) SceneSuggestedMarkers(ctx context.Context, sceneID string, minScore *float64) ([]*SuggestedSceneMarker, error) {
+	id, err := strconv.Atoi(sceneID)
+	if err != nil {
+		return nil, fmt.Errorf("converting scene id: %w", err)
+	}
+
+	var scene *models.Scene
+	var existing []float64
+	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
+		scene, err = r.repository.Scene.Find(ctx, id)
+		if err != nil {
+			return err
+		}
+		if scene == nil {
+			return fmt.Errorf("scene with id %d not found", id)
+		}
+
+		markers, err := r.repository.SceneMarker.FindBySceneID(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, m := range markers {
+			existing = append(existing, m.Seconds)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	mgr := manager.GetInstance()
+	sceneHash := scene.GetHash(mgr.Config.GetVideoFileNamingAlgorithm())
+	if sceneHash == "" {
+		return []*SuggestedSceneMarker{}, nil
+	}
+
+	cuts, err := generate.ReadSceneCuts(mgr.Paths.Scene.GetSceneCutsPath(sceneHash))
+	if err != nil {
+		return nil, err
+	}
+
+	score := suggestedMarkerMinScoreDefault
+	if minScore != nil {
+		score = *minScore
+	}
+
+	ret := []*SuggestedSceneMarker{}
+	for _, c := range cuts.Suggested(score, suggestedMarkerMinGap, existing) {
+		ret = append(ret, &SuggestedSceneMarker{
+			Seconds: c.Time,
+			Score:   c.Score,
+		})
+	}
+
+	return ret, nil
+}
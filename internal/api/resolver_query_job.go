@@ -32,6 +32,22 @@ func (r *queryResolver) FindJob(ctx context.Context, input FindJobInput) (*Job,
 	return jobToJobModel(*j), nil
 }
 
+func (r *queryResolver) SceneClipExport(ctx context.Context, jobID string) (*string, error) {
+	id, err := strconv.Atoi(jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	path := manager.GetInstance().GetSceneClipExport(ctx, id)
+	if path == nil {
+		return nil, nil
+	}
+
+	baseURL, _ := ctx.Value(BaseURLCtxKey).(string)
+	ret := baseURL + "/downloads/" + *path
+	return &ret, nil
+}
+
 func jobToJobModel(j job.Job) *Job {
 	ret := &Job{
 		ID:          strconv.Itoa(j.ID),
@@ -8,9 +8,10 @@ import (
 	"github.com/stashapp/stash/internal/api/urlbuilders"
 	"github.com/stashapp/stash/internal/manager"
 	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
 )
 
-func (r *queryResolver) SceneStreams(ctx context.Context, id *string) ([]*manager.SceneStreamEndpoint, error) {
+func (r *queryResolver) SceneStreams(ctx context.Context, id *string, profile *string) ([]*manager.SceneStreamEndpoint, error) {
 	sceneID, err := strconv.Atoi(*id)
 	if err != nil {
 		return nil, err
@@ -18,15 +19,28 @@ func (r *queryResolver) SceneStreams(ctx context.Context, id *string) ([]*manage
 
 	// find the scene
 	var scene *models.Scene
+	var audioStreams []*models.VideoStream
 	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
 		var err error
 		scene, err = r.repository.Scene.Find(ctx, sceneID)
+		if err != nil || scene == nil {
+			return err
+		}
+
+		if err := scene.LoadPrimaryFile(ctx, r.repository.File); err != nil {
+			return err
+		}
+
+		if pf := scene.Files.Primary(); pf != nil {
+			streams, err := r.repository.File.GetStreams(ctx, pf.ID)
+			if err != nil {
+				return err
+			}
 
-		if scene != nil {
-			err = scene.LoadPrimaryFile(ctx, r.repository.File)
+			audioStreams = models.FilterVideoStreams(streams, models.VideoStreamTypeAudio)
 		}
 
-		return err
+		return nil
 	}); err != nil {
 		return nil, err
 	}
@@ -39,7 +53,31 @@ func (r *queryResolver) SceneStreams(ctx context.Context, id *string) ([]*manage
 
 	baseURL, _ := ctx.Value(BaseURLCtxKey).(string)
 	builder := urlbuilders.NewSceneURLBuilder(baseURL, scene)
-	apiKey := config.GetAPIKey()
+	// external players authenticate streams with the key of the request
+	apiKey := session.GetCurrentAPIKeyValue(ctx)
+
+	deviceProfile, err := getDeviceProfile(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return manager.GetSceneStreamPaths(scene, builder.GetStreamURL(apiKey), config.GetMaxStreamingTranscodeSize(), audioStreams, deviceProfile)
+}
+
+// getDeviceProfile returns the device profile with the given name. If name
+// is nil or empty, the profile is matched from the User-Agent of the request.
+// Returns nil if no profile matches.
+func getDeviceProfile(ctx context.Context, name *string) (*models.DeviceProfile, error) {
+	profiles := manager.GetInstance().Config.GetDeviceProfiles()
+
+	if name != nil && *name != "" {
+		ret := profiles.Find(*name)
+		if ret == nil {
+			return nil, fmt.Errorf("device profile %q not found", *name)
+		}
+		return ret, nil
+	}
 
-	return manager.GetSceneStreamPaths(scene, builder.GetStreamURL(apiKey), config.GetMaxStreamingTranscodeSize())
+	userAgent, _ := ctx.Value(UserAgentCtxKey).(string)
+	return profiles.MatchUserAgent(userAgent), nil
 }
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/stashapp/stash/internal/manager"
+	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/models"
+)
+
+func (r *queryResolver) TranscodeCacheUsage(ctx context.Context) (*TranscodeCacheUsage, error) {
+	usage, err := manager.GetInstance().StreamManager.CacheUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &TranscodeCacheUsage{
+		MaxSize: usage.MaxSize,
+		Size:    usage.Size,
+		Scenes:  []*SceneTranscodeCache{},
+	}
+
+	// entries are ordered least recently accessed first
+	byHash := make(map[string]*SceneTranscodeCache)
+	for i := len(usage.Entries) - 1; i >= 0; i-- {
+		e := usage.Entries[i]
+
+		sc := byHash[e.Hash]
+		if sc == nil {
+			sc = &SceneTranscodeCache{
+				Hash: e.Hash,
+			}
+			byHash[e.Hash] = sc
+			ret.Scenes = append(ret.Scenes, sc)
+		}
+
+		sc.Size += e.Size
+		sc.Entries = append(sc.Entries, &TranscodeCacheEntry{
+			StreamType:   e.StreamType,
+			Resolution:   e.Resolution,
+			AudioTrack:   e.AudioTrack,
+			Size:         e.Size,
+			LastAccessed: e.LastAccessed,
+			Running:      e.Running,
+		})
+	}
+
+	// running streams may have been accessed more recently than their directory
+	sort.SliceStable(ret.Scenes, func(i, j int) bool {
+		return ret.Scenes[i].Entries[0].LastAccessed.After(ret.Scenes[j].Entries[0].LastAccessed)
+	})
+
+	fileNamingAlgo := manager.GetInstance().Config.GetVideoFileNamingAlgorithm()
+
+	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
+		for _, sc := range ret.Scenes {
+			scene, err := r.findSceneByHash(ctx, sc.Hash, fileNamingAlgo)
+			if err != nil {
+				return err
+			}
+			sc.Scene = scene
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (r *queryResolver) findSceneByHash(ctx context.Context, hash string, fileNamingAlgo models.HashAlgorithm) (*models.Scene, error) {
+	qb := r.repository.Scene
+
+	var scenes []*models.Scene
+	var err error
+	if fileNamingAlgo == models.HashAlgorithmMd5 {
+		scenes, err = qb.FindByChecksum(ctx, hash)
+	} else {
+		scenes, err = qb.FindByOSHash(ctx, hash)
+	}
+	if err != nil || len(scenes) == 0 {
+		return nil, err
+	}
+
+	return scenes[0], nil
+}
+
+func (r *queryResolver) StreamSessions(ctx context.Context) ([]*StreamSession, error) {
+	return r.makeStreamSessions(ctx, manager.GetInstance().StreamManager.Sessions())
+}
+
+func makeStreamSession(s ffmpeg.StreamSession, now time.Time) *StreamSession {
+	ret := &StreamSession{
+		ID:         s.ID,
+		StreamType: s.StreamType,
+		Resolution: s.Resolution,
+		AudioTrack: s.AudioTrack,
+		Segment:    s.Segment,
+		Hardware:   s.Hardware,
+		ClientIP:   s.ClientIP,
+		StartedAt:  s.Started,
+		Elapsed:    now.Sub(s.Started).Seconds(),
+	}
+
+	if s.PID != 0 {
+		pid := s.PID
+		ret.Pid = &pid
+	}
+	if s.Codec != "" {
+		codec := string(s.Codec)
+		ret.Codec = &codec
+	}
+
+	return ret
+}
+
+// makeStreamSessions converts sessions to their graphql model, loading the scene of each streamed file.
+func (r *Resolver) makeStreamSessions(ctx context.Context, sessions []ffmpeg.StreamSession) ([]*StreamSession, error) {
+	now := time.Now()
+
+	ret := make([]*StreamSession, len(sessions))
+	for i, s := range sessions {
+		ret[i] = makeStreamSession(s, now)
+	}
+
+	if err := r.withReadTxn(ctx, func(ctx context.Context) error {
+		for i, s := range sessions {
+			scenes, err := r.repository.Scene.FindByFileID(ctx, s.FileID)
+			if err != nil {
+				return err
+			}
+			if len(scenes) > 0 {
+				ret[i].Scene = scenes[0]
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
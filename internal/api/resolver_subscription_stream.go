@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+
+	"github.com/stashapp/stash/internal/manager"
+	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/logger"
+)
+
+var streamSessionEventTypes = map[ffmpeg.StreamSessionEventType]StreamSessionEventType{
+	ffmpeg.StreamSessionEventStart: StreamSessionEventTypeStart,
+	ffmpeg.StreamSessionEventStop:  StreamSessionEventTypeStop,
+}
+
+func (r *subscriptionResolver) StreamSessionsSubscribe(ctx context.Context) (<-chan *StreamSessionEvent, error) {
+	msg := make(chan *StreamSessionEvent, 100)
+
+	subscription := manager.GetInstance().StreamManager.SubscribeSessions(ctx)
+
+	go func() {
+		defer close(msg)
+
+		for e := range subscription {
+			sessions, err := r.makeStreamSessions(ctx, []ffmpeg.StreamSession{e.Session})
+			if err != nil {
+				logger.Errorf("error loading stream session: %v", err)
+				continue
+			}
+
+			select {
+			case msg <- &StreamSessionEvent{
+				Type:    streamSessionEventTypes[e.Type],
+				Session: sessions[0],
+			}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return msg, nil
+}
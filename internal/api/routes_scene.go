@@ -4,18 +4,24 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/stashapp/stash/internal/manager"
 	"github.com/stashapp/stash/internal/manager/config"
 	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/ffmpeg/transcoder"
 	"github.com/stashapp/stash/pkg/file/video"
 	"github.com/stashapp/stash/pkg/fsutil"
 	"github.com/stashapp/stash/pkg/logger"
 	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/scene/generate"
 	"github.com/stashapp/stash/pkg/txn"
 	"github.com/stashapp/stash/pkg/utils"
 )
@@ -42,11 +48,16 @@ type CaptionFinder interface {
 	GetCaptions(ctx context.Context, fileID models.FileID) ([]*models.VideoCaption, error)
 }
 
+type VideoStreamFinder interface {
+	GetStreams(ctx context.Context, fileID models.FileID) ([]*models.VideoStream, error)
+}
+
 type sceneRoutes struct {
 	txnManager        txn.Manager
 	sceneFinder       SceneFinder
 	fileGetter        models.FileGetter
 	captionFinder     CaptionFinder
+	streamFinder      VideoStreamFinder
 	sceneMarkerFinder SceneMarkerFinder
 	tagFinder         SceneMarkerTagFinder
 }
@@ -62,11 +73,24 @@ func (rs sceneRoutes) Routes() chi.Router {
 		r.Get("/stream.mp4", rs.StreamMp4)
 		r.Get("/stream.webm", rs.StreamWebM)
 		r.Get("/stream.mkv", rs.StreamMKV)
+		r.Get("/stream_remux.mp4", rs.StreamRemux)
 		r.Get("/stream.m3u8", rs.StreamHLS)
 		r.Get("/stream.m3u8/{segment}.ts", rs.StreamHLSSegment)
 		r.Get("/stream.mpd", rs.StreamDASH)
 		r.Get("/stream.mpd/{segment}_v.webm", rs.StreamDASHVideoSegment)
 		r.Get("/stream.mpd/{segment}_a.webm", rs.StreamDASHAudioSegment)
+		r.Get("/stream_hevc.m3u8", rs.StreamHLSHEVC)
+		r.Get("/stream_hevc.m3u8/{segment}.m4s", rs.StreamHLSHEVCSegment)
+		r.Get("/stream_hevc.m3u8/{segment}.mp4", rs.StreamHLSHEVCSegment)
+		r.Get("/stream_av1.mpd", rs.StreamDASHAV1)
+		r.Get("/stream_av1.mpd/{segment}_v.webm", rs.StreamDASHAV1VideoSegment)
+		r.Get("/stream_av1.mpd/{segment}_a.webm", rs.StreamDASHAudioSegment)
+		r.Get("/stream_cmaf.m3u8", rs.StreamHLSCMAF)
+		r.Get("/stream_cmaf.m3u8/{segment}.m4s", rs.StreamHLSCMAFSegment)
+		r.Get("/stream_cmaf.m3u8/{segment}.mp4", rs.StreamHLSCMAFSegment)
+		r.Get("/stream_cmaf.mpd", rs.StreamDASHCMAF)
+		r.Get("/stream_cmaf.mpd/{segment}.m4s", rs.StreamDASHCMAFSegment)
+		r.Get("/stream_cmaf.mpd/{segment}.mp4", rs.StreamDASHCMAFSegment)
 
 		r.Get("/screenshot", rs.Screenshot)
 		r.Get("/preview", rs.Preview)
@@ -77,7 +101,11 @@ func (rs sceneRoutes) Routes() chi.Router {
 		r.Get("/funscript", rs.Funscript)
 		r.Get("/interactive_csv", rs.InteractiveCSV)
 		r.Get("/interactive_heatmap", rs.InteractiveHeatmap)
+		r.Get("/trickplay.bif", rs.TrickplayBIF)
+		r.Get("/trickplay/"+generate.TrickplayManifest, rs.TrickplayManifest)
+		r.Get("/trickplay/{tile}.jpg", rs.TrickplayTile)
 		r.Get("/caption", rs.CaptionLang)
+		r.Get("/subtitle", rs.Subtitle)
 
 		r.Get("/scene_marker/{sceneMarkerId}/stream", rs.SceneMarkerStream)
 		r.Get("/scene_marker/{sceneMarkerId}/preview", rs.SceneMarkerPreview)
@@ -133,6 +161,10 @@ func (rs sceneRoutes) StreamMKV(w http.ResponseWriter, r *http.Request) {
 	rs.streamTranscode(w, r, ffmpeg.StreamTypeMKV)
 }
 
+func (rs sceneRoutes) StreamRemux(w http.ResponseWriter, r *http.Request) {
+	rs.streamTranscode(w, r, ffmpeg.StreamTypeRemux)
+}
+
 func (rs sceneRoutes) streamTranscode(w http.ResponseWriter, r *http.Request, streamType ffmpeg.StreamFormat) {
 	scene := r.Context().Value(sceneKey).(*models.Scene)
 
@@ -158,8 +190,10 @@ func (rs sceneRoutes) streamTranscode(w http.ResponseWriter, r *http.Request, st
 	options := ffmpeg.TranscodeOptions{
 		StreamType: streamType,
 		VideoFile:  f,
+		Hash:       scene.GetHash(config.GetInstance().GetVideoFileNamingAlgorithm()),
 		Resolution: resolution,
 		StartTime:  ss,
+		AudioTrack: audioTrackFromForm(r),
 	}
 
 	logger.Debugf("[transcode] streaming scene %d as %s", scene.ID, streamType.MimeType)
@@ -174,6 +208,22 @@ func (rs sceneRoutes) StreamDASH(w http.ResponseWriter, r *http.Request) {
 	rs.streamManifest(w, r, ffmpeg.StreamTypeDASHVideo, "DASH")
 }
 
+func (rs sceneRoutes) StreamHLSHEVC(w http.ResponseWriter, r *http.Request) {
+	rs.streamManifest(w, r, ffmpeg.StreamTypeHLSHEVC, "HLS HEVC")
+}
+
+func (rs sceneRoutes) StreamDASHAV1(w http.ResponseWriter, r *http.Request) {
+	rs.streamManifest(w, r, ffmpeg.StreamTypeDASHAV1, "DASH AV1")
+}
+
+func (rs sceneRoutes) StreamHLSCMAF(w http.ResponseWriter, r *http.Request) {
+	rs.streamManifest(w, r, ffmpeg.StreamTypeHLSCMAF, "HLS CMAF")
+}
+
+func (rs sceneRoutes) StreamDASHCMAF(w http.ResponseWriter, r *http.Request) {
+	rs.streamManifest(w, r, ffmpeg.StreamTypeDASHCMAF, "DASH CMAF")
+}
+
 func (rs sceneRoutes) streamManifest(w http.ResponseWriter, r *http.Request, streamType *ffmpeg.StreamType, logName string) {
 	scene := r.Context().Value(sceneKey).(*models.Scene)
 
@@ -195,7 +245,7 @@ func (rs sceneRoutes) streamManifest(w http.ResponseWriter, r *http.Request, str
 	resolution := r.Form.Get("resolution")
 
 	logger.Debugf("[transcode] returning %s manifest for scene %d", logName, scene.ID)
-	streamManager.ServeManifest(w, r, streamType, f, resolution)
+	streamManager.ServeManifest(w, r, streamType, f, resolution, audioTrackFromForm(r))
 }
 
 func (rs sceneRoutes) StreamHLSSegment(w http.ResponseWriter, r *http.Request) {
@@ -210,6 +260,22 @@ func (rs sceneRoutes) StreamDASHAudioSegment(w http.ResponseWriter, r *http.Requ
 	rs.streamSegment(w, r, ffmpeg.StreamTypeDASHAudio)
 }
 
+func (rs sceneRoutes) StreamHLSHEVCSegment(w http.ResponseWriter, r *http.Request) {
+	rs.streamSegment(w, r, ffmpeg.StreamTypeHLSHEVC)
+}
+
+func (rs sceneRoutes) StreamDASHAV1VideoSegment(w http.ResponseWriter, r *http.Request) {
+	rs.streamSegment(w, r, ffmpeg.StreamTypeDASHAV1)
+}
+
+func (rs sceneRoutes) StreamHLSCMAFSegment(w http.ResponseWriter, r *http.Request) {
+	rs.streamSegment(w, r, ffmpeg.StreamTypeHLSCMAF)
+}
+
+func (rs sceneRoutes) StreamDASHCMAFSegment(w http.ResponseWriter, r *http.Request) {
+	rs.streamSegment(w, r, ffmpeg.StreamTypeDASHCMAF)
+}
+
 func (rs sceneRoutes) streamSegment(w http.ResponseWriter, r *http.Request, streamType *ffmpeg.StreamType) {
 	scene := r.Context().Value(sceneKey).(*models.Scene)
 
@@ -239,11 +305,24 @@ func (rs sceneRoutes) streamSegment(w http.ResponseWriter, r *http.Request, stre
 		Resolution: resolution,
 		Hash:       sceneHash,
 		Segment:    segment,
+		AudioTrack: audioTrackFromForm(r),
 	}
 
 	streamManager.ServeSegment(w, r, options)
 }
 
+// audioTrackFromForm returns the audio stream index from the audio_track
+// query parameter. Returns nil if the parameter is not set or invalid.
+// r.ParseForm must have been called.
+func audioTrackFromForm(r *http.Request) *int {
+	track, err := strconv.Atoi(r.Form.Get("audio_track"))
+	if err != nil || track < 0 {
+		return nil
+	}
+
+	return &track
+}
+
 func (rs sceneRoutes) Screenshot(w http.ResponseWriter, r *http.Request) {
 	scene := r.Context().Value(sceneKey).(*models.Scene)
 
@@ -400,6 +479,38 @@ func (rs sceneRoutes) InteractiveHeatmap(w http.ResponseWriter, r *http.Request)
 	utils.ServeStaticFile(w, r, filepath)
 }
 
+func (rs sceneRoutes) TrickplayBIF(w http.ResponseWriter, r *http.Request) {
+	scene := r.Context().Value(sceneKey).(*models.Scene)
+	sceneHash := scene.GetHash(config.GetInstance().GetVideoFileNamingAlgorithm())
+	filepath := manager.GetInstance().Paths.Scene.GetTrickplayBIFPath(sceneHash)
+
+	utils.ServeStaticFile(w, r, filepath)
+}
+
+func (rs sceneRoutes) TrickplayManifest(w http.ResponseWriter, r *http.Request) {
+	scene := r.Context().Value(sceneKey).(*models.Scene)
+	sceneHash := scene.GetHash(config.GetInstance().GetVideoFileNamingAlgorithm())
+	dir := manager.GetInstance().Paths.Scene.GetTrickplayDir(sceneHash)
+
+	w.Header().Set("Content-Type", ffmpeg.MimeHLS)
+	utils.ServeStaticFile(w, r, filepath.Join(dir, generate.TrickplayManifest))
+}
+
+func (rs sceneRoutes) TrickplayTile(w http.ResponseWriter, r *http.Request) {
+	scene := r.Context().Value(sceneKey).(*models.Scene)
+
+	tile, err := strconv.Atoi(chi.URLParam(r, "tile"))
+	if err != nil || tile < 0 {
+		http.Error(w, "invalid tile", http.StatusBadRequest)
+		return
+	}
+
+	sceneHash := scene.GetHash(config.GetInstance().GetVideoFileNamingAlgorithm())
+	dir := manager.GetInstance().Paths.Scene.GetTrickplayDir(sceneHash)
+
+	utils.ServeStaticFile(w, r, filepath.Join(dir, strconv.Itoa(tile)+".jpg"))
+}
+
 func (rs sceneRoutes) Caption(w http.ResponseWriter, r *http.Request, lang string, ext string) {
 	s := r.Context().Value(sceneKey).(*models.Scene)
 
@@ -461,6 +572,105 @@ func (rs sceneRoutes) CaptionLang(w http.ResponseWriter, r *http.Request) {
 	rs.Caption(w, r, l, ext)
 }
 
+// Subtitle serves the embedded subtitle stream with the index given
+// in the index query parameter, converted to WebVTT.
+func (rs sceneRoutes) Subtitle(w http.ResponseWriter, r *http.Request) {
+	s := r.Context().Value(sceneKey).(*models.Scene)
+
+	if err := r.ParseForm(); err != nil {
+		logger.Warnf("[subtitle] error parsing query form: %v", err)
+	}
+
+	index, err := strconv.Atoi(r.Form.Get("index"))
+	if err != nil || index < 0 {
+		http.Error(w, "invalid subtitle index", http.StatusBadRequest)
+		return
+	}
+
+	primaryFile := s.Files.Primary()
+	if primaryFile == nil {
+		return
+	}
+
+	// extraction reads the whole file, so extracted subtitles are cached
+	var vttPath string
+	if sceneHash := s.GetHash(config.GetInstance().GetVideoFileNamingAlgorithm()); sceneHash != "" {
+		dir := manager.GetInstance().Paths.Scene.GetSubtitlesDir(sceneHash)
+		vttPath = filepath.Join(dir, strconv.Itoa(index)+".vtt")
+
+		if exists, _ := fsutil.FileExists(vttPath); exists {
+			w.Header().Set("Content-Type", "text/vtt")
+			utils.ServeStaticFile(w, r, vttPath)
+			return
+		}
+	}
+
+	var streams []*models.VideoStream
+	readTxnErr := txn.WithReadTxn(r.Context(), rs.txnManager, func(ctx context.Context) error {
+		var err error
+		streams, err = rs.streamFinder.GetStreams(ctx, primaryFile.ID)
+		return err
+	})
+	if errors.Is(readTxnErr, context.Canceled) {
+		return
+	}
+	if readTxnErr != nil {
+		logger.Warnf("read transaction error on fetch scene streams: %v", readTxnErr)
+		http.Error(w, readTxnErr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var stream *models.VideoStream
+	for _, ss := range models.FilterVideoStreams(streams, models.VideoStreamTypeSubtitle) {
+		if ss.Index == index {
+			stream = ss
+			break
+		}
+	}
+
+	if stream == nil {
+		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if !ffmpeg.IsTextSubtitle(stream.Codec) {
+		http.Error(w, "subtitle codec "+stream.Codec+" cannot be converted to WebVTT", http.StatusBadRequest)
+		return
+	}
+
+	encoder := manager.GetInstance().FFMPEG
+	if encoder == nil {
+		http.Error(w, "ffmpeg not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	args := transcoder.SubtitleWebVTT(primaryFile.Path, index, "pipe:")
+	vtt, err := encoder.GenerateOutput(r.Context(), args, nil)
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	if err != nil {
+		logger.Warnf("error extracting subtitle: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if vttPath != "" {
+		// write to a temporary file first, so that a partially written
+		// file is never served
+		tmpPath := fmt.Sprintf("%s.%d.tmp", vttPath, time.Now().UnixNano())
+		if err := fsutil.WriteFile(tmpPath, vtt); err != nil {
+			logger.Warnf("error caching subtitle %s: %v", vttPath, err)
+		} else if err := os.Rename(tmpPath, vttPath); err != nil {
+			logger.Warnf("error caching subtitle %s: %v", vttPath, err)
+			_ = os.Remove(tmpPath)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/vtt")
+	utils.ServeStaticContent(w, r, vtt)
+}
+
 func (rs sceneRoutes) SceneMarkerStream(w http.ResponseWriter, r *http.Request) {
 	scene := r.Context().Value(sceneKey).(*models.Scene)
 	sceneHash := scene.GetHash(config.GetInstance().GetVideoFileNamingAlgorithm())
@@ -34,16 +34,19 @@ import (
 	"github.com/stashapp/stash/internal/manager/config"
 	"github.com/stashapp/stash/pkg/fsutil"
 	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/plugin"
 	"github.com/stashapp/stash/pkg/utils"
 	"github.com/stashapp/stash/ui"
 )
 
 const (
-	loginEndpoint      = "/login"
-	logoutEndpoint     = "/logout"
-	gqlEndpoint        = "/graphql"
-	playgroundEndpoint = "/playground"
+	loginEndpoint        = "/login"
+	logoutEndpoint       = "/logout"
+	oidcLoginEndpoint    = loginEndpoint + "/oidc"
+	oidcCallbackEndpoint = oidcLoginEndpoint + "/callback"
+	gqlEndpoint          = "/graphql"
+	playgroundEndpoint   = "/playground"
 )
 
 var uiBox = ui.UIBox
@@ -125,6 +128,7 @@ func Start() error {
 	gqlSrv.Use(gqlExtension.Introspection{})
 
 	gqlSrv.SetErrorPresenter(gqlErrorHandler)
+	gqlSrv.AroundFields(authorizeField)
 
 	gqlHandlerFunc := func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Cache-Control", "no-store")
@@ -134,7 +138,8 @@ func Start() error {
 	// register GQL handler with plugin cache
 	// chain the visited plugin handler
 	// also requires the dataloader middleware
-	gqlHandler := visitedPluginHandler(dataloaders.Middleware(http.HandlerFunc(gqlHandlerFunc)))
+	// requests are authenticated by the session cookie of the plugin
+	gqlHandler := pluginAuthenticateHandler(visitedPluginHandler(dataloaders.Middleware(http.HandlerFunc(gqlHandlerFunc))))
 	manager.GetInstance().PluginCache.RegisterGQLHandler(gqlHandler)
 
 	r.HandleFunc(gqlEndpoint, gqlHandlerFunc)
@@ -153,6 +158,7 @@ func Start() error {
 		sceneFinder:       txnManager.Scene,
 		fileGetter:        txnManager.File,
 		captionFinder:     txnManager.File,
+		streamFinder:      txnManager.File,
 		sceneMarkerFinder: txnManager.SceneMarker,
 		tagFinder:         txnManager.Tag,
 	}.Routes())
@@ -173,7 +179,7 @@ func Start() error {
 		txnManager: txnManager,
 		tagFinder:  txnManager.Tag,
 	}.Routes())
-	r.Mount("/downloads", downloadsRoutes{}.Routes())
+	r.With(requireRole(models.UserRoleEditor)).Mount("/downloads", downloadsRoutes{}.Routes())
 
 	r.HandleFunc("/css", cssHandler(c, pluginCache))
 	r.HandleFunc("/javascript", javascriptHandler(c, pluginCache))
@@ -184,6 +190,8 @@ func Start() error {
 	r.Get(loginEndpoint, handleLogin(loginUIBox))
 	r.Post(loginEndpoint, handleLoginPost(loginUIBox))
 	r.Get(logoutEndpoint, handleLogout())
+	r.Get(oidcLoginEndpoint, handleOIDCLogin())
+	r.Get(oidcCallbackEndpoint, handleOIDCCallback(loginUIBox))
 	r.HandleFunc(loginEndpoint+"/*", func(w http.ResponseWriter, r *http.Request) {
 		r.URL.Path = strings.TrimPrefix(r.URL.Path, loginEndpoint)
 		w.Header().Set("Cache-Control", "no-cache")
@@ -472,7 +480,8 @@ type contextKey struct {
 }
 
 var (
-	BaseURLCtxKey = &contextKey{"BaseURL"}
+	BaseURLCtxKey   = &contextKey{"BaseURL"}
+	UserAgentCtxKey = &contextKey{"UserAgent"}
 )
 
 func BaseURLMiddleware(next http.Handler) http.Handler {
@@ -492,7 +501,11 @@ func BaseURLMiddleware(next http.Handler) http.Handler {
 			baseURL = externalHost + prefix
 		}
 
-		r = r.WithContext(context.WithValue(ctx, BaseURLCtxKey, baseURL))
+		ctx = context.WithValue(ctx, BaseURLCtxKey, baseURL)
+		// used to match the device profile of the client
+		ctx = context.WithValue(ctx, UserAgentCtxKey, r.UserAgent())
+
+		r = r.WithContext(ctx)
 
 		next.ServeHTTP(w, r)
 	}
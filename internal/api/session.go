@@ -29,6 +29,8 @@ func getLoginPage(loginUIBox fs.FS) []byte {
 type loginTemplateData struct {
 	URL   string
 	Error string
+	// OIDC is true if single sign-on using OpenID Connect is available
+	OIDC bool
 }
 
 func serveLoginPage(loginUIBox fs.FS, w http.ResponseWriter, r *http.Request, returnURL string, loginError string) {
@@ -43,7 +45,11 @@ func serveLoginPage(loginUIBox fs.FS, w http.ResponseWriter, r *http.Request, re
 	}
 
 	buffer := bytes.Buffer{}
-	err = templ.Execute(&buffer, loginTemplateData{URL: returnURL, Error: loginError})
+	err = templ.Execute(&buffer, loginTemplateData{
+		URL:   returnURL,
+		Error: loginError,
+		OIDC:  manager.GetInstance().SessionStore.OIDCEnabled(),
+	})
 	if err != nil {
 		http.Error(w, fmt.Sprintf("error: %s", err), http.StatusInternalServerError)
 		return
@@ -55,11 +61,21 @@ func serveLoginPage(loginUIBox fs.FS, w http.ResponseWriter, r *http.Request, re
 	utils.ServeStaticContent(w, r, buffer.Bytes())
 }
 
+func authenticationRequired(r *http.Request) bool {
+	required, err := manager.GetInstance().SessionStore.AuthenticationRequired(r.Context())
+	if err != nil {
+		logger.Errorf("Error checking for users: %v", err)
+		return true
+	}
+
+	return required
+}
+
 func handleLogin(loginUIBox fs.FS) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		returnURL := r.URL.Query().Get(returnURLParam)
 
-		if !config.GetInstance().HasCredentials() {
+		if !authenticationRequired(r) {
 			if returnURL != "" {
 				http.Redirect(w, r, returnURL, http.StatusFound)
 			} else {
@@ -103,6 +119,56 @@ func handleLoginPost(loginUIBox fs.FS) http.HandlerFunc {
 	}
 }
 
+// oidcRedirectURL returns the URL which the OpenID Connect provider redirects
+// to once the user has authenticated.
+func oidcRedirectURL(r *http.Request) string {
+	if ret := config.GetInstance().GetOIDCRedirectURL(); ret != "" {
+		return ret
+	}
+
+	baseURL, _ := r.Context().Value(BaseURLCtxKey).(string)
+	return baseURL + oidcCallbackEndpoint
+}
+
+func handleOIDCLogin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		returnURL := r.URL.Query().Get(returnURLParam)
+		if returnURL == "" {
+			returnURL = getProxyPrefix(r) + "/"
+		}
+
+		err := manager.GetInstance().SessionStore.OIDCLogin(w, r, oidcRedirectURL(r), returnURL)
+		if errors.Is(err, session.ErrOIDCDisabled) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			logger.Errorf("Error starting OpenID Connect login: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+func handleOIDCCallback(loginUIBox fs.FS) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		returnURL, err := manager.GetInstance().SessionStore.OIDCCallback(w, r, oidcRedirectURL(r))
+		if errors.Is(err, session.ErrOIDCDisabled) {
+			http.NotFound(w, r)
+			return
+		}
+
+		if err != nil {
+			// always log the error, but don't leak the details
+			logger.Errorf("Error logging in using OpenID Connect: %v", err)
+			serveLoginPage(loginUIBox, w, r, "", "Single sign-on failed")
+			return
+		}
+
+		http.Redirect(w, r, returnURL, http.StatusFound)
+	}
+}
+
 func handleLogout() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := manager.GetInstance().SessionStore.Logout(w, r); err != nil {
@@ -112,7 +178,7 @@ func handleLogout() http.HandlerFunc {
 
 		// redirect to the login page if credentials are required
 		prefix := getProxyPrefix(r)
-		if config.GetInstance().HasCredentials() {
+		if authenticationRequired(r) {
 			http.Redirect(w, r, prefix+loginEndpoint, http.StatusFound)
 		} else {
 			http.Redirect(w, r, prefix+"/", http.StatusFound)
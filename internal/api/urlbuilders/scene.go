@@ -69,6 +69,18 @@ func (b SceneURLBuilder) GetCaptionURL() string {
 	return b.BaseURL + "/scene/" + b.SceneID + "/caption"
 }
 
+func (b SceneURLBuilder) GetSubtitleURL() string {
+	return b.BaseURL + "/scene/" + b.SceneID + "/subtitle"
+}
+
 func (b SceneURLBuilder) GetInteractiveHeatmapURL() string {
 	return b.BaseURL + "/scene/" + b.SceneID + "/interactive_heatmap"
 }
+
+func (b SceneURLBuilder) GetTrickplayBIFURL() string {
+	return b.BaseURL + "/scene/" + b.SceneID + "/trickplay.bif"
+}
+
+func (b SceneURLBuilder) GetTrickplayManifestURL() string {
+	return b.BaseURL + "/scene/" + b.SceneID + "/trickplay/tiles.m3u8"
+}
@@ -0,0 +1,229 @@
+package dlna
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/txn"
+)
+
+const (
+	// activitySaveInterval is how often the activity of a playing scene is saved.
+	activitySaveInterval = 10 * time.Second
+	// activitySessionTimeout is how long a playback session is kept after
+	// its last request. A new session increments the play count again.
+	activitySessionTimeout = 5 * time.Minute
+	// resumeCompletePercent is the percentage of the scene after which the
+	// resume time is reset, matching the behaviour of the web player.
+	resumeCompletePercent = 98
+)
+
+// playbackSession tracks the playback of a scene by a renderer.
+// Since renderers do not report their position, the position is estimated
+// from the requested offset and the time spent serving the requests.
+type playbackSession struct {
+	sceneID  int
+	duration float64
+
+	position   float64
+	lastUpdate time.Time
+	lastSeen   time.Time
+	active     int
+
+	// playDuration is the play time which has not yet been saved
+	playDuration         float64
+	totalPlayDuration    float64
+	playCountIncremented bool
+}
+
+// advance updates the position and play duration with the time elapsed
+// since the last update, if the session is being served.
+func (s *playbackSession) advance(now time.Time) {
+	if s.active > 0 {
+		elapsed := now.Sub(s.lastUpdate).Seconds()
+		s.position += elapsed
+		if s.duration > 0 && s.position > s.duration {
+			s.position = s.duration
+		}
+		s.playDuration += elapsed
+		s.totalPlayDuration += elapsed
+	}
+
+	s.lastUpdate = now
+}
+
+// resumeTime returns the resume time to save for the current position.
+func (s *playbackSession) resumeTime() float64 {
+	if s.duration > 0 && s.position*100/s.duration >= resumeCompletePercent {
+		return 0
+	}
+
+	return s.position
+}
+
+type activityTracker struct {
+	txnManager txn.Manager
+	writer     SceneActivityWriter
+	// minimumPlayPercent returns the percentage of a scene which must be
+	// played before its play count is incremented.
+	minimumPlayPercent func() int
+
+	mutex    sync.Mutex
+	sessions map[string]*playbackSession
+}
+
+func newActivityTracker(txnManager txn.Manager, writer SceneActivityWriter, minimumPlayPercent func() int) *activityTracker {
+	return &activityTracker{
+		txnManager:         txnManager,
+		writer:             writer,
+		minimumPlayPercent: minimumPlayPercent,
+		sessions:           make(map[string]*playbackSession),
+	}
+}
+
+func sessionKey(r *http.Request, sceneID int) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return host + "/" + strconv.Itoa(sceneID)
+}
+
+// begin starts tracking a request for the scene, which starts playback at
+// position seconds. The returned function must be called when the request
+// has been served.
+func (t *activityTracker) begin(r *http.Request, scene *models.Scene, position float64) func() {
+	now := time.Now()
+	key := sessionKey(r, scene.ID)
+
+	t.mutex.Lock()
+	t.pruneSessions(now)
+
+	s := t.sessions[key]
+	if s == nil {
+		s = &playbackSession{
+			sceneID: scene.ID,
+		}
+		if f := scene.Files.Primary(); f != nil {
+			s.duration = f.Duration
+		}
+		t.sessions[key] = s
+	}
+
+	s.advance(now)
+	s.position = position
+	s.lastSeen = now
+	s.active++
+	t.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(activitySaveInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				t.save(s)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+
+		t.mutex.Lock()
+		now := time.Now()
+		s.advance(now)
+		s.lastSeen = now
+		s.active--
+		t.mutex.Unlock()
+
+		t.save(s)
+	}
+}
+
+// pruneSessions removes the sessions which have timed out.
+// The mutex must be held.
+func (t *activityTracker) pruneSessions(now time.Time) {
+	for k, s := range t.sessions {
+		if s.active == 0 && now.Sub(s.lastSeen) > activitySessionTimeout {
+			delete(t.sessions, k)
+		}
+	}
+}
+
+// save saves the activity of the session, incrementing the play count once
+// enough of the scene has been played.
+func (t *activityTracker) save(s *playbackSession) {
+	t.mutex.Lock()
+	s.advance(time.Now())
+
+	resumeTime := s.resumeTime()
+	playDuration := s.playDuration
+	s.playDuration = 0
+
+	incrementPlayCount := false
+	if !s.playCountIncremented && s.duration > 0 && s.totalPlayDuration > 0 &&
+		s.totalPlayDuration*100/s.duration >= float64(t.minimumPlayPercent()) {
+		incrementPlayCount = true
+		s.playCountIncremented = true
+	}
+	t.mutex.Unlock()
+
+	if err := txn.WithTxn(context.Background(), t.txnManager, func(ctx context.Context) error {
+		if _, err := t.writer.SaveActivity(ctx, s.sceneID, &resumeTime, &playDuration); err != nil {
+			return err
+		}
+
+		if incrementPlayCount {
+			if _, err := t.writer.IncrementWatchCount(ctx, s.sceneID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		logger.Warnf("dlna: error saving activity for scene %d: %v", s.sceneID, err)
+	}
+}
+
+// saveBookmark saves the position reported by a renderer as the resume time of the scene.
+func (t *activityTracker) saveBookmark(sceneID int, position float64) error {
+	return txn.WithTxn(context.Background(), t.txnManager, func(ctx context.Context) error {
+		_, err := t.writer.SaveActivity(ctx, sceneID, &position, nil)
+		return err
+	})
+}
+
+// byteRangePosition estimates the position in seconds of an open ended byte
+// range request, such as "bytes=1000-", into a file of the given size and
+// duration. Returns false for other requests.
+func byteRangePosition(r *http.Request, size int64, duration float64) (float64, bool) {
+	v := r.Header.Get("Range")
+	if !strings.HasPrefix(v, "bytes=") || size <= 0 {
+		return 0, false
+	}
+
+	start, end, ok := strings.Cut(strings.TrimPrefix(v, "bytes="), "-")
+	if !ok || end != "" {
+		return 0, false
+	}
+
+	offset, err := strconv.ParseInt(start, 10, 64)
+	if err != nil || offset >= size {
+		return 0, false
+	}
+
+	return float64(offset) / float64(size) * duration, true
+}
@@ -0,0 +1,64 @@
+package dlna
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByteRangePosition(t *testing.T) {
+	const (
+		size     = 1000
+		duration = 100
+	)
+
+	tests := []struct {
+		name   string
+		header string
+		want   float64
+		wantOK bool
+	}{
+		{"no range", "", 0, false},
+		{"start", "bytes=0-", 0, true},
+		{"half", "bytes=500-", 50, true},
+		{"closed range", "bytes=500-600", 0, false},
+		{"past end", "bytes=1000-", 0, false},
+		{"suffix", "bytes=-500", 0, false},
+		{"invalid unit", "items=500-", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &http.Request{Header: http.Header{}}
+			if tt.header != "" {
+				r.Header.Set("Range", tt.header)
+			}
+
+			got, ok := byteRangePosition(r, size, duration)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPlaybackSession_resumeTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		position float64
+		duration float64
+		want     float64
+	}{
+		{"start", 0, 100, 0},
+		{"middle", 50, 100, 50},
+		{"near end", 98, 100, 0},
+		{"unknown duration", 50, 0, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &playbackSession{position: tt.position, duration: tt.duration}
+			assert.Equal(t, tt.want, s.resumeTime())
+		})
+	}
+}
@@ -34,12 +34,13 @@ import (
 	"strings"
 	"time"
 
-	"github.com/anacrolix/dms/dlna"
 	"github.com/anacrolix/dms/upnp"
 	"github.com/anacrolix/dms/upnpav"
+	"github.com/stashapp/stash/pkg/image"
 	"github.com/stashapp/stash/pkg/logger"
 	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/scene"
+	"github.com/stashapp/stash/pkg/sliceutil/intslice"
 	"github.com/stashapp/stash/pkg/sliceutil/stringslice"
 	"github.com/stashapp/stash/pkg/txn"
 )
@@ -52,6 +53,24 @@ type browse struct {
 	Filter         string
 	StartingIndex  int
 	RequestedCount int
+	SortCriteria   string
+}
+
+type search struct {
+	ContainerID    string
+	SearchCriteria string
+	Filter         string
+	StartingIndex  int
+	RequestedCount int
+	SortCriteria   string
+}
+
+// setBookmark holds the arguments of the Samsung X_SetBookmark action.
+type setBookmark struct {
+	CategoryType string
+	RID          string
+	ObjectID     string
+	PosSecond    int
 }
 
 type contentDirectoryService struct {
@@ -77,10 +96,10 @@ func (me *contentDirectoryService) updateIDString() string {
 	return fmt.Sprintf("%d", uint32(os.Getpid()))
 }
 
-func sceneToContainer(scene *models.Scene, parent string, host string) interface{} {
+func sceneIconURI(scene *models.Scene, host string) string {
 	// make stash server URL
 	// TODO - fix this
-	iconURI := (&url.URL{
+	return (&url.URL{
 		Scheme: "http",
 		Host:   host,
 		Path:   iconPath,
@@ -89,6 +108,18 @@ func sceneToContainer(scene *models.Scene, parent string, host string) interface
 			"c":     {"jpeg"},
 		}.Encode(),
 	}).String()
+}
+
+// sceneItem is a video item with the playback bookmark of the scene.
+type sceneItem struct {
+	upnpav.Item
+	LastPlaybackPosition string `xml:"upnp:lastPlaybackPosition,omitempty"`
+	// DCMInfo holds the bookmark in the format used by Samsung renderers.
+	DCMInfo string `xml:"sec:dcmInfo,omitempty"`
+}
+
+func sceneToContainer(scene *models.Scene, parent string, host string, profile *deviceProfile) interface{} {
+	iconURI := sceneIconURI(scene, host)
 
 	// Object goes first
 	obj := upnpav.Object{
@@ -107,38 +138,50 @@ func sceneToContainer(scene *models.Scene, parent string, host string) interface
 		Res:    make([]upnpav.Resource, 0, 1),
 	}
 
-	mimeType := "video/mp4"
-	var (
-		size     int
-		bitrate  uint
-		duration int64
-	)
-
-	f := scene.Files.Primary()
-	if f != nil {
-		size = int(f.Size)
-		bitrate = uint(f.BitRate)
-		duration = int64(f.Duration)
-	}
+	item.Res = append(item.Res, sceneResources(scene, host, profile, 0)...)
 
 	item.Res = append(item.Res, upnpav.Resource{
-		URL: (&url.URL{
-			Scheme: "http",
-			Host:   host,
-			Path:   resPath,
-			RawQuery: url.Values{
-				"scene": {strconv.Itoa(scene.ID)},
-			}.Encode(),
-		}).String(),
-		ProtocolInfo: fmt.Sprintf("http-get:*:%s:%s", mimeType, dlna.ContentFeatures{
-			SupportRange: true,
-		}.String()),
-		Bitrate:  bitrate,
-		Duration: formatDurationSexagesimal(time.Duration(duration) * time.Second),
-		Size:     uint64(size),
-		// Resolution: resolution,
+		URL:          iconURI,
+		ProtocolInfo: "http-get:*:image/jpeg:DLNA.ORG_PN=JPEG_MED",
 	})
 
+	ret := sceneItem{
+		Item: item,
+	}
+
+	if scene.ResumeTime > 0 {
+		ret.LastPlaybackPosition = formatDurationSexagesimal(time.Duration(scene.ResumeTime) * time.Second)
+		ret.DCMInfo = fmt.Sprintf("BM=%d", int(scene.ResumeTime))
+	}
+
+	return ret
+}
+
+// markerToContainer returns a clip of the scene which streams from the
+// marker's offset.
+func markerToContainer(marker *models.SceneMarker, scene *models.Scene, parent string, host string, profile *deviceProfile) interface{} {
+	iconURI := sceneIconURI(scene, host)
+
+	title := marker.Title
+	if title == "" {
+		title = scene.GetTitle()
+	}
+
+	obj := upnpav.Object{
+		ID:          "marker/" + strconv.Itoa(marker.ID),
+		Restricted:  1,
+		ParentID:    parent,
+		Title:       title,
+		Class:       "object.item.videoItem",
+		Icon:        iconURI,
+		AlbumArtURI: iconURI,
+	}
+
+	item := upnpav.Item{
+		Object: obj,
+		Res:    sceneResources(scene, host, profile, marker.Seconds),
+	}
+
 	item.Res = append(item.Res, upnpav.Resource{
 		URL:          iconURI,
 		ProtocolInfo: "http-get:*:image/jpeg:DLNA.ORG_PN=JPEG_MED",
@@ -147,6 +190,64 @@ func sceneToContainer(scene *models.Scene, parent string, host string) interface
 	return item
 }
 
+func imageToContainer(image *models.Image, parent string, host string) interface{} {
+	imageURL := func(thumb bool) string {
+		query := url.Values{
+			"image": {strconv.Itoa(image.ID)},
+		}
+		if thumb {
+			query.Set("thumb", "1")
+		}
+
+		return (&url.URL{
+			Scheme:   "http",
+			Host:     host,
+			Path:     imagePath,
+			RawQuery: query.Encode(),
+		}).String()
+	}
+
+	thumbURI := imageURL(true)
+
+	obj := upnpav.Object{
+		ID:          "image/" + strconv.Itoa(image.ID),
+		Restricted:  1,
+		ParentID:    parent,
+		Title:       image.GetTitle(),
+		Class:       "object.item.imageItem",
+		Icon:        thumbURI,
+		AlbumArtURI: thumbURI,
+	}
+
+	item := upnpav.Item{
+		Object: obj,
+		Res:    make([]upnpav.Resource, 0, 2),
+	}
+
+	mimeType := "image/jpeg"
+	res := upnpav.Resource{
+		URL: imageURL(false),
+	}
+
+	if f, ok := image.Files.Primary().(*models.ImageFile); ok {
+		if f.Format != "" {
+			mimeType = "image/" + f.Format
+		}
+		res.Size = uint64(f.Size)
+		res.Resolution = fmt.Sprintf("%dx%d", f.Width, f.Height)
+	}
+
+	res.ProtocolInfo = fmt.Sprintf("http-get:*:%s:*", mimeType)
+	item.Res = append(item.Res, res)
+
+	item.Res = append(item.Res, upnpav.Resource{
+		URL:          thumbURI,
+		ProtocolInfo: "http-get:*:image/jpeg:DLNA.ORG_PN=JPEG_TN",
+	})
+
+	return item
+}
+
 // ContentDirectory object from ObjectID.
 func (me *contentDirectoryService) objectFromID(id string) (o object, err error) {
 	o.Path, err = url.QueryUnescape(id)
@@ -176,7 +277,7 @@ func childPath(paths []string) []string {
 
 func (me *contentDirectoryService) Handle(action string, argsXML []byte, r *http.Request) (map[string]string, error) {
 	host := r.Host
-	// userAgent := r.UserAgent()
+	profile := matchDeviceProfile(r)
 	switch action {
 	case "GetSystemUpdateID":
 		return map[string]string{
@@ -184,7 +285,7 @@ func (me *contentDirectoryService) Handle(action string, argsXML []byte, r *http
 		}, nil
 	case "GetSortCapabilities":
 		return map[string]string{
-			"SortCaps": "dc:title",
+			"SortCaps": getSortCapabilities(),
 		}, nil
 	case "Browse":
 		var browse browse
@@ -199,15 +300,22 @@ func (me *contentDirectoryService) Handle(action string, argsXML []byte, r *http
 
 		switch browse.BrowseFlag {
 		case "BrowseDirectChildren":
-			return me.handleBrowseDirectChildren(obj, host)
+			return me.handleBrowseDirectChildren(obj, parseSortCriteria(browse.SortCriteria), host, profile)
 		case "BrowseMetadata":
-			return me.handleBrowseMetadata(obj, host)
+			return me.handleBrowseMetadata(obj, host, profile)
 		default:
 			return nil, upnp.Errorf(upnp.ArgumentValueInvalidErrorCode, "unhandled browse flag: %v", browse.BrowseFlag)
 		}
+	case "Search":
+		var search search
+		if err := xml.Unmarshal([]byte(argsXML), &search); err != nil {
+			return nil, upnp.Errorf(upnp.ArgumentValueInvalidErrorCode, "cannot unmarshal search argument: %s", err.Error())
+		}
+
+		return me.handleSearch(search, host, profile)
 	case "GetSearchCapabilities":
 		return map[string]string{
-			"SearchCaps": "",
+			"SearchCaps": strings.Join(searchCapabilities, ","),
 		}, nil
 	// from https://github.com/rclone/rclone/blob/master/cmd/serve/dlna/cds.go
 	// Samsung Extensions
@@ -221,14 +329,28 @@ func (me *contentDirectoryService) Handle(action string, argsXML []byte, r *http
 	</Feature>
 	</Features>`}, nil
 	case "X_SetBookmark":
-		// just ignore
+		var bookmark setBookmark
+		if err := xml.Unmarshal([]byte(argsXML), &bookmark); err != nil {
+			return nil, upnp.Errorf(upnp.ArgumentValueInvalidErrorCode, "cannot unmarshal bookmark argument: %s", err.Error())
+		}
+
+		// only scene bookmarks are saved
+		sceneID, err := strconv.Atoi(bookmark.ObjectID)
+		if err != nil {
+			return map[string]string{}, nil
+		}
+
+		if err := me.activityTracker.saveBookmark(sceneID, float64(bookmark.PosSecond)); err != nil {
+			logger.Warnf("dlna: error saving bookmark for scene %d: %v", sceneID, err)
+		}
+
 		return map[string]string{}, nil
 	default:
 		return nil, upnp.InvalidActionError
 	}
 }
 
-func (me *contentDirectoryService) handleBrowseDirectChildren(obj object, host string) (map[string]string, error) {
+func (me *contentDirectoryService) handleBrowseDirectChildren(obj object, findFilter *models.FindFilterType, host string, profile *deviceProfile) (map[string]string, error) {
 	// Read folder and return children
 	// TODO: check if obj == 0 and return root objects
 	// TODO: check if special path and return files
@@ -243,53 +365,42 @@ func (me *contentDirectoryService) handleBrowseDirectChildren(obj object, host s
 
 	// All videos
 	if obj.Path == "all" {
-		objs = me.getAllScenes(host)
+		objs = me.getAllScenes(findFilter, host, profile)
 	}
 
 	if strings.HasPrefix(obj.Path, "all/") {
 		page := getPageFromID(paths)
 		if page != nil {
-			objs = me.getPageVideos(&models.SceneFilterType{}, "all", *page, host)
-		}
-	}
-
-	// Saved searches
-	// if obj.Path == "saved-searches" {
-	// 	var savedPlaylists []models.Playlist
-	// 	db, _ := models.GetDB()
-	// 	db.Where("is_deo_enabled = ?", true).Order("ordering asc").Find(&savedPlaylists)
-	// 	db.Close()
-
-	// 	for _, playlist := range savedPlaylists {
-	// 		objs = append(objs, upnpav.Container{Object: upnpav.Object{
-	// 			ID:         "saved-searches/" + strconv.Itoa(int(playlist.ID)),
-	// 			Restricted: 1,
-	// 			ParentID:   "saved-searches",
-	// 			Class:      "object.container.storageFolder",
-	// 			Title:      playlist.Name,
-	// 		}})
-	// 	}
-	// }
+			objs = me.getPageVideos(&models.SceneFilterType{}, findFilter, "all", *page, host, profile)
+		}
+	}
 
-	// if strings.HasPrefix(obj.Path, "saved-searches/") {
-	// 	id := strings.Split(obj.Path, "/")
+	// Saved filters
+	if obj.Path == "saved-filters" {
+		objs = me.getSavedFilters()
+	}
 
-	// 	var savedPlaylist models.Playlist
-	// 	db, _ := models.GetDB()
-	// 	db.Where("id = ?", id[1]).First(&savedPlaylist)
-	// 	db.Close()
+	if strings.HasPrefix(obj.Path, "saved-filters/") {
+		objs = me.getSavedFilterScenes(childPath(paths), findFilter, host, profile)
+	}
 
-	// 	var r models.RequestSceneList
-	// 	if err := json.Unmarshal([]byte(savedPlaylist.SearchParams), &r); err == nil {
-	// 		r.IsAccessible = optional.NewBool(true)
-	// 		r.IsAvailable = optional.NewBool(true)
-	// 		data := models.QueryScenesFull(r)
+	// Galleries
+	if obj.Path == "galleries" {
+		objs = me.getGalleries()
+	}
 
-	// 		for i := range data.Scenes {
-	// 			objs = append(objs, me.sceneToContainer(data.Scenes[i], "sites/"+id[1], host))
-	// 		}
-	// 	}
-	// }
+	if strings.HasPrefix(obj.Path, "galleries/") {
+		objs = me.getGalleryImages(childPath(paths), host)
+	}
+
+	// Markers
+	if obj.Path == "markers" {
+		objs = me.getMarkerTags()
+	}
+
+	if strings.HasPrefix(obj.Path, "markers/") {
+		objs = me.getTagMarkers(childPath(paths), host, profile)
+	}
 
 	// Studios
 	if obj.Path == "studios" {
@@ -297,7 +408,7 @@ func (me *contentDirectoryService) handleBrowseDirectChildren(obj object, host s
 	}
 
 	if strings.HasPrefix(obj.Path, "studios/") {
-		objs = me.getStudioScenes(childPath(paths), host)
+		objs = me.getStudioScenes(childPath(paths), findFilter, host, profile)
 	}
 
 	// Tags
@@ -306,7 +417,7 @@ func (me *contentDirectoryService) handleBrowseDirectChildren(obj object, host s
 	}
 
 	if strings.HasPrefix(obj.Path, "tags/") {
-		objs = me.getTagScenes(childPath(paths), host)
+		objs = me.getTagScenes(childPath(paths), findFilter, host, profile)
 	}
 
 	// Performers
@@ -315,7 +426,7 @@ func (me *contentDirectoryService) handleBrowseDirectChildren(obj object, host s
 	}
 
 	if strings.HasPrefix(obj.Path, "performers/") {
-		objs = me.getPerformerScenes(childPath(paths), host)
+		objs = me.getPerformerScenes(childPath(paths), findFilter, host, profile)
 	}
 
 	// Movies
@@ -324,7 +435,7 @@ func (me *contentDirectoryService) handleBrowseDirectChildren(obj object, host s
 	}
 
 	if strings.HasPrefix(obj.Path, "movies/") {
-		objs = me.getMovieScenes(childPath(paths), host)
+		objs = me.getMovieScenes(childPath(paths), findFilter, host, profile)
 	}
 
 	// Rating
@@ -333,16 +444,25 @@ func (me *contentDirectoryService) handleBrowseDirectChildren(obj object, host s
 	}
 
 	if strings.HasPrefix(obj.Path, "rating/") {
-		objs = me.getRatingScenes(childPath(paths), host)
+		objs = me.getRatingScenes(childPath(paths), findFilter, host, profile)
 	}
 
 	return makeBrowseResult(objs, me.updateIDString())
 }
 
-func (me *contentDirectoryService) handleBrowseMetadata(obj object, host string) (map[string]string, error) {
+func (me *contentDirectoryService) handleBrowseMetadata(obj object, host string, profile *deviceProfile) (map[string]string, error) {
 	var objs []interface{}
 	var updateID string
 
+	// images and markers are items rather than folders
+	if strings.HasPrefix(obj.Path, "image/") {
+		return me.handleBrowseImageMetadata(obj, host)
+	}
+
+	if strings.HasPrefix(obj.Path, "marker/") {
+		return me.handleBrowseMarkerMetadata(obj, host, profile)
+	}
+
 	// if numeric, then must be scene, otherwise handle as if path
 	sceneID, err := strconv.Atoi(obj.Path)
 	if err != nil {
@@ -376,7 +496,7 @@ func (me *contentDirectoryService) handleBrowseMetadata(obj object, host string)
 		}
 
 		if scene != nil {
-			upnpObject := sceneToContainer(scene, "-1", host)
+			upnpObject := sceneToContainer(scene, "-1", host, profile)
 			objs = []interface{}{upnpObject}
 
 			// http://upnp.org/specs/av/UPnP-av-ContentDirectory-v1-Service.pdf
@@ -391,6 +511,184 @@ func (me *contentDirectoryService) handleBrowseMetadata(obj object, host string)
 	return makeBrowseResult(objs, updateID)
 }
 
+// handleSearch returns the scenes matching the search criteria. Scenes are
+// searched regardless of the container, since all scenes are available from
+// the root container.
+func (me *contentDirectoryService) handleSearch(search search, host string, profile *deviceProfile) (map[string]string, error) {
+	expr, err := parseSearchCriteria(search.SearchCriteria)
+	if err != nil {
+		return nil, upnp.Errorf(upnpSearchCriteriaErrorCode, "invalid search criteria: %s", err.Error())
+	}
+
+	var objs []interface{}
+	total := 0
+
+	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
+		f, err := sceneSearchFilter(expr, &repositorySearchResolver{ctx: ctx, repository: me.repository})
+		if err != nil {
+			return upnp.Errorf(upnpSearchCriteriaErrorCode, err.Error())
+		}
+
+		if f.none {
+			return nil
+		}
+
+		sceneFilter := f.filter
+		if sceneFilter == nil {
+			sceneFilter = &models.SceneFilterType{}
+		}
+
+		sort, direction := me.getSceneSort(sceneFilter, parseSortCriteria(search.SortCriteria))
+
+		// get the scenes up to the end of the requested range,
+		// since the starting index may not be a multiple of the count
+		perPage := -1
+		if search.RequestedCount > 0 {
+			perPage = search.StartingIndex + search.RequestedCount
+		}
+
+		scenes, count, err := scene.QueryWithCount(ctx, me.repository.SceneFinder, sceneFilter, &models.FindFilterType{
+			PerPage:   &perPage,
+			Sort:      &sort,
+			Direction: &direction,
+		})
+		if err != nil {
+			return err
+		}
+
+		total = count
+		if search.StartingIndex >= len(scenes) {
+			return nil
+		}
+
+		for _, s := range scenes[search.StartingIndex:] {
+			if err := s.LoadPrimaryFile(ctx, me.repository.FileGetter); err != nil {
+				return err
+			}
+
+			objs = append(objs, sceneToContainer(s, search.ContainerID, host, profile))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, upnp.ConvertError(err)
+	}
+
+	ret, err := makeBrowseResult(objs, me.updateIDString())
+	if err != nil {
+		return nil, err
+	}
+
+	ret["TotalMatches"] = fmt.Sprint(total)
+	return ret, nil
+}
+
+// repositorySearchResolver resolves names in search criteria using the repository.
+type repositorySearchResolver struct {
+	ctx        context.Context
+	repository Repository
+}
+
+func (r *repositorySearchResolver) performerIDs(name *models.StringCriterionInput) ([]string, error) {
+	perPage := -1
+	performers, _, err := r.repository.PerformerFinder.Query(r.ctx, &models.PerformerFilterType{
+		Name: name,
+	}, &models.FindFilterType{
+		PerPage: &perPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, p := range performers {
+		ret = append(ret, strconv.Itoa(p.ID))
+	}
+
+	return ret, nil
+}
+
+func (r *repositorySearchResolver) tagIDs(name *models.StringCriterionInput) ([]string, error) {
+	perPage := -1
+	tags, _, err := r.repository.TagFinder.Query(r.ctx, &models.TagFilterType{
+		Name: name,
+	}, &models.FindFilterType{
+		PerPage: &perPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []string
+	for _, t := range tags {
+		ret = append(ret, strconv.Itoa(t.ID))
+	}
+
+	return ret, nil
+}
+
+func (me *contentDirectoryService) handleBrowseImageMetadata(obj object, host string) (map[string]string, error) {
+	imageID, err := strconv.Atoi(strings.TrimPrefix(obj.Path, "image/"))
+	if err != nil {
+		return nil, upnp.Errorf(upnpav.NoSuchObjectErrorCode, "image not found")
+	}
+
+	var image *models.Image
+	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
+		image, err = me.repository.ImageFinder.Find(ctx, imageID)
+		if image != nil {
+			err = image.LoadPrimaryFile(ctx, me.repository.FileGetter)
+		}
+
+		return err
+	}); err != nil {
+		logger.Error(err.Error())
+	}
+
+	if image == nil {
+		return nil, upnp.Errorf(upnpav.NoSuchObjectErrorCode, "image not found")
+	}
+
+	const maxUpdateID int64 = 1 << 32
+	updateID := fmt.Sprint(image.UpdatedAt.Unix() % maxUpdateID)
+
+	return makeBrowseResult([]interface{}{imageToContainer(image, "-1", host)}, updateID)
+}
+
+func (me *contentDirectoryService) handleBrowseMarkerMetadata(obj object, host string, profile *deviceProfile) (map[string]string, error) {
+	markerID, err := strconv.Atoi(strings.TrimPrefix(obj.Path, "marker/"))
+	if err != nil {
+		return nil, upnp.Errorf(upnpav.NoSuchObjectErrorCode, "marker not found")
+	}
+
+	var marker *models.SceneMarker
+	var scene *models.Scene
+	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
+		marker, err = me.repository.SceneMarkerFinder.Find(ctx, markerID)
+		if err != nil || marker == nil {
+			return err
+		}
+
+		scene, err = me.repository.SceneFinder.Find(ctx, marker.SceneID)
+		if scene != nil {
+			err = scene.LoadPrimaryFile(ctx, me.repository.FileGetter)
+		}
+
+		return err
+	}); err != nil {
+		logger.Error(err.Error())
+	}
+
+	if marker == nil || scene == nil {
+		return nil, upnp.Errorf(upnpav.NoSuchObjectErrorCode, "marker not found")
+	}
+
+	const maxUpdateID int64 = 1 << 32
+	updateID := fmt.Sprint(marker.UpdatedAt.Unix() % maxUpdateID)
+
+	return makeBrowseResult([]interface{}{markerToContainer(marker, scene, "-1", host, profile)}, updateID)
+}
+
 func makeBrowseResult(objs []interface{}, updateID string) (map[string]string, error) {
 	result, err := xml.Marshal(objs)
 	if err != nil {
@@ -436,6 +734,9 @@ func getRootObjects() []interface{} {
 	objs = append(objs, makeStorageFolder("studios", "studios", rootID))
 	objs = append(objs, makeStorageFolder("movies", "movies", rootID))
 	objs = append(objs, makeStorageFolder("rating", "rating", rootID))
+	objs = append(objs, makeStorageFolder("saved-filters", "saved filters", rootID))
+	objs = append(objs, makeStorageFolder("galleries", "galleries", rootID))
+	objs = append(objs, makeStorageFolder("markers", "markers", rootID))
 
 	return objs
 }
@@ -449,13 +750,36 @@ func getSortDirection(sceneFilter *models.SceneFilterType, sort string) models.S
 	return direction
 }
 
-func (me *contentDirectoryService) getVideos(sceneFilter *models.SceneFilterType, parentID string, host string) []interface{} {
+// getSceneSort returns the sort and direction used to browse scenes.
+// The sort and direction of findFilter are used if set.
+func (me *contentDirectoryService) getSceneSort(sceneFilter *models.SceneFilterType, findFilter *models.FindFilterType) (string, models.SortDirectionEnum) {
+	sort := me.VideoSortOrder
+	if findFilter != nil && findFilter.Sort != nil {
+		sort = *findFilter.Sort
+	}
+
+	direction := getSortDirection(sceneFilter, sort)
+	if findFilter != nil && findFilter.Direction != nil {
+		direction = *findFilter.Direction
+	}
+
+	return sort, direction
+}
+
+// getVideos returns the scenes matching sceneFilter, or page folders if
+// there are too many. The query, sort and direction of findFilter are used if set.
+func (me *contentDirectoryService) getVideos(sceneFilter *models.SceneFilterType, findFilter *models.FindFilterType, parentID string, host string, profile *deviceProfile) []interface{} {
 	var objs []interface{}
 
+	var query *string
+	if findFilter != nil {
+		query = findFilter.Q
+	}
+
 	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
-		sort := me.VideoSortOrder
-		direction := getSortDirection(sceneFilter, sort)
+		sort, direction := me.getSceneSort(sceneFilter, findFilter)
 		findFilter := &models.FindFilterType{
+			Q:         query,
 			PerPage:   &pageSize,
 			Sort:      &sort,
 			Direction: &direction,
@@ -469,6 +793,7 @@ func (me *contentDirectoryService) getVideos(sceneFilter *models.SceneFilterType
 		if total > pageSize {
 			pager := scenePager{
 				sceneFilter: sceneFilter,
+				query:       query,
 				parentID:    parentID,
 			}
 
@@ -482,7 +807,7 @@ func (me *contentDirectoryService) getVideos(sceneFilter *models.SceneFilterType
 					return err
 				}
 
-				objs = append(objs, sceneToContainer(s, parentID, host))
+				objs = append(objs, sceneToContainer(s, parentID, host, profile))
 			}
 		}
 
@@ -494,7 +819,7 @@ func (me *contentDirectoryService) getVideos(sceneFilter *models.SceneFilterType
 	return objs
 }
 
-func (me *contentDirectoryService) getPageVideos(sceneFilter *models.SceneFilterType, parentID string, page int, host string) []interface{} {
+func (me *contentDirectoryService) getPageVideos(sceneFilter *models.SceneFilterType, findFilter *models.FindFilterType, parentID string, page int, host string, profile *deviceProfile) []interface{} {
 	var objs []interface{}
 
 	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
@@ -502,11 +827,13 @@ func (me *contentDirectoryService) getPageVideos(sceneFilter *models.SceneFilter
 			sceneFilter: sceneFilter,
 			parentID:    parentID,
 		}
+		if findFilter != nil {
+			pager.query = findFilter.Q
+		}
 
-		sort := me.VideoSortOrder
-		direction := getSortDirection(sceneFilter, sort)
+		sort, direction := me.getSceneSort(sceneFilter, findFilter)
 		var err error
-		objs, err = pager.getPageVideos(ctx, me.repository.SceneFinder, me.repository.FileGetter, page, host, sort, direction)
+		objs, err = pager.getPageVideos(ctx, me.repository.SceneFinder, me.repository.FileGetter, page, host, profile, sort, direction)
 		if err != nil {
 			return err
 		}
@@ -533,8 +860,8 @@ func getPageFromID(paths []string) *int {
 	return &ret
 }
 
-func (me *contentDirectoryService) getAllScenes(host string) []interface{} {
-	return me.getVideos(&models.SceneFilterType{}, "all", host)
+func (me *contentDirectoryService) getAllScenes(findFilter *models.FindFilterType, host string, profile *deviceProfile) []interface{} {
+	return me.getVideos(&models.SceneFilterType{}, findFilter, "all", host, profile)
 }
 
 func (me *contentDirectoryService) getStudios() []interface{} {
@@ -558,7 +885,7 @@ func (me *contentDirectoryService) getStudios() []interface{} {
 	return objs
 }
 
-func (me *contentDirectoryService) getStudioScenes(paths []string, host string) []interface{} {
+func (me *contentDirectoryService) getStudioScenes(paths []string, findFilter *models.FindFilterType, host string, profile *deviceProfile) []interface{} {
 	sceneFilter := &models.SceneFilterType{
 		Studios: &models.HierarchicalMultiCriterionInput{
 			Modifier: models.CriterionModifierIncludes,
@@ -570,10 +897,10 @@ func (me *contentDirectoryService) getStudioScenes(paths []string, host string)
 
 	page := getPageFromID(paths)
 	if page != nil {
-		return me.getPageVideos(sceneFilter, parentID, *page, host)
+		return me.getPageVideos(sceneFilter, findFilter, parentID, *page, host, profile)
 	}
 
-	return me.getVideos(sceneFilter, parentID, host)
+	return me.getVideos(sceneFilter, findFilter, parentID, host, profile)
 }
 
 func (me *contentDirectoryService) getTags() []interface{} {
@@ -597,7 +924,7 @@ func (me *contentDirectoryService) getTags() []interface{} {
 	return objs
 }
 
-func (me *contentDirectoryService) getTagScenes(paths []string, host string) []interface{} {
+func (me *contentDirectoryService) getTagScenes(paths []string, findFilter *models.FindFilterType, host string, profile *deviceProfile) []interface{} {
 	sceneFilter := &models.SceneFilterType{
 		Tags: &models.HierarchicalMultiCriterionInput{
 			Modifier: models.CriterionModifierIncludes,
@@ -609,10 +936,10 @@ func (me *contentDirectoryService) getTagScenes(paths []string, host string) []i
 
 	page := getPageFromID(paths)
 	if page != nil {
-		return me.getPageVideos(sceneFilter, parentID, *page, host)
+		return me.getPageVideos(sceneFilter, findFilter, parentID, *page, host, profile)
 	}
 
-	return me.getVideos(sceneFilter, parentID, host)
+	return me.getVideos(sceneFilter, findFilter, parentID, host, profile)
 }
 
 func (me *contentDirectoryService) getPerformers() []interface{} {
@@ -636,7 +963,7 @@ func (me *contentDirectoryService) getPerformers() []interface{} {
 	return objs
 }
 
-func (me *contentDirectoryService) getPerformerScenes(paths []string, host string) []interface{} {
+func (me *contentDirectoryService) getPerformerScenes(paths []string, findFilter *models.FindFilterType, host string, profile *deviceProfile) []interface{} {
 	sceneFilter := &models.SceneFilterType{
 		Performers: &models.MultiCriterionInput{
 			Modifier: models.CriterionModifierIncludes,
@@ -648,10 +975,10 @@ func (me *contentDirectoryService) getPerformerScenes(paths []string, host strin
 
 	page := getPageFromID(paths)
 	if page != nil {
-		return me.getPageVideos(sceneFilter, parentID, *page, host)
+		return me.getPageVideos(sceneFilter, findFilter, parentID, *page, host, profile)
 	}
 
-	return me.getVideos(sceneFilter, parentID, host)
+	return me.getVideos(sceneFilter, findFilter, parentID, host, profile)
 }
 
 func (me *contentDirectoryService) getMovies() []interface{} {
@@ -675,7 +1002,7 @@ func (me *contentDirectoryService) getMovies() []interface{} {
 	return objs
 }
 
-func (me *contentDirectoryService) getMovieScenes(paths []string, host string) []interface{} {
+func (me *contentDirectoryService) getMovieScenes(paths []string, findFilter *models.FindFilterType, host string, profile *deviceProfile) []interface{} {
 	sceneFilter := &models.SceneFilterType{
 		Movies: &models.MultiCriterionInput{
 			Modifier: models.CriterionModifierIncludes,
@@ -687,10 +1014,10 @@ func (me *contentDirectoryService) getMovieScenes(paths []string, host string) [
 
 	page := getPageFromID(paths)
 	if page != nil {
-		return me.getPageVideos(sceneFilter, parentID, *page, host)
+		return me.getPageVideos(sceneFilter, findFilter, parentID, *page, host, profile)
 	}
 
-	return me.getVideos(sceneFilter, parentID, host)
+	return me.getVideos(sceneFilter, findFilter, parentID, host, profile)
 }
 
 func (me *contentDirectoryService) getRating() []interface{} {
@@ -704,7 +1031,7 @@ func (me *contentDirectoryService) getRating() []interface{} {
 	return objs
 }
 
-func (me *contentDirectoryService) getRatingScenes(paths []string, host string) []interface{} {
+func (me *contentDirectoryService) getRatingScenes(paths []string, findFilter *models.FindFilterType, host string, profile *deviceProfile) []interface{} {
 	r, err := strconv.Atoi(paths[0])
 	if err != nil {
 		return nil
@@ -721,10 +1048,274 @@ func (me *contentDirectoryService) getRatingScenes(paths []string, host string)
 
 	page := getPageFromID(paths)
 	if page != nil {
-		return me.getPageVideos(sceneFilter, parentID, *page, host)
+		return me.getPageVideos(sceneFilter, findFilter, parentID, *page, host, profile)
+	}
+
+	return me.getVideos(sceneFilter, findFilter, parentID, host, profile)
+}
+
+func (me *contentDirectoryService) getSavedFilters() []interface{} {
+	var objs []interface{}
+
+	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
+		filters, err := me.repository.SavedFilterFinder.FindByMode(ctx, models.FilterModeScenes)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range filters {
+			objs = append(objs, makeStorageFolder("saved-filters/"+strconv.Itoa(f.ID), f.Name, "saved-filters"))
+		}
+
+		return nil
+	}); err != nil {
+		logger.Errorf(err.Error())
+	}
+
+	return objs
+}
+
+func (me *contentDirectoryService) getSavedFilterScenes(paths []string, findFilter *models.FindFilterType, host string, profile *deviceProfile) []interface{} {
+	id, err := strconv.Atoi(paths[0])
+	if err != nil {
+		return nil
+	}
+
+	var savedFilter *models.SavedFilter
+	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
+		savedFilter, err = me.repository.SavedFilterFinder.Find(ctx, id)
+		return err
+	}); err != nil {
+		logger.Errorf(err.Error())
+		return nil
+	}
+
+	if savedFilter == nil || savedFilter.Mode != models.FilterModeScenes {
+		return nil
+	}
+
+	sceneFilter, err := savedSceneFilter(savedFilter)
+	if err != nil {
+		// browse with the criteria which could be converted
+		logger.Warnf("dlna: %v", err)
+	}
+
+	// the sort requested by the renderer takes precedence
+	if savedFilter.FindFilter != nil {
+		f := *savedFilter.FindFilter
+		if findFilter != nil {
+			f.Sort = findFilter.Sort
+			f.Direction = findFilter.Direction
+		}
+		findFilter = &f
+	}
+
+	parentID := "saved-filters/" + strings.Join(paths, "/")
+
+	page := getPageFromID(paths)
+	if page != nil {
+		return me.getPageVideos(sceneFilter, findFilter, parentID, *page, host, profile)
+	}
+
+	return me.getVideos(sceneFilter, findFilter, parentID, host, profile)
+}
+
+func (me *contentDirectoryService) getGalleries() []interface{} {
+	var objs []interface{}
+
+	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
+		perPage := -1
+		sort := "title"
+		direction := models.SortDirectionEnumAsc
+		galleries, _, err := me.repository.GalleryFinder.Query(ctx, &models.GalleryFilterType{}, &models.FindFilterType{
+			PerPage:   &perPage,
+			Sort:      &sort,
+			Direction: &direction,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, g := range galleries {
+			objs = append(objs, makeStorageFolder("galleries/"+strconv.Itoa(g.ID), g.GetTitle(), "galleries"))
+		}
+
+		return nil
+	}); err != nil {
+		logger.Errorf(err.Error())
+	}
+
+	return objs
+}
+
+func (me *contentDirectoryService) getGalleryImages(paths []string, host string) []interface{} {
+	imageFilter := &models.ImageFilterType{
+		Galleries: &models.MultiCriterionInput{
+			Modifier: models.CriterionModifierIncludes,
+			Value:    []string{paths[0]},
+		},
 	}
 
-	return me.getVideos(sceneFilter, parentID, host)
+	parentID := "galleries/" + paths[0]
+	page := getPageFromID(paths)
+
+	var objs []interface{}
+
+	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
+		r := me.repository.ImageFinder
+
+		if page == nil {
+			total, err := r.QueryCount(ctx, imageFilter, nil)
+			if err != nil {
+				return err
+			}
+
+			if total > pageSize {
+				objs = makePageFolders(parentID, total)
+				return nil
+			}
+		}
+
+		sort := "path"
+		direction := models.SortDirectionEnumAsc
+		findFilter := &models.FindFilterType{
+			PerPage:   &pageSize,
+			Page:      page,
+			Sort:      &sort,
+			Direction: &direction,
+		}
+
+		if page != nil {
+			parentID += "/page/" + strconv.Itoa(*page)
+		}
+
+		images, err := image.Query(ctx, r, imageFilter, findFilter)
+		if err != nil {
+			return err
+		}
+
+		for _, i := range images {
+			if err := i.LoadPrimaryFile(ctx, me.repository.FileGetter); err != nil {
+				return err
+			}
+
+			objs = append(objs, imageToContainer(i, parentID, host))
+		}
+
+		return nil
+	}); err != nil {
+		logger.Error(err.Error())
+	}
+
+	return objs
+}
+
+func (me *contentDirectoryService) getMarkerTags() []interface{} {
+	var objs []interface{}
+
+	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
+		perPage := -1
+		sort := "name"
+		direction := models.SortDirectionEnumAsc
+		tags, _, err := me.repository.TagFinder.Query(ctx, &models.TagFilterType{
+			MarkerCount: &models.IntCriterionInput{
+				Modifier: models.CriterionModifierGreaterThan,
+				Value:    0,
+			},
+		}, &models.FindFilterType{
+			PerPage:   &perPage,
+			Sort:      &sort,
+			Direction: &direction,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, t := range tags {
+			objs = append(objs, makeStorageFolder("markers/"+strconv.Itoa(t.ID), t.Name, "markers"))
+		}
+
+		return nil
+	}); err != nil {
+		logger.Errorf(err.Error())
+	}
+
+	return objs
+}
+
+func (me *contentDirectoryService) getTagMarkers(paths []string, host string, profile *deviceProfile) []interface{} {
+	markerFilter := &models.SceneMarkerFilterType{
+		Tags: &models.HierarchicalMultiCriterionInput{
+			Modifier: models.CriterionModifierIncludes,
+			Value:    []string{paths[0]},
+		},
+	}
+
+	parentID := "markers/" + paths[0]
+	page := getPageFromID(paths)
+
+	var objs []interface{}
+
+	if err := txn.WithReadTxn(context.TODO(), me.txnManager, func(ctx context.Context) error {
+		r := me.repository.SceneMarkerFinder
+
+		if page == nil {
+			total, err := r.QueryCount(ctx, markerFilter, nil)
+			if err != nil {
+				return err
+			}
+
+			if total > pageSize {
+				objs = makePageFolders(parentID, total)
+				return nil
+			}
+		}
+
+		sort := "title"
+		direction := models.SortDirectionEnumAsc
+		findFilter := &models.FindFilterType{
+			PerPage:   &pageSize,
+			Page:      page,
+			Sort:      &sort,
+			Direction: &direction,
+		}
+
+		if page != nil {
+			parentID += "/page/" + strconv.Itoa(*page)
+		}
+
+		markers, _, err := r.Query(ctx, markerFilter, findFilter)
+		if err != nil {
+			return err
+		}
+
+		var sceneIDs []int
+		for _, m := range markers {
+			sceneIDs = intslice.IntAppendUnique(sceneIDs, m.SceneID)
+		}
+
+		scenes, err := me.repository.SceneFinder.FindMany(ctx, sceneIDs)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range scenes {
+			if err := s.LoadPrimaryFile(ctx, me.repository.FileGetter); err != nil {
+				return err
+			}
+		}
+
+		for _, m := range markers {
+			s := scenes[intslice.IntIndex(sceneIDs, m.SceneID)]
+			objs = append(objs, markerToContainer(m, s, parentID, host, profile))
+		}
+
+		return nil
+	}); err != nil {
+		logger.Error(err.Error())
+	}
+
+	return objs
 }
 
 // Represents a ContentDirectory object.
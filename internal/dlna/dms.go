@@ -42,10 +42,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/anacrolix/dms/dlna"
 	"github.com/anacrolix/dms/soap"
 	"github.com/anacrolix/dms/ssdp"
 	"github.com/anacrolix/dms/upnp"
 
+	"github.com/stashapp/stash/pkg/ffmpeg"
 	"github.com/stashapp/stash/pkg/logger"
 	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/txn"
@@ -61,10 +63,13 @@ type StudioFinder interface {
 }
 
 type TagFinder interface {
+	models.TagGetter
+	models.TagQueryer
 	All(ctx context.Context) ([]*models.Tag, error)
 }
 
 type PerformerFinder interface {
+	models.PerformerQueryer
 	All(ctx context.Context) ([]*models.Performer, error)
 }
 
@@ -72,12 +77,38 @@ type MovieFinder interface {
 	All(ctx context.Context) ([]*models.Movie, error)
 }
 
+type GalleryFinder interface {
+	models.GalleryGetter
+	models.GalleryQueryer
+}
+
+type ImageFinder interface {
+	models.ImageGetter
+	models.ImageQueryer
+}
+
+type SceneMarkerFinder interface {
+	models.SceneMarkerGetter
+	models.SceneMarkerQueryer
+}
+
+type SceneActivityWriter interface {
+	SaveActivity(ctx context.Context, sceneID int, resumeTime *float64, playDuration *float64) (bool, error)
+	IncrementWatchCount(ctx context.Context, sceneID int) (int, error)
+}
+
+type SavedFilterFinder interface {
+	Find(ctx context.Context, id int) (*models.SavedFilter, error)
+	FindByMode(ctx context.Context, mode models.FilterMode) ([]*models.SavedFilter, error)
+}
+
 const (
 	serverField                 = "Linux/3.4 DLNADOC/1.50 UPnP/1.0 DMS/1.0"
 	rootDeviceType              = "urn:schemas-upnp-org:device:MediaServer:1"
 	rootDeviceModelName         = "dms 1.0xb"
 	resPath                     = "/res"
 	iconPath                    = "/icon"
+	imagePath                   = "/image"
 	rootDescPath                = "/rootDesc.xml"
 	contentDirectoryEventSubURL = "/evt/ContentDirectory"
 	serviceControlURL           = "/ctl"
@@ -274,6 +305,8 @@ type Server struct {
 	txnManager         txn.Manager
 	repository         Repository
 	sceneServer        sceneServer
+	imageServer        imageServer
+	activityTracker    *activityTracker
 	ipWhitelistManager *ipWhitelistManager
 	VideoSortOrder     string
 }
@@ -458,6 +491,115 @@ func (me *Server) serveIcon(w http.ResponseWriter, r *http.Request) {
 	me.sceneServer.ServeScreenshot(scene, w, r)
 }
 
+func (me *Server) serveResource(w http.ResponseWriter, r *http.Request) {
+	sceneId := r.URL.Query().Get("scene")
+	var scene *models.Scene
+	err := txn.WithReadTxn(r.Context(), me.txnManager, func(ctx context.Context) error {
+		sceneIdInt, err := strconv.Atoi(sceneId)
+		if err != nil {
+			return nil
+		}
+		scene, _ = me.repository.SceneFinder.Find(ctx, sceneIdInt)
+		if scene != nil {
+			return scene.LoadPrimaryFile(ctx, me.repository.FileGetter)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warnf("failed to execute read transaction for scene id (%v): %v", sceneId, err)
+	}
+
+	if scene == nil {
+		return
+	}
+
+	t := resourceType(r.URL.Query().Get("t"))
+	streamType, ok := t.streamFormat()
+	if !ok {
+		if r.Method != http.MethodHead {
+			if f := scene.Files.Primary(); f != nil {
+				position, ok := byteRangePosition(r, f.Size, f.Duration)
+				if ok || r.Header.Get("Range") == "" {
+					defer me.activityTracker.begin(r, scene, position)()
+				}
+			}
+		}
+
+		me.sceneServer.StreamSceneDirect(scene, w, r)
+		return
+	}
+
+	var startTime float64
+	if v := r.Header.Get(dlna.TimeSeekRangeDomain); v != "" {
+		startTime, err = parseTimeSeekRange(v)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotAcceptable)
+			return
+		}
+	}
+
+	// clips of a scene, such as markers, start at an offset
+	offset, _ := strconv.ParseFloat(r.URL.Query().Get("start"), 64)
+	startTime += offset
+
+	var (
+		container  ffmpeg.Container
+		videoCodec string
+		duration   float64
+	)
+	if f := scene.Files.Primary(); f != nil {
+		container = ffmpeg.Container(f.Format)
+		videoCodec = f.VideoCodec
+		duration = f.Duration
+	}
+
+	w.Header().Set(dlna.TransferModeDomain, "Streaming")
+	w.Header().Set(dlna.ContentFeaturesDomain, t.contentFeatures(container, videoCodec).String())
+	if r.Header.Get(dlna.TimeSeekRangeDomain) != "" && duration > 0 {
+		w.Header().Set(dlna.TimeSeekRangeDomain, fmt.Sprintf("npt=%.3f-%.3f/%.3f", startTime, duration, duration))
+	}
+
+	// renderers probe the resource before playing it, don't start a transcode for that
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", streamType.MimeType)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	defer me.activityTracker.begin(r, scene, startTime)()
+	me.sceneServer.StreamSceneTranscode(scene, streamType, startTime, w, r)
+}
+
+func (me *Server) serveImage(w http.ResponseWriter, r *http.Request) {
+	imageId := r.URL.Query().Get("image")
+	var image *models.Image
+	err := txn.WithReadTxn(r.Context(), me.txnManager, func(ctx context.Context) error {
+		imageIdInt, err := strconv.Atoi(imageId)
+		if err != nil {
+			return nil
+		}
+		image, _ = me.repository.ImageFinder.Find(ctx, imageIdInt)
+		if image != nil {
+			return image.LoadPrimaryFile(ctx, me.repository.FileGetter)
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Warnf("failed to execute read transaction for image id (%v): %v", imageId, err)
+	}
+
+	if image == nil {
+		return
+	}
+
+	if r.URL.Query().Has("thumb") {
+		me.imageServer.ServeThumbnail(image, w, r)
+		return
+	}
+
+	me.imageServer.ServeImage(image, w, r)
+}
+
 func (me *Server) contentDirectoryInitialEvent(ctx context.Context, urls []*url.URL, sid string) {
 	body := xmlMarshalOrPanic(upnp.PropertySet{
 		Properties: []upnp.Property{
@@ -576,27 +718,8 @@ func (me *Server) initMux(mux *http.ServeMux) {
 	})
 	mux.HandleFunc(contentDirectoryEventSubURL, me.contentDirectoryEventSubHandler)
 	mux.HandleFunc(iconPath, me.serveIcon)
-	mux.HandleFunc(resPath, func(w http.ResponseWriter, r *http.Request) {
-		sceneId := r.URL.Query().Get("scene")
-		var scene *models.Scene
-		err := txn.WithReadTxn(r.Context(), me.txnManager, func(ctx context.Context) error {
-			sceneIdInt, err := strconv.Atoi(sceneId)
-			if err != nil {
-				return nil
-			}
-			scene, _ = me.repository.SceneFinder.Find(ctx, sceneIdInt)
-			return nil
-		})
-		if err != nil {
-			logger.Warnf("failed to execute read transaction for scene id (%v): %v", sceneId, err)
-		}
-
-		if scene == nil {
-			return
-		}
-
-		me.sceneServer.StreamSceneDirect(scene, w, r)
-	})
+	mux.HandleFunc(resPath, me.serveResource)
+	mux.HandleFunc(imagePath, me.serveImage)
 	mux.HandleFunc(rootDescPath, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("content-type", `text/xml; charset="utf-8"`)
 		w.Header().Set("content-length", fmt.Sprint(len(me.rootDescXML)))
@@ -711,7 +834,8 @@ func didl_lite(chardata string) string {
 		` xmlns:dc="http://purl.org/dc/elements/1.1/"` +
 		` xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/"` +
 		` xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/"` +
-		` xmlns:dlna="urn:schemas-dlna-org:metadata-1-0/">` +
+		` xmlns:dlna="urn:schemas-dlna-org:metadata-1-0/"` +
+		` xmlns:sec="http://www.sec.co.kr/">` +
 		chardata +
 		`</DIDL-Lite>`
 }
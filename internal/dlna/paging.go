@@ -12,6 +12,7 @@ import (
 
 type scenePager struct {
 	sceneFilter *models.SceneFilterType
+	query       *string
 	parentID    string
 }
 
@@ -28,6 +29,7 @@ func (p *scenePager) getPages(ctx context.Context, r models.SceneQueryer, total
 	singlePageSize := 1
 	sort := "title"
 	findFilter := &models.FindFilterType{
+		Q:       p.query,
 		PerPage: &singlePageSize,
 		Sort:    &sort,
 	}
@@ -59,10 +61,11 @@ func (p *scenePager) getPages(ctx context.Context, r models.SceneQueryer, total
 	return objs, nil
 }
 
-func (p *scenePager) getPageVideos(ctx context.Context, r SceneFinder, f models.FileGetter, page int, host string, sort string, direction models.SortDirectionEnum) ([]interface{}, error) {
+func (p *scenePager) getPageVideos(ctx context.Context, r SceneFinder, f models.FileGetter, page int, host string, profile *deviceProfile, sort string, direction models.SortDirectionEnum) ([]interface{}, error) {
 	var objs []interface{}
 
 	findFilter := &models.FindFilterType{
+		Q:         p.query,
 		PerPage:   &pageSize,
 		Page:      &page,
 		Sort:      &sort,
@@ -79,8 +82,21 @@ func (p *scenePager) getPageVideos(ctx context.Context, r SceneFinder, f models.
 			return nil, err
 		}
 
-		objs = append(objs, sceneToContainer(s, p.parentID, host))
+		objs = append(objs, sceneToContainer(s, p.parentID, host, profile))
 	}
 
 	return objs, nil
 }
+
+// makePageFolders returns a folder for each page of total results.
+func makePageFolders(parentID string, total int) []interface{} {
+	var objs []interface{}
+
+	pages := int(math.Ceil(float64(total) / float64(pageSize)))
+	for page := 1; page <= pages; page++ {
+		id := parentID + "/page/" + strconv.Itoa(page)
+		objs = append(objs, makeStorageFolder(id, fmt.Sprintf("Page %d", page), parentID))
+	}
+
+	return objs
+}
@@ -0,0 +1,293 @@
+package dlna
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/dms/dlna"
+	"github.com/anacrolix/dms/upnpav"
+	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/models"
+)
+
+// clientInfoHeader is sent by some renderers, such as Sony devices, to identify themselves.
+const clientInfoHeader = "X-AV-Client-Info"
+
+// deviceProfile describes the media which a renderer can play without transcoding.
+type deviceProfile struct {
+	Name string
+	// Match contains substrings of the User-Agent or X-AV-Client-Info
+	// headers which identify the renderer.
+	Match       []string
+	Containers  []ffmpeg.Container
+	VideoCodecs []string
+}
+
+// defaultProfile is used for renderers which do not match any known profile.
+var defaultProfile = &deviceProfile{
+	Name:        "Default",
+	Containers:  []ffmpeg.Container{ffmpeg.Mp4, ffmpeg.M4v},
+	VideoCodecs: []string{ffmpeg.H264},
+}
+
+var deviceProfiles = []*deviceProfile{
+	{
+		Name:        "Samsung",
+		Match:       []string{"SEC_HHP_", "Samsung"},
+		Containers:  []ffmpeg.Container{ffmpeg.Mp4, ffmpeg.M4v, ffmpeg.Mov, ffmpeg.Matroska, ffmpeg.Avi, ffmpeg.Mpegts, ffmpeg.Wmv},
+		VideoCodecs: []string{ffmpeg.H264, ffmpeg.Hevc, ffmpeg.H265, ffmpeg.Mpeg2Video, ffmpeg.Vc1, "mpeg4"},
+	},
+	{
+		Name:        "LG",
+		Match:       []string{"LGE", "webOS", "LG-"},
+		Containers:  []ffmpeg.Container{ffmpeg.Mp4, ffmpeg.M4v, ffmpeg.Mov, ffmpeg.Matroska, ffmpeg.Mpegts},
+		VideoCodecs: []string{ffmpeg.H264, ffmpeg.Hevc, ffmpeg.H265, ffmpeg.Mpeg2Video},
+	},
+	{
+		Name:        "Sony Bravia",
+		Match:       []string{"BRAVIA", "KDL-"},
+		Containers:  []ffmpeg.Container{ffmpeg.Mp4, ffmpeg.M4v, ffmpeg.Matroska, ffmpeg.Mpegts},
+		VideoCodecs: []string{ffmpeg.H264, ffmpeg.Hevc, ffmpeg.Mpeg2Video},
+	},
+	{
+		Name:        "PlayStation",
+		Match:       []string{"PLAYSTATION"},
+		Containers:  []ffmpeg.Container{ffmpeg.Mp4, ffmpeg.M4v, ffmpeg.Mpegts},
+		VideoCodecs: []string{ffmpeg.H264, ffmpeg.Mpeg2Video},
+	},
+	{
+		Name:        "Xbox",
+		Match:       []string{"Xbox", "Xenon"},
+		Containers:  []ffmpeg.Container{ffmpeg.Mp4, ffmpeg.M4v, ffmpeg.Avi, ffmpeg.Wmv},
+		VideoCodecs: []string{ffmpeg.H264, ffmpeg.Vc1, "mpeg4"},
+	},
+}
+
+// matchDeviceProfile returns the profile of the renderer making the request.
+func matchDeviceProfile(r *http.Request) *deviceProfile {
+	headers := []string{r.UserAgent(), r.Header.Get(clientInfoHeader)}
+
+	for _, p := range deviceProfiles {
+		for _, m := range p.Match {
+			for _, h := range headers {
+				if h != "" && strings.Contains(h, m) {
+					return p
+				}
+			}
+		}
+	}
+
+	return defaultProfile
+}
+
+func (p *deviceProfile) canDirectPlay(container ffmpeg.Container, videoCodec string) bool {
+	containerOK := false
+	for _, c := range p.Containers {
+		if c == container {
+			containerOK = true
+			break
+		}
+	}
+
+	if !containerOK {
+		return false
+	}
+
+	for _, c := range p.VideoCodecs {
+		if c == videoCodec {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resourceType is a variant in which a scene is served.
+type resourceType string
+
+const (
+	// resourceOriginal serves the file as is.
+	resourceOriginal resourceType = ""
+	// resourceRemux copies the video stream into a fragmented mp4.
+	resourceRemux resourceType = "remux"
+	// resourceTranscode transcodes the file to h264 in MPEG-TS.
+	resourceTranscode resourceType = "transcode"
+)
+
+const (
+	dlnaProfileMP4AVC = "AVC_MP4_HP_HD_AAC"
+	dlnaProfileTSAVC  = "AVC_TS_MP_HD_AAC_MULT5_ISO"
+	dlnaProfileTSMPEG = "MPEG_TS_HD_NA_ISO"
+)
+
+var containerMimeTypes = map[ffmpeg.Container]string{
+	ffmpeg.Mp4:      ffmpeg.MimeMp4Video,
+	ffmpeg.M4v:      ffmpeg.MimeMp4Video,
+	ffmpeg.Mov:      "video/quicktime",
+	ffmpeg.Matroska: ffmpeg.MimeMkvVideo,
+	ffmpeg.Webm:     ffmpeg.MimeWebmVideo,
+	ffmpeg.Avi:      "video/x-msvideo",
+	ffmpeg.Wmv:      "video/x-ms-wmv",
+	ffmpeg.Flv:      "video/x-flv",
+	ffmpeg.Mpegts:   ffmpeg.MimeMpegVideo,
+}
+
+// streamFormat returns the live transcode format of the resource type.
+// Returns false for resourceOriginal.
+func (t resourceType) streamFormat() (ffmpeg.StreamFormat, bool) {
+	switch t {
+	case resourceRemux:
+		return ffmpeg.StreamTypeRemux, true
+	case resourceTranscode:
+		return ffmpeg.StreamTypeMPEGTS, true
+	}
+
+	return ffmpeg.StreamFormat{}, false
+}
+
+// contentFeatures returns the DLNA content features of the resource type
+// for a file with the given container and video codec.
+func (t resourceType) contentFeatures(container ffmpeg.Container, videoCodec string) dlna.ContentFeatures {
+	switch t {
+	case resourceRemux:
+		return dlna.ContentFeatures{
+			ProfileName:     dlnaProfileMP4AVC,
+			SupportTimeSeek: true,
+			Transcoded:      true,
+		}
+	case resourceTranscode:
+		return dlna.ContentFeatures{
+			ProfileName:     dlnaProfileTSAVC,
+			SupportTimeSeek: true,
+			Transcoded:      true,
+		}
+	}
+
+	ret := dlna.ContentFeatures{
+		SupportRange: true,
+	}
+
+	switch {
+	case (container == ffmpeg.Mp4 || container == ffmpeg.M4v) && videoCodec == ffmpeg.H264:
+		ret.ProfileName = dlnaProfileMP4AVC
+	case container == ffmpeg.Mpegts && videoCodec == ffmpeg.H264:
+		ret.ProfileName = dlnaProfileTSAVC
+	case container == ffmpeg.Mpegts && videoCodec == ffmpeg.Mpeg2Video:
+		ret.ProfileName = dlnaProfileTSMPEG
+	}
+
+	return ret
+}
+
+func (t resourceType) mimeType(container ffmpeg.Container) string {
+	if f, ok := t.streamFormat(); ok {
+		return f.MimeType
+	}
+
+	if ret, ok := containerMimeTypes[container]; ok {
+		return ret
+	}
+
+	return ffmpeg.MimeMp4Video
+}
+
+// resourceTypes returns the variants in which the file is offered to the
+// renderer, in order of preference.
+func (p *deviceProfile) resourceTypes(container ffmpeg.Container, videoCodec string) []resourceType {
+	remux := ffmpeg.IsRemuxable(videoCodec)
+
+	switch {
+	case p.canDirectPlay(container, videoCodec):
+		ret := []resourceType{resourceOriginal}
+		if remux {
+			ret = append(ret, resourceRemux)
+		}
+		return append(ret, resourceTranscode)
+	case remux && p.canDirectPlay(ffmpeg.Mp4, videoCodec):
+		return []resourceType{resourceRemux, resourceTranscode, resourceOriginal}
+	default:
+		return []resourceType{resourceTranscode, resourceOriginal}
+	}
+}
+
+// sceneResources returns the resources of the primary file of the scene.
+// If start is greater than zero, the resources stream from start seconds,
+// and the original file is not offered.
+func sceneResources(scene *models.Scene, host string, profile *deviceProfile, start float64) []upnpav.Resource {
+	f := scene.Files.Primary()
+	if f == nil {
+		return nil
+	}
+
+	container := ffmpeg.Container(f.Format)
+	duration := formatDurationSexagesimal(time.Duration(math.Max(f.Duration-start, 0) * float64(time.Second)))
+	resolution := fmt.Sprintf("%dx%d", f.Width, f.Height)
+
+	var ret []upnpav.Resource
+	for _, t := range profile.resourceTypes(container, f.VideoCodec) {
+		if t == resourceOriginal && start > 0 {
+			continue
+		}
+
+		query := url.Values{
+			"scene": {strconv.Itoa(scene.ID)},
+		}
+		if t != resourceOriginal {
+			query.Set("t", string(t))
+		}
+		if start > 0 {
+			query.Set("start", strconv.FormatFloat(start, 'f', -1, 64))
+		}
+
+		res := upnpav.Resource{
+			URL: (&url.URL{
+				Scheme:   "http",
+				Host:     host,
+				Path:     resPath,
+				RawQuery: query.Encode(),
+			}).String(),
+			ProtocolInfo: fmt.Sprintf("http-get:*:%s:%s", t.mimeType(container), t.contentFeatures(container, f.VideoCodec).String()),
+			Duration:     duration,
+			Resolution:   resolution,
+		}
+
+		if t == resourceOriginal {
+			res.Size = uint64(f.Size)
+			res.Bitrate = uint(f.BitRate)
+		}
+
+		ret = append(ret, res)
+	}
+
+	return ret
+}
+
+// parseTimeSeekRange returns the start time in seconds of a
+// TimeSeekRange.dlna.org header value, such as "npt=10.5-" or "npt=0:01:10-".
+func parseTimeSeekRange(v string) (float64, error) {
+	v = strings.TrimSpace(v)
+	if !strings.HasPrefix(v, "npt=") {
+		return 0, fmt.Errorf("unsupported time seek range: %s", v)
+	}
+
+	start, _, _ := strings.Cut(strings.TrimPrefix(v, "npt="), "-")
+	if start == "" {
+		return 0, nil
+	}
+
+	var ret float64
+	for _, part := range strings.Split(start, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time seek range: %s", v)
+		}
+		ret = ret*60 + n
+	}
+
+	return ret, nil
+}
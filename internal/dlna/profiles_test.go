@@ -0,0 +1,83 @@
+package dlna
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchDeviceProfile(t *testing.T) {
+	tests := []struct {
+		name       string
+		userAgent  string
+		clientInfo string
+		want       string
+	}{
+		{"samsung", "SEC_HHP_[TV] Samsung Q7 Series (55)/1.0 DLNADOC/1.50", "", "Samsung"},
+		{"sony client info", "UPnP/1.0", "av=5.0; cn=\"Sony Corporation\"; mn=\"BRAVIA KDL-40EX720\"", "Sony Bravia"},
+		{"playstation", "PLAYSTATION 3", "", "PlayStation"},
+		{"unknown", "VLC/3.0.18 LibVLC/3.0.18", "", "Default"},
+		{"empty", "", "", "Default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &http.Request{Header: http.Header{}}
+			r.Header.Set("User-Agent", tt.userAgent)
+			if tt.clientInfo != "" {
+				r.Header.Set(clientInfoHeader, tt.clientInfo)
+			}
+
+			assert.Equal(t, tt.want, matchDeviceProfile(r).Name)
+		})
+	}
+}
+
+func TestDeviceProfile_resourceTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		container  ffmpeg.Container
+		videoCodec string
+		want       []resourceType
+	}{
+		{"direct play", ffmpeg.Mp4, ffmpeg.H264, []resourceType{resourceOriginal, resourceRemux, resourceTranscode}},
+		{"remux", ffmpeg.Matroska, ffmpeg.H264, []resourceType{resourceRemux, resourceTranscode, resourceOriginal}},
+		{"transcode", ffmpeg.Matroska, ffmpeg.Hevc, []resourceType{resourceTranscode, resourceOriginal}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, defaultProfile.resourceTypes(tt.container, tt.videoCodec))
+		})
+	}
+}
+
+func TestParseTimeSeekRange(t *testing.T) {
+	tests := []struct {
+		v       string
+		want    float64
+		wantErr bool
+	}{
+		{"npt=10.5-", 10.5, false},
+		{"npt=0:01:10-", 70, false},
+		{"npt=1:00:00.5-1:10:00", 3600.5, false},
+		{"npt=-", 0, false},
+		{"bytes=0-", 0, true},
+		{"npt=abc-", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.v, func(t *testing.T) {
+			got, err := parseTimeSeekRange(tt.v)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package dlna
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+// savedSceneFilter converts the object filter of a saved scene filter into a
+// SceneFilterType. Saved filters store the criteria as they are held by the UI,
+// which differ from the filter input for criteria which reference other objects
+// or have a value range. Criteria which cannot be converted are returned as
+// an error, and are omitted from the returned filter.
+func savedSceneFilter(f *models.SavedFilter) (*models.SceneFilterType, error) {
+	ret := &models.SceneFilterType{}
+
+	var invalid []string
+	for field, c := range f.ObjectFilter {
+		criterion, ok := c.(map[string]interface{})
+		if !ok {
+			invalid = append(invalid, field)
+			continue
+		}
+
+		if !unmarshalCriterion(ret, field, criterion) {
+			invalid = append(invalid, field)
+		}
+	}
+
+	if len(invalid) > 0 {
+		return ret, fmt.Errorf("unsupported criteria in saved filter %q: %v", f.Name, invalid)
+	}
+
+	return ret, nil
+}
+
+// unmarshalCriterion sets field of out to the converted criterion.
+// Returns false if the criterion could not be converted.
+func unmarshalCriterion(out interface{}, field string, criterion map[string]interface{}) bool {
+	for _, v := range criterionCandidates(criterion) {
+		data, err := json.Marshal(map[string]interface{}{field: v})
+		if err != nil {
+			continue
+		}
+
+		// check against an empty value first, so that a failed
+		// conversion does not leave out partially set
+		tmp := reflect.New(reflect.TypeOf(out).Elem()).Interface()
+		if err := json.Unmarshal(data, tmp); err != nil {
+			continue
+		}
+
+		return json.Unmarshal(data, out) == nil
+	}
+
+	return false
+}
+
+// criterionCandidates returns the possible filter input values of a saved
+// criterion, in order of preference.
+func criterionCandidates(criterion map[string]interface{}) []interface{} {
+	modifier := criterion["modifier"]
+	value := criterion["value"]
+
+	input := map[string]interface{}{
+		"modifier": modifier,
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if items, ok := v["items"]; ok {
+			// hierarchical criterion, such as tags or studios
+			input["value"] = criterionItemIDs(items)
+			if excluded, ok := v["excluded"]; ok {
+				input["excludes"] = criterionItemIDs(excluded)
+			}
+			if depth, ok := v["depth"]; ok {
+				input["depth"] = depth
+			}
+		} else {
+			// range criterion, such as rating or duration
+			for k, vv := range v {
+				input[k] = vv
+			}
+		}
+	case []interface{}:
+		// multi criterion, such as performers
+		input["value"] = criterionItemIDs(v)
+	default:
+		input["value"] = v
+	}
+
+	ret := []interface{}{input}
+
+	// boolean criteria are stored as strings, and do not have a modifier
+	if s, ok := value.(string); ok {
+		if b, err := strconv.ParseBool(s); err == nil {
+			ret = append(ret, b)
+		}
+		ret = append(ret, s)
+	}
+
+	return ret
+}
+
+// criterionItemIDs returns the IDs of a list of saved criterion items.
+func criterionItemIDs(v interface{}) []string {
+	items, _ := v.([]interface{})
+
+	ret := []string{}
+	for _, item := range items {
+		switch i := item.(type) {
+		case map[string]interface{}:
+			if id, ok := i["id"]; ok {
+				ret = append(ret, fmt.Sprint(id))
+			}
+		default:
+			ret = append(ret, fmt.Sprint(i))
+		}
+	}
+
+	return ret
+}
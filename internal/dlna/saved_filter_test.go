@@ -0,0 +1,99 @@
+package dlna
+
+import (
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavedSceneFilter(t *testing.T) {
+	depth := 1
+	truthy := true
+
+	f := &models.SavedFilter{
+		Name: "test",
+		ObjectFilter: map[string]interface{}{
+			"title": map[string]interface{}{
+				"modifier": "INCLUDES",
+				"value":    "foo",
+			},
+			"rating100": map[string]interface{}{
+				"modifier": "GREATER_THAN",
+				"value": map[string]interface{}{
+					"value": 60,
+				},
+			},
+			"tags": map[string]interface{}{
+				"modifier": "INCLUDES_ALL",
+				"value": map[string]interface{}{
+					"items": []interface{}{
+						map[string]interface{}{"id": "1", "label": "tag 1"},
+						map[string]interface{}{"id": "2", "label": "tag 2"},
+					},
+					"excluded": []interface{}{
+						map[string]interface{}{"id": "3", "label": "tag 3"},
+					},
+					"depth": depth,
+				},
+			},
+			"performers": map[string]interface{}{
+				"modifier": "INCLUDES",
+				"value": []interface{}{
+					map[string]interface{}{"id": "4", "label": "performer"},
+				},
+			},
+			"organized": map[string]interface{}{
+				"modifier": "EQUALS",
+				"value":    "true",
+			},
+		},
+	}
+
+	got, err := savedSceneFilter(f)
+	assert.Nil(t, err)
+
+	assert.Equal(t, &models.StringCriterionInput{
+		Value:    "foo",
+		Modifier: models.CriterionModifierIncludes,
+	}, got.Title)
+	assert.Equal(t, &models.IntCriterionInput{
+		Value:    60,
+		Modifier: models.CriterionModifierGreaterThan,
+	}, got.Rating100)
+	assert.Equal(t, &models.HierarchicalMultiCriterionInput{
+		Value:    []string{"1", "2"},
+		Excludes: []string{"3"},
+		Depth:    &depth,
+		Modifier: models.CriterionModifierIncludesAll,
+	}, got.Tags)
+	assert.Equal(t, &models.MultiCriterionInput{
+		Value:    []string{"4"},
+		Modifier: models.CriterionModifierIncludes,
+	}, got.Performers)
+	assert.Equal(t, &truthy, got.Organized)
+}
+
+func TestSavedSceneFilter_invalid(t *testing.T) {
+	f := &models.SavedFilter{
+		Name: "test",
+		ObjectFilter: map[string]interface{}{
+			"title": map[string]interface{}{
+				"modifier": "INCLUDES",
+				"value":    "foo",
+			},
+			"rating100": map[string]interface{}{
+				"modifier": "GREATER_THAN",
+				"value":    "not a number",
+			},
+			"bad": "value",
+		},
+	}
+
+	got, err := savedSceneFilter(f)
+	assert.Error(t, err)
+
+	// valid criteria are still converted
+	assert.NotNil(t, got.Title)
+	assert.Nil(t, got.Rating100)
+}
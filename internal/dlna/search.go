@@ -0,0 +1,504 @@
+package dlna
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+// upnpSearchCriteriaErrorCode is returned by Search for search criteria
+// which cannot be parsed or are not supported.
+const upnpSearchCriteriaErrorCode = 708
+
+// searchCapabilities are the properties which may be used in search criteria.
+var searchCapabilities = []string{
+	"dc:title",
+	"dc:creator",
+	"upnp:artist",
+	"upnp:actor",
+	"upnp:genre",
+	"upnp:class",
+}
+
+// sortCapabilities maps the properties which may be used in sort criteria
+// to the scene sort.
+var sortCapabilities = []struct {
+	property string
+	sort     string
+}{
+	{"dc:title", "title"},
+	{"dc:date", "date"},
+	{"upnp:rating", "rating"},
+}
+
+const videoItemClass = "object.item.videoItem"
+
+// searchExpr is a node of parsed UPnP search criteria.
+type searchExpr interface{}
+
+// searchLogical joins two expressions with `and` or `or`.
+type searchLogical struct {
+	or          bool
+	left, right searchExpr
+}
+
+// searchRel is a relational expression, such as `dc:title contains "foo"`.
+type searchRel struct {
+	property string
+	op       string
+	value    string
+}
+
+// searchAll matches all objects, and is the result of the `*` criteria.
+type searchAll struct{}
+
+type searchToken struct {
+	value  string
+	quoted bool
+}
+
+func tokenizeSearchCriteria(s string) ([]searchToken, error) {
+	var ret []searchToken
+
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')':
+			ret = append(ret, searchToken{value: string(c)})
+			i++
+		case c == '"':
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(r) {
+				if r[i] == '\\' && i+1 < len(r) {
+					sb.WriteRune(r[i+1])
+					i += 2
+					continue
+				}
+				if r[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(r[i])
+				i++
+			}
+			if !closed {
+				return nil, errors.New("unterminated string")
+			}
+			ret = append(ret, searchToken{value: sb.String(), quoted: true})
+		default:
+			start := i
+			for i < len(r) && !unicode.IsSpace(r[i]) && r[i] != '(' && r[i] != ')' && r[i] != '"' {
+				i++
+			}
+			ret = append(ret, searchToken{value: string(r[start:i])})
+		}
+	}
+
+	return ret, nil
+}
+
+type searchParser struct {
+	tokens []searchToken
+	pos    int
+}
+
+func (p *searchParser) peek() *searchToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+func (p *searchParser) next() (searchToken, error) {
+	t := p.peek()
+	if t == nil {
+		return searchToken{}, errors.New("unexpected end of search criteria")
+	}
+	p.pos++
+	return *t, nil
+}
+
+func (p *searchParser) peekKeyword(k string) bool {
+	t := p.peek()
+	return t != nil && !t.quoted && strings.EqualFold(t.value, k)
+}
+
+// parseOr parses expressions joined by `or`, which binds more loosely than `and`.
+func (p *searchParser) parseOr() (searchExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = searchLogical{or: true, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *searchParser) parseAnd() (searchExpr, error) {
+	left, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		left = searchLogical{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *searchParser) parsePrimary() (searchExpr, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if !t.quoted && t.value == "(" {
+		ret, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if t, err := p.next(); err != nil || t.quoted || t.value != ")" {
+			return nil, errors.New("expected )")
+		}
+
+		return ret, nil
+	}
+
+	if t.quoted {
+		return nil, fmt.Errorf("expected property, got %q", t.value)
+	}
+
+	op, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := searchRel{
+		property: t.value,
+		op:       op.value,
+		value:    value.value,
+	}
+
+	switch ret.op {
+	case "exists":
+		if value.quoted || (value.value != "true" && value.value != "false") {
+			return nil, fmt.Errorf("invalid exists value %q", value.value)
+		}
+	case "=", "!=", "<", "<=", ">", ">=", "contains", "doesNotContain", "derivedfrom":
+		if !value.quoted {
+			return nil, fmt.Errorf("expected quoted value, got %q", value.value)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported operator %q", ret.op)
+	}
+
+	return ret, nil
+}
+
+// parseSearchCriteria parses the SearchCriteria argument of a Search action.
+func parseSearchCriteria(s string) (searchExpr, error) {
+	if strings.TrimSpace(s) == "*" {
+		return searchAll{}, nil
+	}
+
+	tokens, err := tokenizeSearchCriteria(s)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &searchParser{tokens: tokens}
+	ret, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() != nil {
+		return nil, fmt.Errorf("unexpected %q", p.peek().value)
+	}
+
+	return ret, nil
+}
+
+// searchResolver returns the IDs of the objects whose names match the criterion.
+type searchResolver interface {
+	performerIDs(name *models.StringCriterionInput) ([]string, error)
+	tagIDs(name *models.StringCriterionInput) ([]string, error)
+}
+
+// searchFilter is the result of translating search criteria.
+// A nil filter matches all scenes, unless none is true.
+type searchFilter struct {
+	filter *models.SceneFilterType
+	none   bool
+}
+
+func (f searchFilter) all() bool {
+	return !f.none && f.filter == nil
+}
+
+func (f searchFilter) hasSubFilter() bool {
+	return f.filter.And != nil || f.filter.Or != nil || f.filter.Not != nil
+}
+
+var errSearchTooComplex = errors.New("search criteria too complex")
+
+func combineSearchFilters(left, right searchFilter, or bool) (searchFilter, error) {
+	switch {
+	case or && (left.all() || right.all()):
+		return searchFilter{}, nil
+	case !or && (left.none || right.none):
+		return searchFilter{none: true}, nil
+	case or && left.none, !or && left.all():
+		return right, nil
+	case or && right.none, !or && right.all():
+		return left, nil
+	}
+
+	// a filter may only have a single sub-filter
+	set := func(f *models.SceneFilterType, sub *models.SceneFilterType) {
+		if or {
+			f.Or = sub
+		} else {
+			f.And = sub
+		}
+	}
+
+	switch {
+	case !left.hasSubFilter():
+		set(left.filter, right.filter)
+		return left, nil
+	case !right.hasSubFilter():
+		set(right.filter, left.filter)
+		return right, nil
+	}
+
+	return searchFilter{}, errSearchTooComplex
+}
+
+// searchNameCriterion returns the criterion on the name of the object for
+// the relational operator, and whether the operator is negated.
+func searchNameCriterion(rel searchRel) (*models.StringCriterionInput, bool, error) {
+	ret := &models.StringCriterionInput{
+		Value: rel.value,
+	}
+
+	negated := false
+	switch rel.op {
+	case "=":
+		ret.Modifier = models.CriterionModifierEquals
+	case "!=":
+		ret.Modifier = models.CriterionModifierEquals
+		negated = true
+	case "contains":
+		ret.Modifier = models.CriterionModifierIncludes
+	case "doesNotContain":
+		ret.Modifier = models.CriterionModifierIncludes
+		negated = true
+	default:
+		return nil, false, fmt.Errorf("unsupported operator %q for %s", rel.op, rel.property)
+	}
+
+	return ret, negated, nil
+}
+
+func searchRelFilter(rel searchRel, r searchResolver) (searchFilter, error) {
+	switch rel.property {
+	case "upnp:class":
+		switch rel.op {
+		case "exists":
+			return searchFilter{none: rel.value == "false"}, nil
+		case "=":
+			return searchFilter{none: rel.value != videoItemClass}, nil
+		case "derivedfrom":
+			return searchFilter{none: !strings.HasPrefix(videoItemClass, rel.value)}, nil
+		}
+	case "dc:title":
+		if rel.op == "exists" {
+			// all scenes have a title
+			return searchFilter{none: rel.value == "false"}, nil
+		}
+
+		c, negated, err := searchNameCriterion(rel)
+		if err != nil {
+			return searchFilter{}, err
+		}
+
+		if negated {
+			if c.Modifier == models.CriterionModifierEquals {
+				c.Modifier = models.CriterionModifierNotEquals
+			} else {
+				c.Modifier = models.CriterionModifierExcludes
+			}
+		}
+
+		return searchFilter{filter: &models.SceneFilterType{Title: c}}, nil
+	case "dc:creator", "upnp:artist", "upnp:actor":
+		if rel.op == "exists" {
+			return searchFilter{filter: &models.SceneFilterType{
+				Performers: &models.MultiCriterionInput{Modifier: existsModifier(rel.value)},
+			}}, nil
+		}
+
+		c, negated, err := searchNameCriterion(rel)
+		if err != nil {
+			return searchFilter{}, err
+		}
+
+		ids, err := r.performerIDs(c)
+		if err != nil {
+			return searchFilter{}, err
+		}
+
+		if len(ids) == 0 {
+			return searchFilter{none: !negated}, nil
+		}
+
+		modifier := models.CriterionModifierIncludes
+		if negated {
+			modifier = models.CriterionModifierExcludes
+		}
+
+		return searchFilter{filter: &models.SceneFilterType{
+			Performers: &models.MultiCriterionInput{Value: ids, Modifier: modifier},
+		}}, nil
+	case "upnp:genre":
+		if rel.op == "exists" {
+			return searchFilter{filter: &models.SceneFilterType{
+				Tags: &models.HierarchicalMultiCriterionInput{Modifier: existsModifier(rel.value)},
+			}}, nil
+		}
+
+		c, negated, err := searchNameCriterion(rel)
+		if err != nil {
+			return searchFilter{}, err
+		}
+
+		ids, err := r.tagIDs(c)
+		if err != nil {
+			return searchFilter{}, err
+		}
+
+		if len(ids) == 0 {
+			return searchFilter{none: !negated}, nil
+		}
+
+		modifier := models.CriterionModifierIncludes
+		if negated {
+			modifier = models.CriterionModifierExcludes
+		}
+
+		return searchFilter{filter: &models.SceneFilterType{
+			Tags: &models.HierarchicalMultiCriterionInput{Value: ids, Modifier: modifier},
+		}}, nil
+	default:
+		// some renderers include properties such as @refID which scenes never have
+		if rel.op == "exists" && rel.value == "false" {
+			return searchFilter{}, nil
+		}
+	}
+
+	return searchFilter{}, fmt.Errorf("unsupported search criteria: %s %s %q", rel.property, rel.op, rel.value)
+}
+
+func existsModifier(v string) models.CriterionModifier {
+	if v == "true" {
+		return models.CriterionModifierNotNull
+	}
+
+	return models.CriterionModifierIsNull
+}
+
+// sceneSearchFilter translates parsed search criteria into a scene filter.
+func sceneSearchFilter(e searchExpr, r searchResolver) (searchFilter, error) {
+	switch e := e.(type) {
+	case searchAll:
+		return searchFilter{}, nil
+	case searchRel:
+		return searchRelFilter(e, r)
+	case searchLogical:
+		left, err := sceneSearchFilter(e.left, r)
+		if err != nil {
+			return searchFilter{}, err
+		}
+
+		right, err := sceneSearchFilter(e.right, r)
+		if err != nil {
+			return searchFilter{}, err
+		}
+
+		return combineSearchFilters(left, right, e.or)
+	}
+
+	return searchFilter{}, fmt.Errorf("unexpected search expression %T", e)
+}
+
+// parseSortCriteria returns the scene sort and direction of the first
+// supported property of the SortCriteria argument, such as "+dc:title,-dc:date".
+// Returns nil if no supported property is given.
+func parseSortCriteria(s string) *models.FindFilterType {
+	for _, c := range strings.Split(s, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+
+		direction := models.SortDirectionEnumAsc
+		switch c[0] {
+		case '-':
+			direction = models.SortDirectionEnumDesc
+			c = c[1:]
+		case '+':
+			c = c[1:]
+		}
+
+		for _, sc := range sortCapabilities {
+			if sc.property == c {
+				sort := sc.sort
+				return &models.FindFilterType{
+					Sort:      &sort,
+					Direction: &direction,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func getSortCapabilities() string {
+	var ret []string
+	for _, sc := range sortCapabilities {
+		ret = append(ret, sc.property)
+	}
+
+	return strings.Join(ret, ",")
+}
@@ -0,0 +1,159 @@
+package dlna
+
+import (
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSearchCriteria(t *testing.T) {
+	tests := []struct {
+		criteria string
+		want     searchExpr
+		wantErr  bool
+	}{
+		{"*", searchAll{}, false},
+		{`dc:title contains "foo"`, searchRel{"dc:title", "contains", "foo"}, false},
+		{`dc:title = "say \"hi\""`, searchRel{"dc:title", "=", `say "hi"`}, false},
+		{
+			`upnp:class derivedfrom "object.item.videoItem" and (dc:title contains "a" or upnp:artist contains "b")`,
+			searchLogical{
+				left: searchRel{"upnp:class", "derivedfrom", "object.item.videoItem"},
+				right: searchLogical{
+					or:    true,
+					left:  searchRel{"dc:title", "contains", "a"},
+					right: searchRel{"upnp:artist", "contains", "b"},
+				},
+			},
+			false,
+		},
+		{
+			// and binds more tightly than or
+			`dc:title contains "a" or dc:title contains "b" and @refID exists false`,
+			searchLogical{
+				or:   true,
+				left: searchRel{"dc:title", "contains", "a"},
+				right: searchLogical{
+					left:  searchRel{"dc:title", "contains", "b"},
+					right: searchRel{"@refID", "exists", "false"},
+				},
+			},
+			false,
+		},
+		{`dc:title contains foo`, nil, true},
+		{`dc:title contains "foo`, nil, true},
+		{`dc:title like "foo"`, nil, true},
+		{`(dc:title contains "foo"`, nil, true},
+		{`dc:title contains "foo" and`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.criteria, func(t *testing.T) {
+			got, err := parseSearchCriteria(tt.criteria)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+type testSearchResolver struct{}
+
+func (testSearchResolver) performerIDs(name *models.StringCriterionInput) ([]string, error) {
+	if name.Value == "alice" {
+		return []string{"1"}, nil
+	}
+	return nil, nil
+}
+
+func (testSearchResolver) tagIDs(name *models.StringCriterionInput) ([]string, error) {
+	if name.Value == "outdoor" {
+		return []string{"2", "3"}, nil
+	}
+	return nil, nil
+}
+
+func TestSceneSearchFilter(t *testing.T) {
+	title := func(v string) *models.SceneFilterType {
+		return &models.SceneFilterType{
+			Title: &models.StringCriterionInput{Value: v, Modifier: models.CriterionModifierIncludes},
+		}
+	}
+
+	tests := []struct {
+		criteria string
+		want     searchFilter
+		wantErr  bool
+	}{
+		{"*", searchFilter{}, false},
+		{`upnp:class derivedfrom "object.item"`, searchFilter{}, false},
+		{`upnp:class derivedfrom "object.item.audioItem"`, searchFilter{none: true}, false},
+		{`upnp:class derivedfrom "object.item.videoItem" and dc:title contains "foo"`, searchFilter{filter: title("foo")}, false},
+		{`upnp:class derivedfrom "object.item.audioItem" and dc:title contains "foo"`, searchFilter{none: true}, false},
+		{`dc:title doesNotContain "foo"`, searchFilter{filter: &models.SceneFilterType{
+			Title: &models.StringCriterionInput{Value: "foo", Modifier: models.CriterionModifierExcludes},
+		}}, false},
+		{`upnp:artist contains "alice"`, searchFilter{filter: &models.SceneFilterType{
+			Performers: &models.MultiCriterionInput{Value: []string{"1"}, Modifier: models.CriterionModifierIncludes},
+		}}, false},
+		{`upnp:artist contains "bob"`, searchFilter{none: true}, false},
+		{`upnp:artist doesNotContain "bob"`, searchFilter{}, false},
+		{`upnp:genre = "outdoor"`, searchFilter{filter: &models.SceneFilterType{
+			Tags: &models.HierarchicalMultiCriterionInput{Value: []string{"2", "3"}, Modifier: models.CriterionModifierIncludes},
+		}}, false},
+		{`dc:title contains "foo" or upnp:artist contains "bob"`, searchFilter{filter: title("foo")}, false},
+		{`dc:title contains "foo" or dc:title contains "bar"`, searchFilter{filter: func() *models.SceneFilterType {
+			f := title("foo")
+			f.Or = title("bar")
+			return f
+		}()}, false},
+		{`(dc:title contains "a" or dc:title contains "b") and (dc:title contains "c" or dc:title contains "d")`, searchFilter{}, true},
+		{`dc:date >= "2020-01-01"`, searchFilter{}, true},
+		{`@refID exists false`, searchFilter{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.criteria, func(t *testing.T) {
+			e, err := parseSearchCriteria(tt.criteria)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := sceneSearchFilter(e, testSearchResolver{})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSortCriteria(t *testing.T) {
+	sortPtr := func(s string) *string { return &s }
+	dirPtr := func(d models.SortDirectionEnum) *models.SortDirectionEnum { return &d }
+
+	tests := []struct {
+		criteria string
+		want     *models.FindFilterType
+	}{
+		{"", nil},
+		{"+dc:title", &models.FindFilterType{Sort: sortPtr("title"), Direction: dirPtr(models.SortDirectionEnumAsc)}},
+		{"-dc:date,+dc:title", &models.FindFilterType{Sort: sortPtr("date"), Direction: dirPtr(models.SortDirectionEnumDesc)}},
+		{"+upnp:album,-upnp:rating", &models.FindFilterType{Sort: sortPtr("rating"), Direction: dirPtr(models.SortDirectionEnumDesc)}},
+		{"+upnp:album", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.criteria, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseSortCriteria(tt.criteria))
+		})
+	}
+}
@@ -8,18 +8,25 @@ import (
 	"sync"
 	"time"
 
+	"github.com/stashapp/stash/pkg/ffmpeg"
 	"github.com/stashapp/stash/pkg/logger"
 	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/txn"
 )
 
 type Repository struct {
-	SceneFinder     SceneFinder
-	FileGetter      models.FileGetter
-	StudioFinder    StudioFinder
-	TagFinder       TagFinder
-	PerformerFinder PerformerFinder
-	MovieFinder     MovieFinder
+	SceneFinder       SceneFinder
+	FileGetter        models.FileGetter
+	StudioFinder      StudioFinder
+	TagFinder         TagFinder
+	PerformerFinder   PerformerFinder
+	MovieFinder       MovieFinder
+	GalleryFinder     GalleryFinder
+	ImageFinder       ImageFinder
+	SceneMarkerFinder SceneMarkerFinder
+	SavedFilterFinder SavedFilterFinder
+	// SceneActivityWriter saves the playback activity of scenes played by renderers.
+	SceneActivityWriter SceneActivityWriter
 }
 
 type Status struct {
@@ -49,14 +56,21 @@ type dmsConfig struct {
 
 type sceneServer interface {
 	StreamSceneDirect(scene *models.Scene, w http.ResponseWriter, r *http.Request)
+	StreamSceneTranscode(scene *models.Scene, streamType ffmpeg.StreamFormat, startTime float64, w http.ResponseWriter, r *http.Request)
 	ServeScreenshot(scene *models.Scene, w http.ResponseWriter, r *http.Request)
 }
 
+type imageServer interface {
+	ServeImage(image *models.Image, w http.ResponseWriter, r *http.Request)
+	ServeThumbnail(image *models.Image, w http.ResponseWriter, r *http.Request)
+}
+
 type Config interface {
 	GetDLNAInterfaces() []string
 	GetDLNAServerName() string
 	GetDLNADefaultIPWhitelist() []string
 	GetVideoSortOrder() string
+	GetMinimumPlayPercent() int
 }
 
 type Service struct {
@@ -64,6 +78,7 @@ type Service struct {
 	repository     Repository
 	config         Config
 	sceneServer    sceneServer
+	imageServer    imageServer
 	ipWhitelistMgr *ipWhitelistManager
 
 	server  *Server
@@ -135,6 +150,8 @@ func (s *Service) init() error {
 	s.server = &Server{
 		txnManager:         s.txnManager,
 		sceneServer:        s.sceneServer,
+		imageServer:        s.imageServer,
+		activityTracker:    newActivityTracker(s.txnManager, s.repository.SceneActivityWriter, s.config.GetMinimumPlayPercent),
 		repository:         s.repository,
 		ipWhitelistManager: s.ipWhitelistMgr,
 		Interfaces:         interfaces,
@@ -197,11 +214,12 @@ func (s *Service) init() error {
 // }
 
 // NewService initialises and returns a new DLNA service.
-func NewService(txnManager txn.Manager, repo Repository, cfg Config, sceneServer sceneServer) *Service {
+func NewService(txnManager txn.Manager, repo Repository, cfg Config, sceneServer sceneServer, imageServer imageServer) *Service {
 	ret := &Service{
 		txnManager:  txnManager,
 		repository:  repo,
 		sceneServer: sceneServer,
+		imageServer: imageServer,
 		config:      cfg,
 		ipWhitelistMgr: &ipWhitelistManager{
 			config: cfg,
@@ -1,46 +1,74 @@
 package manager
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stashapp/stash/internal/manager/config"
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+	"github.com/stashapp/stash/pkg/user"
 )
 
-var ErrInvalidToken = errors.New("invalid apikey")
+const legacyAPIKeyName = "Legacy API key"
 
-const APIKeySubject = "APIKey"
+// AuthenticateAPIKey returns the user and API key for the key provided in a
+// request. Returns session.ErrUnauthorized if the key is invalid or has
+// expired.
+func (s *Manager) AuthenticateAPIKey(ctx context.Context, key string) (*models.User, *models.APIKey, error) {
+	// API keys are stored in the database
+	if s.Database.Ready() != nil {
+		return nil, nil, session.ErrUnauthorized
+	}
 
-type APIKeyClaims struct {
-	UserID string `json:"uid"`
-	jwt.StandardClaims
-}
+	var (
+		u      *models.User
+		apiKey *models.APIKey
+	)
 
-func GenerateAPIKey(userID string) (string, error) {
-	claims := &APIKeyClaims{
-		UserID: userID,
-		StandardClaims: jwt.StandardClaims{
-			Subject:  APIKeySubject,
-			IssuedAt: time.Now().Unix(),
-		},
-	}
+	r := s.Repository
+	if err := r.WithReadTxn(ctx, func(ctx context.Context) error {
+		var err error
+		u, apiKey, err = user.AuthenticateAPIKey(ctx, key, r.APIKey, r.User, time.Now())
+		return err
+	}); err != nil {
+		if errors.Is(err, user.ErrInvalidAPIKey) {
+			return nil, nil, session.ErrUnauthorized
+		}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return nil, nil, err
+	}
 
-	ss, err := token.SignedString(config.GetInstance().GetJWTSignKey())
-	if err != nil {
-		return "", err
+	now := time.Now()
+	if user.ShouldUpdateLastUsed(apiKey, now) {
+		if err := r.WithTxn(ctx, func(ctx context.Context) error {
+			return r.APIKey.UpdateLastUsed(ctx, apiKey.ID, now)
+		}); err != nil {
+			// not fatal
+			logger.Warnf("Error updating last used time of API key %d: %v", apiKey.ID, err)
+		} else {
+			apiKey.LastUsedAt = &now
+		}
 	}
 
-	return ss, nil
+	return u, apiKey, nil
+}
+
+type legacyAPIKeyClaims struct {
+	UserID string `json:"uid"`
+	jwt.StandardClaims
 }
 
-// GetUserIDFromAPIKey validates the provided api key and returns the user ID
-func GetUserIDFromAPIKey(apiKey string) (string, error) {
-	claims := &APIKeyClaims{}
+// legacyAPIKeyUsername validates an API key generated by earlier versions
+// and returns the username that it was generated for.
+func legacyAPIKeyUsername(c *config.Instance, apiKey string) (string, error) {
+	claims := &legacyAPIKeyClaims{}
 	token, err := jwt.ParseWithClaims(apiKey, claims, func(t *jwt.Token) (interface{}, error) {
-		return config.GetInstance().GetJWTSignKey(), nil
+		return c.GetJWTSignKey(), nil
 	})
 
 	if err != nil {
@@ -48,8 +76,60 @@ func GetUserIDFromAPIKey(apiKey string) (string, error) {
 	}
 
 	if !token.Valid {
-		return "", ErrInvalidToken
+		return "", errors.New("invalid token")
 	}
 
 	return claims.UserID, nil
 }
+
+// MigrateConfigAPIKey moves the API key in the configuration into an API key
+// of the user that generated it, with the admin scope. The key is then
+// removed from the configuration.
+func (s *Manager) MigrateConfigAPIKey(ctx context.Context) error {
+	c := s.Config
+	configKey := c.GetAPIKey()
+	if configKey == "" || s.Database.Ready() != nil {
+		return nil
+	}
+
+	username, err := legacyAPIKeyUsername(c, configKey)
+	if err != nil {
+		logger.Warnf("Discarding invalid API key: %v", err)
+	} else {
+		r := s.Repository
+		if err := r.WithTxn(ctx, func(ctx context.Context) error {
+			u, err := r.User.FindByUsername(ctx, username)
+			if err != nil {
+				return err
+			}
+
+			if u == nil {
+				logger.Warnf("Discarding API key of unknown user %s", username)
+				return nil
+			}
+
+			keyHash := user.HashAPIKey(configKey)
+			existing, err := r.APIKey.FindByKeyHash(ctx, keyHash)
+			if err != nil || existing != nil {
+				return err
+			}
+
+			logger.Infof("Moving configured API key to user %s", username)
+
+			now := time.Now()
+			return r.APIKey.Create(ctx, &models.APIKey{
+				UserID:    u.ID,
+				Name:      legacyAPIKeyName,
+				KeyHash:   keyHash,
+				Scopes:    []models.APIKeyScope{models.APIKeyScopeAdmin},
+				CreatedAt: now,
+				UpdatedAt: now,
+			})
+		}); err != nil {
+			return fmt.Errorf("migrating API key: %w", err)
+		}
+	}
+
+	c.Set(config.ApiKey, "")
+	return c.Write()
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 
 	"golang.org/x/crypto/bcrypt"
 
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
 
 	"github.com/stashapp/stash/internal/identify"
@@ -67,6 +69,27 @@ const (
 	LiveTranscodeInputArgs  = "ffmpeg.live_transcode.input_args"
 	LiveTranscodeOutputArgs = "ffmpeg.live_transcode.output_args"
 
+	// LiveTranscodeCacheSize is the size budget of the live transcode cache
+	// in bytes. Segments are deleted after streaming if 0.
+	LiveTranscodeCacheSize = "ffmpeg.live_transcode.cache_size"
+
+	// maximum number of concurrent live transcodes. 0 for no limit.
+	MaxLiveHardwareTranscodes = "ffmpeg.live_transcode.max_hardware_transcodes"
+	MaxLiveSoftwareTranscodes = "ffmpeg.live_transcode.max_software_transcodes"
+
+	// profiles of the media supported by clients, used to choose between
+	// direct play, remux and transcode
+	DeviceProfiles = "ffmpeg.device_profiles"
+
+	// apply a gain to the audio of live transcodes and previews, using the
+	// loudness analysed during generate
+	NormaliseLoudness     = "ffmpeg.normalise_loudness"
+	LoudnessTarget        = "ffmpeg.loudness_target"
+	loudnessTargetDefault = -16.0
+
+	// crop detected black bars from generated transcodes
+	CropTranscodes = "crop_transcodes"
+
 	ParallelTasks        = "parallel_tasks"
 	parallelTasksDefault = 1
 
@@ -91,6 +114,12 @@ const (
 	PreviewExcludeEnd        = "preview_exclude_end"
 	previewExcludeEndDefault = "0"
 
+	TrickplayInterval        = "trickplay_interval"
+	trickplayIntervalDefault = 10.0
+
+	TrickplayWidth        = "trickplay_width"
+	trickplayWidthDefault = 320
+
 	WriteImageThumbnails        = "write_image_thumbnails"
 	writeImageThumbnailsDefault = true
 
@@ -208,11 +237,27 @@ const (
 	SecurityTripwireAccessedFromPublicInternet        = "security_tripwire_accessed_from_public_internet"
 	securityTripwireAccessedFromPublicInternetDefault = ""
 
+	// Single sign-on options
+	OIDCIssuer               = "oidc.issuer"
+	OIDCClientID             = "oidc.client_id"
+	OIDCClientSecret         = "oidc.client_secret"
+	OIDCRedirectURL          = "oidc.redirect_url"
+	OIDCUsernameClaim        = "oidc.username_claim"
+	oidcUsernameClaimDefault = "preferred_username"
+
+	TrustedProxyHeader   = "trusted_proxy.header"
+	TrustedProxyNetworks = "trusted_proxy.networks"
+
+	SSOCreateUsers        = "sso.create_users"
+	SSODefaultRole        = "sso.default_role"
+	ssoDefaultRoleDefault = models.UserRoleReadOnly
+
 	// DLNA options
 	DLNAServerName         = "dlna.server_name"
 	DLNADefaultEnabled     = "dlna.default_enabled"
 	DLNADefaultIPWhitelist = "dlna.default_whitelist"
 	DLNAInterfaces         = "dlna.interfaces"
+	DLNAActivityUser       = "dlna.activity_user"
 
 	DLNAVideoSortOrder        = "dlna.video_sort_order"
 	dlnaVideoSortOrderDefault = "title"
@@ -485,6 +530,13 @@ func (i *Instance) getInt(key string) int {
 	return i.viper(key).GetInt(key)
 }
 
+func (i *Instance) getInt64(key string) int64 {
+	i.RLock()
+	defer i.RUnlock()
+
+	return i.viper(key).GetInt64(key)
+}
+
 func (i *Instance) getFloat64(key string) float64 {
 	i.RLock()
 	defer i.RUnlock()
@@ -758,6 +810,24 @@ func (i *Instance) GetPreviewSegmentDuration() float64 {
 	return i.getFloat64(PreviewSegmentDuration)
 }
 
+// GetTrickplayInterval returns the time between trickplay thumbnails, in seconds.
+func (i *Instance) GetTrickplayInterval() float64 {
+	ret := i.getFloat64(TrickplayInterval)
+	if ret <= 0 {
+		return trickplayIntervalDefault
+	}
+	return ret
+}
+
+// GetTrickplayWidth returns the width of trickplay thumbnails.
+func (i *Instance) GetTrickplayWidth() int {
+	ret := i.getInt(TrickplayWidth)
+	if ret <= 0 {
+		return trickplayWidthDefault
+	}
+	return ret
+}
+
 // GetParallelTasks returns the number of parallel tasks that should be started
 // by scan or generate task.
 func (i *Instance) GetParallelTasks() int {
@@ -855,6 +925,81 @@ func (i *Instance) GetLiveTranscodeOutputArgs() []string {
 	return i.getStringSlice(LiveTranscodeOutputArgs)
 }
 
+// GetLiveTranscodeCacheSize returns the size budget of the live transcode
+// cache in bytes. Returns 0 if the cache is disabled.
+func (i *Instance) GetLiveTranscodeCacheSize() int64 {
+	return i.getInt64(LiveTranscodeCacheSize)
+}
+
+// GetMaxLiveHardwareTranscodes returns the maximum number of concurrent live
+// transcodes using a hardware encoder. Further transcodes fall back to software
+// encoding. Returns 0 if there is no limit.
+func (i *Instance) GetMaxLiveHardwareTranscodes() int {
+	return i.getInt(MaxLiveHardwareTranscodes)
+}
+
+// GetMaxLiveSoftwareTranscodes returns the maximum number of concurrent live
+// transcodes using a software encoder. Returns 0 if there is no limit.
+func (i *Instance) GetMaxLiveSoftwareTranscodes() int {
+	return i.getInt(MaxLiveSoftwareTranscodes)
+}
+
+// GetDeviceProfiles returns the configured client device profiles.
+func (i *Instance) GetDeviceProfiles() models.DeviceProfiles {
+	var ret models.DeviceProfiles
+	if err := i.unmarshalKey(DeviceProfiles, &ret); err != nil {
+		logger.Warnf("error in unmarshalkey: %v", err)
+	}
+
+	return ret
+}
+
+// ValidateDeviceProfiles returns an error if any of the profiles are
+// missing a name, or if more than one profile has the same name.
+func (i *Instance) ValidateDeviceProfiles(profiles models.DeviceProfiles) error {
+	for idx, p := range profiles {
+		if p.Name == "" {
+			return errors.New("device profile name cannot be blank")
+		}
+
+		if profiles[:idx].Find(p.Name) != nil {
+			return fmt.Errorf("duplicate device profile name: %s", p.Name)
+		}
+
+		if p.MaxResolution != "" && !p.MaxResolution.IsValid() {
+			return fmt.Errorf("invalid max resolution for device profile %s: %s", p.Name, p.MaxResolution)
+		}
+
+		if p.MaxBitrate < 0 {
+			return fmt.Errorf("max bitrate for device profile %s cannot be negative", p.Name)
+		}
+	}
+
+	return nil
+}
+
+// GetCropTranscodes returns true if detected black bars should be cropped
+// from generated transcodes.
+func (i *Instance) GetCropTranscodes() bool {
+	return i.getBool(CropTranscodes)
+}
+
+// GetNormaliseLoudness returns true if the audio of live transcodes and
+// previews should be normalised to the loudness target.
+func (i *Instance) GetNormaliseLoudness() bool {
+	return i.getBool(NormaliseLoudness)
+}
+
+// GetLoudnessTarget returns the integrated loudness in LUFS that audio is
+// normalised to.
+func (i *Instance) GetLoudnessTarget() float64 {
+	ret := i.getFloat64(LoudnessTarget)
+	if ret >= 0 {
+		return loudnessTargetDefault
+	}
+	return ret
+}
+
 func (i *Instance) GetDrawFunscriptHeatmapRange() bool {
 	return i.getBoolDefault(DrawFunscriptHeatmapRange, drawFunscriptHeatmapRangeDefault)
 }
@@ -963,6 +1108,64 @@ func (i *Instance) GetMaxSessionAge() int {
 	return ret
 }
 
+// GetOIDCIssuer returns the URL of the OpenID Connect provider used for
+// single sign-on. Returns an empty string if OpenID Connect is disabled.
+func (i *Instance) GetOIDCIssuer() string {
+	return i.getString(OIDCIssuer)
+}
+
+func (i *Instance) GetOIDCClientID() string {
+	return i.getString(OIDCClientID)
+}
+
+func (i *Instance) GetOIDCClientSecret() string {
+	return i.getString(OIDCClientSecret)
+}
+
+// GetOIDCRedirectURL returns the configured callback URL registered with the
+// OpenID Connect provider. If empty, the URL is derived from the request.
+func (i *Instance) GetOIDCRedirectURL() string {
+	return i.getString(OIDCRedirectURL)
+}
+
+// GetOIDCUsernameClaim returns the ID token claim which is matched against
+// the username of stash users.
+func (i *Instance) GetOIDCUsernameClaim() string {
+	if ret := i.getString(OIDCUsernameClaim); ret != "" {
+		return ret
+	}
+
+	return oidcUsernameClaimDefault
+}
+
+// GetTrustedProxyHeader returns the request header containing the username
+// authenticated by a reverse proxy. Returns an empty string if disabled.
+func (i *Instance) GetTrustedProxyHeader() string {
+	return i.getString(TrustedProxyHeader)
+}
+
+// GetTrustedProxyNetworks returns the IP addresses and CIDR ranges of the
+// reverse proxies which are trusted to set the trusted proxy header.
+func (i *Instance) GetTrustedProxyNetworks() []string {
+	return i.getStringSlice(TrustedProxyNetworks)
+}
+
+// GetSSOCreateUsers returns true if users authenticated by single sign-on
+// should be created if they do not exist.
+func (i *Instance) GetSSOCreateUsers() bool {
+	return i.getBool(SSOCreateUsers)
+}
+
+// GetSSODefaultRole returns the role of users created by single sign-on.
+func (i *Instance) GetSSODefaultRole() models.UserRole {
+	ret := models.UserRole(i.getString(SSODefaultRole))
+	if !ret.IsValid() {
+		return ssoDefaultRoleDefault
+	}
+
+	return ret
+}
+
 // GetCustomServedFolders gets the map of custom paths to their applicable
 // filesystem locations
 func (i *Instance) GetCustomServedFolders() URLMap {
@@ -1112,6 +1315,12 @@ func (i *Instance) GetUIConfiguration() map[string]interface{} {
 	return fromSnakeCaseMap(v)
 }
 
+// GetMinimumPlayPercent returns the percentage of a scene which must be played
+// before its play count is incremented, as configured in the interface settings.
+func (i *Instance) GetMinimumPlayPercent() int {
+	return cast.ToInt(i.GetUIConfiguration()["minimumPlayPercent"])
+}
+
 func (i *Instance) SetUIConfiguration(v map[string]interface{}) {
 	i.RLock()
 	defer i.RUnlock()
@@ -1382,6 +1591,13 @@ func (i *Instance) GetDLNAInterfaces() []string {
 	return i.getStringSlice(DLNAInterfaces)
 }
 
+// GetDLNAActivityUser returns the username of the user whose activity is
+// recorded when scenes are played by DLNA renderers. If empty, the activity
+// is recorded without a user.
+func (i *Instance) GetDLNAActivityUser() string {
+	return i.getString(DLNAActivityUser)
+}
+
 // GetVideoSortOrder returns the sort order to display videos. If
 // empty, videos will be sorted by titles.
 func (i *Instance) GetVideoSortOrder() string {
@@ -1517,6 +1733,9 @@ func (i *Instance) setDefaultValues(write bool) error {
 	i.main.SetDefault(PreviewExcludeStart, previewExcludeStartDefault)
 	i.main.SetDefault(PreviewExcludeEnd, previewExcludeEndDefault)
 	i.main.SetDefault(PreviewAudio, previewAudioDefault)
+	i.main.SetDefault(TrickplayInterval, trickplayIntervalDefault)
+	i.main.SetDefault(TrickplayWidth, trickplayWidthDefault)
+	i.main.SetDefault(LoudnessTarget, loudnessTargetDefault)
 	i.main.SetDefault(SoundOnPreview, false)
 
 	i.main.SetDefault(ThemeColor, DefaultThemeColor)
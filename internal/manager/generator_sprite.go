@@ -12,6 +12,7 @@ import (
 	"github.com/stashapp/stash/pkg/ffmpeg"
 	"github.com/stashapp/stash/pkg/fsutil"
 	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/scene/generate"
 )
 
@@ -25,6 +26,12 @@ type SpriteGenerator struct {
 	Columns         int
 	SlowSeek        bool // use alternate seek function, very slow!
 
+	// SceneCuts are used to move frames away from transitions, if set.
+	SceneCuts ffmpeg.SceneCuts
+
+	// Crop is the region of the frame to keep, excluding black bars.
+	Crop *models.VideoCrop
+
 	Overwrite bool
 
 	g *generate.Generator
@@ -106,9 +113,9 @@ func (g *SpriteGenerator) generateSpriteImage() error {
 		stepSize := g.Info.VideoFile.VideoStreamDuration / float64(g.Info.ChunkCount)
 
 		for i := 0; i < g.Info.ChunkCount; i++ {
-			time := float64(i) * stepSize
+			time := g.SceneCuts.RepresentativeTime(float64(i)*stepSize, g.Info.VideoFile.VideoStreamDuration)
 
-			img, err := g.g.SpriteScreenshot(context.TODO(), g.Info.VideoFile.Path, time)
+			img, err := g.g.SpriteScreenshot(context.TODO(), g.Info.VideoFile.Path, time, g.Crop)
 			if err != nil {
 				return err
 			}
@@ -126,7 +133,7 @@ func (g *SpriteGenerator) generateSpriteImage() error {
 				return errors.New("invalid frame number conversion")
 			}
 
-			img, err := g.g.SpriteScreenshotSlow(context.TODO(), g.Info.VideoFile.Path, int(frame))
+			img, err := g.g.SpriteScreenshotSlow(context.TODO(), g.Info.VideoFile.Path, int(frame), g.Crop)
 			if err != nil {
 				return err
 			}
@@ -120,6 +120,8 @@ type Manager struct {
 	ReadLockManager *fsutil.ReadLockManager
 
 	SessionStore *session.Store
+	userCache    userCache
+	clipExports  clipExports
 
 	JobManager *job.Manager
 
@@ -221,14 +223,26 @@ func initialize() error {
 		SceneCoverGetter: instance.Repository.Scene,
 	}
 
-	instance.DLNAService = dlna.NewService(instance.Repository, dlna.Repository{
-		SceneFinder:     instance.Repository.Scene,
-		FileGetter:      instance.Repository.File,
-		StudioFinder:    instance.Repository.Studio,
-		TagFinder:       instance.Repository.Tag,
-		PerformerFinder: instance.Repository.Performer,
-		MovieFinder:     instance.Repository.Movie,
-	}, instance.Config, &sceneServer)
+	dlnaTxnManager := dlnaTxnManager{
+		Manager: instance.Repository,
+		users:   instance.Repository.User,
+		cache:   &instance.userCache,
+		config:  instance.Config,
+	}
+
+	instance.DLNAService = dlna.NewService(dlnaTxnManager, dlna.Repository{
+		SceneFinder:         instance.Repository.Scene,
+		FileGetter:          instance.Repository.File,
+		StudioFinder:        instance.Repository.Studio,
+		TagFinder:           instance.Repository.Tag,
+		PerformerFinder:     instance.Repository.Performer,
+		MovieFinder:         instance.Repository.Movie,
+		GalleryFinder:       instance.Repository.Gallery,
+		ImageFinder:         instance.Repository.Image,
+		SceneMarkerFinder:   instance.Repository.SceneMarker,
+		SavedFilterFinder:   instance.Repository.SavedFilter,
+		SceneActivityWriter: instance.Repository.Scene,
+	}, instance.Config, &sceneServer, &ImageServer{})
 
 	if !cfg.IsNewSystem() {
 		logger.Infof("using config file: %s", cfg.GetConfigFile())
@@ -250,7 +264,7 @@ func initialize() error {
 			}
 		}
 
-		initSecurity(cfg)
+		initSecurity(instance.ExternalAccessConfig(ctx))
 	} else {
 		cfgFile := cfg.GetConfigFile()
 		if cfgFile != "" {
@@ -259,7 +273,7 @@ func initialize() error {
 
 		// create temporary session store - this will be re-initialised
 		// after config is complete
-		instance.SessionStore = session.NewStore(cfg)
+		instance.SessionStore = session.NewStore(cfg, instance.userFinder())
 
 		logger.Warnf("config file %snot found. Assuming new system...", cfgFile)
 	}
@@ -304,7 +318,9 @@ func makeScanner(db *sqlite.Database, pluginCache *plugin.Cache) *file.Scanner {
 		FileDecorators: []file.Decorator{
 			&file.FilteredDecorator{
 				Decorator: &video.Decorator{
-					FFProbe: instance.FFProbe,
+					FFProbe:      instance.FFProbe,
+					TxnManager:   db,
+					StreamFinder: db.File,
 				},
 				Filter: file.FilterFunc(videoFileFilter),
 			},
@@ -369,7 +385,7 @@ func formatDuration(t time.Duration) string {
 	return fmt.Sprintf("%02.f:%02.f:%02.f", t.Hours(), t.Minutes(), t.Seconds())
 }
 
-func initSecurity(cfg *config.Instance) {
+func initSecurity(cfg session.ExternalAccessConfig) {
 	if err := session.CheckExternalAccessTripwire(cfg); err != nil {
 		session.LogExternalAccessError(*err)
 	}
@@ -451,7 +467,7 @@ func (s *Manager) PostInit(ctx context.Context) error {
 
 	*s.Paths = paths.NewPaths(s.Config.GetGeneratedPath(), s.Config.GetBlobsPath())
 	s.RefreshConfig()
-	s.SessionStore = session.NewStore(s.Config)
+	s.SessionStore = session.NewStore(s.Config, s.userFinder())
 	s.PluginCache.RegisterSessionStore(s.SessionStore)
 
 	if err := s.PluginCache.LoadPlugins(); err != nil {
@@ -491,6 +507,14 @@ func (s *Manager) PostInit(ctx context.Context) error {
 		return err
 	}
 
+	if err := s.MigrateConfigCredentials(ctx); err != nil {
+		logger.Errorf("Error migrating credentials: %v", err)
+	}
+
+	if err := s.MigrateConfigAPIKey(ctx); err != nil {
+		logger.Errorf("Error migrating API key: %v", err)
+	}
+
 	// Set the proxy if defined in config
 	if s.Config.GetProxy() != "" {
 		os.Setenv("HTTP_PROXY", s.Config.GetProxy())
@@ -757,6 +781,14 @@ func (s *Manager) Migrate(ctx context.Context, input MigrateInput) error {
 		}
 	}
 
+	if err := s.MigrateConfigCredentials(ctx); err != nil {
+		logger.Errorf("Error migrating credentials: %v", err)
+	}
+
+	if err := s.MigrateConfigAPIKey(ctx); err != nil {
+		logger.Errorf("Error migrating API key: %v", err)
+	}
+
 	return nil
 }
 
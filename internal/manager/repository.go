@@ -25,6 +25,8 @@ type Repository struct {
 	Studio         models.StudioReaderWriter
 	Tag            models.TagReaderWriter
 	SavedFilter    models.SavedFilterReaderWriter
+	User           models.UserReaderWriter
+	APIKey         models.APIKeyReaderWriter
 }
 
 func (r *Repository) WithTxn(ctx context.Context, fn txn.TxnFunc) error {
@@ -56,6 +58,8 @@ func sqliteRepository(d *sqlite.Database) Repository {
 		Studio:         txnRepo.Studio,
 		Tag:            txnRepo.Tag,
 		SavedFilter:    txnRepo.SavedFilter,
+		User:           txnRepo.User,
+		APIKey:         txnRepo.APIKey,
 	}
 }
 
@@ -9,6 +9,7 @@ import (
 	"github.com/stashapp/stash/internal/manager/config"
 	"github.com/stashapp/stash/internal/static"
 	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/file"
 	"github.com/stashapp/stash/pkg/fsutil"
 	"github.com/stashapp/stash/pkg/logger"
 	"github.com/stashapp/stash/pkg/models"
@@ -27,6 +28,11 @@ func KillRunningStreams(scene *models.Scene, fileNamingAlgo models.HashAlgorithm
 
 	transcodePath := GetInstance().Paths.Scene.GetTranscodePath(sceneHash)
 	instance.ReadLockManager.Cancel(transcodePath)
+
+	// stop any live transcodes and remove their cached segments
+	if err := instance.StreamManager.PurgeCache(sceneHash); err != nil {
+		logger.Warnf("error purging transcode cache for %s: %v", sceneHash, err)
+	}
 }
 
 type SceneCoverGetter interface {
@@ -57,6 +63,32 @@ func (s *SceneServer) StreamSceneDirect(scene *models.Scene, w http.ResponseWrit
 	http.ServeFile(w, r, filepath)
 }
 
+// StreamSceneTranscode streams the primary file of the scene, live transcoded
+// to the given stream type from startTime.
+func (s *SceneServer) StreamSceneTranscode(scene *models.Scene, streamType ffmpeg.StreamFormat, startTime float64, w http.ResponseWriter, r *http.Request) {
+	f := scene.Files.Primary()
+	if f == nil {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+
+	streamManager := GetInstance().StreamManager
+	if streamManager == nil {
+		http.Error(w, "Live transcoding disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	options := ffmpeg.TranscodeOptions{
+		StreamType: streamType,
+		VideoFile:  f,
+		Hash:       scene.GetHash(config.GetInstance().GetVideoFileNamingAlgorithm()),
+		StartTime:  startTime,
+	}
+
+	logger.Debugf("[transcode] streaming scene %d as %s", scene.ID, streamType.MimeType)
+	streamManager.ServeTranscode(w, r, options)
+}
+
 func (s *SceneServer) ServeScreenshot(scene *models.Scene, w http.ResponseWriter, r *http.Request) {
 	const defaultSceneImage = "scene/scene.svg"
 
@@ -100,3 +132,33 @@ func (s *SceneServer) ServeScreenshot(scene *models.Scene, w http.ResponseWriter
 
 	utils.ServeImage(w, r, cover)
 }
+
+type ImageServer struct{}
+
+// ServeImage serves the primary file of the image.
+func (s *ImageServer) ServeImage(image *models.Image, w http.ResponseWriter, r *http.Request) {
+	f := image.Files.Primary()
+	if f == nil {
+		http.Error(w, http.StatusText(404), 404)
+		return
+	}
+
+	if err := f.Base().Serve(&file.OsFS{}, w, r); err != nil {
+		logger.Debugf("error serving %s: %v", image.DisplayName(), err)
+		http.Error(w, http.StatusText(404), 404)
+	}
+}
+
+// ServeThumbnail serves the generated thumbnail of the image,
+// falling back to the image itself if no thumbnail has been generated.
+func (s *ImageServer) ServeThumbnail(image *models.Image, w http.ResponseWriter, r *http.Request) {
+	filepath := GetInstance().Paths.Generated.GetThumbnailPath(image.Checksum, models.DefaultGthumbWidth)
+
+	exists, _ := fsutil.FileExists(filepath)
+	if !exists {
+		s.ServeImage(image, w, r)
+		return
+	}
+
+	utils.ServeStaticFile(w, r, filepath)
+}
@@ -3,6 +3,7 @@ package manager
 import (
 	"fmt"
 	"net/url"
+	"strconv"
 
 	"github.com/stashapp/stash/internal/manager/config"
 	"github.com/stashapp/stash/pkg/ffmpeg"
@@ -20,6 +21,25 @@ type endpointType struct {
 	label     string
 	mimeType  string
 	extension string
+	// container and videoCodec are the output of transcoded endpoints, used
+	// to filter the endpoints by device profile. container is empty for
+	// segmented streams.
+	container  ffmpeg.Container
+	videoCodec string
+}
+
+// supportedBy returns true if a client with profile p can play the endpoint.
+// All endpoints are supported if p is nil.
+func (t endpointType) supportedBy(p *models.DeviceProfile) bool {
+	if p == nil || t.videoCodec == "" {
+		return true
+	}
+
+	if t.container != "" && !p.SupportsContainer(string(t.container)) {
+		return false
+	}
+
+	return p.SupportsVideoCodec(t.videoCodec)
 }
 
 var (
@@ -29,9 +49,16 @@ var (
 		extension: "",
 	}
 	mp4EndpointType = endpointType{
-		label:     "MP4",
+		label:      "MP4",
+		mimeType:   ffmpeg.MimeMp4Video,
+		extension:  ".mp4",
+		container:  ffmpeg.Mp4,
+		videoCodec: ffmpeg.H264,
+	}
+	remuxEndpointType = endpointType{
+		label:     "Remux",
 		mimeType:  ffmpeg.MimeMp4Video,
-		extension: ".mp4",
+		extension: "_remux.mp4",
 	}
 	mkvEndpointType = endpointType{
 		label: "MKV",
@@ -40,19 +67,61 @@ var (
 		extension: ".mkv",
 	}
 	webmEndpointType = endpointType{
-		label:     "WEBM",
-		mimeType:  ffmpeg.MimeWebmVideo,
-		extension: ".webm",
+		label:      "WEBM",
+		mimeType:   ffmpeg.MimeWebmVideo,
+		extension:  ".webm",
+		container:  ffmpeg.Webm,
+		videoCodec: ffmpeg.Vp9,
 	}
 	hlsEndpointType = endpointType{
-		label:     "HLS",
-		mimeType:  ffmpeg.MimeHLS,
-		extension: ".m3u8",
+		label:      "HLS",
+		mimeType:   ffmpeg.MimeHLS,
+		extension:  ".m3u8",
+		videoCodec: ffmpeg.H264,
 	}
 	dashEndpointType = endpointType{
-		label:     "DASH",
-		mimeType:  ffmpeg.MimeDASH,
-		extension: ".mpd",
+		label:      "DASH",
+		mimeType:   ffmpeg.MimeDASH,
+		extension:  ".mpd",
+		videoCodec: ffmpeg.Vp9,
+	}
+	// adaptive endpoints list all resolutions in a single manifest
+	hlsAdaptiveEndpointType = endpointType{
+		label:      "HLS Adaptive",
+		mimeType:   ffmpeg.MimeHLS,
+		extension:  ".m3u8",
+		videoCodec: ffmpeg.H264,
+	}
+	dashAdaptiveEndpointType = endpointType{
+		label:      "DASH Adaptive",
+		mimeType:   ffmpeg.MimeDASH,
+		extension:  ".mpd",
+		videoCodec: ffmpeg.Vp9,
+	}
+	// CMAF endpoints share the same fragmented mp4 segments
+	hlsCMAFEndpointType = endpointType{
+		label:      "HLS CMAF",
+		mimeType:   ffmpeg.MimeHLS,
+		extension:  "_cmaf.m3u8",
+		videoCodec: ffmpeg.H264,
+	}
+	dashCMAFEndpointType = endpointType{
+		label:      "DASH CMAF",
+		mimeType:   ffmpeg.MimeDASH,
+		extension:  "_cmaf.mpd",
+		videoCodec: ffmpeg.H264,
+	}
+	hlsHEVCEndpointType = endpointType{
+		label:      "HLS HEVC",
+		mimeType:   ffmpeg.MimeHLS,
+		extension:  "_hevc.m3u8",
+		videoCodec: ffmpeg.Hevc,
+	}
+	dashAV1EndpointType = endpointType{
+		label:      "DASH AV1",
+		mimeType:   ffmpeg.MimeDASH,
+		extension:  "_av1.mpd",
+		videoCodec: ffmpeg.Av1,
 	}
 )
 
@@ -75,7 +144,25 @@ func GetVideoFileContainer(file *models.VideoFile) (ffmpeg.Container, error) {
 	return container, nil
 }
 
-func GetSceneStreamPaths(scene *models.Scene, directStreamURL *url.URL, maxStreamingTranscodeSize models.StreamingResolutionEnum) ([]*SceneStreamEndpoint, error) {
+// defaultAudioTrack returns the index of the audio stream which ffmpeg
+// selects by default. This is the first stream flagged as default,
+// otherwise the first stream.
+func defaultAudioTrack(audioStreams []*models.VideoStream) int {
+	for _, s := range audioStreams {
+		if s.Default {
+			return s.Index
+		}
+	}
+
+	return 0
+}
+
+// GetSceneStreamPaths returns the stream endpoints for the scene. audioStreams
+// are the audio streams of the primary file. If there is more than one, then
+// additional endpoints are returned for each audio stream other than the default.
+// If profile is not nil, only the endpoints which the client can play are
+// returned, with the preferred playback method first.
+func GetSceneStreamPaths(scene *models.Scene, directStreamURL *url.URL, maxStreamingTranscodeSize models.StreamingResolutionEnum, audioStreams []*models.VideoStream, profile *models.DeviceProfile) ([]*SceneStreamEndpoint, error) {
 	if scene == nil {
 		return nil, fmt.Errorf("nil scene")
 	}
@@ -85,6 +172,10 @@ func GetSceneStreamPaths(scene *models.Scene, directStreamURL *url.URL, maxStrea
 		return nil, nil
 	}
 
+	if profile != nil {
+		maxStreamingTranscodeSize = profile.MaxStreamingResolution(maxStreamingTranscodeSize)
+	}
+
 	// convert StreamingResolutionEnum to ResolutionEnum
 	maxStreamingResolution := models.ResolutionEnum(maxStreamingTranscodeSize)
 	sceneResolution := models.GetMinResolution(pf)
@@ -112,7 +203,13 @@ func GetSceneStreamPaths(scene *models.Scene, directStreamURL *url.URL, maxStrea
 		return maxStreamingResolution.GetMinResolution() >= minResolution
 	}
 
+	// endpoints which the client cannot play are returned as nil, and
+	// removed from the result
 	makeStreamEndpoint := func(t endpointType, resolution models.StreamingResolutionEnum) *SceneStreamEndpoint {
+		if !t.supportedBy(profile) {
+			return nil
+		}
+
 		url := *directStreamURL
 		url.Path += t.extension
 
@@ -144,6 +241,27 @@ func GetSceneStreamPaths(scene *models.Scene, directStreamURL *url.URL, maxStrea
 		}
 	}
 
+	makeAudioTrackEndpoint := func(t endpointType, stream *models.VideoStream) *SceneStreamEndpoint {
+		if !t.supportedBy(profile) {
+			return nil
+		}
+
+		url := *directStreamURL
+		url.Path += t.extension
+
+		v := url.Query()
+		v.Set("audio_track", strconv.Itoa(stream.Index))
+		url.RawQuery = v.Encode()
+
+		label := t.label + " - Audio: " + stream.DisplayName()
+
+		return &SceneStreamEndpoint{
+			URL:      url.String(),
+			MimeType: &t.mimeType,
+			Label:    &label,
+		}
+	}
+
 	var endpoints []*SceneStreamEndpoint
 
 	// direct stream should only apply when the audio codec is supported
@@ -155,19 +273,54 @@ func GetSceneStreamPaths(scene *models.Scene, directStreamURL *url.URL, maxStrea
 	// don't care if we can't get the container
 	container, _ := GetVideoFileContainer(pf)
 
-	if HasTranscode(scene, config.GetInstance().GetVideoFileNamingAlgorithm()) || ffmpeg.IsValidAudioForContainer(audioCodec, container) {
-		endpoints = append(endpoints, makeStreamEndpoint(directEndpointType, ""))
-	}
+	hasTranscode := HasTranscode(scene, config.GetInstance().GetVideoFileNamingAlgorithm())
+	remuxable := ffmpeg.IsRemuxable(pf.VideoCodec)
 
-	// only add mkv stream endpoint if the scene container is an mkv already
-	if container == ffmpeg.Matroska {
-		endpoints = append(endpoints, makeStreamEndpoint(mkvEndpointType, ""))
+	if profile != nil {
+		method := sceneProfilePlaybackMethod(pf, audioCodec, container, hasTranscode, profile)
+		remuxable = remuxable && method != ffmpeg.PlaybackTranscode && profile.SupportsContainer(string(ffmpeg.Mp4))
+
+		switch method {
+		case ffmpeg.PlaybackDirect:
+			endpoints = append(endpoints, makeStreamEndpoint(directEndpointType, ""))
+			if remuxable {
+				endpoints = append(endpoints, makeStreamEndpoint(remuxEndpointType, ""))
+			}
+		case ffmpeg.PlaybackRemux:
+			endpoints = append(endpoints, makeStreamEndpoint(remuxEndpointType, ""))
+		}
+
+		// the mkv endpoint copies the video stream
+		if container == ffmpeg.Matroska && method != ffmpeg.PlaybackTranscode && profile.SupportsContainer(string(ffmpeg.Matroska)) {
+			endpoints = append(endpoints, makeStreamEndpoint(mkvEndpointType, ""))
+		}
+	} else {
+		// a remux is much cheaper than a transcode, so offer it first when only
+		// the container or audio codec prevent the file from being streamed
+		if remuxable && ffmpeg.IsStreamable(pf.VideoCodec, audioCodec, container) != nil {
+			endpoints = append(endpoints, makeStreamEndpoint(remuxEndpointType, ""))
+		}
+
+		if hasTranscode || ffmpeg.IsValidAudioForContainer(audioCodec, container) {
+			endpoints = append(endpoints, makeStreamEndpoint(directEndpointType, ""))
+		}
+
+		// only add mkv stream endpoint if the scene container is an mkv already
+		if container == ffmpeg.Matroska {
+			endpoints = append(endpoints, makeStreamEndpoint(mkvEndpointType, ""))
+		}
 	}
 
 	mp4Streams := []*SceneStreamEndpoint{}
 	webmStreams := []*SceneStreamEndpoint{}
-	hlsStreams := []*SceneStreamEndpoint{}
-	dashStreams := []*SceneStreamEndpoint{}
+	hlsStreams := []*SceneStreamEndpoint{
+		makeStreamEndpoint(hlsAdaptiveEndpointType, ""),
+		makeStreamEndpoint(hlsCMAFEndpointType, ""),
+	}
+	dashStreams := []*SceneStreamEndpoint{
+		makeStreamEndpoint(dashAdaptiveEndpointType, ""),
+		makeStreamEndpoint(dashCMAFEndpointType, ""),
+	}
 
 	if includeSceneStreamPath(models.StreamingResolutionEnumOriginal) {
 		mp4Streams = append(mp4Streams, makeStreamEndpoint(mp4EndpointType, models.StreamingResolutionEnumOriginal))
@@ -216,7 +369,65 @@ func GetSceneStreamPaths(scene *models.Scene, directStreamURL *url.URL, maxStrea
 	endpoints = append(endpoints, hlsStreams...)
 	endpoints = append(endpoints, dashStreams...)
 
-	return endpoints, nil
+	// the streams above use the default audio track, so add
+	// transcoded streams for each of the other audio tracks
+	if len(audioStreams) > 1 {
+		fileEndpointType := mp4EndpointType
+		if remuxable {
+			fileEndpointType = remuxEndpointType
+		}
+
+		defaultTrack := defaultAudioTrack(audioStreams)
+		for _, s := range audioStreams {
+			if s.Index == defaultTrack {
+				continue
+			}
+
+			endpoints = append(endpoints,
+				makeAudioTrackEndpoint(hlsAdaptiveEndpointType, s),
+				makeAudioTrackEndpoint(fileEndpointType, s),
+			)
+		}
+	}
+
+	// HEVC and AV1 are too slow to encode in software, so are
+	// only offered when a hardware encoder is available
+	encoder := GetInstance().FFMPEG
+	if encoder != nil && config.GetInstance().GetTranscodeHardwareAcceleration() {
+		if encoder.HWCodecHEVCCompatible() != nil {
+			endpoints = append(endpoints, makeStreamEndpoint(hlsHEVCEndpointType, ""))
+		}
+		if encoder.HWCodecAV1Compatible() != nil {
+			endpoints = append(endpoints, makeStreamEndpoint(dashAV1EndpointType, ""))
+		}
+	}
+
+	ret := endpoints[:0]
+	for _, e := range endpoints {
+		if e != nil {
+			ret = append(ret, e)
+		}
+	}
+
+	return ret, nil
+}
+
+// sceneProfilePlaybackMethod returns how the primary file pf should be served
+// to a client with the given profile. If the scene has a generated transcode,
+// it is served instead of the file by the direct endpoint.
+func sceneProfilePlaybackMethod(pf *models.VideoFile, audioCodec ffmpeg.ProbeAudioCodec, container ffmpeg.Container, hasTranscode bool, profile *models.DeviceProfile) ffmpeg.PlaybackMethod {
+	resolution := models.GetMinResolution(pf)
+
+	if hasTranscode {
+		// generated transcodes are h264 and aac in mp4. The bitrate of the
+		// transcode is not known.
+		if ffmpeg.ProfilePlaybackMethod(profile, ffmpeg.H264, ffmpeg.Aac, ffmpeg.Mp4, resolution, 0) == ffmpeg.PlaybackDirect {
+			return ffmpeg.PlaybackDirect
+		}
+		return ffmpeg.PlaybackTranscode
+	}
+
+	return ffmpeg.ProfilePlaybackMethod(profile, pf.VideoCodec, audioCodec, container, resolution, pf.BitRate)
 }
 
 // HasTranscode returns true if a transcoded video exists for the provided
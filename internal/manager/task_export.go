@@ -360,6 +360,16 @@ func (t *ExportTask) ExportScenes(ctx context.Context, workers int, repo Reposit
 		logger.Errorf("[scenes] failed to fetch scenes: %s", err.Error())
 	}
 
+	// the activity of each user is exported with the username
+	usernames := make(map[int]string)
+	users, err := repo.User.All(ctx)
+	if err != nil {
+		logger.Errorf("[scenes] failed to fetch users: %s", err.Error())
+	}
+	for _, u := range users {
+		usernames[u.ID] = u.Username
+	}
+
 	jobCh := make(chan *models.Scene, workers*2) // make a buffered channel to feed workers
 
 	logger.Info("[scenes] exporting")
@@ -367,7 +377,7 @@ func (t *ExportTask) ExportScenes(ctx context.Context, workers int, repo Reposit
 
 	for w := 0; w < workers; w++ { // create export Scene workers
 		scenesWg.Add(1)
-		go exportScene(ctx, &scenesWg, jobCh, repo, t)
+		go exportScene(ctx, &scenesWg, jobCh, repo, usernames, t)
 	}
 
 	for i, scene := range scenes {
@@ -423,7 +433,7 @@ func fileToJSON(f models.File) jsonschema.DirEntry {
 	switch ff := f.(type) {
 	case *models.VideoFile:
 		base.Type = jsonschema.DirEntryTypeVideo
-		return jsonschema.VideoFile{
+		vf := jsonschema.VideoFile{
 			BaseFile:         &base,
 			Format:           ff.Format,
 			Width:            ff.Width,
@@ -435,7 +445,30 @@ func fileToJSON(f models.File) jsonschema.DirEntry {
 			BitRate:          ff.BitRate,
 			Interactive:      ff.Interactive,
 			InteractiveSpeed: ff.InteractiveSpeed,
+
+			IntegratedLoudness: ff.IntegratedLoudness,
+			TruePeak:           ff.TruePeak,
 		}
+
+		if ff.Crop != nil {
+			vf.Crop = &jsonschema.VideoCrop{
+				X:      ff.Crop.X,
+				Y:      ff.Crop.Y,
+				Width:  ff.Crop.Width,
+				Height: ff.Crop.Height,
+			}
+		}
+
+		if ff.Integrity != nil {
+			vf.Integrity = &jsonschema.VideoIntegrity{
+				Status:         ff.Integrity.Status.String(),
+				ErrorCount:     ff.Integrity.ErrorCount,
+				FirstErrorTime: ff.Integrity.FirstErrorTime,
+				Truncated:      ff.Integrity.Truncated,
+			}
+		}
+
+		return vf
 	case *models.ImageFile:
 		base.Type = jsonschema.DirEntryTypeImage
 		return jsonschema.ImageFile{
@@ -475,7 +508,7 @@ func folderToJSON(f models.Folder) jsonschema.DirEntry {
 	return &base
 }
 
-func exportScene(ctx context.Context, wg *sync.WaitGroup, jobChan <-chan *models.Scene, repo Repository, t *ExportTask) {
+func exportScene(ctx context.Context, wg *sync.WaitGroup, jobChan <-chan *models.Scene, repo Repository, usernames map[int]string, t *ExportTask) {
 	defer wg.Done()
 	sceneReader := repo.Scene
 	studioReader := repo.Studio
@@ -527,6 +560,15 @@ func exportScene(ctx context.Context, wg *sync.WaitGroup, jobChan <-chan *models
 		newSceneJSON.ResumeTime = s.ResumeTime
 		newSceneJSON.PlayCount = s.PlayCount
 		newSceneJSON.PlayDuration = s.PlayDuration
+		if s.LastPlayedAt != nil {
+			newSceneJSON.LastPlayedAt = json.JSONTime{Time: *s.LastPlayedAt}
+		}
+
+		newSceneJSON.Activity, err = scene.GetActivityJSON(ctx, sceneReader, usernames, s)
+		if err != nil {
+			logger.Errorf("[scenes] <%s> error getting scene activity: %s", sceneHash, err.Error())
+			continue
+		}
 
 		performers, err := performerReader.FindBySceneID(ctx, s.ID)
 		if err != nil {
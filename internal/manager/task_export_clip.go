@@ -0,0 +1,426 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stashapp/stash/pkg/fsutil"
+	"github.com/stashapp/stash/pkg/job"
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/scene/generate"
+	"github.com/stashapp/stash/pkg/session"
+)
+
+// defaultClipMarkerDuration is the length of the clip cut from each marker
+// if not specified.
+const defaultClipMarkerDuration = 20
+
+type ClipExportMode string
+
+const (
+	ClipExportModeCopy     ClipExportMode = "COPY"
+	ClipExportModeAccurate ClipExportMode = "ACCURATE"
+)
+
+var AllClipExportMode = []ClipExportMode{
+	ClipExportModeCopy,
+	ClipExportModeAccurate,
+}
+
+func (e ClipExportMode) IsValid() bool {
+	switch e {
+	case ClipExportModeCopy, ClipExportModeAccurate:
+		return true
+	}
+	return false
+}
+
+func (e ClipExportMode) String() string {
+	return string(e)
+}
+
+func (e *ClipExportMode) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ClipExportMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ClipExportMode", str)
+	}
+	return nil
+}
+
+func (e ClipExportMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ExportSceneClipsInput struct {
+	SceneID        *string         `json:"scene_id"`
+	Start          *float64        `json:"start"`
+	End            *float64        `json:"end"`
+	MarkerTagID    *string         `json:"marker_tag_id"`
+	MarkerDuration *float64        `json:"marker_duration"`
+	Mode           *ClipExportMode `json:"mode"`
+}
+
+// ExportClipsTask cuts a time range of a scene, or the markers with a tag,
+// into a single file and registers it for download. It is run as a job by
+// Manager.ExportSceneClips.
+type ExportClipsTask struct {
+	txnManager Repository
+
+	sceneID        int
+	start          float64
+	end            float64
+	markerTagID    int
+	markerDuration float64
+	mode           generate.ClipMode
+
+	DownloadHash string
+}
+
+func CreateExportClipsTask(repository Repository, input ExportSceneClipsInput) (*ExportClipsTask, error) {
+	t := &ExportClipsTask{
+		txnManager:     repository,
+		markerDuration: defaultClipMarkerDuration,
+		mode:           generate.ClipModeCopy,
+	}
+
+	if input.Mode != nil && *input.Mode == ClipExportModeAccurate {
+		t.mode = generate.ClipModeAccurate
+	}
+
+	switch {
+	case input.SceneID != nil && input.MarkerTagID != nil:
+		return nil, errors.New("only one of scene_id and marker_tag_id may be set")
+	case input.SceneID != nil:
+		var err error
+		t.sceneID, err = strconv.Atoi(*input.SceneID)
+		if err != nil {
+			return nil, fmt.Errorf("converting scene id: %w", err)
+		}
+
+		if input.Start == nil || input.End == nil {
+			return nil, errors.New("start and end are required when exporting a scene clip")
+		}
+		t.start = *input.Start
+		t.end = *input.End
+
+		if t.start < 0 || t.end <= t.start {
+			return nil, fmt.Errorf("invalid clip range %v-%v", t.start, t.end)
+		}
+	case input.MarkerTagID != nil:
+		var err error
+		t.markerTagID, err = strconv.Atoi(*input.MarkerTagID)
+		if err != nil {
+			return nil, fmt.Errorf("converting tag id: %w", err)
+		}
+
+		if input.MarkerDuration != nil {
+			if *input.MarkerDuration <= 0 {
+				return nil, errors.New("marker_duration must be positive")
+			}
+			t.markerDuration = *input.MarkerDuration
+		}
+	default:
+		return nil, errors.New("one of scene_id or marker_tag_id must be set")
+	}
+
+	return t, nil
+}
+
+func (t *ExportClipsTask) GetDescription() string {
+	if t.markerTagID != 0 {
+		return fmt.Sprintf("Exporting clips of markers with tag ID %d", t.markerTagID)
+	}
+
+	return fmt.Sprintf("Exporting clip of scene ID %d", t.sceneID)
+}
+
+// Extension returns the file extension of the exported clip.
+func (t *ExportClipsTask) Extension() string {
+	return t.mode.Extension()
+}
+
+func (t *ExportClipsTask) Start(ctx context.Context, progress *job.Progress) error {
+	startTime := time.Now()
+
+	var clips []generate.ClipRange
+	if err := t.txnManager.WithReadTxn(ctx, func(ctx context.Context) error {
+		var err error
+		if t.markerTagID != 0 {
+			clips, err = t.markerClips(ctx)
+		} else {
+			clips, err = t.sceneClip(ctx)
+		}
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if len(clips) == 0 {
+		return errors.New("nothing to export")
+	}
+
+	progress.SetTotal(len(clips))
+
+	if err := fsutil.EnsureDir(instance.Paths.Generated.Downloads); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(instance.Paths.Generated.Downloads, "clip*"+t.mode.Extension())
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	g := &generate.Generator{
+		Encoder:      instance.FFMPEG,
+		FFMpegConfig: instance.Config,
+		LockManager:  instance.ReadLockManager,
+		ScenePaths:   instance.Paths.Scene,
+	}
+
+	if err := g.Clip(ctx, f.Name(), clips, t.mode, progress.Increment); err != nil {
+		_ = os.Remove(f.Name())
+		if job.IsCancelled(ctx) {
+			return err
+		}
+		logErrorOutput(err)
+		return fmt.Errorf("cutting clip: %w", err)
+	}
+
+	t.DownloadHash, err = instance.DownloadStore.RegisterFile(f.Name(), "", false)
+	if err != nil {
+		return fmt.Errorf("error registering file for download: %w", err)
+	}
+
+	logger.Infof("Clip export complete in %s.", time.Since(startTime))
+	return nil
+}
+
+func (t *ExportClipsTask) findScene(ctx context.Context, sceneID int) (*models.Scene, error) {
+	s, err := t.txnManager.Scene.Find(ctx, sceneID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("scene with id %d not found", sceneID)
+	}
+
+	if err := s.LoadPrimaryFile(ctx, t.txnManager.File); err != nil {
+		return nil, err
+	}
+
+	if s.Files.Primary() == nil {
+		return nil, fmt.Errorf("scene with id %d has no files", sceneID)
+	}
+
+	return s, nil
+}
+
+func (t *ExportClipsTask) sceneClip(ctx context.Context) ([]generate.ClipRange, error) {
+	s, err := t.findScene(ctx, t.sceneID)
+	if err != nil {
+		return nil, err
+	}
+
+	f := s.Files.Primary()
+	if t.start >= f.Duration {
+		return nil, fmt.Errorf("clip start %v is beyond the end of the scene", t.start)
+	}
+
+	return []generate.ClipRange{
+		{
+			Input: f.Path,
+			Start: t.start,
+			End:   math.Min(t.end, f.Duration),
+		},
+	}, nil
+}
+
+func (t *ExportClipsTask) markerClips(ctx context.Context) ([]generate.ClipRange, error) {
+	markerFilter := &models.SceneMarkerFilterType{
+		Tags: &models.HierarchicalMultiCriterionInput{
+			Modifier: models.CriterionModifierIncludes,
+			Value:    []string{strconv.Itoa(t.markerTagID)},
+		},
+	}
+
+	sort := "scene_id"
+	perPage := models.PerPageAll
+	findFilter := &models.FindFilterType{
+		Sort:    &sort,
+		PerPage: &perPage,
+	}
+
+	markers, _, err := t.txnManager.SceneMarker.Query(ctx, markerFilter, findFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	scenes := make(map[int]*models.Scene)
+	var files []*models.VideoFile
+	var ret []generate.ClipRange
+	for _, m := range markers {
+		s, found := scenes[m.SceneID]
+		if !found {
+			s, err = t.findScene(ctx, m.SceneID)
+			if err != nil {
+				logger.Warnf("skipping marker %d: %v", m.ID, err)
+			}
+			scenes[m.SceneID] = s
+
+			if s != nil {
+				files = append(files, s.Files.Primary())
+			}
+		}
+
+		if s == nil {
+			continue
+		}
+
+		f := s.Files.Primary()
+		if m.Seconds >= f.Duration {
+			logger.Warnf("skipping marker %d: beyond the end of the scene", m.ID)
+			continue
+		}
+
+		ret = append(ret, generate.ClipRange{
+			Input: f.Path,
+			Start: m.Seconds,
+			End:   math.Min(m.Seconds+t.markerDuration, f.Duration),
+		})
+	}
+
+	if t.mode == generate.ClipModeCopy && !copyCompatible(files) {
+		logger.Warnf("markers with tag ID %d are in files with different codecs or dimensions, which cannot be joined without re-encoding. Using accurate mode.", t.markerTagID)
+		t.mode = generate.ClipModeAccurate
+	}
+
+	return ret, nil
+}
+
+// copyCompatible returns true if the streams of the files may be copied and
+// joined. Joining copied streams requires the same codecs, dimensions and
+// frame rate.
+func copyCompatible(files []*models.VideoFile) bool {
+	for i := 1; i < len(files); i++ {
+		f, first := files[i], files[0]
+		if f.VideoCodec != first.VideoCodec || f.AudioCodec != first.AudioCodec ||
+			f.Width != first.Width || f.Height != first.Height || f.FrameRate != first.FrameRate {
+			return false
+		}
+	}
+
+	return true
+}
+
+// maxClipExports is the number of completed clip exports which are kept for
+// retrieval with GetSceneClipExport.
+const maxClipExports = 10
+
+type clipExport struct {
+	jobID  int
+	userID *int
+	// path of the download, relative to the downloads route
+	path string
+}
+
+// clipExports holds the downloads of the most recently completed clip export
+// jobs.
+type clipExports struct {
+	mutex   sync.Mutex
+	exports []clipExport
+}
+
+func (c *clipExports) add(e clipExport) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.exports = append(c.exports, e)
+	if len(c.exports) > maxClipExports {
+		c.exports = c.exports[1:]
+	}
+}
+
+func (c *clipExports) get(jobID int) *clipExport {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, e := range c.exports {
+		if e.jobID == jobID {
+			ret := e
+			return &ret
+		}
+	}
+
+	return nil
+}
+
+// ExportSceneClips queues a job which cuts a time range of a scene, or the
+// markers with a tag, into a file. Returns the job ID. The download path of
+// the file is returned by GetSceneClipExport once the job is complete.
+func (s *Manager) ExportSceneClips(ctx context.Context, input ExportSceneClipsInput) (int, error) {
+	t, err := CreateExportClipsTask(s.Repository, input)
+	if err != nil {
+		return 0, err
+	}
+
+	userID := session.GetCurrentUserID(ctx)
+
+	// the job ID is not known until the job is added
+	jobID := make(chan int, 1)
+	j := job.MakeJobExec(func(ctx context.Context, progress *job.Progress) {
+		id := <-jobID
+
+		if err := t.Start(ctx, progress); err != nil {
+			if job.IsCancelled(ctx) {
+				logger.Info("Stopping due to user request")
+				return
+			}
+
+			logger.Errorf("Error exporting clip: %v", err)
+			return
+		}
+
+		// generate timestamp
+		suffix := time.Now().Format("20060102-150405")
+		s.clipExports.add(clipExport{
+			jobID:  id,
+			userID: userID,
+			path:   t.DownloadHash + "/clip" + suffix + t.Extension(),
+		})
+	})
+
+	id := s.JobManager.Add(ctx, t.GetDescription(), j)
+	jobID <- id
+
+	return id, nil
+}
+
+// GetSceneClipExport returns the download path, relative to the downloads
+// route, of the file exported by the ExportSceneClips job with the given ID.
+// Returns nil if the job is not complete, or was started by another user.
+func (s *Manager) GetSceneClipExport(ctx context.Context, jobID int) *string {
+	e := s.clipExports.get(jobID)
+	if e == nil {
+		return nil
+	}
+
+	userID := session.GetCurrentUserID(ctx)
+	if (e.userID == nil) != (userID == nil) || (userID != nil && *e.userID != *userID) {
+		return nil
+	}
+
+	return &e.path
+}
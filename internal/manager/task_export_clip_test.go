@@ -0,0 +1,168 @@
+package manager
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/models/mocks"
+	"github.com/stashapp/stash/pkg/scene/generate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateExportClipsTask(t *testing.T) {
+	var (
+		sceneID        = "1"
+		tagID          = "2"
+		start          = 10.0
+		end            = 20.0
+		zero           = 0.0
+		negative       = -1.0
+		markerDuration = 5.0
+		accurate       = ClipExportModeAccurate
+	)
+
+	tests := []struct {
+		name    string
+		input   ExportSceneClipsInput
+		wantErr bool
+	}{
+		{"neither id set", ExportSceneClipsInput{}, true},
+		{"both ids set", ExportSceneClipsInput{SceneID: &sceneID, Start: &start, End: &end, MarkerTagID: &tagID}, true},
+		{"scene range", ExportSceneClipsInput{SceneID: &sceneID, Start: &start, End: &end}, false},
+		{"missing end", ExportSceneClipsInput{SceneID: &sceneID, Start: &start}, true},
+		{"start equals end", ExportSceneClipsInput{SceneID: &sceneID, Start: &start, End: &start}, true},
+		{"start after end", ExportSceneClipsInput{SceneID: &sceneID, Start: &end, End: &start}, true},
+		{"negative start", ExportSceneClipsInput{SceneID: &sceneID, Start: &negative, End: &end}, true},
+		{"marker tag", ExportSceneClipsInput{MarkerTagID: &tagID}, false},
+		{"marker duration", ExportSceneClipsInput{MarkerTagID: &tagID, MarkerDuration: &markerDuration, Mode: &accurate}, false},
+		{"zero marker duration", ExportSceneClipsInput{MarkerTagID: &tagID, MarkerDuration: &zero}, true},
+		{"negative marker duration", ExportSceneClipsInput{MarkerTagID: &tagID, MarkerDuration: &negative}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CreateExportClipsTask(Repository{}, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CreateExportClipsTask() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	task, err := CreateExportClipsTask(Repository{}, ExportSceneClipsInput{MarkerTagID: &tagID})
+	if assert.Nil(t, err) {
+		assert.Equal(t, float64(defaultClipMarkerDuration), task.markerDuration)
+		assert.Equal(t, generate.ClipModeCopy, task.mode)
+	}
+}
+
+func makeClipScene(id int, path string, duration float64, videoCodec string) *models.Scene {
+	return &models.Scene{
+		ID: id,
+		Files: models.NewRelatedVideoFiles([]*models.VideoFile{
+			{
+				BaseFile:   &models.BaseFile{Path: path},
+				Duration:   duration,
+				VideoCodec: videoCodec,
+				Width:      1920,
+				Height:     1080,
+			},
+		}),
+	}
+}
+
+func TestExportClipsTask_sceneClip(t *testing.T) {
+	const sceneID = 1
+
+	sceneReader := &mocks.SceneReaderWriter{}
+	sceneReader.On("Find", mock.Anything, sceneID).Return(makeClipScene(sceneID, "scene.mp4", 100, "h264"), nil)
+
+	repo := Repository{Scene: sceneReader}
+
+	tests := []struct {
+		name    string
+		start   float64
+		end     float64
+		want    []generate.ClipRange
+		wantErr bool
+	}{
+		{"within scene", 10, 20, []generate.ClipRange{{Input: "scene.mp4", Start: 10, End: 20}}, false},
+		{"end clamped", 90, 120, []generate.ClipRange{{Input: "scene.mp4", Start: 90, End: 100}}, false},
+		{"start at end", 100, 120, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task := &ExportClipsTask{
+				txnManager: repo,
+				sceneID:    sceneID,
+				start:      tt.start,
+				end:        tt.end,
+			}
+
+			got, err := task.sceneClip(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExportClipsTask.sceneClip() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExportClipsTask_markerClips(t *testing.T) {
+	const (
+		scene1ID = 1
+		scene2ID = 2
+		tagID    = 3
+	)
+
+	markers := []*models.SceneMarker{
+		{ID: 1, SceneID: scene1ID, Seconds: 10},
+		// clamped to the end of the scene
+		{ID: 2, SceneID: scene1ID, Seconds: 95},
+		// beyond the end of the scene
+		{ID: 3, SceneID: scene1ID, Seconds: 100},
+		{ID: 4, SceneID: scene2ID, Seconds: 0},
+	}
+
+	newTask := func(scene2Codec string) *ExportClipsTask {
+		sceneReader := &mocks.SceneReaderWriter{}
+		sceneReader.On("Find", mock.Anything, scene1ID).Return(makeClipScene(scene1ID, "scene1.mp4", 100, "h264"), nil)
+		sceneReader.On("Find", mock.Anything, scene2ID).Return(makeClipScene(scene2ID, "scene2.mp4", 50, scene2Codec), nil)
+
+		markerReader := &mocks.SceneMarkerReaderWriter{}
+		markerReader.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(markers, len(markers), nil)
+
+		return &ExportClipsTask{
+			txnManager: Repository{
+				Scene:       sceneReader,
+				SceneMarker: markerReader,
+			},
+			markerTagID:    tagID,
+			markerDuration: 20,
+			mode:           generate.ClipModeCopy,
+		}
+	}
+
+	task := newTask("h264")
+	got, err := task.markerClips(context.Background())
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	assert.Equal(t, []generate.ClipRange{
+		{Input: "scene1.mp4", Start: 10, End: 30},
+		{Input: "scene1.mp4", Start: 95, End: 100},
+		{Input: "scene2.mp4", Start: 0, End: 20},
+	}, got)
+	assert.Equal(t, generate.ClipModeCopy, task.mode)
+
+	// files with different codecs cannot be joined without re-encoding
+	task = newTask("hevc")
+	if _, err := task.markerClips(context.Background()); assert.Nil(t, err) {
+		assert.Equal(t, generate.ClipModeAccurate, task.mode)
+	}
+}
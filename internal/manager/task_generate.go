@@ -19,6 +19,7 @@ import (
 type GenerateMetadataInput struct {
 	Covers              bool                         `json:"covers"`
 	Sprites             bool                         `json:"sprites"`
+	Trickplay           bool                         `json:"trickplay"`
 	Previews            bool                         `json:"previews"`
 	ImagePreviews       bool                         `json:"imagePreviews"`
 	PreviewOptions      *GeneratePreviewOptionsInput `json:"previewOptions"`
@@ -31,6 +32,17 @@ type GenerateMetadataInput struct {
 	Phashes                   bool `json:"phashes"`
 	InteractiveHeatmapsSpeeds bool `json:"interactiveHeatmapsSpeeds"`
 	ClipPreviews              bool `json:"clipPreviews"`
+	// Analyse the loudness of the audio
+	Loudness bool `json:"loudness"`
+	// Detect scene cuts, used to choose covers and sprite frames and to
+	// suggest markers
+	SceneCuts bool `json:"sceneCuts"`
+	// Detect black bars, which are cropped from generated previews and images
+	CropDetect bool `json:"cropDetect"`
+	// Decode files to check for errors and truncation
+	IntegrityCheck bool `json:"integrityCheck"`
+	// Only decode keyframes when checking integrity. Faster but less thorough
+	IntegrityCheckKeyframesOnly bool `json:"integrityCheckKeyframesOnly"`
 	// scene ids to generate for
 	SceneIDs []string `json:"sceneIDs"`
 	// marker ids to generate for
@@ -65,6 +77,7 @@ type GenerateJob struct {
 type totalsGenerate struct {
 	covers                   int64
 	sprites                  int64
+	trickplay                int64
 	previews                 int64
 	imagePreviews            int64
 	markers                  int64
@@ -72,6 +85,10 @@ type totalsGenerate struct {
 	phashes                  int64
 	interactiveHeatmapSpeeds int64
 	clipPreviews             int64
+	loudness                 int64
+	sceneCuts                int64
+	cropDetects              int64
+	integrityChecks          int64
 
 	tasks int
 }
@@ -152,6 +169,9 @@ func (j *GenerateJob) Execute(ctx context.Context, progress *job.Progress) {
 		if j.input.Sprites {
 			logMsg += fmt.Sprintf(" %d sprites", totals.sprites)
 		}
+		if j.input.Trickplay {
+			logMsg += fmt.Sprintf(" %d trickplay", totals.trickplay)
+		}
 		if j.input.Previews {
 			logMsg += fmt.Sprintf(" %d previews", totals.previews)
 		}
@@ -173,6 +193,18 @@ func (j *GenerateJob) Execute(ctx context.Context, progress *job.Progress) {
 		if j.input.ClipPreviews {
 			logMsg += fmt.Sprintf(" %d Image Clip Previews", totals.clipPreviews)
 		}
+		if j.input.Loudness {
+			logMsg += fmt.Sprintf(" %d loudness analyses", totals.loudness)
+		}
+		if j.input.SceneCuts {
+			logMsg += fmt.Sprintf(" %d scene cut detections", totals.sceneCuts)
+		}
+		if j.input.CropDetect {
+			logMsg += fmt.Sprintf(" %d crop detections", totals.cropDetects)
+		}
+		if j.input.IntegrityCheck {
+			logMsg += fmt.Sprintf(" %d integrity checks", totals.integrityChecks)
+		}
 		if logMsg == "Generating" {
 			logMsg = "Nothing selected to generate"
 		}
@@ -331,6 +363,37 @@ func getGeneratePreviewOptions(optionsInput GeneratePreviewOptionsInput) generat
 }
 
 func (j *GenerateJob) queueSceneJobs(ctx context.Context, g *generate.Generator, scene *models.Scene, queue chan<- Task, totals *totalsGenerate) {
+	// queue crop detection and scene cuts first, since covers, sprites and
+	// previews use them if present
+	if j.input.CropDetect {
+		task := &GenerateCropTask{
+			Scene:      *scene,
+			Overwrite:  j.overwrite,
+			TxnManager: j.txnManager,
+		}
+
+		if task.required() {
+			totals.cropDetects++
+			totals.tasks++
+			queue <- task
+		}
+	}
+
+	if j.input.SceneCuts {
+		task := &GenerateSceneCutsTask{
+			Scene:               *scene,
+			Overwrite:           j.overwrite,
+			fileNamingAlgorithm: j.fileNamingAlgo,
+			generator:           g,
+		}
+
+		if task.required() {
+			totals.sceneCuts++
+			totals.tasks++
+			queue <- task
+		}
+	}
+
 	if j.input.Covers {
 		task := &GenerateCoverTask{
 			txnManager: j.txnManager,
@@ -359,6 +422,21 @@ func (j *GenerateJob) queueSceneJobs(ctx context.Context, g *generate.Generator,
 		}
 	}
 
+	if j.input.Trickplay {
+		task := &GenerateTrickplayTask{
+			Scene:               *scene,
+			Overwrite:           j.overwrite,
+			fileNamingAlgorithm: j.fileNamingAlgo,
+			generator:           g,
+		}
+
+		if task.required() {
+			totals.trickplay++
+			totals.tasks++
+			queue <- task
+		}
+	}
+
 	generatePreviewOptions := j.input.PreviewOptions
 	if generatePreviewOptions == nil {
 		generatePreviewOptions = &GeneratePreviewOptionsInput{}
@@ -458,6 +536,35 @@ func (j *GenerateJob) queueSceneJobs(ctx context.Context, g *generate.Generator,
 			queue <- task
 		}
 	}
+
+	if j.input.IntegrityCheck {
+		task := &GenerateIntegrityTask{
+			Scene:         *scene,
+			Overwrite:     j.overwrite,
+			KeyframesOnly: j.input.IntegrityCheckKeyframesOnly,
+			TxnManager:    j.txnManager,
+		}
+
+		if task.required() {
+			totals.integrityChecks++
+			totals.tasks++
+			queue <- task
+		}
+	}
+
+	if j.input.Loudness {
+		task := &GenerateLoudnessTask{
+			Scene:      *scene,
+			Overwrite:  j.overwrite,
+			TxnManager: j.txnManager,
+		}
+
+		if task.required() {
+			totals.loudness++
+			totals.tasks++
+			queue <- task
+		}
+	}
 }
 
 func (j *GenerateJob) queueMarkerJob(g *generate.Generator, marker *models.SceneMarker, queue chan<- Task, totals *totalsGenerate) {
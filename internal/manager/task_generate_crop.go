@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+)
+
+type GenerateCropTask struct {
+	Scene      models.Scene
+	Overwrite  bool
+	TxnManager Repository
+}
+
+func (t *GenerateCropTask) GetDescription() string {
+	return fmt.Sprintf("Detecting black bars of %s", t.Scene.Path)
+}
+
+func (t *GenerateCropTask) Start(ctx context.Context) {
+	if !t.required() {
+		return
+	}
+
+	primaryFile := t.Scene.Files.Primary()
+
+	crop, err := instance.FFMPEG.DetectCrop(ctx, primaryFile.Path, primaryFile.Duration, primaryFile.Width, primaryFile.Height)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Errorf("error detecting black bars of %s: %v", primaryFile.Path, err)
+		}
+		return
+	}
+
+	if err := t.TxnManager.WithTxn(ctx, func(ctx context.Context) error {
+		primaryFile.Crop = crop
+		qb := t.TxnManager.File
+		return qb.Update(ctx, primaryFile)
+	}); err != nil && ctx.Err() == nil {
+		logger.Error(err.Error())
+	}
+}
+
+func (t *GenerateCropTask) required() bool {
+	primaryFile := t.Scene.Files.Primary()
+	if primaryFile == nil || primaryFile.Width == 0 || primaryFile.Height == 0 {
+		return false
+	}
+
+	if t.Overwrite {
+		return true
+	}
+
+	return primaryFile.Crop == nil
+}
+
+// sceneCrop returns the region of the scene's primary file to keep when
+// generating previews and images, or nil if no black bars were detected.
+func sceneCrop(s *models.Scene) *models.VideoCrop {
+	if !s.Files.PrimaryLoaded() {
+		return nil
+	}
+
+	f := s.Files.Primary()
+	if f == nil || !f.IsCropped() {
+		return nil
+	}
+
+	return f.Crop
+}
@@ -0,0 +1,67 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+)
+
+type GenerateIntegrityTask struct {
+	Scene         models.Scene
+	Overwrite     bool
+	KeyframesOnly bool
+	TxnManager    Repository
+}
+
+func (t *GenerateIntegrityTask) GetDescription() string {
+	return fmt.Sprintf("Checking integrity of %s", t.Scene.Path)
+}
+
+func (t *GenerateIntegrityTask) Start(ctx context.Context) {
+	if !t.required() {
+		return
+	}
+
+	primaryFile := t.Scene.Files.Primary()
+
+	lockCtx := instance.ReadLockManager.ReadLock(ctx, primaryFile.Path)
+	defer lockCtx.Cancel()
+
+	result, err := instance.FFMPEG.CheckIntegrity(lockCtx, primaryFile.Path, primaryFile.Duration, t.KeyframesOnly)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Errorf("error checking integrity of %s: %v", primaryFile.Path, err)
+		}
+		return
+	}
+
+	integrity := models.NewVideoIntegrity(result.ErrorCount, result.FirstErrorTime, result.Truncated)
+	if integrity.Status != models.IntegrityStatusOk {
+		logger.Warnf("%s is %s: %d errors, decoded %.1fs of %.1fs", primaryFile.Path, integrity.Status, result.ErrorCount, result.DecodedDuration, primaryFile.Duration)
+	}
+
+	// the result is saved immediately, so that a cancelled check resumes
+	// from the next unchecked file
+	if err := t.TxnManager.WithTxn(ctx, func(ctx context.Context) error {
+		primaryFile.Integrity = integrity
+		qb := t.TxnManager.File
+		return qb.Update(ctx, primaryFile)
+	}); err != nil && ctx.Err() == nil {
+		logger.Error(err.Error())
+	}
+}
+
+func (t *GenerateIntegrityTask) required() bool {
+	primaryFile := t.Scene.Files.Primary()
+	if primaryFile == nil {
+		return false
+	}
+
+	if t.Overwrite {
+		return true
+	}
+
+	return primaryFile.Integrity == nil
+}
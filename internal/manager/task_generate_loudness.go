@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+)
+
+type GenerateLoudnessTask struct {
+	Scene      models.Scene
+	Overwrite  bool
+	TxnManager Repository
+}
+
+func (t *GenerateLoudnessTask) GetDescription() string {
+	return fmt.Sprintf("Analysing loudness of %s", t.Scene.Path)
+}
+
+func (t *GenerateLoudnessTask) Start(ctx context.Context) {
+	if !t.required() {
+		return
+	}
+
+	primaryFile := t.Scene.Files.Primary()
+
+	loudness, err := instance.FFMPEG.AnalyseLoudness(ctx, primaryFile.Path)
+	if errors.Is(err, ffmpeg.ErrNoAudio) {
+		logger.Debugf("no audible audio in %s, skipping loudness analysis", primaryFile.Path)
+		return
+	}
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Errorf("error analysing loudness of %s: %v", primaryFile.Path, err)
+		}
+		return
+	}
+
+	if err := t.TxnManager.WithTxn(ctx, func(ctx context.Context) error {
+		primaryFile.IntegratedLoudness = &loudness.Integrated
+		primaryFile.TruePeak = &loudness.TruePeak
+		qb := t.TxnManager.File
+		return qb.Update(ctx, primaryFile)
+	}); err != nil && ctx.Err() == nil {
+		logger.Error(err.Error())
+	}
+}
+
+func (t *GenerateLoudnessTask) required() bool {
+	primaryFile := t.Scene.Files.Primary()
+	if primaryFile == nil || ffmpeg.ProbeAudioCodec(primaryFile.AudioCodec) == ffmpeg.MissingUnsupported {
+		return false
+	}
+
+	if t.Overwrite {
+		return true
+	}
+
+	return primaryFile.IntegratedLoudness == nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/stashapp/stash/pkg/ffmpeg"
 	"github.com/stashapp/stash/pkg/fsutil"
 	"github.com/stashapp/stash/pkg/logger"
 	"github.com/stashapp/stash/pkg/models"
@@ -59,14 +60,20 @@ func (t *GeneratePreviewTask) generateVideo(videoChecksum string, videoDuration
 	videoFilename := t.Scene.Path
 	useVsync2 := false
 
+	options := t.Options
+	options.Crop = sceneCrop(&t.Scene)
+	if options.Audio {
+		options.AudioGain = t.audioGain()
+	}
+
 	if videoFrameRate <= 0.01 {
 		logger.Errorf("[generator] Video framerate very low/high (%f) most likely vfr so using -vsync 2", videoFrameRate)
 		useVsync2 = true
 	}
 
-	if err := t.generator.PreviewVideo(context.TODO(), videoFilename, videoDuration, videoChecksum, t.Options, false, useVsync2); err != nil {
+	if err := t.generator.PreviewVideo(context.TODO(), videoFilename, videoDuration, videoChecksum, options, false, useVsync2); err != nil {
 		logger.Warnf("[generator] failed generating scene preview, trying fallback")
-		if err := t.generator.PreviewVideo(context.TODO(), videoFilename, videoDuration, videoChecksum, t.Options, true, useVsync2); err != nil {
+		if err := t.generator.PreviewVideo(context.TODO(), videoFilename, videoDuration, videoChecksum, options, true, useVsync2); err != nil {
 			return err
 		}
 	}
@@ -74,6 +81,23 @@ func (t *GeneratePreviewTask) generateVideo(videoChecksum string, videoDuration
 	return nil
 }
 
+// audioGain returns the loudness normalisation gain for the preview audio,
+// or nil if normalisation is disabled or the loudness has not been analysed.
+func (t *GeneratePreviewTask) audioGain() *float64 {
+	config := instance.Config
+	if !config.GetNormaliseLoudness() || !t.Scene.Files.PrimaryLoaded() {
+		return nil
+	}
+
+	f := t.Scene.Files.Primary()
+	if f == nil || f.IntegratedLoudness == nil || f.TruePeak == nil {
+		return nil
+	}
+
+	gain := ffmpeg.LoudnessGain(*f.IntegratedLoudness, *f.TruePeak, config.GetLoudnessTarget())
+	return &gain
+}
+
 func (t *GeneratePreviewTask) generateWebp(videoChecksum string) error {
 	videoFilename := t.Scene.Path
 	return t.generator.PreviewWebp(context.TODO(), videoFilename, videoChecksum)
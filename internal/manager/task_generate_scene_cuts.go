@@ -0,0 +1,72 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/fsutil"
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/scene/generate"
+)
+
+type GenerateSceneCutsTask struct {
+	Scene               models.Scene
+	Overwrite           bool
+	fileNamingAlgorithm models.HashAlgorithm
+
+	generator *generate.Generator
+}
+
+func (t *GenerateSceneCutsTask) GetDescription() string {
+	return fmt.Sprintf("Detecting scene cuts for %s", t.Scene.Path)
+}
+
+func (t *GenerateSceneCutsTask) Start(ctx context.Context) {
+	if !t.required() {
+		return
+	}
+
+	sceneHash := t.Scene.GetHash(t.fileNamingAlgorithm)
+	if err := t.generator.SceneCuts(ctx, t.Scene.Path, sceneHash); err != nil {
+		logger.Errorf("error detecting scene cuts: %v", err)
+		logErrorOutput(err)
+		return
+	}
+}
+
+// required returns true if the scene cuts need to be detected
+func (t GenerateSceneCutsTask) required() bool {
+	if t.Scene.Path == "" {
+		return false
+	}
+
+	if t.Overwrite {
+		return true
+	}
+
+	sceneHash := t.Scene.GetHash(t.fileNamingAlgorithm)
+	if sceneHash == "" {
+		return false
+	}
+
+	exists, _ := fsutil.FileExists(instance.Paths.Scene.GetSceneCutsPath(sceneHash))
+	return !exists
+}
+
+// loadSceneCuts returns the detected scene cuts of the scene with the given
+// hash, or nil if they have not been generated.
+func loadSceneCuts(sceneHash string) ffmpeg.SceneCuts {
+	if sceneHash == "" {
+		return nil
+	}
+
+	cuts, err := generate.ReadSceneCuts(instance.Paths.Scene.GetSceneCutsPath(sceneHash))
+	if err != nil {
+		logger.Warnf("error reading scene cuts: %v", err)
+		return nil
+	}
+
+	return cuts
+}
@@ -44,6 +44,10 @@ func (t *GenerateCoverTask) Start(ctx context.Context) {
 	var at float64
 	if t.ScreenshotAt == nil {
 		at = float64(videoFile.Duration) * 0.2
+
+		// take the cover from the middle of a shot rather than a transition
+		cuts := loadSceneCuts(t.Scene.GetHash(instance.Config.GetVideoFileNamingAlgorithm()))
+		at = cuts.RepresentativeTime(at, videoFile.Duration)
 	} else {
 		at = *t.ScreenshotAt
 	}
@@ -62,7 +66,8 @@ func (t *GenerateCoverTask) Start(ctx context.Context) {
 	}
 
 	coverImageData, err := g.Screenshot(context.TODO(), videoFile.Path, videoFile.Width, videoFile.Duration, generate.ScreenshotOptions{
-		At: &at,
+		At:   &at,
+		Crop: sceneCrop(&t.Scene),
 	})
 	if err != nil {
 		logger.Errorf("Error generating screenshot: %v", err)
@@ -41,6 +41,8 @@ func (t *GenerateSpriteTask) Start(ctx context.Context) {
 		return
 	}
 	generator.Overwrite = t.Overwrite
+	generator.SceneCuts = loadSceneCuts(sceneHash)
+	generator.Crop = sceneCrop(&t.Scene)
 
 	if err := generator.Generate(); err != nil {
 		logger.Errorf("error generating sprite: %s", err.Error())
@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/stashapp/stash/internal/manager/config"
+	"github.com/stashapp/stash/pkg/fsutil"
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/scene/generate"
+)
+
+type GenerateTrickplayTask struct {
+	Scene               models.Scene
+	Overwrite           bool
+	fileNamingAlgorithm models.HashAlgorithm
+
+	generator *generate.Generator
+}
+
+func (t *GenerateTrickplayTask) GetDescription() string {
+	return fmt.Sprintf("Generating trickplay for %s", t.Scene.Path)
+}
+
+func (t *GenerateTrickplayTask) Start(ctx context.Context) {
+	if !t.required() {
+		return
+	}
+
+	ffprobe := instance.FFProbe
+	videoFile, err := ffprobe.NewVideoFile(t.Scene.Path)
+	if err != nil {
+		logger.Errorf("error reading video file: %v", err)
+		return
+	}
+
+	c := config.GetInstance()
+	options := generate.TrickplayOptions{
+		Interval: c.GetTrickplayInterval(),
+		Width:    c.GetTrickplayWidth(),
+		Crop:     sceneCrop(&t.Scene),
+	}
+
+	sceneHash := t.Scene.GetHash(t.fileNamingAlgorithm)
+	if err := t.generator.Trickplay(ctx, t.Scene.Path, videoFile.VideoStreamDuration, sceneHash, options); err != nil {
+		logger.Errorf("error generating trickplay: %v", err)
+		logErrorOutput(err)
+		return
+	}
+}
+
+// required returns true if the trickplay files need to be generated
+func (t GenerateTrickplayTask) required() bool {
+	if t.Scene.Path == "" {
+		return false
+	}
+
+	if t.Overwrite {
+		return true
+	}
+
+	sceneHash := t.Scene.GetHash(t.fileNamingAlgorithm)
+	return !t.doesTrickplayExist(sceneHash)
+}
+
+func (t *GenerateTrickplayTask) doesTrickplayExist(sceneChecksum string) bool {
+	if sceneChecksum == "" {
+		return false
+	}
+
+	bifExists, _ := fsutil.FileExists(instance.Paths.Scene.GetTrickplayBIFPath(sceneChecksum))
+	manifestExists, _ := fsutil.FileExists(filepath.Join(instance.Paths.Scene.GetTrickplayDir(sceneChecksum), generate.TrickplayManifest))
+	return bifExists && manifestExists
+}
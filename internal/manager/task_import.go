@@ -115,6 +115,9 @@ func (t *ImportTask) Start(ctx context.Context) {
 			logger.Errorf("Error resetting database: %s", err.Error())
 			return
 		}
+
+		// the users are removed with the database
+		instance.InvalidateUserCache()
 	}
 
 	t.ImportTags(ctx)
@@ -650,6 +653,7 @@ func (t *ImportTask) ImportScenes(ctx context.Context) {
 				PerformerWriter: performerWriter,
 				StudioWriter:    studioWriter,
 				TagWriter:       tagWriter,
+				UserFinder:      r.User,
 			}
 
 			if err := performImport(ctx, sceneImporter, t.DuplicateBehaviour); err != nil {
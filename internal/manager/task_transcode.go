@@ -68,16 +68,32 @@ func (t *GenerateTranscodeTask) Start(ctc context.Context) {
 	}
 
 	sceneHash := t.Scene.GetHash(t.fileNamingAlgorithm)
-	transcodeSize := config.GetInstance().GetMaxTranscodeSize()
+	c := config.GetInstance()
+	transcodeSize := c.GetMaxTranscodeSize()
+
+	var crop *models.VideoCrop
+	if c.GetCropTranscodes() {
+		crop = sceneCrop(&t.Scene)
+	}
+
+	// scale the picture rather than the frame when cropping
+	if crop != nil {
+		videoFile.Width = crop.Width
+		videoFile.Height = crop.Height
+	}
 
 	w, h := videoFile.TranscodeScale(transcodeSize.GetMaxResolution())
 
 	options := generate.TranscodeOptions{
-		Width:  w,
-		Height: h,
+		Width:      w,
+		Height:     h,
+		VideoCodec: videoFile.VideoCodec,
+		Crop:       crop,
 	}
 
-	if videoCodec == ffmpeg.H264 { // for non supported h264 files stream copy the video part
+	// for non supported h264 files stream copy the video part, unless it
+	// needs to be cropped
+	if videoCodec == ffmpeg.H264 && crop == nil {
 		if audioCodec == ffmpeg.MissingUnsupported {
 			err = t.g.TranscodeCopyVideo(context.TODO(), videoFile.Path, sceneHash, options)
 		} else {
@@ -0,0 +1,312 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/stashapp/stash/internal/manager/config"
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+	"github.com/stashapp/stash/pkg/sqlite"
+	"github.com/stashapp/stash/pkg/txn"
+	"github.com/stashapp/stash/pkg/user"
+)
+
+// userFinder finds users in the database for the session store.
+// If the database is not ready, such as when a migration is required, then
+// the credentials in the configuration are used as a temporary admin user,
+// so that the migration can be performed.
+type userFinder struct {
+	database *sqlite.Database
+	repo     Repository
+	config   *config.Instance
+	cache    *userCache
+}
+
+// databaseReady returns true if the database is ready. The cache is
+// invalidated while it is not, since the users may change once it is.
+func (f *userFinder) databaseReady() bool {
+	if f.database.Ready() != nil {
+		f.cache.invalidate()
+		return false
+	}
+
+	return true
+}
+
+// configUser returns the temporary admin user for the credentials in the
+// configuration. The user has ID 0, so that it is never found in the
+// database once it is ready.
+func (f *userFinder) configUser() *models.User {
+	username, passwordHash := f.config.GetCredentials()
+	return &models.User{
+		Username:     username,
+		PasswordHash: passwordHash,
+		Role:         models.UserRoleAdmin,
+	}
+}
+
+func (f *userFinder) FindUser(ctx context.Context, id int) (*models.User, error) {
+	if !f.databaseReady() {
+		if id == 0 && f.config.HasCredentials() {
+			return f.configUser(), nil
+		}
+
+		return nil, nil
+	}
+
+	ret, generation := f.cache.getUser(id)
+	if ret != nil {
+		return ret, nil
+	}
+
+	if err := txn.WithReadTxn(ctx, f.repo, func(ctx context.Context) error {
+		var err error
+		ret, err = f.repo.User.Find(ctx, id)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if ret != nil {
+		f.cache.setUser(generation, ret)
+	}
+
+	return ret, nil
+}
+
+func (f *userFinder) FindUserByCredentials(ctx context.Context, username string, password string) (*models.User, error) {
+	if !f.databaseReady() {
+		if f.config.HasCredentials() && f.config.ValidateCredentials(username, password) {
+			return f.configUser(), nil
+		}
+
+		return nil, nil
+	}
+
+	var ret *models.User
+	if err := txn.WithReadTxn(ctx, f.repo, func(ctx context.Context) error {
+		var err error
+		ret, err = f.repo.User.FindByUsername(ctx, username)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if ret == nil || !user.CheckPassword(ret, password) {
+		return nil, nil
+	}
+
+	return ret, nil
+}
+
+// FindExternalUser finds the user with the username authenticated by single
+// sign-on. If the user does not exist and the configuration permits it, a
+// user is created with the default role and no password.
+func (f *userFinder) FindExternalUser(ctx context.Context, username string) (*models.User, error) {
+	if !f.databaseReady() {
+		return nil, nil
+	}
+
+	// the trusted proxy header is checked on every request, so avoid a write
+	// transaction unless the user must be created
+	ret, generation := f.cache.getUserByUsername(username)
+	if ret != nil {
+		return ret, nil
+	}
+
+	r := f.repo
+	if err := txn.WithReadTxn(ctx, r, func(ctx context.Context) error {
+		var err error
+		ret, err = r.User.FindByUsername(ctx, username)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	if ret != nil {
+		f.cache.setUser(generation, ret)
+		return ret, nil
+	}
+
+	if !f.config.GetSSOCreateUsers() {
+		return nil, nil
+	}
+
+	if err := r.WithTxn(ctx, func(ctx context.Context) error {
+		now := time.Now()
+		newUser := &models.User{
+			Username:  username,
+			Role:      f.config.GetSSODefaultRole(),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		if err := user.Create(ctx, newUser, r.User, r.Scene); err != nil {
+			return err
+		}
+
+		ret = newUser
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("creating user %s: %w", username, err)
+	}
+
+	f.cache.invalidate()
+
+	logger.Infof("Created user %s authenticated by single sign-on", username)
+	return ret, nil
+}
+
+func (f *userFinder) HasUsers(ctx context.Context) (bool, error) {
+	if f.config.HasCredentials() {
+		return true, nil
+	}
+
+	if !f.databaseReady() {
+		return false, nil
+	}
+
+	hasUsers, generation := f.cache.getHasUsers()
+	if hasUsers != nil {
+		return *hasUsers, nil
+	}
+
+	var count int
+	if err := txn.WithReadTxn(ctx, f.repo, func(ctx context.Context) error {
+		var err error
+		count, err = f.repo.User.Count(ctx)
+		return err
+	}); err != nil {
+		return false, err
+	}
+
+	f.cache.setHasUsers(generation, count > 0)
+	return count > 0, nil
+}
+
+// MigrateConfigCredentials creates an admin user from the username and
+// password in the configuration, if there are no users in the database.
+// The credentials are then removed from the configuration.
+func (s *Manager) MigrateConfigCredentials(ctx context.Context) error {
+	c := s.Config
+	if !c.HasCredentials() || s.Database.Ready() != nil {
+		return nil
+	}
+
+	r := s.Repository
+	if err := r.WithTxn(ctx, func(ctx context.Context) error {
+		qb := r.User
+		count, err := qb.Count(ctx)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return nil
+		}
+
+		username, passwordHash := c.GetCredentials()
+		now := time.Now()
+		newUser := &models.User{
+			Username:     username,
+			PasswordHash: passwordHash,
+			Role:         models.UserRoleAdmin,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+
+		if err := user.Create(ctx, newUser, qb, r.Scene); err != nil {
+			return err
+		}
+
+		logger.Infof("Created admin user %s from configured credentials", username)
+		return nil
+	}); err != nil {
+		return fmt.Errorf("creating admin user from configured credentials: %w", err)
+	}
+
+	s.InvalidateUserCache()
+
+	c.Set(config.Username, "")
+	c.Set(config.Password, "")
+	return c.Write()
+}
+
+func (s *Manager) userFinder() *userFinder {
+	return &userFinder{
+		database: s.Database,
+		repo:     s.Repository,
+		config:   s.Config,
+		cache:    &s.userCache,
+	}
+}
+
+// externalAccessConfig treats the existence of users as configured
+// credentials when checking external access.
+type externalAccessConfig struct {
+	*config.Instance
+	hasUsers bool
+}
+
+func (c externalAccessConfig) HasCredentials() bool {
+	return c.hasUsers
+}
+
+// ExternalAccessConfig returns the configuration used to check whether
+// stash may be accessed from the public internet.
+func (s *Manager) ExternalAccessConfig(ctx context.Context) session.ExternalAccessConfig {
+	hasUsers, err := s.userFinder().HasUsers(ctx)
+	if err != nil {
+		logger.Errorf("Error checking for users: %v", err)
+		// fail closed, so that an error does not disable authentication
+		hasUsers = true
+	}
+
+	return externalAccessConfig{
+		Instance: s.Config,
+		hasUsers: hasUsers,
+	}
+}
+
+// dlnaTxnManager is the transaction manager used by the DLNA service. DLNA
+// clients are not authenticated, so content which is restricted for any
+// non-admin user is excluded from its transactions. If a DLNA activity user
+// is configured, it is the current user of the transactions, so that
+// playback activity is recorded for that user. The users are cached until
+// the user cache is invalidated.
+type dlnaTxnManager struct {
+	txn.Manager
+	users  models.UserReader
+	cache  *userCache
+	config *config.Instance
+}
+
+func (m dlnaTxnManager) Begin(ctx context.Context, exclusive bool) (context.Context, error) {
+	ctx, err := m.Manager.Begin(ctx, exclusive)
+	if err != nil {
+		return nil, err
+	}
+
+	all, generation := m.cache.getAllUsers()
+	if all == nil {
+		users, err := m.users.All(ctx)
+		if err != nil {
+			_ = m.Manager.Rollback(ctx)
+			return nil, fmt.Errorf("loading user restrictions: %w", err)
+		}
+
+		all = m.cache.setAllUsers(generation, users)
+	}
+
+	if activityUser := m.config.GetDLNAActivityUser(); activityUser != "" {
+		if u := all.find(activityUser); u != nil {
+			ctx = session.SetCurrentUser(ctx, u)
+		}
+	}
+
+	r := all.restrictions
+	return session.SetContentRestrictions(ctx, &r), nil
+}
@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+// userCache caches whether users exist, the users which authenticate
+// requests and the users used by the DLNA service, so that the database is
+// not read on every request.
+// It must be invalidated whenever users are created, updated or destroyed.
+type userCache struct {
+	mutex sync.Mutex
+	// generation is incremented on invalidation, so that values loaded
+	// before an invalidation are not stored after it
+	generation int
+	hasUsers   *bool
+	users      map[int]*models.User
+	allUsers   *allUsers
+}
+
+// allUsers holds all users and the union of their content restrictions.
+type allUsers struct {
+	users        []*models.User
+	restrictions models.ContentRestrictions
+}
+
+// find returns a copy of the user with the given username, ignoring case,
+// or nil if not found.
+func (a *allUsers) find(username string) *models.User {
+	for _, u := range a.users {
+		if strings.EqualFold(u.Username, username) {
+			ret := *u
+			return &ret
+		}
+	}
+
+	return nil
+}
+
+// getHasUsers returns the cached result of HasUsers, and the generation to
+// pass to setHasUsers if it is not cached.
+func (c *userCache) getHasUsers() (hasUsers *bool, generation int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.hasUsers, c.generation
+}
+
+func (c *userCache) setHasUsers(generation int, hasUsers bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation == c.generation {
+		c.hasUsers = &hasUsers
+	}
+}
+
+// getUser returns a copy of the cached user with the given id, and the
+// generation to pass to setUser if it is not cached.
+func (c *userCache) getUser(id int) (u *models.User, generation int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if cached := c.users[id]; cached != nil {
+		ret := *cached
+		u = &ret
+	}
+
+	return u, c.generation
+}
+
+// getUserByUsername returns a copy of the cached user with the given
+// username, and the generation to pass to setUser if it is not cached.
+func (c *userCache) getUserByUsername(username string) (u *models.User, generation int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, cached := range c.users {
+		if cached.Username == username {
+			ret := *cached
+			u = &ret
+			break
+		}
+	}
+
+	return u, c.generation
+}
+
+func (c *userCache) setUser(generation int, u *models.User) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if c.users == nil {
+		c.users = make(map[int]*models.User)
+	}
+
+	cached := *u
+	c.users[u.ID] = &cached
+}
+
+// getAllUsers returns the cached users, and the generation to pass to
+// setAllUsers if they are not cached.
+func (c *userCache) getAllUsers() (a *allUsers, generation int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.allUsers, c.generation
+}
+
+// setAllUsers caches the users and the union of their content restrictions,
+// and returns the cached value.
+func (c *userCache) setAllUsers(generation int, users []*models.User) *allUsers {
+	a := &allUsers{}
+	for _, u := range users {
+		cached := *u
+		a.users = append(a.users, &cached)
+
+		if r := u.ContentRestrictions(); r != nil {
+			a.restrictions = a.restrictions.Merge(*r)
+		}
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation == c.generation {
+		c.allUsers = a
+	}
+
+	return a
+}
+
+func (c *userCache) invalidate() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.generation++
+	c.hasUsers = nil
+	c.users = nil
+	c.allUsers = nil
+}
+
+// InvalidateUserCache clears the cached users. It must be called after
+// users are created, updated or destroyed.
+func (s *Manager) InvalidateUserCache() {
+	s.userCache.invalidate()
+}
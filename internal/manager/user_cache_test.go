@@ -0,0 +1,79 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserCache(t *testing.T) {
+	c := &userCache{}
+
+	hasUsers, generation := c.getHasUsers()
+	assert.Nil(t, hasUsers)
+	c.setHasUsers(generation, true)
+
+	hasUsers, _ = c.getHasUsers()
+	if assert.NotNil(t, hasUsers) {
+		assert.True(t, *hasUsers)
+	}
+
+	u, generation := c.getUser(1)
+	assert.Nil(t, u)
+	c.setUser(generation, &models.User{ID: 1, Username: "user", Role: models.UserRoleEditor})
+
+	u, _ = c.getUser(1)
+	if assert.NotNil(t, u) {
+		assert.Equal(t, "user", u.Username)
+
+		// modifying the returned user must not modify the cache
+		u.Role = models.UserRoleAdmin
+	}
+
+	u, _ = c.getUserByUsername("user")
+	if assert.NotNil(t, u) {
+		assert.Equal(t, models.UserRoleEditor, u.Role)
+	}
+
+	// values loaded before an invalidation are not stored
+	_, generation = c.getUser(2)
+	c.invalidate()
+	c.setUser(generation, &models.User{ID: 2, Username: "stale"})
+	c.setHasUsers(generation, false)
+
+	u, _ = c.getUser(1)
+	assert.Nil(t, u)
+	u, _ = c.getUser(2)
+	assert.Nil(t, u)
+	hasUsers, _ = c.getHasUsers()
+	assert.Nil(t, hasUsers)
+}
+
+func TestUserCacheAllUsers(t *testing.T) {
+	c := &userCache{}
+
+	all, generation := c.getAllUsers()
+	assert.Nil(t, all)
+	c.setAllUsers(generation, []*models.User{
+		{ID: 1, Username: "admin", Role: models.UserRoleAdmin, Restrictions: models.ContentRestrictions{ExcludedTagIDs: []int{1}}},
+		{ID: 2, Username: "User", Role: models.UserRoleReadOnly, Restrictions: models.ContentRestrictions{ExcludedTagIDs: []int{2}}},
+		{ID: 3, Username: "other", Role: models.UserRoleEditor, Restrictions: models.ContentRestrictions{ExcludedTagIDs: []int{2, 3}}},
+	})
+
+	all, _ = c.getAllUsers()
+	if assert.NotNil(t, all) {
+		// admins are not restricted
+		assert.Equal(t, []int{2, 3}, all.restrictions.ExcludedTagIDs)
+
+		u := all.find("user")
+		if assert.NotNil(t, u) {
+			assert.Equal(t, 2, u.ID)
+		}
+		assert.Nil(t, all.find("missing"))
+	}
+
+	c.invalidate()
+	all, _ = c.getAllUsers()
+	assert.Nil(t, all)
+}
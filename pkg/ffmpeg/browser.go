@@ -53,6 +53,13 @@ func IsStreamable(videoCodec string, audioCodec ProbeAudioCodec, container Conta
 	return nil
 }
 
+// IsRemuxable returns true if the video codec is supported by browsers,
+// regardless of the container. Files with such a video codec can be remuxed
+// into a streamable container instead of being transcoded.
+func IsRemuxable(videoCodec string) bool {
+	return isValidCodec(videoCodec, defaultSupportedCodecs)
+}
+
 func isValidCodec(codecName string, supportedCodecs []string) bool {
 	for _, c := range supportedCodecs {
 		if c == codecName {
@@ -3,6 +3,7 @@ package ffmpeg
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -22,11 +23,111 @@ var (
 	VideoCodecIVP9 VideoCodec = "vp9_qsv"
 	VideoCodecVVP9 VideoCodec = "vp9_vaapi"
 	VideoCodecVVPX VideoCodec = "vp8_vaapi"
+	VideoCodecN265 VideoCodec = "hevc_nvenc"
+	VideoCodecI265 VideoCodec = "hevc_qsv"
+	VideoCodecV265 VideoCodec = "hevc_vaapi"
+	VideoCodecNAV1 VideoCodec = "av1_nvenc"
+	VideoCodecIAV1 VideoCodec = "av1_qsv"
+	VideoCodecVAV1 VideoCodec = "av1_vaapi"
 )
 
+// hwAccel is the hardware acceleration API used by a hardware codec.
+type hwAccel string
+
+const (
+	hwAccelNone  hwAccel = ""
+	hwAccelCUDA  hwAccel = "cuda"
+	hwAccelQSV   hwAccel = "qsv"
+	hwAccelVAAPI hwAccel = "vaapi"
+)
+
+// hwDecoders maps each hardware acceleration API to the input codecs it can decode.
+// The value is the decoder to use for the input codec. If empty, the native
+// decoder is used with -hwaccel.
+var hwDecoders = map[hwAccel]map[string]string{
+	hwAccelCUDA: {
+		H264:       "",
+		Hevc:       "",
+		Vp8:        "",
+		Vp9:        "",
+		Av1:        "",
+		Mpeg2Video: "",
+		Vc1:        "",
+	},
+	hwAccelQSV: {
+		H264:       "h264_qsv",
+		Hevc:       "hevc_qsv",
+		Vp9:        "vp9_qsv",
+		Av1:        "av1_qsv",
+		Mpeg2Video: "mpeg2_qsv",
+		Vc1:        "vc1_qsv",
+	},
+	hwAccelVAAPI: {
+		H264:       "",
+		Hevc:       "",
+		Vp8:        "",
+		Vp9:        "",
+		Av1:        "",
+		Mpeg2Video: "",
+		Vc1:        "",
+	},
+}
+
+// hwCodecAccel returns the hardware acceleration API used by codec.
+// Returns hwAccelNone for software codecs.
+func hwCodecAccel(codec VideoCodec) hwAccel {
+	switch codec {
+	case VideoCodecN264,
+		VideoCodecN265,
+		VideoCodecNAV1:
+		return hwAccelCUDA
+	case VideoCodecI264,
+		VideoCodecIVP9,
+		VideoCodecI265,
+		VideoCodecIAV1:
+		return hwAccelQSV
+	case VideoCodecV264,
+		VideoCodecVVP9,
+		VideoCodecVVPX,
+		VideoCodecV265,
+		VideoCodecVAV1:
+		return hwAccelVAAPI
+	}
+
+	return hwAccelNone
+}
+
+// IsHWCodec returns true if codec encodes on a hardware device.
+func IsHWCodec(codec VideoCodec) bool {
+	switch codec {
+	case VideoCodecA264,
+		VideoCodecM264,
+		VideoCodecR264,
+		VideoCodecO264:
+		return true
+	}
+
+	return hwCodecAccel(codec) != hwAccelNone
+}
+
+// HWPipeline describes which stages of a transcode to Codec run on the
+// hardware device. Each stage falls back to software independently when
+// the hardware stage is not supported. Frames only stay on the device
+// between stages when both Decode and Scale are true.
+type HWPipeline struct {
+	Codec VideoCodec
+	// Decode is true if the input is decoded on the hardware device.
+	Decode bool
+	// Scale is true if frames are scaled on the hardware device.
+	Scale bool
+
+	inputCodec string
+}
+
 // Tests all (given) hardware codec's
 func (f *FFMpeg) InitHWSupport(ctx context.Context) {
 	var hwCodecSupport []VideoCodec
+	var hwSoftwareScale []VideoCodec
 
 	for _, codec := range []VideoCodec{
 		VideoCodecN264,
@@ -35,45 +136,20 @@ func (f *FFMpeg) InitHWSupport(ctx context.Context) {
 		VideoCodecR264,
 		VideoCodecIVP9,
 		VideoCodecVVP9,
+		VideoCodecN265,
+		VideoCodecI265,
+		VideoCodecV265,
+		VideoCodecNAV1,
+		VideoCodecIAV1,
+		VideoCodecVAV1,
 	} {
-		var args Args
-		args = append(args, "-hide_banner")
-		args = args.LogLevel(LogLevelWarning)
-		args = f.hwDeviceInit(args, codec)
-		args = args.Format("lavfi")
-		args = args.Input("color=c=red")
-		args = args.Duration(0.1)
-
-		videoFilter := f.hwFilterInit(codec)
-		// Test scaling
-		videoFilter = videoFilter.ScaleDimensions(-2, 160)
-		videoFilter = f.hwCodecFilter(videoFilter, codec)
-		args = append(args, CodecInit(codec)...)
-		args = args.VideoFilter(videoFilter)
-
-		args = args.Format("null")
-		args = args.Output("-")
-
-		cmd := f.Command(ctx, args)
-
-		var stderr bytes.Buffer
-		cmd.Stderr = &stderr
-
-		if err := cmd.Start(); err != nil {
-			logger.Debugf("[InitHWSupport] error starting command: %w", err)
-			continue
-		}
-
-		if err := cmd.Wait(); err != nil {
-			errOutput := stderr.String()
-
-			if len(errOutput) == 0 {
-				errOutput = err.Error()
-			}
-
-			logger.Debugf("[InitHWSupport] Codec %s not supported. Error output:\n%s", codec, errOutput)
-		} else {
+		if f.hwProbeEncode(ctx, HWPipeline{Codec: codec, Scale: true}) {
+			hwCodecSupport = append(hwCodecSupport, codec)
+		} else if hwCodecAccel(codec) != hwAccelNone && f.hwProbeEncode(ctx, HWPipeline{Codec: codec}) {
+			// the encoder works, but the hardware scaler does not
+			logger.Debugf("[InitHWSupport] Codec %s supported with software scaling", codec)
 			hwCodecSupport = append(hwCodecSupport, codec)
+			hwSoftwareScale = append(hwSoftwareScale, codec)
 		}
 	}
 
@@ -84,42 +160,262 @@ func (f *FFMpeg) InitHWSupport(ctx context.Context) {
 	logger.Info(outstr)
 
 	f.hwCodecSupport = hwCodecSupport
+	f.hwSoftwareScale = hwSoftwareScale
 }
 
-// Prepend input for hardware encoding only
-func (f *FFMpeg) hwDeviceInit(args Args, codec VideoCodec) Args {
-	switch codec {
-	case VideoCodecN264:
+// hwProbeEncode tests encoding a generated input with the pipeline.
+func (f *FFMpeg) hwProbeEncode(ctx context.Context, p HWPipeline) bool {
+	var args Args
+	args = append(args, "-hide_banner")
+	args = args.LogLevel(LogLevelWarning)
+	args = p.InputArgs(args)
+	args = args.Format("lavfi")
+	args = args.Input("color=c=red")
+	args = args.Duration(0.1)
+
+	// Test scaling
+	var videoFilter VideoFilter
+	videoFilter = videoFilter.ScaleDimensions(-2, 160)
+	videoFilter = p.VideoFilter(videoFilter)
+	args = append(args, CodecInit(p.Codec)...)
+	args = args.VideoFilter(videoFilter)
+
+	args = args.Format("null")
+	args = args.Output("-")
+
+	if err := f.hwProbe(ctx, args); err != nil {
+		logger.Debugf("[InitHWSupport] Codec %s not supported. Error output:\n%s", p.Codec, err)
+		return false
+	}
+
+	return true
+}
+
+// hwDecodeKey identifies a hardware decoding probe. Support depends only on
+// the encoder and the input codec, so the number of probes is bounded.
+type hwDecodeKey struct {
+	codec      VideoCodec
+	inputCodec string
+}
+
+// hwDecodeResult returns the cached result of the hardware decoding probe
+// for the pipeline, and whether it has been probed.
+func (f *FFMpeg) hwDecodeResult(p HWPipeline) (supported bool, probed bool) {
+	f.hwDecodeMutex.Lock()
+	defer f.hwDecodeMutex.Unlock()
+
+	supported, probed = f.hwDecodeSupport[hwDecodeKey{p.Codec, p.inputCodec}]
+	return
+}
+
+// hwProbeDecode tests transcoding the start of the video file at path with the pipeline.
+// The result is cached per codec and input codec. Concurrent calls for the same
+// codec and input codec wait for a single probe.
+func (f *FFMpeg) hwProbeDecode(ctx context.Context, p HWPipeline, path string) bool {
+	key := hwDecodeKey{p.Codec, p.inputCodec}
+
+	f.hwDecodeMutex.Lock()
+	if supported, probed := f.hwDecodeSupport[key]; probed {
+		f.hwDecodeMutex.Unlock()
+		return supported
+	}
+
+	if running, found := f.hwDecodeProbes[key]; found {
+		f.hwDecodeMutex.Unlock()
+
+		select {
+		case <-running:
+		case <-ctx.Done():
+			return false
+		}
+
+		// probe again if the running probe was cancelled
+		return f.hwProbeDecode(ctx, p, path)
+	}
+
+	if f.hwDecodeProbes == nil {
+		f.hwDecodeProbes = make(map[hwDecodeKey]chan struct{})
+	}
+	done := make(chan struct{})
+	f.hwDecodeProbes[key] = done
+	f.hwDecodeMutex.Unlock()
+
+	defer func() {
+		f.hwDecodeMutex.Lock()
+		delete(f.hwDecodeProbes, key)
+		f.hwDecodeMutex.Unlock()
+		close(done)
+	}()
+
+	var args Args
+	args = append(args, "-hide_banner")
+	args = args.LogLevel(LogLevelWarning)
+	args = args.XError()
+	args = p.InputArgs(args)
+	args = args.Input(path)
+	args = args.Duration(0.1)
+
+	var videoFilter VideoFilter
+	videoFilter = videoFilter.ScaleDimensions(-2, 160)
+	videoFilter = p.VideoFilter(videoFilter)
+	args = append(args, CodecInit(p.Codec)...)
+	args = args.VideoFilter(videoFilter)
+	args = args.SkipAudio()
+
+	args = args.Format("null")
+	args = args.Output("-")
+
+	err := f.hwProbe(ctx, args)
+	if ctx.Err() != nil {
+		// don't cache the result of a cancelled probe
+		return false
+	}
+
+	supported := err == nil
+	if !supported {
+		logger.Debugf("[transcode] hardware decoding of %s with %s not supported, falling back to software decoding. Error output:\n%s", p.inputCodec, p.Codec, err)
+	}
+
+	f.hwDecodeMutex.Lock()
+	if f.hwDecodeSupport == nil {
+		f.hwDecodeSupport = make(map[hwDecodeKey]bool)
+	}
+	f.hwDecodeSupport[key] = supported
+	f.hwDecodeMutex.Unlock()
+
+	return supported
+}
+
+// hwProbe runs ffmpeg with args, returning an error containing the error output if it fails.
+func (f *FFMpeg) hwProbe(ctx context.Context, args Args) error {
+	cmd := f.Command(ctx, args)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting command: %w", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		errOutput := stderr.String()
+
+		if len(errOutput) == 0 {
+			errOutput = err.Error()
+		}
+
+		return errors.New(errOutput)
+	}
+
+	return nil
+}
+
+// NewHWPipeline returns the transcode pipeline to codec for the video file at path,
+// where inputCodec is the codec of its video stream. Hardware decoding is probed
+// using the file, once per codec and input codec, and disabled if it fails.
+// The probe runs ffmpeg, so NewHWPipeline must not be called while holding a lock.
+func (f *FFMpeg) NewHWPipeline(ctx context.Context, codec VideoCodec, path string, inputCodec string) HWPipeline {
+	p := f.hwPipeline(codec, inputCodec)
+	if p.Decode {
+		p.Decode = f.hwProbeDecode(ctx, p, path)
+	}
+
+	return p
+}
+
+// ProbeHWDecode probes hardware decoding as NewHWPipeline does, so that
+// ProbedHWPipeline can be called later without blocking.
+func (f *FFMpeg) ProbeHWDecode(ctx context.Context, codec VideoCodec, path string, inputCodec string) {
+	f.NewHWPipeline(ctx, codec, path, inputCodec)
+}
+
+// ProbedHWPipeline returns the transcode pipeline to codec as NewHWPipeline does,
+// without probing. Hardware decoding is only enabled if a previous probe for
+// codec and inputCodec succeeded.
+func (f *FFMpeg) ProbedHWPipeline(codec VideoCodec, inputCodec string) HWPipeline {
+	p := f.hwPipeline(codec, inputCodec)
+	if p.Decode {
+		supported, probed := f.hwDecodeResult(p)
+		p.Decode = probed && supported
+	}
+
+	return p
+}
+
+// hwPipeline returns the pipeline to codec for inputCodec, with Decode set
+// if hardware decoding of inputCodec should be attempted.
+func (f *FFMpeg) hwPipeline(codec VideoCodec, inputCodec string) HWPipeline {
+	p := HWPipeline{
+		Codec:      codec,
+		inputCodec: inputCodec,
+	}
+
+	accel := hwCodecAccel(codec)
+	if accel == hwAccelNone {
+		return p
+	}
+
+	p.Scale = !f.hwCodecSoftwareScaled(codec)
+
+	// frames can only be kept on the device if they are also scaled there
+	if _, found := hwDecoders[accel][inputCodec]; found && p.Scale {
+		p.Decode = true
+	}
+
+	return p
+}
+
+// InputArgs appends the hardware device initialisation and decoder
+// arguments for the pipeline to args. These must precede the input.
+func (p HWPipeline) InputArgs(args Args) Args {
+	accel := hwCodecAccel(p.Codec)
+
+	switch accel {
+	case hwAccelCUDA:
 		args = append(args, "-hwaccel_device")
 		args = append(args, "0")
-	case VideoCodecV264,
-		VideoCodecVVP9:
+		if p.Decode {
+			args = append(args, "-hwaccel", "cuda")
+			args = append(args, "-hwaccel_output_format", "cuda")
+		}
+	case hwAccelVAAPI:
 		args = append(args, "-vaapi_device")
 		args = append(args, "/dev/dri/renderD128")
-	case VideoCodecI264,
-		VideoCodecIVP9:
+		if p.Decode {
+			args = append(args, "-hwaccel", "vaapi")
+			args = append(args, "-hwaccel_output_format", "vaapi")
+		}
+	case hwAccelQSV:
 		args = append(args, "-init_hw_device")
 		args = append(args, "qsv=hw")
 		args = append(args, "-filter_hw_device")
 		args = append(args, "hw")
+		if p.Decode {
+			args = append(args, "-hwaccel", "qsv")
+			args = append(args, "-hwaccel_output_format", "qsv")
+		}
+	}
+
+	if p.Decode {
+		if decoder := hwDecoders[accel][p.inputCodec]; decoder != "" {
+			args = append(args, "-c:v", decoder)
+		}
 	}
 
 	return args
 }
 
-// Initialise a video filter for HW encoding
-func (f *FFMpeg) hwFilterInit(codec VideoCodec) VideoFilter {
+// hwUploadFilter returns a filter uploading software frames to the device used by accel.
+func hwUploadFilter(accel hwAccel) VideoFilter {
 	var videoFilter VideoFilter
-	switch codec {
-	case VideoCodecV264,
-		VideoCodecVVP9:
+	switch accel {
+	case hwAccelVAAPI:
 		videoFilter = videoFilter.Append("format=nv12")
 		videoFilter = videoFilter.Append("hwupload")
-	case VideoCodecN264:
+	case hwAccelCUDA:
 		videoFilter = videoFilter.Append("format=nv12")
 		videoFilter = videoFilter.Append("hwupload_cuda")
-	case VideoCodecI264,
-		VideoCodecIVP9:
+	case hwAccelQSV:
 		videoFilter = videoFilter.Append("hwupload=extra_hw_frames=64")
 		videoFilter = videoFilter.Append("format=qsv")
 	}
@@ -127,45 +423,90 @@ func (f *FFMpeg) hwFilterInit(codec VideoCodec) VideoFilter {
 	return videoFilter
 }
 
-// Replace video filter scaling with hardware scaling for full hardware transcoding
-func (f *FFMpeg) hwCodecFilter(args VideoFilter, codec VideoCodec) VideoFilter {
-	sargs := string(args)
+// hwScaleFilters are the hardware scaling filters for each hardware acceleration API.
+var hwScaleFilters = map[hwAccel]string{
+	hwAccelCUDA:  "scale_cuda",
+	hwAccelQSV:   "scale_qsv",
+	hwAccelVAAPI: "scale_vaapi",
+}
 
-	if strings.Contains(sargs, "scale=") {
-		switch codec {
-		case VideoCodecN264:
-			args = VideoFilter(strings.Replace(sargs, "scale=", "scale_cuda=", 1))
-		case VideoCodecV264,
-			VideoCodecVVP9:
-			args = VideoFilter(strings.Replace(sargs, "scale=", "scale_vaapi=", 1))
-		case VideoCodecI264,
-			VideoCodecIVP9:
-			// BUG: [scale_qsv]: Size values less than -1 are not acceptable.
-			// Fix: Replace all instances of -2 with -1 in a scale operation
-			re := regexp.MustCompile(`(scale=)([\d:]*)(-2)(.*)`)
-			sargs = re.ReplaceAllString(sargs, "scale=$2-1$4")
-			args = VideoFilter(strings.Replace(sargs, "scale=", "scale_qsv=", 1))
+var scaleFilterRE = regexp.MustCompile(`scale=([^,]*)`)
+
+// VideoFilter converts the software video filter videoFilter into the filter chain for
+// the pipeline. Frames are uploaded to the device if they were decoded in software, and
+// scaling is replaced with hardware scaling where supported.
+func (p HWPipeline) VideoFilter(videoFilter VideoFilter) VideoFilter {
+	accel := hwCodecAccel(p.Codec)
+	if accel == hwAccelNone {
+		return videoFilter
+	}
+
+	if !p.Scale {
+		// scale in software, then upload for encoding
+		return videoFilter.Append(string(hwUploadFilter(accel)))
+	}
+
+	sargs := string(videoFilter)
+	scaler := hwScaleFilters[accel]
+
+	if accel == hwAccelQSV {
+		// BUG: [scale_qsv]: Size values less than -1 are not acceptable.
+		// Fix: Replace all instances of -2 with -1 in a scale operation
+		re := regexp.MustCompile(`(scale=)([\d:]*)(-2)(.*)`)
+		sargs = re.ReplaceAllString(sargs, "scale=$2-1$4")
+	}
+
+	if p.Decode {
+		// decoded frames may be 10-bit, which the encoders don't support.
+		// Convert on the device while scaling.
+		if scaleFilterRE.MatchString(sargs) {
+			sargs = scaleFilterRE.ReplaceAllString(sargs, scaler+"=$1:format=nv12")
+		} else {
+			sargs = string(VideoFilter(sargs).Append(scaler + "=format=nv12"))
 		}
+
+		return VideoFilter(sargs)
 	}
 
-	return args
+	sargs = strings.Replace(sargs, "scale=", scaler+"=", 1)
+
+	ret := hwUploadFilter(accel)
+	if sargs != "" {
+		ret = ret.Append(sargs)
+	}
+
+	return ret
+}
+
+// hwCodecSoftwareScaled returns true if codec must be scaled in software.
+func (f *FFMpeg) hwCodecSoftwareScaled(codec VideoCodec) bool {
+	for _, c := range f.hwSoftwareScale {
+		if c == codec {
+			return true
+		}
+	}
+	return false
 }
 
 // Returns the max resolution for a given codec, or a default
 func (f *FFMpeg) hwCodecMaxRes(codec VideoCodec, dW int, dH int) (int, int) {
-	if codec == VideoCodecN264 {
+	switch codec {
+	case VideoCodecN264:
 		return 4096, 4096
+	case VideoCodecN265,
+		VideoCodecNAV1:
+		return 8192, 8192
 	}
 
 	return dW, dH
 }
 
 // Return a maxres filter
-func (f *FFMpeg) hwMaxResFilter(codec VideoCodec, width int, height int, max int) VideoFilter {
-	videoFilter := f.hwFilterInit(codec)
-	maxWidth, maxHeight := f.hwCodecMaxRes(codec, width, height)
+func (f *FFMpeg) hwMaxResFilter(p HWPipeline, width int, height int, max int) VideoFilter {
+	var videoFilter VideoFilter
+	maxWidth, maxHeight := f.hwCodecMaxRes(p.Codec, width, height)
 	videoFilter = videoFilter.ScaleMaxLM(width, height, max, maxWidth, maxHeight)
-	return f.hwCodecFilter(videoFilter, codec)
+	return p.VideoFilter(videoFilter)
 }
 
 // Return if a hardware accelerated for HLS is available
@@ -182,8 +523,8 @@ func (f *FFMpeg) hwCodecHLSCompatible() *VideoCodec {
 	return nil
 }
 
-// Return if a hardware accelerated codec for MP4 is available
-func (f *FFMpeg) hwCodecMP4Compatible() *VideoCodec {
+// HWCodecMP4Compatible returns a hardware accelerated codec for MP4 if available.
+func (f *FFMpeg) HWCodecMP4Compatible() *VideoCodec {
 	for _, element := range f.hwCodecSupport {
 		switch element {
 		case VideoCodecN264,
@@ -205,3 +546,29 @@ func (f *FFMpeg) hwCodecWEBMCompatible() *VideoCodec {
 	}
 	return nil
 }
+
+// HWCodecHEVCCompatible returns a hardware accelerated codec for HEVC if available.
+func (f *FFMpeg) HWCodecHEVCCompatible() *VideoCodec {
+	for _, element := range f.hwCodecSupport {
+		switch element {
+		case VideoCodecN265,
+			VideoCodecI265,
+			VideoCodecV265:
+			return &element
+		}
+	}
+	return nil
+}
+
+// HWCodecAV1Compatible returns a hardware accelerated codec for AV1 if available.
+func (f *FFMpeg) HWCodecAV1Compatible() *VideoCodec {
+	for _, element := range f.hwCodecSupport {
+		switch element {
+		case VideoCodecNAV1,
+			VideoCodecIAV1,
+			VideoCodecVAV1:
+			return &element
+		}
+	}
+	return nil
+}
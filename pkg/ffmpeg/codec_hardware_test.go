@@ -0,0 +1,211 @@
+package ffmpeg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeFakeFFMpeg writes a fake ffmpeg script which exits successfully only
+// when its arguments contain one of the given patterns.
+func writeFakeFFMpeg(t *testing.T, patterns ...string) string {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg requires a posix shell")
+	}
+
+	var script strings.Builder
+	script.WriteString("#!/bin/sh\ncase \"$*\" in\n")
+	for _, p := range patterns {
+		script.WriteString("\t*\"" + p + "\"*) exit 0 ;;\n")
+	}
+	script.WriteString("esac\necho \"Unknown encoder\" >&2\nexit 1\n")
+
+	path := filepath.Join(t.TempDir(), "ffmpeg")
+	if err := os.WriteFile(path, []byte(script.String()), 0755); err != nil {
+		t.Fatalf("error writing fake ffmpeg: %v", err)
+	}
+
+	return path
+}
+
+func TestFFMpeg_InitHWSupport(t *testing.T) {
+	tests := []struct {
+		name              string
+		patterns          []string
+		wantSupport       []VideoCodec
+		wantSoftwareScale []VideoCodec
+		wantHEVC          *VideoCodec
+		wantAV1           *VideoCodec
+	}{
+		{
+			"none",
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"nvenc",
+			[]string{"-c:v h264_nvenc", "-c:v hevc_nvenc", "-c:v av1_nvenc"},
+			[]VideoCodec{VideoCodecN264, VideoCodecN265, VideoCodecNAV1},
+			nil,
+			&VideoCodecN265,
+			&VideoCodecNAV1,
+		},
+		{
+			"qsv hevc and av1",
+			[]string{"-c:v hevc_qsv", "-c:v av1_qsv"},
+			[]VideoCodec{VideoCodecI265, VideoCodecIAV1},
+			nil,
+			&VideoCodecI265,
+			&VideoCodecIAV1,
+		},
+		{
+			"vaapi without hardware scaling",
+			[]string{"-c:v av1_vaapi -rc_mode CQP -global_quality 120 -vf scale=-2:160,format=nv12,hwupload"},
+			[]VideoCodec{VideoCodecVAV1},
+			[]VideoCodec{VideoCodecVAV1},
+			nil,
+			&VideoCodecVAV1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewEncoder(writeFakeFFMpeg(t, tt.patterns...))
+			f.InitHWSupport(context.Background())
+
+			assert.Equal(t, tt.wantSupport, f.hwCodecSupport)
+			assert.Equal(t, tt.wantSoftwareScale, f.hwSoftwareScale)
+			assert.Equal(t, tt.wantHEVC, f.HWCodecHEVCCompatible())
+			assert.Equal(t, tt.wantAV1, f.HWCodecAV1Compatible())
+		})
+	}
+}
+
+func TestFFMpeg_NewHWPipeline(t *testing.T) {
+	const path = "video.mp4"
+
+	tests := []struct {
+		name       string
+		patterns   []string
+		codec      VideoCodec
+		inputCodec string
+		want       HWPipeline
+	}{
+		{
+			"software codec",
+			nil,
+			VideoCodecLibX265,
+			Hevc,
+			HWPipeline{Codec: VideoCodecLibX265, inputCodec: Hevc},
+		},
+		{
+			"hardware decode",
+			[]string{"-hwaccel cuda"},
+			VideoCodecN265,
+			Hevc,
+			HWPipeline{Codec: VideoCodecN265, Decode: true, Scale: true, inputCodec: Hevc},
+		},
+		{
+			"hardware decode fails",
+			nil,
+			VideoCodecN265,
+			Hevc,
+			HWPipeline{Codec: VideoCodecN265, Scale: true, inputCodec: Hevc},
+		},
+		{
+			"no hardware decoder for input",
+			[]string{"-hwaccel qsv"},
+			VideoCodecIAV1,
+			Vp8,
+			HWPipeline{Codec: VideoCodecIAV1, Scale: true, inputCodec: Vp8},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewEncoder(writeFakeFFMpeg(t, tt.patterns...))
+
+			got := f.NewHWPipeline(context.Background(), tt.codec, path, tt.inputCodec)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFFMpeg_NewHWPipeline_Cached(t *testing.T) {
+	// only decoding a.mp4 succeeds
+	f := NewEncoder(writeFakeFFMpeg(t, "-i a.mp4"))
+	want := HWPipeline{Codec: VideoCodecN265, Decode: true, Scale: true, inputCodec: Hevc}
+
+	assert.Equal(t, want, f.NewHWPipeline(context.Background(), VideoCodecN265, "a.mp4", Hevc))
+
+	// the result is cached per codec and input codec, not per file
+	assert.Equal(t, want, f.NewHWPipeline(context.Background(), VideoCodecN265, "b.mp4", Hevc))
+	assert.Len(t, f.hwDecodeSupport, 1)
+}
+
+func TestFFMpeg_NewHWPipeline_Concurrent(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg requires a posix shell")
+	}
+
+	// fake ffmpeg which records each run and is slow enough for the
+	// concurrent calls to overlap
+	dir := t.TempDir()
+	runs := filepath.Join(dir, "runs")
+	script := "#!/bin/sh\necho run >> \"" + runs + "\"\nsleep 0.2\nexit 0\n"
+	path := filepath.Join(dir, "ffmpeg")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatalf("error writing fake ffmpeg: %v", err)
+	}
+
+	f := NewEncoder(path)
+	want := HWPipeline{Codec: VideoCodecN265, Decode: true, Scale: true, inputCodec: Hevc}
+
+	const calls = 10
+	got := make([]HWPipeline, calls)
+
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i] = f.NewHWPipeline(context.Background(), VideoCodecN265, "video.mp4", Hevc)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, p := range got {
+		assert.Equal(t, want, p)
+	}
+
+	// concurrent calls wait for a single probe
+	output, err := os.ReadFile(runs)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, strings.Count(string(output), "run"))
+	}
+	assert.Empty(t, f.hwDecodeProbes)
+}
+
+func TestFFMpeg_ProbedHWPipeline(t *testing.T) {
+	f := NewEncoder(writeFakeFFMpeg(t, "-hwaccel cuda"))
+
+	// hardware decoding is disabled until probed
+	got := f.ProbedHWPipeline(VideoCodecN264, H264)
+	assert.Equal(t, HWPipeline{Codec: VideoCodecN264, Scale: true, inputCodec: H264}, got)
+
+	f.ProbeHWDecode(context.Background(), VideoCodecN264, "video.mp4", H264)
+
+	got = f.ProbedHWPipeline(VideoCodecN264, H264)
+	assert.Equal(t, HWPipeline{Codec: VideoCodecN264, Decode: true, Scale: true, inputCodec: H264}, got)
+}
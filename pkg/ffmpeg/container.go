@@ -34,6 +34,9 @@ const (
 	Hevc           string = "hevc"
 	Vp8            string = "vp8"
 	Vp9            string = "vp9"
+	Av1            string = "av1"
+	Mpeg2Video     string = "mpeg2video"
+	Vc1            string = "vc1"
 	Mkv            string = "mkv" // only used from the browser to indicate mkv support
 	Hls            string = "hls" // only used from the browser to indicate hls support
 )
@@ -57,3 +60,25 @@ func MatchContainer(format string, filePath string) (Container, error) { // matc
 	}
 	return container, nil
 }
+
+// textSubtitleCodecs are the subtitle codecs that can be converted to WebVTT.
+// Bitmap subtitles such as dvd_subtitle and hdmv_pgs_subtitle cannot.
+var textSubtitleCodecs = []string{
+	"subrip",
+	"ass",
+	"ssa",
+	"webvtt",
+	"mov_text",
+	"text",
+}
+
+// IsTextSubtitle returns true if the subtitle codec is text based,
+// and can therefore be converted to WebVTT.
+func IsTextSubtitle(codec string) bool {
+	for _, c := range textSubtitleCodecs {
+		if c == codec {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+const (
+	// cropDetectSamples is the number of positions in the video sampled for
+	// black bars. The picture may be dark at any one position, so the crop
+	// is the union of the picture detected at each position.
+	cropDetectSamples = 6
+
+	// cropDetectSampleDuration is the number of seconds analysed at each
+	// sample position.
+	cropDetectSampleDuration = 2.0
+
+	// cropDetectLimit is the luma value at or below which pixels are
+	// considered black.
+	cropDetectLimit = 24
+
+	// minCropProportion is the minimum proportion of a dimension which must be
+	// black for it to be cropped. This ignores thin borders left by encoders.
+	minCropProportion = 0.02
+)
+
+// DetectCrop samples the default video stream of the file for black bars
+// and returns the rectangle containing the picture. The full frame is
+// returned if no black bars are detected.
+func (f *FFMpeg) DetectCrop(ctx context.Context, path string, duration float64, width int, height int) (*models.VideoCrop, error) {
+	full := models.VideoCrop{Width: width, Height: height}
+	var union *models.VideoCrop
+
+	for i := 0; i < cropDetectSamples; i++ {
+		// sample evenly between the start and end, excluding both
+		at := duration * float64(i+1) / float64(cropDetectSamples+1)
+
+		c, err := f.cropDetectSample(ctx, path, at)
+		if err != nil {
+			return nil, err
+		}
+
+		// no picture was found in the sample
+		if c == nil {
+			continue
+		}
+
+		union = unionCrop(union, c)
+	}
+
+	if union == nil {
+		return &full, nil
+	}
+
+	return normaliseCrop(*union, width, height), nil
+}
+
+func (f *FFMpeg) cropDetectSample(ctx context.Context, path string, at float64) (*models.VideoCrop, error) {
+	var videoFilter VideoFilter
+	videoFilter = videoFilter.Append(fmt.Sprintf("cropdetect=limit=%d:round=2:reset=0", cropDetectLimit))
+
+	args := Args{"-hide_banner", "-nostats"}
+	args = args.Seek(at)
+	args = args.Input(path)
+	args = append(args, "-map", "0:v:0")
+	args = args.Duration(cropDetectSampleDuration)
+	args = args.VideoFilter(videoFilter)
+	args = args.Format(FormatNull).NullOutput()
+
+	command := f.Command(ctx, args)
+	var stdErrBuffer bytes.Buffer
+	command.Stderr = &stdErrBuffer // cropdetect logs to stderr
+	if err := command.Run(); err != nil {
+		return nil, fmt.Errorf("error running ffmpeg command <%s>: %w", strings.Join(args, " "), err)
+	}
+
+	return parseCropDetect(stdErrBuffer.String()), nil
+}
+
+var cropDetectRegex = regexp.MustCompile(`crop=(-?\d+):(-?\d+):(-?\d+):(-?\d+)`)
+
+// parseCropDetect returns the last crop reported by the cropdetect filter.
+// Since the filter is not reset, the last crop covers all analysed frames.
+// Returns nil if no picture was detected.
+func parseCropDetect(output string) *models.VideoCrop {
+	matches := cropDetectRegex.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	m := matches[len(matches)-1]
+	w, _ := strconv.Atoi(m[1])
+	h, _ := strconv.Atoi(m[2])
+	x, _ := strconv.Atoi(m[3])
+	y, _ := strconv.Atoi(m[4])
+
+	// entirely black frames report negative dimensions
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+
+	return &models.VideoCrop{X: x, Y: y, Width: w, Height: h}
+}
+
+// unionCrop returns the smallest rectangle containing both a and b.
+func unionCrop(a *models.VideoCrop, b *models.VideoCrop) *models.VideoCrop {
+	if a == nil {
+		ret := *b
+		return &ret
+	}
+
+	x1 := intMin(a.X, b.X)
+	y1 := intMin(a.Y, b.Y)
+	x2 := intMax(a.X+a.Width, b.X+b.Width)
+	y2 := intMax(a.Y+a.Height, b.Y+b.Height)
+
+	return &models.VideoCrop{X: x1, Y: y1, Width: x2 - x1, Height: y2 - y1}
+}
+
+// normaliseCrop clamps c to the frame, and restores the full width or
+// height if the bars in that dimension are too small to be worth cropping.
+func normaliseCrop(c models.VideoCrop, width int, height int) *models.VideoCrop {
+	c.X = intMax(c.X, 0)
+	c.Y = intMax(c.Y, 0)
+	c.Width = intMin(c.Width, width-c.X)
+	c.Height = intMin(c.Height, height-c.Y)
+
+	if float64(width-c.Width) < float64(width)*minCropProportion {
+		c.X = 0
+		c.Width = width
+	}
+	if float64(height-c.Height) < float64(height)*minCropProportion {
+		c.Y = 0
+		c.Height = height
+	}
+
+	return &c
+}
+
+func intMin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func intMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const cropDetectOutput = `[Parsed_cropdetect_0 @ 0x5581e0] x1:0 x2:1919 y1:142 y2:939 w:1920 h:784 x:0 y:150 pts:1001 t:0.041708 limit:0.094118 crop=1920:784:0:150
+[Parsed_cropdetect_0 @ 0x5581e0] x1:0 x2:1919 y1:138 y2:941 w:1920 h:800 x:0 y:140 pts:2002 t:0.083417 limit:0.094118 crop=1920:800:0:140
+`
+
+const cropDetectBlackOutput = `[Parsed_cropdetect_0 @ 0x5581e0] x1:1919 x2:0 y1:1079 y2:0 w:-1904 h:-1072 x:1912 y:1076 pts:1001 t:0.041708 limit:0.094118 crop=-1904:-1072:1912:1076
+`
+
+func TestParseCropDetect(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   *models.VideoCrop
+	}{
+		{"letterbox", cropDetectOutput, &models.VideoCrop{X: 0, Y: 140, Width: 1920, Height: 800}},
+		{"black", cropDetectBlackOutput, nil},
+		{"no output", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseCropDetect(tt.output))
+		})
+	}
+}
+
+func TestUnionCrop(t *testing.T) {
+	a := &models.VideoCrop{X: 0, Y: 140, Width: 1920, Height: 800}
+	b := &models.VideoCrop{X: 10, Y: 120, Width: 1900, Height: 800}
+
+	assert.Equal(t, a, unionCrop(nil, a))
+	assert.Equal(t, &models.VideoCrop{X: 0, Y: 120, Width: 1920, Height: 820}, unionCrop(a, b))
+}
+
+func TestNormaliseCrop(t *testing.T) {
+	tests := []struct {
+		name string
+		crop models.VideoCrop
+		want *models.VideoCrop
+	}{
+		{"letterbox", models.VideoCrop{X: 0, Y: 140, Width: 1920, Height: 800}, &models.VideoCrop{X: 0, Y: 140, Width: 1920, Height: 800}},
+		{"pillarbox", models.VideoCrop{X: 240, Y: 0, Width: 1440, Height: 1080}, &models.VideoCrop{X: 240, Y: 0, Width: 1440, Height: 1080}},
+		{"thin border", models.VideoCrop{X: 8, Y: 4, Width: 1904, Height: 1072}, &models.VideoCrop{X: 0, Y: 0, Width: 1920, Height: 1080}},
+		{"out of frame", models.VideoCrop{X: -2, Y: 140, Width: 1924, Height: 800}, &models.VideoCrop{X: 0, Y: 140, Width: 1920, Height: 800}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normaliseCrop(tt.crop, 1920, 1080))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package ffmpeg
+
+import "github.com/stashapp/stash/pkg/models"
+
+// PlaybackMethod is how a file is served to a client.
+type PlaybackMethod string
+
+const (
+	// PlaybackDirect serves the file as is.
+	PlaybackDirect PlaybackMethod = "direct"
+	// PlaybackRemux copies the video stream into a fragmented mp4,
+	// transcoding the audio to AAC if necessary.
+	PlaybackRemux PlaybackMethod = "remux"
+	// PlaybackTranscode transcodes the file.
+	PlaybackTranscode PlaybackMethod = "transcode"
+)
+
+// ProfilePlaybackMethod returns how a file should be served to a client with
+// the given device profile. resolution is the smaller of the width and height
+// of the file, and bitrate is in bits per second.
+func ProfilePlaybackMethod(p *models.DeviceProfile, videoCodec string, audioCodec ProbeAudioCodec, container Container, resolution int, bitrate int64) PlaybackMethod {
+	if p.ExceedsLimits(resolution, bitrate) || !p.SupportsVideoCodec(videoCodec) {
+		return PlaybackTranscode
+	}
+
+	audioOK := audioCodec == MissingUnsupported || p.SupportsAudioCodec(string(audioCodec))
+	if audioOK && p.SupportsContainer(string(container)) {
+		return PlaybackDirect
+	}
+
+	// the remux copies the audio if it is valid for mp4, otherwise
+	// it is transcoded to AAC
+	remuxAudioOK := audioOK && isValidAudio(audioCodec, validAudioForMp4)
+	if !remuxAudioOK {
+		remuxAudioOK = p.SupportsAudioCodec(string(Aac))
+	}
+
+	if IsRemuxable(videoCodec) && p.SupportsContainer(string(Mp4)) && remuxAudioOK {
+		return PlaybackRemux
+	}
+
+	return PlaybackTranscode
+}
@@ -0,0 +1,44 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfilePlaybackMethod(t *testing.T) {
+	tv := &models.DeviceProfile{
+		Name:          "TV",
+		Containers:    []string{"mp4", "matroska"},
+		VideoCodecs:   []string{"h264", "hevc", "mpeg4"},
+		AudioCodecs:   []string{"aac", "ac3"},
+		MaxResolution: models.StreamingResolutionEnumFullHd,
+		MaxBitrate:    20000000,
+	}
+
+	tests := []struct {
+		name       string
+		videoCodec string
+		audioCodec ProbeAudioCodec
+		container  Container
+		resolution int
+		bitrate    int64
+		want       PlaybackMethod
+	}{
+		{"supported", H264, Aac, Mp4, 1080, 8000000, PlaybackDirect},
+		{"no audio", Hevc, MissingUnsupported, Matroska, 720, 4000000, PlaybackDirect},
+		{"unsupported container", H264, Aac, Avi, 1080, 8000000, PlaybackRemux},
+		{"unsupported audio", H264, Opus, Matroska, 1080, 8000000, PlaybackRemux},
+		{"unsupported video codec", Vp9, Opus, Webm, 1080, 8000000, PlaybackTranscode},
+		{"resolution too large", H264, Aac, Mp4, 2160, 8000000, PlaybackTranscode},
+		{"bitrate too high", H264, Aac, Mp4, 1080, 40000000, PlaybackTranscode},
+		{"not remuxable", "mpeg4", Aac, Avi, 480, 2000000, PlaybackTranscode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ProfilePlaybackMethod(tv, tt.videoCodec, tt.audioCodec, tt.container, tt.resolution, tt.bitrate))
+		})
+	}
+}
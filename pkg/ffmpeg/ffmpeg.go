@@ -4,6 +4,7 @@ package ffmpeg
 import (
 	"context"
 	"os/exec"
+	"sync"
 
 	stashExec "github.com/stashapp/stash/pkg/exec"
 )
@@ -12,6 +13,14 @@ import (
 type FFMpeg struct {
 	ffmpeg         string
 	hwCodecSupport []VideoCodec
+	// hardware codecs that are supported, but only with software scaling
+	hwSoftwareScale []VideoCodec
+
+	// cached results of hardware decoding probes
+	hwDecodeSupport map[hwDecodeKey]bool
+	// running hardware decoding probes, closed when the probe finishes
+	hwDecodeProbes map[hwDecodeKey]chan struct{}
+	hwDecodeMutex  sync.Mutex
 }
 
 // Creates a new FFMpeg encoder
@@ -19,6 +19,11 @@ type VideoFile struct {
 	AudioStream *FFProbeStream
 	VideoStream *FFProbeStream
 
+	// AudioStreams and SubtitleStreams contain all audio and subtitle
+	// streams, in the order they appear in the file.
+	AudioStreams    []*FFProbeStream
+	SubtitleStreams []*FFProbeStream
+
 	Path      string
 	Title     string
 	Comment   string
@@ -143,6 +148,9 @@ func parse(filePath string, probeJSON *FFProbeJSON) (*VideoFile, error) {
 	result.StartTime, _ = strconv.ParseFloat(probeJSON.Format.StartTime, 64)
 	result.CreationTime = probeJSON.Format.Tags.CreationTime.Time
 
+	result.AudioStreams = result.getStreams("audio")
+	result.SubtitleStreams = result.getStreams("subtitle")
+
 	audioStream := result.getAudioStream()
 	if audioStream != nil {
 		result.AudioCodec = audioStream.CodecName
@@ -209,6 +217,20 @@ func (v *VideoFile) getVideoStream() *FFProbeStream {
 	return nil
 }
 
+// getStreams returns all streams of the given type. The position of a
+// stream in the returned slice is its index for stream specifiers such
+// as 0:a:1.
+func (v *VideoFile) getStreams(fileType string) []*FFProbeStream {
+	var ret []*FFProbeStream
+	for i, stream := range v.JSON.Streams {
+		if stream.CodecType == fileType {
+			ret = append(ret, &v.JSON.Streams[i])
+		}
+	}
+
+	return ret
+}
+
 func (v *VideoFile) getStreamIndex(fileType string, probeJSON FFProbeJSON) int {
 	ret := -1
 	for i, stream := range probeJSON.Streams {
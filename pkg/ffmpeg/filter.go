@@ -2,6 +2,8 @@ package ffmpeg
 
 import (
 	"fmt"
+
+	"github.com/stashapp/stash/pkg/models"
 )
 
 // VideoFilter represents video filter parameters to be passed to ffmpeg.
@@ -88,6 +90,16 @@ func (f VideoFilter) ScaleMaxLM(width int, height int, reqHeight int, maxWidth i
 	return f.ScaleMax(width, height, reqHeight)
 }
 
+// Crop returns a VideoFilter cropping the frame to the given rectangle.
+func (f VideoFilter) Crop(w, h, x, y int) VideoFilter {
+	return f.Append(fmt.Sprintf("crop=%v:%v:%v:%v", w, h, x, y))
+}
+
+// CropRect returns a VideoFilter cropping the frame to c.
+func (f VideoFilter) CropRect(c models.VideoCrop) VideoFilter {
+	return f.Crop(c.Width, c.Height, c.X, c.Y)
+}
+
 // Fps returns a VideoFilter setting the frames per second.
 func (f VideoFilter) Fps(fps int) VideoFilter {
 	return f.Append(fmt.Sprintf("fps=%v", fps))
@@ -20,6 +20,8 @@ var (
 	FormatMP4      Format = "mp4"
 	FormatWebm     Format = "webm"
 	FormatMatroska Format = "matroska"
+	FormatWebVTT   Format = "webvtt"
+	FormatNull     Format = "null"
 )
 
 // ImageFormat represents the input format for an image for ffmpeg.
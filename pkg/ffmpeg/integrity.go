@@ -0,0 +1,169 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// minTruncatedSeconds is the minimum number of seconds missing from the
+	// end of a file for it to be considered truncated.
+	minTruncatedSeconds = 2.0
+	// minTruncatedKeyframeSeconds is used instead of minTruncatedSeconds when
+	// only keyframes are decoded, since the last keyframe may be some
+	// distance from the end.
+	minTruncatedKeyframeSeconds = 10.0
+	// minTruncatedProportion is the minimum proportion of the duration
+	// missing from the end of a file for it to be considered truncated.
+	minTruncatedProportion = 0.01
+)
+
+// IntegrityResult is the result of decoding a file to check for errors.
+type IntegrityResult struct {
+	// ErrorCount is the number of errors logged while decoding.
+	ErrorCount int
+	// FirstErrorTime is the approximate time in seconds of the first error.
+	// Nil if there were no errors.
+	FirstErrorTime *float64
+	// DecodedDuration is the time of the last decoded frame in seconds.
+	DecodedDuration float64
+	// Truncated is true if decoding ended before the declared duration.
+	Truncated bool
+}
+
+// CheckIntegrity decodes the file, discarding the output, and reports any
+// decoding errors. If keyframesOnly is true, only keyframes are decoded,
+// which is much faster but may miss errors in other frames. duration is the
+// declared duration of the file, used to detect truncation.
+func (f *FFMpeg) CheckIntegrity(ctx context.Context, path string, duration float64, keyframesOnly bool) (*IntegrityResult, error) {
+	args := Args{"-hide_banner", "-nostats"}
+	args = args.LogLevel(LogLevelError)
+	if keyframesOnly {
+		args = append(args, "-skip_frame", "nokey")
+	}
+	args = args.Input(path)
+	args = append(args, "-progress", "pipe:1")
+	args = args.Format(FormatNull).NullOutput()
+
+	// progress is written to stdout and errors to stderr. Both are parsed
+	// by the same writer so that errors can be timed using the progress.
+	parser := &integrityParser{}
+	command := f.Command(ctx, args)
+	command.Stdout = parser
+	command.Stderr = parser
+
+	err := command.Run()
+	parser.flush()
+
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || ctx.Err() != nil {
+			return nil, fmt.Errorf("error running ffmpeg command <%s>: %w", strings.Join(args, " "), err)
+		}
+
+		// ffmpeg exits with an error if the file cannot be read at all
+		parser.addError()
+	}
+
+	return parser.result(duration, keyframesOnly), nil
+}
+
+var (
+	progressLineRegex = regexp.MustCompile(`^[a-z0-9_]+=`)
+	outTimeRegex      = regexp.MustCompile(`^out_time_us=(\d+)$`)
+)
+
+// integrityParser parses the interleaved progress and error output of ffmpeg.
+type integrityParser struct {
+	mutex sync.Mutex
+	buf   bytes.Buffer
+
+	currentTime    float64
+	errorCount     int
+	firstErrorTime *float64
+}
+
+func (p *integrityParser) Write(b []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.buf.Write(b)
+
+	for {
+		i := bytes.IndexByte(p.buf.Bytes(), '\n')
+		if i == -1 {
+			break
+		}
+
+		line := string(p.buf.Next(i + 1))
+		p.parseLine(strings.TrimSpace(line))
+	}
+
+	return len(b), nil
+}
+
+func (p *integrityParser) flush() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.buf.Len() > 0 {
+		p.parseLine(strings.TrimSpace(p.buf.String()))
+		p.buf.Reset()
+	}
+}
+
+func (p *integrityParser) parseLine(line string) {
+	if line == "" {
+		return
+	}
+
+	if m := outTimeRegex.FindStringSubmatch(line); m != nil {
+		us, _ := strconv.ParseInt(m[1], 10, 64)
+		p.currentTime = float64(us) / 1000000
+		return
+	}
+
+	if progressLineRegex.MatchString(line) {
+		return
+	}
+
+	p.addErrorLocked()
+}
+
+func (p *integrityParser) addError() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.addErrorLocked()
+}
+
+func (p *integrityParser) addErrorLocked() {
+	p.errorCount++
+	if p.firstErrorTime == nil {
+		t := p.currentTime
+		p.firstErrorTime = &t
+	}
+}
+
+func (p *integrityParser) result(duration float64, keyframesOnly bool) *IntegrityResult {
+	minMissing := minTruncatedSeconds
+	if keyframesOnly {
+		minMissing = minTruncatedKeyframeSeconds
+	}
+	minMissing = math.Max(minMissing, duration*minTruncatedProportion)
+
+	return &IntegrityResult{
+		ErrorCount:      p.errorCount,
+		FirstErrorTime:  p.firstErrorTime,
+		DecodedDuration: p.currentTime,
+		Truncated:       duration-p.currentTime > minMissing,
+	}
+}
@@ -0,0 +1,60 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const integrityOutput = `frame=120
+fps=0.00
+stream_0_0_q=-0.0
+out_time_us=5005000
+out_time=00:00:05.005000
+speed= 10x
+progress=continue
+[h264 @ 0x55d3c0] error while decoding MB 53 20, bytestream -7
+[h264 @ 0x55d3c0] concealing 1400 DC, 1400 AC, 1400 MV errors in P frame
+out_time_us=9009000
+progress=end
+`
+
+func TestIntegrityParser(t *testing.T) {
+	errorTime := 5.005
+
+	tests := []struct {
+		name          string
+		output        string
+		duration      float64
+		keyframesOnly bool
+		want          *IntegrityResult
+	}{
+		{"errors", integrityOutput, 10, false, &IntegrityResult{
+			ErrorCount:      2,
+			FirstErrorTime:  &errorTime,
+			DecodedDuration: 9.009,
+		}},
+		{"truncated", integrityOutput, 60, false, &IntegrityResult{
+			ErrorCount:      2,
+			FirstErrorTime:  &errorTime,
+			DecodedDuration: 9.009,
+			Truncated:       true,
+		}},
+		{"keyframes only", "out_time_us=50000000\nprogress=end\n", 59, true, &IntegrityResult{
+			DecodedDuration: 50,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &integrityParser{}
+			// write in pieces to test partial lines
+			half := len(tt.output) / 2
+			_, _ = p.Write([]byte(tt.output[:half]))
+			_, _ = p.Write([]byte(tt.output[half:]))
+			p.flush()
+
+			assert.Equal(t, tt.want, p.result(tt.duration, tt.keyframesOnly))
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package ffmpeg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+// maxTruePeak is the maximum true peak in dBTP after applying a
+// normalisation gain, leaving headroom to avoid clipping.
+const maxTruePeak = -1.0
+
+// minLoudness is the lowest integrated loudness reported by ebur128.
+// Files at or below this level are treated as silent.
+const minLoudness = -70.0
+
+// maxLoudnessGain is the maximum gain in dB applied to quiet files.
+const maxLoudnessGain = 20.0
+
+// Loudness contains the EBU R128 loudness measurements of an audio stream.
+type Loudness struct {
+	// Integrated is the integrated loudness in LUFS.
+	Integrated float64
+	// TruePeak is the true peak in dBTP.
+	TruePeak float64
+}
+
+var ErrNoAudio = errors.New("no audio to analyse")
+
+// AnalyseLoudness measures the integrated loudness and true peak of the
+// default audio stream of the file using the ebur128 filter.
+func (f *FFMpeg) AnalyseLoudness(ctx context.Context, path string) (*Loudness, error) {
+	args := Args{"-hide_banner", "-nostats"}
+	args = args.Input(path)
+	args = append(args, "-map", "0:a:0")
+	args = args.AudioFilter("ebur128=peak=true")
+	args = args.Format(FormatNull).NullOutput()
+
+	command := f.Command(ctx, args)
+	var stdErrBuffer bytes.Buffer
+	command.Stderr = &stdErrBuffer // the summary goes to stderr
+	if err := command.Run(); err != nil {
+		return nil, fmt.Errorf("error running ffmpeg command <%s>: %w", strings.Join(args, " "), err)
+	}
+
+	return parseLoudnessSummary(stdErrBuffer.String())
+}
+
+var (
+	integratedLoudnessRegex = regexp.MustCompile(`I:\s+(-?[0-9.]+|-inf) LUFS`)
+	truePeakRegex           = regexp.MustCompile(`Peak:\s+(-?[0-9.]+|-inf) dBFS`)
+)
+
+// parseLoudnessSummary parses the summary printed by the ebur128 filter.
+func parseLoudnessSummary(output string) (*Loudness, error) {
+	// the filter prints per-frame values before the summary
+	i := strings.LastIndex(output, "Summary:")
+	if i == -1 {
+		return nil, ErrNoAudio
+	}
+	summary := output[i:]
+
+	integrated := integratedLoudnessRegex.FindStringSubmatch(summary)
+	peak := truePeakRegex.FindStringSubmatch(summary)
+	if len(integrated) != 2 || len(peak) != 2 {
+		return nil, fmt.Errorf("could not parse loudness summary: %q", summary)
+	}
+
+	ret := &Loudness{}
+	ret.Integrated, _ = strconv.ParseFloat(integrated[1], 64)
+	ret.TruePeak, _ = strconv.ParseFloat(peak[1], 64)
+
+	if math.IsInf(ret.TruePeak, -1) || ret.Integrated <= minLoudness {
+		return nil, ErrNoAudio
+	}
+
+	return ret, nil
+}
+
+// LoudnessGain returns the gain in dB to bring audio with the given
+// integrated loudness to target LUFS. The gain is limited so that the
+// true peak does not exceed -1 dBTP.
+func LoudnessGain(integrated float64, truePeak float64, target float64) float64 {
+	gain := target - integrated
+	if truePeak+gain > maxTruePeak {
+		gain = maxTruePeak - truePeak
+	}
+
+	return math.Min(gain, maxLoudnessGain)
+}
+
+// loudnessGain returns the normalisation gain to apply to the default audio
+// stream of vf. Returns false if normalisation is disabled or the loudness of
+// vf has not been analysed.
+func (sm *StreamManager) loudnessGain(vf *models.VideoFile) (float64, bool) {
+	if !sm.config.GetNormaliseLoudness() || vf.IntegratedLoudness == nil || vf.TruePeak == nil {
+		return 0, false
+	}
+
+	return LoudnessGain(*vf.IntegratedLoudness, *vf.TruePeak, sm.config.GetLoudnessTarget()), true
+}
+
+// VolumeFilter returns an audio filter applying the gain in dB.
+func VolumeFilter(gain float64) string {
+	return fmt.Sprintf("volume=%.2fdB", gain)
+}
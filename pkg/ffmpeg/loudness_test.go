@@ -0,0 +1,79 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ebur128Summary = `[Parsed_ebur128_0 @ 0x55d0c1a0] t: 9.9  TARGET:-23 LUFS    M: -20.1 S: -21.0     I: -22.0 LUFS       LRA:   4.1 LU  FTPK: -3.2 dBFS  TPK: -2.5 dBFS
+[Parsed_ebur128_0 @ 0x55d0c1a0] Summary:
+
+  Integrated loudness:
+    I:         -18.4 LUFS
+    Threshold: -28.6 LUFS
+
+  Loudness range:
+    LRA:         6.2 LU
+    Threshold: -38.7 LUFS
+    LRA low:   -22.3 LUFS
+    LRA high:  -16.1 LUFS
+
+  True peak:
+    Peak:       -0.6 dBFS
+`
+
+const ebur128SilentSummary = `[Parsed_ebur128_0 @ 0x55d0c1a0] Summary:
+
+  Integrated loudness:
+    I:         -70.0 LUFS
+    Threshold:   0.0 LUFS
+
+  True peak:
+    Peak:       -inf dBFS
+`
+
+func TestParseLoudnessSummary(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    *Loudness
+		wantErr bool
+	}{
+		{"summary", ebur128Summary, &Loudness{Integrated: -18.4, TruePeak: -0.6}, false},
+		{"silent", ebur128SilentSummary, nil, true},
+		{"no summary", "Stream map '0:a:0' matches no streams.", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLoudnessSummary(tt.output)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseLoudnessSummary() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoudnessGain(t *testing.T) {
+	tests := []struct {
+		name       string
+		integrated float64
+		truePeak   float64
+		target     float64
+		want       float64
+	}{
+		{"reduce", -10, -0.5, -16, -6},
+		{"increase", -24, -10, -16, 8},
+		{"limited by peak", -24, -4, -16, 3},
+		{"limited by max gain", -50, -40, -16, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, LoudnessGain(tt.integrated, tt.truePeak, tt.target))
+		})
+	}
+}
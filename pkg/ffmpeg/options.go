@@ -101,6 +101,17 @@ func (a Args) SkipAudio() Args {
 	return append(a, "-an")
 }
 
+// AudioFilter adds the audio filter (-af) and returns the result.
+func (a Args) AudioFilter(af string) Args {
+	return append(a, "-af", af)
+}
+
+// MapAudioTrack maps the first video stream and the audio stream with the
+// given index (among the audio streams of the input) and returns the result.
+func (a Args) MapAudioTrack(track int) Args {
+	return append(a, "-map", "0:v:0", "-map", fmt.Sprintf("0:a:%d", track))
+}
+
 // VideoCodec adds the given video codec and returns the result.
 func (a Args) VideoCodec(c VideoCodec) Args {
 	return append(a, c.Args()...)
@@ -0,0 +1,181 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// SceneCutThreshold is the minimum scene change score of frames that are
+	// considered to be cuts.
+	SceneCutThreshold = 0.3
+
+	// sceneCutWidth is the width frames are scaled to before being compared.
+	// The scene score is not sensitive to resolution, so comparing small
+	// frames is much faster without affecting the results.
+	sceneCutWidth = 320
+
+	// minShotOffset is the minimum distance in seconds from a cut to a
+	// representative frame, to avoid fades and transitional frames.
+	minShotOffset = 1.0
+)
+
+// SceneCut is a point in a video where the shot changes.
+type SceneCut struct {
+	// Time is the time of the first frame of the new shot in seconds.
+	Time float64 `json:"time"`
+	// Score is the scene change score between 0 and 1. Higher scores are
+	// bigger changes.
+	Score float64 `json:"score"`
+}
+
+// SceneCuts is a list of scene cuts sorted by time.
+type SceneCuts []SceneCut
+
+// DetectSceneCuts returns the frames of the default video stream of the file
+// with a scene change score greater than threshold.
+func (f *FFMpeg) DetectSceneCuts(ctx context.Context, path string, threshold float64) (SceneCuts, error) {
+	var videoFilter VideoFilter
+	videoFilter = videoFilter.ScaleWidth(sceneCutWidth)
+	videoFilter = videoFilter.Append(fmt.Sprintf("select='gt(scene,%v)'", threshold))
+	videoFilter = videoFilter.Append("metadata=print:file=-")
+
+	args := Args{"-hide_banner", "-nostats"}
+	args = args.Input(path)
+	args = append(args, "-map", "0:v:0")
+	args = args.VideoFilter(videoFilter)
+	args = args.Format(FormatNull).NullOutput()
+
+	command := f.Command(ctx, args)
+	output, err := command.Output()
+	if err != nil {
+		return nil, fmt.Errorf("error running ffmpeg command <%s>: %w", strings.Join(args, " "), err)
+	}
+
+	return parseSceneCuts(string(output))
+}
+
+var (
+	ptsTimeRegex    = regexp.MustCompile(`pts_time:(-?[0-9.]+)`)
+	sceneScoreRegex = regexp.MustCompile(`lavfi\.scene_score=([0-9.]+)`)
+)
+
+// parseSceneCuts parses the frame metadata printed by the metadata filter.
+// Each frame is printed as a frame line followed by its metadata.
+func parseSceneCuts(output string) (SceneCuts, error) {
+	var ret SceneCuts
+	var current *SceneCut
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := ptsTimeRegex.FindStringSubmatch(line); m != nil {
+			t, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing frame time %q: %w", m[1], err)
+			}
+
+			ret = append(ret, SceneCut{Time: t})
+			current = &ret[len(ret)-1]
+			continue
+		}
+
+		if m := sceneScoreRegex.FindStringSubmatch(line); m != nil && current != nil {
+			score, err := strconv.ParseFloat(m[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("parsing scene score %q: %w", m[1], err)
+			}
+			current.Score = score
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Time < ret[j].Time
+	})
+
+	return ret, nil
+}
+
+// RepresentativeTime returns a time close to t which is not near a cut, so
+// that the frame is from the middle of a shot rather than a transition.
+// If the shot containing t is too short, the middle of the shot is returned.
+// Returns t if there are no cuts.
+func (c SceneCuts) RepresentativeTime(t float64, duration float64) float64 {
+	if len(c) == 0 {
+		return t
+	}
+
+	// find the shot containing t
+	i := sort.Search(len(c), func(i int) bool {
+		return c[i].Time > t
+	})
+
+	start := 0.0
+	if i > 0 {
+		start = c[i-1].Time
+	}
+	end := duration
+	if i < len(c) {
+		end = c[i].Time
+	}
+
+	if end-start <= 2*minShotOffset {
+		return start + (end-start)/2
+	}
+
+	return math.Max(start+minShotOffset, math.Min(t, end-minShotOffset))
+}
+
+// Suggested returns the cuts with a score of at least minScore, keeping only
+// the highest scoring cut within minGap seconds of each other. Cuts within
+// minGap seconds of any of the exclude times are omitted.
+func (c SceneCuts) Suggested(minScore float64, minGap float64, exclude []float64) SceneCuts {
+	var candidates SceneCuts
+	for _, cut := range c {
+		if cut.Score >= minScore {
+			candidates = append(candidates, cut)
+		}
+	}
+
+	// consider the biggest changes first
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+
+	taken := append([]float64(nil), exclude...)
+	isNear := func(t float64) bool {
+		for _, tt := range taken {
+			if math.Abs(tt-t) < minGap {
+				return true
+			}
+		}
+		return false
+	}
+
+	var ret SceneCuts
+	for _, cut := range candidates {
+		if isNear(cut.Time) {
+			continue
+		}
+
+		ret = append(ret, cut)
+		taken = append(taken, cut.Time)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Time < ret[j].Time
+	})
+
+	return ret
+}
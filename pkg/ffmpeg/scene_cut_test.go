@@ -0,0 +1,98 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const metadataOutput = `frame:0    pts:250250  pts_time:10.427
+lavfi.scene_score=0.412000
+frame:1    pts:1001000 pts_time:41.708
+lavfi.scene_score=0.873500
+`
+
+func TestParseSceneCuts(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   SceneCuts
+	}{
+		{"cuts", metadataOutput, SceneCuts{
+			{Time: 10.427, Score: 0.412},
+			{Time: 41.708, Score: 0.8735},
+		}},
+		{"no cuts", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSceneCuts(tt.output)
+			if err != nil {
+				t.Errorf("parseSceneCuts() error = %v", err)
+				return
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSceneCuts_RepresentativeTime(t *testing.T) {
+	cuts := SceneCuts{
+		{Time: 10, Score: 0.5},
+		{Time: 11, Score: 0.5},
+		{Time: 30, Score: 0.5},
+	}
+
+	tests := []struct {
+		name string
+		cuts SceneCuts
+		t    float64
+		want float64
+	}{
+		{"no cuts", nil, 10.2, 10.2},
+		{"middle of shot", cuts, 20, 20},
+		{"after cut", cuts, 11.2, 12},
+		{"before cut", cuts, 29.5, 29},
+		{"short shot", cuts, 10.2, 10.5},
+		{"last shot", cuts, 59.8, 59},
+		{"first shot", cuts, 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.cuts.RepresentativeTime(tt.t, 60))
+		})
+	}
+}
+
+func TestSceneCuts_Suggested(t *testing.T) {
+	cuts := SceneCuts{
+		{Time: 10, Score: 0.6},
+		{Time: 15, Score: 0.9},
+		{Time: 40, Score: 0.4},
+		{Time: 60, Score: 0.7},
+		{Time: 100, Score: 0.8},
+	}
+
+	tests := []struct {
+		name    string
+		exclude []float64
+		want    SceneCuts
+	}{
+		{"no markers", nil, SceneCuts{
+			{Time: 15, Score: 0.9},
+			{Time: 60, Score: 0.7},
+			{Time: 100, Score: 0.8},
+		}},
+		{"existing markers", []float64{58, 110}, SceneCuts{
+			{Time: 15, Score: 0.9},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, cuts.Suggested(0.5, 30, tt.exclude))
+		})
+	}
+}
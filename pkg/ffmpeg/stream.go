@@ -18,6 +18,7 @@ const (
 	MimeMkvAudio  string = "audio/x-matroska"
 	MimeMp4Video  string = "video/mp4"
 	MimeMp4Audio  string = "audio/mp4"
+	MimeMpegVideo string = "video/mpeg"
 )
 
 type StreamManager struct {
@@ -31,8 +32,19 @@ type StreamManager struct {
 	context    context.Context
 	cancelFunc context.CancelFunc
 
-	runningStreams map[string]*runningStream
+	runningStreams    map[string]*runningStream
+	runningTranscodes map[string]*runningTranscode
+	transcodeCount    int
+	// stoppedStreams maps the segmented streams stopped by StopSession to
+	// the time until which their segment requests are rejected
+	stoppedStreams map[string]time.Time
 	streamsMutex   sync.Mutex
+
+	// number of running hardware and software transcode processes
+	hwTranscodes int
+	swTranscodes int
+
+	sessionSubs sessionSubscriptions
 }
 
 type StreamManagerConfig interface {
@@ -40,6 +52,11 @@ type StreamManagerConfig interface {
 	GetLiveTranscodeInputArgs() []string
 	GetLiveTranscodeOutputArgs() []string
 	GetTranscodeHardwareAcceleration() bool
+	GetLiveTranscodeCacheSize() int64
+	GetMaxLiveHardwareTranscodes() int
+	GetMaxLiveSoftwareTranscodes() int
+	GetNormaliseLoudness() bool
+	GetLoudnessTarget() float64
 }
 
 func NewStreamManager(cacheDir string, encoder *FFMpeg, ffprobe FFProbe, config StreamManagerConfig, lockManager *fsutil.ReadLockManager) *StreamManager {
@@ -50,14 +67,16 @@ func NewStreamManager(cacheDir string, encoder *FFMpeg, ffprobe FFProbe, config
 	ctx, cancel := context.WithCancel(context.Background())
 
 	ret := &StreamManager{
-		cacheDir:       cacheDir,
-		encoder:        encoder,
-		ffprobe:        ffprobe,
-		config:         config,
-		lockManager:    lockManager,
-		context:        ctx,
-		cancelFunc:     cancel,
-		runningStreams: make(map[string]*runningStream),
+		cacheDir:          cacheDir,
+		encoder:           encoder,
+		ffprobe:           ffprobe,
+		config:            config,
+		lockManager:       lockManager,
+		context:           ctx,
+		cancelFunc:        cancel,
+		runningStreams:    make(map[string]*runningStream),
+		runningTranscodes: make(map[string]*runningTranscode),
+		stoppedStreams:    make(map[string]time.Time),
 	}
 
 	go func() {
@@ -71,10 +90,25 @@ func NewStreamManager(cacheDir string, encoder *FFMpeg, ffprobe FFProbe, config
 		}
 	}()
 
+	go func() {
+		ticker := time.NewTicker(cacheEvictInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				ret.evictCache()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
 	return ret
 }
 
-// Shutdown shuts down the stream manager, killing any running transcoding processes and removing all cached files.
+// Shutdown shuts down the stream manager, killing any running transcoding processes.
+// Cached files are removed, unless the transcode cache is enabled.
 func (sm *StreamManager) Shutdown() {
 	sm.cancelFunc()
 	sm.stopAndRemoveAll()
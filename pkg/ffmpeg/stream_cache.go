@@ -0,0 +1,285 @@
+package ffmpeg
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/stashapp/stash/pkg/logger"
+)
+
+// interval between checks of the transcode cache size
+const cacheEvictInterval = time.Minute
+
+// cachedStreamTypes are the segmented stream types which write to the cache directory.
+var cachedStreamTypes = []*StreamType{
+	StreamTypeHLS,
+	StreamTypeHLSCopy,
+	StreamTypeHLSHEVC,
+	StreamTypeHLSCMAF,
+	StreamTypeDASHVideo,
+	StreamTypeDASHAV1,
+	StreamTypeDASHAudio,
+}
+
+// CacheEntry describes the cached segments of a single stream.
+type CacheEntry struct {
+	Dir        string
+	Hash       string
+	StreamType string
+	// Resolution is the maximum transcode size of the stream. 0 if the original resolution.
+	Resolution int
+	// AudioTrack is the selected audio track of the stream. nil if the default track.
+	AudioTrack *int
+	// Normalised is true if the loudness of the audio is normalised.
+	Normalised   bool
+	Size         int64
+	LastAccessed time.Time
+	// Running is true if the stream is currently being served.
+	Running bool
+}
+
+// CacheUsage describes the contents of the transcode cache.
+type CacheUsage struct {
+	// MaxSize is the configured size budget of the cache in bytes. 0 if the cache is disabled.
+	MaxSize int64
+	Size    int64
+	Entries []*CacheEntry
+}
+
+// parseCacheDir parses a stream directory name as created by StreamType.FileDir.
+// Returns false if the name is not a stream directory.
+func parseCacheDir(dir string) (*CacheEntry, bool) {
+	parts := strings.Split(dir, "_")
+	if len(parts) < 2 || len(parts) > 5 || parts[0] == "" {
+		return nil, false
+	}
+
+	ret := &CacheEntry{
+		Dir:        dir,
+		Hash:       parts[0],
+		StreamType: parts[1],
+	}
+
+	valid := false
+	for _, t := range cachedStreamTypes {
+		if t.Name == ret.StreamType {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return nil, false
+	}
+
+	rest := parts[2:]
+	if len(rest) > 0 && rest[len(rest)-1] == "n" {
+		ret.Normalised = true
+		rest = rest[:len(rest)-1]
+	}
+
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "a") {
+		resolution, err := strconv.Atoi(rest[0])
+		if err != nil {
+			return nil, false
+		}
+		ret.Resolution = resolution
+		rest = rest[1:]
+	}
+
+	if len(rest) > 0 {
+		if !strings.HasPrefix(rest[0], "a") {
+			return nil, false
+		}
+		audioTrack, err := strconv.Atoi(rest[0][1:])
+		if err != nil {
+			return nil, false
+		}
+		ret.AudioTrack = &audioTrack
+		rest = rest[1:]
+	}
+
+	if len(rest) > 0 {
+		return nil, false
+	}
+
+	return ret, true
+}
+
+func (sm *StreamManager) cacheEnabled() bool {
+	return sm.cacheDir != "" && sm.config.GetLiveTranscodeCacheSize() > 0
+}
+
+// cacheEntries returns the stream directories in the cache directory,
+// ordered by least recently accessed first.
+func (sm *StreamManager) cacheEntries() ([]*CacheEntry, error) {
+	if sm.cacheDir == "" {
+		return nil, nil
+	}
+
+	dirs, err := os.ReadDir(sm.cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ret []*CacheEntry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+
+		entry, ok := parseCacheDir(d.Name())
+		if !ok {
+			continue
+		}
+
+		path := filepath.Join(sm.cacheDir, d.Name())
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		entry.LastAccessed = info.ModTime()
+
+		_ = filepath.WalkDir(path, func(_ string, f fs.DirEntry, err error) error {
+			if err != nil || f.IsDir() {
+				return nil
+			}
+			if fi, err := f.Info(); err == nil {
+				entry.Size += fi.Size()
+			}
+			return nil
+		})
+
+		ret = append(ret, entry)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].LastAccessed.Before(ret[j].LastAccessed)
+	})
+
+	return ret, nil
+}
+
+// CacheUsage returns the current contents of the transcode cache.
+func (sm *StreamManager) CacheUsage() (*CacheUsage, error) {
+	entries, err := sm.cacheEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	sm.streamsMutex.Lock()
+	for _, e := range entries {
+		if stream := sm.runningStreams[e.Dir]; stream != nil {
+			e.Running = true
+			if stream.lastAccessed.After(e.LastAccessed) {
+				e.LastAccessed = stream.lastAccessed
+			}
+		}
+	}
+	sm.streamsMutex.Unlock()
+
+	ret := &CacheUsage{
+		MaxSize: sm.config.GetLiveTranscodeCacheSize(),
+		Entries: entries,
+	}
+	for _, e := range entries {
+		ret.Size += e.Size
+	}
+
+	return ret, nil
+}
+
+// PurgeCache stops any running streams of the scene with the given hash and
+// removes its cached segments. If hash is empty, then the entire cache is purged.
+func (sm *StreamManager) PurgeCache(hash string) error {
+	entries, err := sm.cacheEntries()
+	if err != nil {
+		return err
+	}
+
+	sm.streamsMutex.Lock()
+	defer sm.streamsMutex.Unlock()
+
+	for _, stream := range sm.runningStreams {
+		if hash != "" && stream.hash != hash {
+			continue
+		}
+
+		cancelWaitingSegments(stream)
+		sm.stopTranscode(stream)
+		sm.removeTranscodeFiles(stream)
+		sm.removeStream(stream)
+	}
+
+	for _, e := range entries {
+		if hash != "" && e.Hash != hash {
+			continue
+		}
+
+		path := filepath.Join(sm.cacheDir, e.Dir)
+		if err := os.RemoveAll(path); err != nil {
+			logger.Warnf("[transcode] error removing segment directory %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+// evictCache removes the least recently accessed streams from the cache
+// until the cache fits within the configured size budget.
+// Streams which are currently running are never evicted.
+func (sm *StreamManager) evictCache() {
+	maxSize := sm.config.GetLiveTranscodeCacheSize()
+	if maxSize <= 0 {
+		return
+	}
+
+	entries, err := sm.cacheEntries()
+	if err != nil {
+		logger.Warnf("[transcode] error reading cache directory: %v", err)
+		return
+	}
+
+	var size int64
+	for _, e := range entries {
+		size += e.Size
+	}
+
+	sm.streamsMutex.Lock()
+	defer sm.streamsMutex.Unlock()
+
+	for _, e := range entries {
+		if size <= maxSize {
+			break
+		}
+
+		if sm.runningStreams[e.Dir] != nil {
+			continue
+		}
+
+		logger.Debugf("[transcode] evicting %s from cache", e.Dir)
+		path := filepath.Join(sm.cacheDir, e.Dir)
+		if err := os.RemoveAll(path); err != nil {
+			logger.Warnf("[transcode] error removing segment directory %s: %v", path, err)
+			continue
+		}
+		size -= e.Size
+	}
+}
+
+// assume lock is held
+func cancelWaitingSegments(stream *runningStream) {
+	for _, segment := range stream.waitingSegments {
+		if len(segment.available) == 0 {
+			segment.available <- context.Canceled
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package ffmpeg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+type testStreamManagerConfig struct {
+	cacheSize   int64
+	maxHardware int
+	maxSoftware int
+}
+
+func (c testStreamManagerConfig) GetMaxStreamingTranscodeSize() models.StreamingResolutionEnum {
+	return models.StreamingResolutionEnumOriginal
+}
+
+func (c testStreamManagerConfig) GetLiveTranscodeInputArgs() []string  { return nil }
+func (c testStreamManagerConfig) GetLiveTranscodeOutputArgs() []string { return nil }
+func (c testStreamManagerConfig) GetTranscodeHardwareAcceleration() bool {
+	return false
+}
+func (c testStreamManagerConfig) GetLiveTranscodeCacheSize() int64  { return c.cacheSize }
+func (c testStreamManagerConfig) GetMaxLiveHardwareTranscodes() int { return c.maxHardware }
+func (c testStreamManagerConfig) GetMaxLiveSoftwareTranscodes() int { return c.maxSoftware }
+func (c testStreamManagerConfig) GetNormaliseLoudness() bool        { return false }
+func (c testStreamManagerConfig) GetLoudnessTarget() float64        { return 0 }
+
+func TestParseCacheDir(t *testing.T) {
+	audioTrack := 1
+
+	tests := []struct {
+		name   string
+		dir    string
+		want   *CacheEntry
+		wantOK bool
+	}{
+		{
+			"original",
+			"abc_hls",
+			&CacheEntry{Dir: "abc_hls", Hash: "abc", StreamType: "hls"},
+			true,
+		},
+		{
+			"resolution",
+			"abc_dash-v_720",
+			&CacheEntry{Dir: "abc_dash-v_720", Hash: "abc", StreamType: "dash-v", Resolution: 720},
+			true,
+		},
+		{
+			"resolution and audio track",
+			"abc_cmaf_720_a1",
+			&CacheEntry{Dir: "abc_cmaf_720_a1", Hash: "abc", StreamType: "cmaf", Resolution: 720, AudioTrack: &audioTrack},
+			true,
+		},
+		{
+			"audio track",
+			"abc_hls_a1",
+			&CacheEntry{Dir: "abc_hls_a1", Hash: "abc", StreamType: "hls", AudioTrack: &audioTrack},
+			true,
+		},
+		{
+			"normalised",
+			"abc_hls_720_n",
+			&CacheEntry{Dir: "abc_hls_720_n", Hash: "abc", StreamType: "hls", Resolution: 720, Normalised: true},
+			true,
+		},
+		{
+			"unknown stream type",
+			"abc_foo",
+			nil,
+			false,
+		},
+		{
+			"invalid resolution",
+			"abc_hls_big",
+			nil,
+			false,
+		},
+		{
+			"too many parts",
+			"abc_hls_720_a1_x",
+			nil,
+			false,
+		},
+		{
+			"not a stream dir",
+			"thumbnails",
+			nil,
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCacheDir(tt.dir)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStreamManager_evictCache(t *testing.T) {
+	cacheDir := t.TempDir()
+	now := time.Now()
+
+	writeStream := func(dir string, size int, accessed time.Time) {
+		path := filepath.Join(cacheDir, dir)
+		if err := os.MkdirAll(path, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "0.ts"), make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, accessed, accessed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeStream("old_hls", 100, now.Add(-3*time.Hour))
+	writeStream("running_hls", 100, now.Add(-2*time.Hour))
+	writeStream("recent_hls_720", 100, now.Add(-time.Hour))
+	writeStream("new_hls", 100, now)
+
+	sm := &StreamManager{
+		cacheDir: cacheDir,
+		config:   testStreamManagerConfig{cacheSize: 250},
+		runningStreams: map[string]*runningStream{
+			"running_hls": {dir: "running_hls"},
+		},
+	}
+
+	sm.evictCache()
+
+	usage, err := sm.CacheUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dirs []string
+	for _, e := range usage.Entries {
+		dirs = append(dirs, e.Dir)
+	}
+
+	// the oldest streams are evicted, except for running streams
+	assert.Equal(t, []string{"running_hls", "new_hls"}, dirs)
+	assert.Equal(t, int64(200), usage.Size)
+	assert.Equal(t, int64(250), usage.MaxSize)
+}
@@ -0,0 +1,66 @@
+package ffmpeg
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/stashapp/stash/pkg/logger"
+)
+
+// time in seconds that clients are asked to wait before retrying
+// when the transcode limit is reached
+const transcodeRetryAfter = 5
+
+// ErrTranscodeLimit is returned when a live transcode cannot be started
+// because the maximum number of concurrent transcodes is running.
+var ErrTranscodeLimit = errors.New("maximum number of concurrent transcodes reached")
+
+// acquireTranscodeSlot reserves a transcode slot for codec, falling back to
+// the software codec fallback if all hardware slots are in use.
+// Returns the codec to transcode with, which must be released with
+// releaseTranscodeSlot when the transcode exits.
+// Stream copies are not limited.
+// assume lock is held
+func (sm *StreamManager) acquireTranscodeSlot(codec VideoCodec, fallback VideoCodec) (VideoCodec, error) {
+	if codec == VideoCodecCopy {
+		return codec, nil
+	}
+
+	if IsHWCodec(codec) {
+		maxHW := sm.config.GetMaxLiveHardwareTranscodes()
+		if maxHW <= 0 || sm.hwTranscodes < maxHW {
+			sm.hwTranscodes++
+			return codec, nil
+		}
+
+		logger.Debugf("[transcode] all %d hardware transcode slots in use, falling back to %s", maxHW, fallback)
+		codec = fallback
+	}
+
+	maxSW := sm.config.GetMaxLiveSoftwareTranscodes()
+	if maxSW > 0 && sm.swTranscodes >= maxSW {
+		return "", ErrTranscodeLimit
+	}
+
+	sm.swTranscodes++
+	return codec, nil
+}
+
+// releaseTranscodeSlot releases the slot acquired for codec.
+// assume lock is held
+func (sm *StreamManager) releaseTranscodeSlot(codec VideoCodec) {
+	switch {
+	case codec == VideoCodecCopy:
+	case IsHWCodec(codec):
+		sm.hwTranscodes--
+	default:
+		sm.swTranscodes--
+	}
+}
+
+// writeTranscodeLimitError writes a 503 response asking the client to retry later.
+func writeTranscodeLimitError(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(transcodeRetryAfter))
+	http.Error(w, ErrTranscodeLimit.Error(), http.StatusServiceUnavailable)
+}
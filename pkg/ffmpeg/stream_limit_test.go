@@ -0,0 +1,48 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamManager_acquireTranscodeSlot(t *testing.T) {
+	sm := &StreamManager{
+		config: testStreamManagerConfig{
+			maxHardware: 1,
+			maxSoftware: 1,
+		},
+	}
+
+	// copies are not limited
+	codec, err := sm.acquireTranscodeSlot(VideoCodecCopy, VideoCodecCopy)
+	assert.Nil(t, err)
+	assert.Equal(t, VideoCodecCopy, codec)
+
+	codec, err = sm.acquireTranscodeSlot(VideoCodecN264, VideoCodecLibX264)
+	assert.Nil(t, err)
+	assert.Equal(t, VideoCodecN264, codec)
+
+	// hardware slots are full, so fall back to software
+	codec, err = sm.acquireTranscodeSlot(VideoCodecN264, VideoCodecLibX264)
+	assert.Nil(t, err)
+	assert.Equal(t, VideoCodecLibX264, codec)
+
+	_, err = sm.acquireTranscodeSlot(VideoCodecN264, VideoCodecLibX264)
+	assert.ErrorIs(t, err, ErrTranscodeLimit)
+
+	_, err = sm.acquireTranscodeSlot(VideoCodecVP9, VideoCodecVP9)
+	assert.ErrorIs(t, err, ErrTranscodeLimit)
+
+	sm.releaseTranscodeSlot(VideoCodecN264)
+
+	codec, err = sm.acquireTranscodeSlot(VideoCodecN264, VideoCodecLibX264)
+	assert.Nil(t, err)
+	assert.Equal(t, VideoCodecN264, codec)
+
+	sm.releaseTranscodeSlot(VideoCodecLibX264)
+
+	codec, err = sm.acquireTranscodeSlot(VideoCodecVP9, VideoCodecVP9)
+	assert.Nil(t, err)
+	assert.Equal(t, VideoCodecVP9, codec)
+}
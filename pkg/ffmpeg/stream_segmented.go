@@ -8,6 +8,7 @@ import (
 	"io"
 	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -43,14 +44,22 @@ const (
 	maxSegmentBuffer = 15
 
 	// maximum idle time between segment requests before
-	// stopping transcode and deleting cache folder
+	// stopping transcode and deleting cache folder.
+	// The cache folder is kept if the transcode cache is enabled.
 	maxIdleTime = 30 * time.Second
+
+	// estimated bits per pixel of transcoded video and bitrate
+	// of transcoded audio, used to advertise rendition bandwidth
+	transcodeBitsPerPixel = 0.1
+	transcodeAudioBitrate = 128000
 )
 
 type StreamType struct {
-	Name          string
-	SegmentType   *SegmentType
-	ServeManifest func(sm *StreamManager, w http.ResponseWriter, r *http.Request, vf *models.VideoFile, resolution string)
+	Name        string
+	SegmentType *SegmentType
+	// Codecs is the RFC 6381 codecs string of the video, advertised in manifests.
+	Codecs        string
+	ServeManifest func(sm *StreamManager, w http.ResponseWriter, r *http.Request, streamType *StreamType, vf *models.VideoFile, resolution string, audioTrack *int)
 	Args          func(codec VideoCodec, segment int, videoFilter VideoFilter, videoOnly bool, outputDir string) Args
 }
 
@@ -118,35 +127,48 @@ var (
 			return
 		},
 	}
+	StreamTypeHLSHEVC = &StreamType{
+		Name:          "hls-hevc",
+		SegmentType:   SegmentTypeFMP4,
+		Codecs:        "hvc1.1.6.L120.90",
+		ServeManifest: serveHLSManifest,
+		Args: func(codec VideoCodec, segment int, videoFilter VideoFilter, videoOnly bool, outputDir string) (args Args) {
+			// Safari only plays HEVC tagged as hvc1
+			args = Args{"-tag:v", "hvc1"}
+			args = append(args, fmp4Args(codec, segment, videoFilter, videoOnly, outputDir)...)
+			return
+		},
+	}
+	// StreamTypeHLSCMAF and StreamTypeDASHCMAF share the same name, and
+	// therefore the same transcode and cached segments. Only the manifest
+	// differs, so a rendition is transcoded once for both protocols.
+	StreamTypeHLSCMAF = &StreamType{
+		Name:          "cmaf",
+		SegmentType:   SegmentTypeFMP4,
+		Codecs:        "avc1.640028",
+		ServeManifest: serveHLSManifest,
+		Args:          fmp4Args,
+	}
+	StreamTypeDASHCMAF = &StreamType{
+		Name:          "cmaf",
+		SegmentType:   SegmentTypeFMP4,
+		Codecs:        "avc1.640028",
+		ServeManifest: serveDASHManifest,
+		Args:          fmp4Args,
+	}
 	StreamTypeDASHVideo = &StreamType{
 		Name:          "dash-v",
 		SegmentType:   SegmentTypeWEBMVideo,
+		Codecs:        "vp09.00.40.08",
 		ServeManifest: serveDASHManifest,
-		Args: func(codec VideoCodec, segment int, videoFilter VideoFilter, videoOnly bool, outputDir string) (args Args) {
-			// only generate the actual init segment (init_v.webm)
-			// when generating the first segment
-			init := ".init"
-			if segment == 0 {
-				init = "init"
-			}
-
-			args = CodecInit(codec)
-			args = append(args,
-				"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentLength),
-			)
-
-			args = args.VideoFilter(videoFilter)
-			args = append(args,
-				"-copyts",
-				"-avoid_negative_ts", "disabled",
-				"-map", "0:v:0",
-				"-f", "webm_chunk",
-				"-chunk_start_index", fmt.Sprint(segment),
-				"-header", filepath.Join(outputDir, init+"_v.webm"),
-				filepath.Join(outputDir, ".%d_v.webm"),
-			)
-			return
-		},
+		Args:          dashVideoArgs,
+	}
+	StreamTypeDASHAV1 = &StreamType{
+		Name:          "dash-av1",
+		SegmentType:   SegmentTypeWEBMVideo,
+		Codecs:        "av01.0.08M.08",
+		ServeManifest: serveDASHManifest,
+		Args:          dashVideoArgs,
 	}
 	StreamTypeDASHAudio = &StreamType{
 		Name:          "dash-a",
@@ -165,7 +187,6 @@ var (
 				"-ar", "48000",
 				"-copyts",
 				"-avoid_negative_ts", "disabled",
-				"-map", "0:a:0",
 				"-f", "webm_chunk",
 				"-chunk_start_index", fmt.Sprint(segment),
 				"-audio_chunk_duration", fmt.Sprint(segmentLength*1000),
@@ -178,8 +199,10 @@ var (
 )
 
 type SegmentType struct {
-	Format       string
-	MimeType     string
+	Format   string
+	MimeType string
+	// InitSegment is true if the segments require an init segment (segment -1).
+	InitSegment  bool
 	MakeFilename func(segment int) string
 	ParseSegment func(str string) (int, error)
 }
@@ -199,9 +222,33 @@ var (
 			return segment, err
 		},
 	}
+	SegmentTypeFMP4 = &SegmentType{
+		Format:      "%d.m4s",
+		MimeType:    MimeMp4Video,
+		InitSegment: true,
+		MakeFilename: func(segment int) string {
+			if segment == -1 {
+				return "init.mp4"
+			} else {
+				return fmt.Sprintf("%d.m4s", segment)
+			}
+		},
+		ParseSegment: func(str string) (int, error) {
+			if str == "init" {
+				return -1, nil
+			} else {
+				segment, err := strconv.Atoi(str)
+				if err != nil || segment < 0 {
+					err = ErrInvalidSegment
+				}
+				return segment, err
+			}
+		},
+	}
 	SegmentTypeWEBMVideo = &SegmentType{
-		Format:   "%d_v.webm",
-		MimeType: MimeWebmVideo,
+		Format:      "%d_v.webm",
+		MimeType:    MimeWebmVideo,
+		InitSegment: true,
 		MakeFilename: func(segment int) string {
 			if segment == -1 {
 				return "init_v.webm"
@@ -222,8 +269,9 @@ var (
 		},
 	}
 	SegmentTypeWEBMAudio = &SegmentType{
-		Format:   "%d_a.webm",
-		MimeType: MimeWebmAudio,
+		Format:      "%d_a.webm",
+		MimeType:    MimeWebmAudio,
+		InitSegment: true,
 		MakeFilename: func(segment int) string {
 			if segment == -1 {
 				return "init_a.webm"
@@ -247,12 +295,81 @@ var (
 
 var ErrInvalidSegment = errors.New("invalid segment")
 
+// fmp4Args returns the arguments to transcode to fragmented mp4 segments,
+// with the video and audio muxed into each segment.
+func fmp4Args(codec VideoCodec, segment int, videoFilter VideoFilter, videoOnly bool, outputDir string) (args Args) {
+	// only generate the actual init segment (init.mp4)
+	// when generating the first segment
+	init := ".init"
+	if segment == 0 {
+		init = "init"
+	}
+
+	args = CodecInit(codec)
+	args = append(args,
+		"-flags", "+cgop",
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentLength),
+	)
+	args = args.VideoFilter(videoFilter)
+	if videoOnly {
+		args = append(args, "-an")
+	} else {
+		args = append(args,
+			"-c:a", "aac",
+			"-ac", "2",
+		)
+	}
+	args = append(args,
+		"-sn",
+		"-copyts",
+		"-avoid_negative_ts", "disabled",
+		"-f", "hls",
+		"-start_number", fmt.Sprint(segment),
+		"-hls_time", fmt.Sprint(segmentLength),
+		"-hls_segment_type", "fmp4",
+		"-hls_fmp4_init_filename", init+".mp4",
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(outputDir, ".%d.m4s"),
+		filepath.Join(outputDir, "manifest.m3u8"),
+	)
+	return
+}
+
+func dashVideoArgs(codec VideoCodec, segment int, videoFilter VideoFilter, videoOnly bool, outputDir string) (args Args) {
+	// only generate the actual init segment (init_v.webm)
+	// when generating the first segment
+	init := ".init"
+	if segment == 0 {
+		init = "init"
+	}
+
+	args = CodecInit(codec)
+	args = append(args,
+		"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", segmentLength),
+	)
+
+	args = args.VideoFilter(videoFilter)
+	args = append(args,
+		"-copyts",
+		"-avoid_negative_ts", "disabled",
+		"-map", "0:v:0",
+		"-f", "webm_chunk",
+		"-chunk_start_index", fmt.Sprint(segment),
+		"-header", filepath.Join(outputDir, init+"_v.webm"),
+		filepath.Join(outputDir, ".%d_v.webm"),
+	)
+	return
+}
+
 type StreamOptions struct {
 	StreamType *StreamType
 	VideoFile  *models.VideoFile
 	Resolution string
 	Hash       string
 	Segment    string
+	// AudioTrack is the index of the audio stream to include in the stream.
+	// If nil, ffmpeg selects the default audio stream.
+	AudioTrack *int
 }
 
 type transcodeProcess struct {
@@ -277,39 +394,92 @@ type waitingSegment struct {
 
 type runningStream struct {
 	dir              string
+	hash             string
 	streamType       *StreamType
 	vf               *models.VideoFile
 	maxTranscodeSize int
-	outputDir        string
+	audioTrack       *int
+	// loudnessGain is the normalisation gain in dB applied to the audio.
+	// nil if the audio is not normalised.
+	loudnessGain *float64
+	outputDir    string
 
 	waitingSegments []*waitingSegment
 	tp              *transcodeProcess
+	started         time.Time
 	lastAccessed    time.Time
 	lastSegment     int
+	// codec is the video codec of the most recent transcode process
+	codec    VideoCodec
+	clientIP string
 }
 
 func (t StreamType) String() string {
 	return t.Name
 }
 
-func (t StreamType) FileDir(hash string, maxTranscodeSize int) string {
-	if maxTranscodeSize == 0 {
-		return fmt.Sprintf("%s_%s", hash, t)
-	} else {
-		return fmt.Sprintf("%s_%s_%d", hash, t, maxTranscodeSize)
+func (t StreamType) FileDir(hash string, maxTranscodeSize int, audioTrack *int, normalised bool) string {
+	dir := fmt.Sprintf("%s_%s", hash, t)
+	if maxTranscodeSize != 0 {
+		dir += fmt.Sprintf("_%d", maxTranscodeSize)
+	}
+	if audioTrack != nil {
+		dir += fmt.Sprintf("_a%d", *audioTrack)
+	}
+	if normalised {
+		dir += "_n"
+	}
+	return dir
+}
+
+// streamQuery returns the query string to append to variant and segment URLs,
+// so that they are transcoded with the same options as the manifest.
+func streamQuery(resolution string, audioTrack *int) string {
+	v := url.Values{}
+	if resolution != "" {
+		v.Set("resolution", resolution)
+	}
+	if audioTrack != nil {
+		v.Set("audio_track", strconv.Itoa(*audioTrack))
+	}
+
+	if len(v) == 0 {
+		return ""
 	}
+	return "?" + v.Encode()
 }
 
 func HLSGetCodec(sm *StreamManager, name string) (codec VideoCodec) {
+	return hlsGetCodec(sm, name, sm.config.GetTranscodeHardwareAcceleration())
+}
+
+// hlsGetCodec returns the codec for the stream type with the given name.
+// A hardware codec is only returned if hardware is true.
+func hlsGetCodec(sm *StreamManager, name string, hardware bool) (codec VideoCodec) {
 	switch name {
 	case "hls":
 		codec = VideoCodecLibX264
-		if hwcodec := sm.encoder.hwCodecHLSCompatible(); hwcodec != nil && sm.config.GetTranscodeHardwareAcceleration() {
+		if hwcodec := sm.encoder.hwCodecHLSCompatible(); hwcodec != nil && hardware {
 			codec = *hwcodec
 		}
 	case "dash-v":
 		codec = VideoCodecVP9
-		if hwcodec := sm.encoder.hwCodecWEBMCompatible(); hwcodec != nil && sm.config.GetTranscodeHardwareAcceleration() {
+		if hwcodec := sm.encoder.hwCodecWEBMCompatible(); hwcodec != nil && hardware {
+			codec = *hwcodec
+		}
+	case "hls-hevc":
+		codec = VideoCodecLibX265
+		if hwcodec := sm.encoder.HWCodecHEVCCompatible(); hwcodec != nil && hardware {
+			codec = *hwcodec
+		}
+	case "dash-av1":
+		codec = VideoCodecSVTAV1
+		if hwcodec := sm.encoder.HWCodecAV1Compatible(); hwcodec != nil && hardware {
+			codec = *hwcodec
+		}
+	case "cmaf":
+		codec = VideoCodecLibX264
+		if hwcodec := sm.encoder.hwCodecHLSCompatible(); hwcodec != nil && hardware {
 			codec = *hwcodec
 		}
 	case "hls-copy":
@@ -319,16 +489,14 @@ func HLSGetCodec(sm *StreamManager, name string) (codec VideoCodec) {
 	return codec
 }
 
-func (s *runningStream) makeStreamArgs(sm *StreamManager, segment int) Args {
+func (s *runningStream) makeStreamArgs(sm *StreamManager, pipeline HWPipeline, segment int) Args {
 	extraInputArgs := sm.config.GetLiveTranscodeInputArgs()
 	extraOutputArgs := sm.config.GetLiveTranscodeOutputArgs()
 
 	args := Args{"-hide_banner"}
 	args = args.LogLevel(LogLevelError)
 
-	codec := HLSGetCodec(sm, s.streamType.Name)
-
-	args = sm.encoder.hwDeviceInit(args, codec)
+	args = pipeline.InputArgs(args)
 	args = append(args, extraInputArgs...)
 
 	if segment > 0 {
@@ -338,10 +506,27 @@ func (s *runningStream) makeStreamArgs(sm *StreamManager, segment int) Args {
 	args = args.Input(s.vf.Path)
 
 	videoOnly := ProbeAudioCodec(s.vf.AudioCodec) == MissingUnsupported
+	switch {
+	case s.streamType.SegmentType == SegmentTypeWEBMAudio:
+		// WebM audio segments only contain the selected audio stream
+		track := 0
+		if s.audioTrack != nil {
+			track = *s.audioTrack
+		}
+		args = append(args, "-map", fmt.Sprintf("0:a:%d", track))
+	case s.streamType.SegmentType == SegmentTypeWEBMVideo:
+		// WebM video segments contain no audio
+	case s.audioTrack != nil && !videoOnly:
+		args = args.MapAudioTrack(*s.audioTrack)
+	}
+
+	videoFilter := sm.encoder.hwMaxResFilter(pipeline, s.vf.Width, s.vf.Height, s.maxTranscodeSize)
 
-	videoFilter := sm.encoder.hwMaxResFilter(codec, s.vf.Width, s.vf.Height, s.maxTranscodeSize)
+	if s.loudnessGain != nil && !videoOnly {
+		args = args.AudioFilter(VolumeFilter(*s.loudnessGain))
+	}
 
-	args = append(args, s.streamType.Args(codec, segment, videoFilter, videoOnly, s.outputDir)...)
+	args = append(args, s.streamType.Args(pipeline.Codec, segment, videoFilter, videoOnly, s.outputDir)...)
 
 	args = append(args, extraOutputArgs...)
 
@@ -402,15 +587,139 @@ func segmentExists(path string) bool {
 	return exists
 }
 
+// streamRendition is a single resolution of an adaptive stream.
+type streamRendition struct {
+	resolution models.StreamingResolutionEnum
+	width      int
+	height     int
+}
+
+// bandwidth returns the estimated peak video bitrate of the rendition.
+func (r streamRendition) bandwidth(frameRate float64) int {
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+
+	return int(float64(r.width*r.height) * frameRate * transcodeBitsPerPixel)
+}
+
+// scaleDimensions returns the dimensions of a video of the given width and
+// height scaled so that its smaller dimension is at most maxSize.
+func scaleDimensions(width int, height int, maxSize int) (int, int) {
+	videoSize := height
+	if width < videoSize {
+		videoSize = width
+	}
+
+	if maxSize == 0 || maxSize >= videoSize {
+		return width, height
+	}
+
+	scaleFactor := float64(maxSize) / float64(videoSize)
+	return int(float64(width) * scaleFactor), int(float64(height) * scaleFactor)
+}
+
+// streamRenditions returns the renditions of an adaptive stream for a video of
+// the given width and height, from lowest to highest resolution. Resolutions
+// larger than the video or the maximum streaming transcode size are excluded.
+// If the dimensions of the video are unknown, then only the original
+// resolution is returned.
+func (sm *StreamManager) streamRenditions(width int, height int) []streamRendition {
+	if width <= 0 || height <= 0 {
+		return []streamRendition{{
+			resolution: models.StreamingResolutionEnumOriginal,
+		}}
+	}
+
+	videoSize := height
+	if width < videoSize {
+		videoSize = width
+	}
+
+	maxTranscodeSize := sm.config.GetMaxStreamingTranscodeSize().GetMaxResolution()
+
+	var ret []streamRendition
+	for _, resolution := range models.AllStreamingResolutionEnum {
+		size := resolution.GetMaxResolution()
+		if resolution == models.StreamingResolutionEnumOriginal {
+			// the original resolution is only included if it isn't capped
+			if maxTranscodeSize != 0 && maxTranscodeSize < videoSize {
+				continue
+			}
+		} else if size >= videoSize || (maxTranscodeSize != 0 && size > maxTranscodeSize) {
+			continue
+		}
+
+		w, h := scaleDimensions(width, height, size)
+		ret = append(ret, streamRendition{
+			resolution: resolution,
+			width:      w,
+			height:     h,
+		})
+	}
+
+	return ret
+}
+
+// serveHLSMasterManifest serves a HLS master playlist listing a variant
+// playlist for each rendition of the video file. Each variant is transcoded
+// separately, when its segments are first requested.
+func serveHLSMasterManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request, streamType *StreamType, vf *models.VideoFile, audioTrack *int) {
+	baseUrl := *r.URL
+	baseUrl.RawQuery = ""
+	baseURL := baseUrl.String()
+
+	audio := ProbeAudioCodec(vf.AudioCodec) != MissingUnsupported
+
+	var codecs string
+	if streamType.Codecs != "" {
+		codecs = fmt.Sprintf(",CODECS=\"%s\"", streamType.Codecs)
+		if audio {
+			codecs = fmt.Sprintf(",CODECS=\"%s,mp4a.40.2\"", streamType.Codecs)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	fmt.Fprint(&buf, "#EXTM3U\n")
+	fmt.Fprint(&buf, "#EXT-X-VERSION:3\n")
+	fmt.Fprint(&buf, "#EXT-X-INDEPENDENT-SEGMENTS\n")
+
+	for _, rendition := range sm.streamRenditions(vf.Width, vf.Height) {
+		bandwidth := rendition.bandwidth(vf.FrameRate)
+		if audio {
+			bandwidth += transcodeAudioBitrate
+		}
+
+		var resolution string
+		if rendition.width > 0 && rendition.height > 0 {
+			resolution = fmt.Sprintf(",RESOLUTION=%dx%d", rendition.width, rendition.height)
+		}
+
+		fmt.Fprintf(&buf, "#EXT-X-STREAM-INF:BANDWIDTH=%d%s%s\n", bandwidth, resolution, codecs)
+		fmt.Fprintf(&buf, "%s%s\n", baseURL, streamQuery(string(rendition.resolution), audioTrack))
+	}
+
+	w.Header().Set("Content-Type", MimeHLS)
+	utils.ServeStaticContent(w, r, buf.Bytes())
+}
+
 // serveHLSManifest serves a generated HLS playlist. The URLs for the segments
-// are of the form {r.URL}/%d.ts{?urlQuery} where %d is the segment index.
-func serveHLSManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request, vf *models.VideoFile, resolution string) {
+// are of the form {r.URL}/{segment}{?urlQuery} where segment is the segment filename.
+// If resolution is empty, then a master playlist of all renditions is served.
+// If audioTrack is not nil, then the segments include the given audio stream.
+func serveHLSManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request, streamType *StreamType, vf *models.VideoFile, resolution string, audioTrack *int) {
 	if sm.cacheDir == "" {
 		logger.Error("[transcode] cannot live transcode with HLS because cache dir is unset")
 		http.Error(w, "cannot live transcode with HLS because cache dir is unset", http.StatusServiceUnavailable)
 		return
 	}
 
+	if resolution == "" {
+		serveHLSMasterManifest(sm, w, r, streamType, vf, audioTrack)
+		return
+	}
+
 	probeResult, err := sm.ffprobe.NewVideoFile(vf.Path)
 	if err != nil {
 		logger.Warnf("[transcode] error generating HLS manifest: %v", err)
@@ -422,20 +731,28 @@ func serveHLSManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request,
 	baseUrl.RawQuery = ""
 	baseURL := baseUrl.String()
 
-	var urlQuery string
-	if resolution != "" {
-		urlQuery = fmt.Sprintf("?resolution=%s", resolution)
-	}
+	urlQuery := streamQuery(resolution, audioTrack)
+
+	segmentType := streamType.SegmentType
 
 	var buf bytes.Buffer
 
 	fmt.Fprint(&buf, "#EXTM3U\n")
 
-	fmt.Fprint(&buf, "#EXT-X-VERSION:3\n")
+	if segmentType.InitSegment {
+		// fragmented mp4 segments require version 7
+		fmt.Fprint(&buf, "#EXT-X-VERSION:7\n")
+	} else {
+		fmt.Fprint(&buf, "#EXT-X-VERSION:3\n")
+	}
 	fmt.Fprint(&buf, "#EXT-X-MEDIA-SEQUENCE:0\n")
 	fmt.Fprintf(&buf, "#EXT-X-TARGETDURATION:%d\n", segmentLength)
 	fmt.Fprint(&buf, "#EXT-X-PLAYLIST-TYPE:VOD\n")
 
+	if segmentType.InitSegment {
+		fmt.Fprintf(&buf, "#EXT-X-MAP:URI=\"%s/%s%s\"\n", baseURL, segmentType.MakeFilename(-1), urlQuery)
+	}
+
 	leftover := probeResult.FileDuration
 	segment := 0
 
@@ -446,7 +763,7 @@ func serveHLSManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request,
 		}
 
 		fmt.Fprintf(&buf, "#EXTINF:%f,\n", thisLength)
-		fmt.Fprintf(&buf, "%s/%d.ts%s\n", baseURL, segment, urlQuery)
+		fmt.Fprintf(&buf, "%s/%s%s\n", baseURL, segmentType.MakeFilename(segment), urlQuery)
 
 		leftover -= thisLength
 		segment++
@@ -459,7 +776,10 @@ func serveHLSManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request,
 }
 
 // serveDASHManifest serves a generated DASH manifest.
-func serveDASHManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request, vf *models.VideoFile, resolution string) {
+// If resolution is empty, then a representation is included for each rendition.
+// If audioTrack is not nil, then the audio segments contain the given audio
+// stream. WebM video segments contain no audio, so are shared by all audio tracks.
+func serveDASHManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request, streamType *StreamType, vf *models.VideoFile, resolution string, audioTrack *int) {
 	if sm.cacheDir == "" {
 		logger.Error("[transcode] cannot live transcode with DASH because cache dir is unset")
 		http.Error(w, "cannot live transcode files with DASH because cache dir is unset", http.StatusServiceUnavailable)
@@ -474,13 +794,15 @@ func serveDASHManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request
 	}
 
 	var framerate string
-	var videoWidth int
-	var videoHeight int
+	videoWidth := vf.Width
+	videoHeight := vf.Height
 	videoStream := probeResult.VideoStream
 	if videoStream != nil {
 		framerate = videoStream.AvgFrameRate
-		videoWidth = videoStream.Width
-		videoHeight = videoStream.Height
+		if videoStream.Width > 0 && videoStream.Height > 0 {
+			videoWidth = videoStream.Width
+			videoHeight = videoStream.Height
+		}
 	} else {
 		// extract the framerate fraction from the file framerate
 		// framerates 0.1% below round numbers are common,
@@ -501,27 +823,20 @@ func serveDASHManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request
 			denominator = 1000
 		}
 		framerate = fmt.Sprintf("%d/%d", numerator, denominator)
-		videoHeight = vf.Height
-		videoWidth = vf.Width
 	}
 
-	var urlQuery string
-	maxTranscodeSize := sm.config.GetMaxStreamingTranscodeSize().GetMaxResolution()
+	// serve a representation for each rendition if no resolution is requested
+	var renditions []streamRendition
 	if resolution != "" {
-		maxTranscodeSize = models.StreamingResolutionEnum(resolution).GetMaxResolution()
-		urlQuery = fmt.Sprintf("?resolution=%s", resolution)
-	}
-	if maxTranscodeSize != 0 {
-		videoSize := videoHeight
-		if videoWidth < videoSize {
-			videoSize = videoWidth
-		}
-
-		if maxTranscodeSize < videoSize {
-			scaleFactor := float64(maxTranscodeSize) / float64(videoSize)
-			videoWidth = int(float64(videoWidth) * scaleFactor)
-			videoHeight = int(float64(videoHeight) * scaleFactor)
-		}
+		maxTranscodeSize := models.StreamingResolutionEnum(resolution).GetMaxResolution()
+		width, height := scaleDimensions(videoWidth, videoHeight, maxTranscodeSize)
+		renditions = []streamRendition{{
+			resolution: models.StreamingResolutionEnum(resolution),
+			width:      width,
+			height:     height,
+		}}
+	} else {
+		renditions = sm.streamRenditions(videoWidth, videoHeight)
 	}
 
 	mediaDuration := mpd.Duration(time.Duration(probeResult.FileDuration * float64(time.Second)))
@@ -531,15 +846,48 @@ func serveDASHManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request
 	baseUrl.RawQuery = ""
 	m.BaseURL = baseUrl.String()
 
-	video, _ := m.AddNewAdaptationSetVideo(MimeWebmVideo, "progressive", true, 1)
+	audio := ProbeAudioCodec(vf.AudioCodec) != MissingUnsupported
 
-	_, _ = video.SetNewSegmentTemplate(2, "init_v.webm"+urlQuery, "$Number$_v.webm"+urlQuery, 0, 1)
-	_, _ = video.AddNewRepresentationVideo(200000, "vp09.00.40.08", "0", framerate, int64(videoWidth), int64(videoHeight))
+	if streamType.SegmentType == SegmentTypeFMP4 {
+		// fragmented mp4 segments contain both video and audio,
+		// so a single adaptation set is used
+		codecs := streamType.Codecs
+		if audio {
+			codecs += ",mp4a.40.2"
+		}
+
+		// representation IDs are the rendition resolutions
+		query := "?resolution=$RepresentationID$"
+		if audioTrack != nil {
+			query += fmt.Sprintf("&audio_track=%d", *audioTrack)
+		}
+
+		video, _ := m.AddNewAdaptationSetVideo(MimeMp4Video, "progressive", true, 1)
+		_, _ = video.SetNewSegmentTemplate(segmentLength, "init.mp4"+query, "$Number$.m4s"+query, 0, 1)
+		for _, rendition := range renditions {
+			bandwidth := rendition.bandwidth(vf.FrameRate)
+			if audio {
+				bandwidth += transcodeAudioBitrate
+			}
+			_, _ = video.AddNewRepresentationVideo(int64(bandwidth), codecs, rendition.resolution.String(), framerate, int64(rendition.width), int64(rendition.height))
+		}
+	} else {
+		video, _ := m.AddNewAdaptationSetVideo(MimeWebmVideo, "progressive", true, 1)
+
+		// representation IDs are the rendition resolutions
+		videoQuery := "?resolution=$RepresentationID$"
+		_, _ = video.SetNewSegmentTemplate(2, "init_v.webm"+videoQuery, "$Number$_v.webm"+videoQuery, 0, 1)
+		for _, rendition := range renditions {
+			bandwidth := rendition.bandwidth(vf.FrameRate)
+			_, _ = video.AddNewRepresentationVideo(int64(bandwidth), streamType.Codecs, rendition.resolution.String(), framerate, int64(rendition.width), int64(rendition.height))
+		}
 
-	if ProbeAudioCodec(vf.AudioCodec) != MissingUnsupported {
-		audio, _ := m.AddNewAdaptationSetAudio(MimeWebmAudio, true, 1, "und")
-		_, _ = audio.SetNewSegmentTemplate(2, "init_a.webm"+urlQuery, "$Number$_a.webm"+urlQuery, 0, 1)
-		_, _ = audio.AddNewRepresentationAudio(48000, 96000, "opus", "1")
+		if audio {
+			audioQuery := streamQuery(resolution, audioTrack)
+			audioSet, _ := m.AddNewAdaptationSetAudio(MimeWebmAudio, true, 1, "und")
+			_, _ = audioSet.SetNewSegmentTemplate(2, "init_a.webm"+audioQuery, "$Number$_a.webm"+audioQuery, 0, 1)
+			_, _ = audioSet.AddNewRepresentationAudio(48000, 96000, "opus", "1")
+		}
 	}
 
 	var buf bytes.Buffer
@@ -549,8 +897,8 @@ func serveDASHManifest(sm *StreamManager, w http.ResponseWriter, r *http.Request
 	utils.ServeStaticContent(w, r, buf.Bytes())
 }
 
-func (sm *StreamManager) ServeManifest(w http.ResponseWriter, r *http.Request, streamType *StreamType, vf *models.VideoFile, resolution string) {
-	streamType.ServeManifest(sm, w, r, vf, resolution)
+func (sm *StreamManager) ServeManifest(w http.ResponseWriter, r *http.Request, streamType *StreamType, vf *models.VideoFile, resolution string, audioTrack *int) {
+	streamType.ServeManifest(sm, w, r, streamType, vf, resolution, audioTrack)
 }
 
 func (sm *StreamManager) serveWaitingSegment(w http.ResponseWriter, r *http.Request, segment *waitingSegment) {
@@ -562,6 +910,8 @@ func (sm *StreamManager) serveWaitingSegment(w http.ResponseWriter, r *http.Requ
 			logger.Tracef("[transcode] streaming segment file %s", segment.file)
 			w.Header().Set("Content-Type", segment.segmentType.MimeType)
 			utils.ServeStaticFile(w, r, segment.path)
+		} else if errors.Is(err, ErrTranscodeLimit) {
+			writeTranscodeLimitError(w)
 		} else if !errors.Is(err, context.Canceled) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 		}
@@ -595,31 +945,66 @@ func (sm *StreamManager) ServeSegment(w http.ResponseWriter, r *http.Request, op
 		maxTranscodeSize = models.StreamingResolutionEnum(options.Resolution).GetMaxResolution()
 	}
 
-	dir := options.StreamType.FileDir(options.Hash, maxTranscodeSize)
+	audioTrack := options.AudioTrack
+	if streamType.SegmentType == SegmentTypeWEBMVideo {
+		// WebM video segments contain no audio, so are shared by all audio tracks
+		audioTrack = nil
+	}
+
+	var loudnessGain *float64
+	if audioTrack == nil {
+		if gain, ok := sm.loudnessGain(options.VideoFile); ok {
+			loudnessGain = &gain
+		}
+	}
+
+	dir := options.StreamType.FileDir(options.Hash, maxTranscodeSize, audioTrack, loudnessGain != nil)
 	outputDir := filepath.Join(sm.cacheDir, dir)
 
 	name := streamType.SegmentType.MakeFilename(segment)
 	file := filepath.Join(dir, name)
 
+	// reject the segment requests of a stream stopped by StopSession,
+	// otherwise the player restarts the transcode
+	sm.streamsMutex.Lock()
+	stopped := sm.isStreamStopped(dir, clientIP(r), time.Now())
+	sm.streamsMutex.Unlock()
+	if stopped {
+		http.Error(w, ErrStreamStopped.Error(), http.StatusGone)
+		return
+	}
+
+	// probe hardware decoding before taking the lock, since the transcode
+	// is started while it is held. Concurrent requests wait for a single probe,
+	// and later requests use the cached result.
+	sm.encoder.ProbeHWDecode(sm.context, HLSGetCodec(sm, streamType.Name), options.VideoFile.Path, options.VideoFile.VideoCodec)
+
 	sm.streamsMutex.Lock()
 
+	now := time.Now()
+
 	stream := sm.runningStreams[dir]
 	if stream == nil {
 		stream = &runningStream{
 			dir:              dir,
+			hash:             options.Hash,
 			streamType:       options.StreamType,
 			vf:               options.VideoFile,
 			maxTranscodeSize: maxTranscodeSize,
+			audioTrack:       audioTrack,
+			loudnessGain:     loudnessGain,
 			outputDir:        outputDir,
+			started:          now,
+			clientIP:         clientIP(r),
 
 			// initialize to cap 10 to avoid reallocations
 			waitingSegments: make([]*waitingSegment, 0, 10),
 		}
-		sm.runningStreams[dir] = stream
+		sm.addStream(stream)
 	}
 
-	now := time.Now()
 	stream.lastAccessed = now
+	stream.clientIP = clientIP(r)
 	if segment != -1 {
 		stream.lastSegment = segment
 	}
@@ -654,9 +1039,23 @@ func (sm *StreamManager) startTranscode(stream *runningStream, segment int, done
 		return
 	}
 
+	codec, err := sm.acquireTranscodeSlot(HLSGetCodec(sm, stream.streamType.Name), hlsGetCodec(sm, stream.streamType.Name, false))
+	if err != nil {
+		logger.Warnf("[transcode] cannot start transcode for %s: %v", stream.dir, err)
+		// don't block if the previous error has not been consumed
+		select {
+		case done <- err:
+		default:
+		}
+		return
+	}
+
 	lockCtx := sm.lockManager.ReadLock(sm.context, stream.vf.Path)
 
-	args := stream.makeStreamArgs(sm, segment)
+	// hardware decoding is probed in ServeSegment, as the lock is held
+	pipeline := sm.encoder.ProbedHWPipeline(codec, stream.vf.VideoCodec)
+
+	args := stream.makeStreamArgs(sm, pipeline, segment)
 	cmd := sm.encoder.Command(lockCtx, args)
 
 	stderr, err := cmd.StderrPipe()
@@ -672,6 +1071,7 @@ func (sm *StreamManager) startTranscode(stream *runningStream, segment int, done
 	logger.Tracef("[transcode] running %s", cmd)
 	if err := cmd.Start(); err != nil {
 		lockCtx.Cancel()
+		sm.releaseTranscodeSlot(codec)
 		err = fmt.Errorf("error starting transcode process: %w", err)
 		logger.Errorf("[transcode] %v", err)
 		done <- err
@@ -687,6 +1087,7 @@ func (sm *StreamManager) startTranscode(stream *runningStream, segment int, done
 		segment:     segment,
 	}
 	stream.tp = tp
+	stream.codec = pipeline.Codec
 
 	go func() {
 		errStr, _ := io.ReadAll(stderr)
@@ -723,6 +1124,8 @@ func (sm *StreamManager) startTranscode(stream *runningStream, segment int, done
 		// make sure that cancel is called to prevent memory leaks
 		tp.cancel()
 
+		sm.releaseTranscodeSlot(codec)
+
 		// clear remaining segments after ffmpeg exit
 		tp.checkSegments()
 
@@ -749,13 +1152,23 @@ func (sm *StreamManager) stopTranscode(stream *runningStream) {
 
 func (sm *StreamManager) checkTranscode(stream *runningStream, now time.Time) {
 	if len(stream.waitingSegments) == 0 && stream.lastAccessed.Add(maxIdleTime).Before(now) {
-		// Stream expired. Cancel the transcode process and delete the files
-		logger.Debugf("[transcode] stream for %s not accessed recently. Cancelling transcode and removing files", stream.dir)
-
 		sm.stopTranscode(stream)
-		sm.removeTranscodeFiles(stream)
 
-		delete(sm.runningStreams, stream.dir)
+		if sm.cacheEnabled() {
+			// Stream expired. Cancel the transcode process and keep the files for reuse.
+			// The directory modification time is used to evict the least recently
+			// accessed streams from the cache.
+			logger.Debugf("[transcode] stream for %s not accessed recently. Cancelling transcode", stream.dir)
+			if err := os.Chtimes(stream.outputDir, stream.lastAccessed, stream.lastAccessed); err != nil && !os.IsNotExist(err) {
+				logger.Warnf("[transcode] error updating access time of %s: %v", stream.outputDir, err)
+			}
+		} else {
+			// Stream expired. Cancel the transcode process and delete the files
+			logger.Debugf("[transcode] stream for %s not accessed recently. Cancelling transcode and removing files", stream.dir)
+			sm.removeTranscodeFiles(stream)
+		}
+
+		sm.removeStream(stream)
 		return
 	}
 
@@ -811,6 +1224,8 @@ func (sm *StreamManager) monitorStreams() {
 
 	now := time.Now()
 
+	sm.removeExpiredStoppedStreams(now)
+
 	for _, stream := range sm.runningStreams {
 		if stream.tp != nil {
 			stream.tp.checkSegments()
@@ -845,19 +1260,21 @@ func (sm *StreamManager) removeTranscodeFiles(stream *runningStream) {
 	}
 }
 
-// stopAndRemoveAll stops all current streams and removes all cache files
+// stopAndRemoveAll stops all current streams and removes all cache files.
+// Cache files are kept if the transcode cache is enabled.
 func (sm *StreamManager) stopAndRemoveAll() {
 	sm.streamsMutex.Lock()
 	defer sm.streamsMutex.Unlock()
 
+	keepFiles := sm.cacheEnabled()
+
 	for _, stream := range sm.runningStreams {
-		for _, segment := range stream.waitingSegments {
-			if len(segment.available) == 0 {
-				segment.available <- context.Canceled
-			}
-		}
+		cancelWaitingSegments(stream)
 		sm.stopTranscode(stream)
-		sm.removeTranscodeFiles(stream)
+		if !keepFiles {
+			sm.removeTranscodeFiles(stream)
+		}
+		sm.sessionSubs.notify(StreamSessionEventStop, stream.session())
 	}
 
 	// ensure nothing else can use the map
@@ -0,0 +1,216 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamType_FileDir(t *testing.T) {
+	const hash = "abc"
+	audioTrack := 1
+
+	tests := []struct {
+		name             string
+		streamType       *StreamType
+		maxTranscodeSize int
+		audioTrack       *int
+		normalised       bool
+		want             string
+	}{
+		{
+			"original",
+			StreamTypeHLS,
+			0,
+			nil,
+			false,
+			"abc_hls",
+		},
+		{
+			"max transcode size",
+			StreamTypeHLS,
+			720,
+			nil,
+			false,
+			"abc_hls_720",
+		},
+		{
+			"audio track",
+			StreamTypeHLS,
+			720,
+			&audioTrack,
+			false,
+			"abc_hls_720_a1",
+		},
+		{
+			"hls cmaf",
+			StreamTypeHLSCMAF,
+			720,
+			nil,
+			false,
+			"abc_cmaf_720",
+		},
+		{
+			"dash cmaf shares hls cmaf segments",
+			StreamTypeDASHCMAF,
+			720,
+			nil,
+			false,
+			"abc_cmaf_720",
+		},
+		{
+			"normalised",
+			StreamTypeHLS,
+			720,
+			nil,
+			true,
+			"abc_hls_720_n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.streamType.FileDir(hash, tt.maxTranscodeSize, tt.audioTrack, tt.normalised)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStreamQuery(t *testing.T) {
+	audioTrack := 2
+
+	tests := []struct {
+		name       string
+		resolution string
+		audioTrack *int
+		want       string
+	}{
+		{
+			"none",
+			"",
+			nil,
+			"",
+		},
+		{
+			"resolution",
+			"STANDARD_HD",
+			nil,
+			"?resolution=STANDARD_HD",
+		},
+		{
+			"resolution and audio track",
+			"STANDARD_HD",
+			&audioTrack,
+			"?audio_track=2&resolution=STANDARD_HD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := streamQuery(tt.resolution, tt.audioTrack)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestStreamManager_streamRenditions(t *testing.T) {
+	sm := &StreamManager{
+		config: testStreamManagerConfig{},
+	}
+
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		want   []streamRendition
+	}{
+		{
+			"smaller resolutions and original",
+			1280,
+			720,
+			[]streamRendition{
+				{models.StreamingResolutionEnumLow, 426, 240},
+				{models.StreamingResolutionEnumStandard, 853, 480},
+				{models.StreamingResolutionEnumOriginal, 1280, 720},
+			},
+		},
+		{
+			"unknown dimensions",
+			0,
+			0,
+			[]streamRendition{
+				{models.StreamingResolutionEnumOriginal, 0, 0},
+			},
+		},
+		{
+			"unknown height",
+			1280,
+			0,
+			[]streamRendition{
+				{models.StreamingResolutionEnumOriginal, 0, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sm.streamRenditions(tt.width, tt.height)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// mappedStreams returns the values of the -map arguments of args.
+func mappedStreams(args Args) []string {
+	var ret []string
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == "-map" {
+			ret = append(ret, args[i+1])
+		}
+	}
+	return ret
+}
+
+func TestRunningStream_makeStreamArgs(t *testing.T) {
+	sm := &StreamManager{
+		encoder: &FFMpeg{},
+		config:  testStreamManagerConfig{},
+	}
+
+	vf := &models.VideoFile{
+		BaseFile:   &models.BaseFile{Path: "video.mkv"},
+		AudioCodec: string(Aac),
+		Width:      1280,
+		Height:     720,
+	}
+
+	audioTrack := 2
+
+	tests := []struct {
+		name       string
+		streamType *StreamType
+		audioTrack *int
+		want       []string
+	}{
+		{"hls default audio", StreamTypeHLS, nil, nil},
+		{"hls audio track", StreamTypeHLS, &audioTrack, []string{"0:v:0", "0:a:2"}},
+		{"webm video", StreamTypeDASHVideo, &audioTrack, []string{"0:v:0"}},
+		{"webm default audio", StreamTypeDASHAudio, nil, []string{"0:a:0"}},
+		{"webm audio track", StreamTypeDASHAudio, &audioTrack, []string{"0:a:2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &runningStream{
+				streamType: tt.streamType,
+				vf:         vf,
+				audioTrack: tt.audioTrack,
+				outputDir:  t.TempDir(),
+			}
+
+			got := s.makeStreamArgs(sm, HWPipeline{Codec: hlsGetCodec(sm, tt.streamType.Name, false)}, 0)
+			assert.Equal(t, tt.want, mappedStreams(got))
+		})
+	}
+}
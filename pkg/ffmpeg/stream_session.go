@@ -0,0 +1,272 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+// stoppedStreamTTL is how long segment requests of the client of a stopped
+// segmented stream are rejected, so that the player cannot restart the transcode.
+const stoppedStreamTTL = 30 * time.Second
+
+var ErrStreamStopped = errors.New("stream was stopped")
+
+// StreamSession describes an active live transcode.
+type StreamSession struct {
+	ID     string
+	Hash   string
+	FileID models.FileID
+	// StreamType is the name of the stream type, eg hls, dash-v or mp4.
+	StreamType string
+	// Resolution is the maximum transcode size of the stream. 0 if the original resolution.
+	Resolution int
+	AudioTrack *int
+	// Segment is the most recently requested segment. nil for non-segmented streams.
+	Segment *int
+	// PID is the process ID of the running ffmpeg process. 0 if no process is running.
+	PID int
+	// Codec is the video codec of the most recent ffmpeg process. Empty if no process has been started.
+	Codec    VideoCodec
+	Hardware bool
+	ClientIP string
+	Started  time.Time
+}
+
+type StreamSessionEventType string
+
+const (
+	StreamSessionEventStart StreamSessionEventType = "start"
+	StreamSessionEventStop  StreamSessionEventType = "stop"
+)
+
+type StreamSessionEvent struct {
+	Type    StreamSessionEventType
+	Session StreamSession
+}
+
+// maximum number of unread events per subscriber before events are dropped
+const sessionSubscriptionBuffer = 100
+
+type sessionSubscriptions struct {
+	subscriptions []chan StreamSessionEvent
+	mutex         sync.Mutex
+}
+
+func (s *sessionSubscriptions) subscribe(ctx context.Context) <-chan StreamSessionEvent {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	c := make(chan StreamSessionEvent, sessionSubscriptionBuffer)
+	s.subscriptions = append(s.subscriptions, c)
+
+	go func() {
+		<-ctx.Done()
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		close(c)
+
+		for i, sub := range s.subscriptions {
+			if sub == c {
+				s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+				break
+			}
+		}
+	}()
+
+	return c
+}
+
+func (s *sessionSubscriptions) notify(t StreamSessionEventType, session StreamSession) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	e := StreamSessionEvent{
+		Type:    t,
+		Session: session,
+	}
+
+	for _, sub := range s.subscriptions {
+		// never block the stream manager on a slow subscriber
+		select {
+		case sub <- e:
+		default:
+		}
+	}
+}
+
+// runningTranscode is a non-segmented transcode, piped directly to the client.
+type runningTranscode struct {
+	id               string
+	options          TranscodeOptions
+	maxTranscodeSize int
+	pid              int
+	cancel           func()
+	codec            VideoCodec
+	clientIP         string
+	started          time.Time
+}
+
+func (t *runningTranscode) session() StreamSession {
+	return StreamSession{
+		ID:         t.id,
+		Hash:       t.options.Hash,
+		FileID:     t.options.VideoFile.ID,
+		StreamType: t.options.StreamType.Name,
+		Resolution: t.maxTranscodeSize,
+		AudioTrack: t.options.AudioTrack,
+		PID:        t.pid,
+		Codec:      t.codec,
+		Hardware:   IsHWCodec(t.codec),
+		ClientIP:   t.clientIP,
+		Started:    t.started,
+	}
+}
+
+// assume lock is held
+func (s *runningStream) session() StreamSession {
+	segment := s.lastSegment
+
+	ret := StreamSession{
+		ID:         s.dir,
+		Hash:       s.hash,
+		FileID:     s.vf.ID,
+		StreamType: s.streamType.Name,
+		Resolution: s.maxTranscodeSize,
+		AudioTrack: s.audioTrack,
+		Segment:    &segment,
+		Codec:      s.codec,
+		Hardware:   IsHWCodec(s.codec),
+		ClientIP:   s.clientIP,
+		Started:    s.started,
+	}
+
+	if s.tp != nil && s.tp.cmd.Process != nil {
+		ret.PID = s.tp.cmd.Process.Pid
+	}
+
+	return ret
+}
+
+// clientIP returns the IP address of the client of r.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// Sessions returns the active live transcodes, ordered by start time.
+func (sm *StreamManager) Sessions() []StreamSession {
+	sm.streamsMutex.Lock()
+	defer sm.streamsMutex.Unlock()
+
+	var ret []StreamSession
+	for _, stream := range sm.runningStreams {
+		ret = append(ret, stream.session())
+	}
+	for _, t := range sm.runningTranscodes {
+		ret = append(ret, t.session())
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Started.Before(ret[j].Started)
+	})
+
+	return ret
+}
+
+// SubscribeSessions returns a channel which receives an event whenever a
+// live transcode is started or stopped. The channel is closed when ctx is done.
+func (sm *StreamManager) SubscribeSessions(ctx context.Context) <-chan StreamSessionEvent {
+	return sm.sessionSubs.subscribe(ctx)
+}
+
+// StopSession stops the live transcode with the given ID.
+// Returns false if no such transcode is running.
+func (sm *StreamManager) StopSession(id string) bool {
+	sm.streamsMutex.Lock()
+
+	if stream := sm.runningStreams[id]; stream != nil {
+		sm.stoppedStreams[stoppedStreamKey(stream.dir, stream.clientIP)] = time.Now().Add(stoppedStreamTTL)
+		cancelWaitingSegments(stream)
+		sm.stopTranscode(stream)
+		if !sm.cacheEnabled() {
+			sm.removeTranscodeFiles(stream)
+		}
+		sm.removeStream(stream)
+		sm.streamsMutex.Unlock()
+		return true
+	}
+
+	t := sm.runningTranscodes[id]
+	sm.streamsMutex.Unlock()
+
+	if t == nil {
+		return false
+	}
+
+	// the transcode is removed once the process exits
+	t.cancel()
+	return true
+}
+
+func stoppedStreamKey(dir string, clientIP string) string {
+	return dir + "|" + clientIP
+}
+
+// assume lock is held
+func (sm *StreamManager) isStreamStopped(dir string, clientIP string, now time.Time) bool {
+	until, found := sm.stoppedStreams[stoppedStreamKey(dir, clientIP)]
+	return found && now.Before(until)
+}
+
+// assume lock is held
+func (sm *StreamManager) removeExpiredStoppedStreams(now time.Time) {
+	for key, until := range sm.stoppedStreams {
+		if !now.Before(until) {
+			delete(sm.stoppedStreams, key)
+		}
+	}
+}
+
+// assume lock is held
+func (sm *StreamManager) addStream(stream *runningStream) {
+	sm.runningStreams[stream.dir] = stream
+	sm.sessionSubs.notify(StreamSessionEventStart, stream.session())
+}
+
+// assume lock is held
+func (sm *StreamManager) removeStream(stream *runningStream) {
+	delete(sm.runningStreams, stream.dir)
+	sm.sessionSubs.notify(StreamSessionEventStop, stream.session())
+}
+
+func (sm *StreamManager) addTranscode(t *runningTranscode) {
+	sm.streamsMutex.Lock()
+	defer sm.streamsMutex.Unlock()
+
+	sm.transcodeCount++
+	t.id = "transcode-" + strconv.Itoa(sm.transcodeCount)
+	t.started = time.Now()
+
+	sm.runningTranscodes[t.id] = t
+	sm.sessionSubs.notify(StreamSessionEventStart, t.session())
+}
+
+func (sm *StreamManager) removeTranscode(t *runningTranscode) {
+	sm.streamsMutex.Lock()
+	defer sm.streamsMutex.Unlock()
+
+	delete(sm.runningTranscodes, t.id)
+	sm.releaseTranscodeSlot(t.codec)
+	sm.sessionSubs.notify(StreamSessionEventStop, t.session())
+}
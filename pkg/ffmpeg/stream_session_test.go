@@ -0,0 +1,128 @@
+package ffmpeg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamManager_Sessions(t *testing.T) {
+	sm := &StreamManager{
+		cacheDir:          t.TempDir(),
+		config:            testStreamManagerConfig{},
+		runningStreams:    make(map[string]*runningStream),
+		runningTranscodes: make(map[string]*runningTranscode),
+		stoppedStreams:    make(map[string]time.Time),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := sm.SubscribeSessions(ctx)
+
+	started := time.Now()
+	stream := &runningStream{
+		dir:              "abc_hls_720",
+		hash:             "abc",
+		streamType:       StreamTypeHLS,
+		vf:               &models.VideoFile{BaseFile: &models.BaseFile{ID: 1}},
+		maxTranscodeSize: 720,
+		outputDir:        t.TempDir(),
+		started:          started,
+		lastSegment:      3,
+		codec:            VideoCodecN264,
+		clientIP:         "192.168.1.2",
+	}
+
+	sm.streamsMutex.Lock()
+	sm.addStream(stream)
+	sm.streamsMutex.Unlock()
+
+	segment := 3
+	want := StreamSession{
+		ID:         "abc_hls_720",
+		Hash:       "abc",
+		FileID:     1,
+		StreamType: "hls",
+		Resolution: 720,
+		Segment:    &segment,
+		Codec:      VideoCodecN264,
+		Hardware:   true,
+		ClientIP:   "192.168.1.2",
+		Started:    started,
+	}
+
+	assert.Equal(t, StreamSessionEvent{Type: StreamSessionEventStart, Session: want}, <-events)
+	assert.Equal(t, []StreamSession{want}, sm.Sessions())
+
+	assert.False(t, sm.StopSession("unknown"))
+	assert.True(t, sm.StopSession("abc_hls_720"))
+
+	assert.Equal(t, StreamSessionEvent{Type: StreamSessionEventStop, Session: want}, <-events)
+	assert.Empty(t, sm.Sessions())
+}
+
+func TestStreamManager_StopSessionSegment(t *testing.T) {
+	sm := &StreamManager{
+		cacheDir:          t.TempDir(),
+		config:            testStreamManagerConfig{},
+		runningStreams:    make(map[string]*runningStream),
+		runningTranscodes: make(map[string]*runningTranscode),
+		stoppedStreams:    make(map[string]time.Time),
+	}
+
+	vf := &models.VideoFile{
+		BaseFile: &models.BaseFile{ID: 1},
+		Duration: 60,
+	}
+
+	stream := &runningStream{
+		dir:              "abc_hls_720",
+		hash:             "abc",
+		streamType:       StreamTypeHLS,
+		vf:               vf,
+		maxTranscodeSize: 720,
+		outputDir:        t.TempDir(),
+		started:          time.Now(),
+		clientIP:         "192.168.1.2",
+	}
+
+	sm.streamsMutex.Lock()
+	sm.addStream(stream)
+	sm.streamsMutex.Unlock()
+
+	assert.True(t, sm.StopSession("abc_hls_720"))
+
+	r := httptest.NewRequest(http.MethodGet, "/scene/1/stream.m3u8/4.ts", nil)
+	r.RemoteAddr = "192.168.1.2:1234"
+	w := httptest.NewRecorder()
+
+	sm.ServeSegment(w, r, StreamOptions{
+		StreamType: StreamTypeHLS,
+		VideoFile:  vf,
+		Resolution: string(models.StreamingResolutionEnumStandardHd),
+		Hash:       "abc",
+		Segment:    "4",
+	})
+
+	// the segment request must not restart the transcode
+	assert.Equal(t, http.StatusGone, w.Code)
+	assert.Empty(t, sm.Sessions())
+
+	now := time.Now()
+	sm.streamsMutex.Lock()
+	assert.False(t, sm.isStreamStopped("abc_hls_720", "192.168.1.3", now))
+	assert.False(t, sm.isStreamStopped("abc_hls_480", "192.168.1.2", now))
+
+	// requests are accepted again once the stop expires
+	expired := now.Add(stoppedStreamTTL)
+	assert.False(t, sm.isStreamStopped("abc_hls_720", "192.168.1.2", expired))
+	sm.removeExpiredStoppedStreams(expired)
+	assert.Empty(t, sm.stoppedStreams)
+	sm.streamsMutex.Unlock()
+}
@@ -14,13 +14,23 @@ import (
 )
 
 type StreamFormat struct {
+	Name     string
 	MimeType string
-	Args     func(codec VideoCodec, videoFilter VideoFilter, videoOnly bool) Args
+	// Remux is true if the video stream is copied rather than transcoded.
+	// The audio stream is only transcoded if it is not valid for the container.
+	Remux bool
+	Args  func(codec VideoCodec, videoFilter VideoFilter, videoOnly bool) Args
 }
 
 func CodecInit(codec VideoCodec) (args Args) {
 	args = args.VideoCodec(codec)
+	args = append(args, CodecOptions(codec)...)
 
+	return args
+}
+
+// CodecOptions returns the encoder options used when transcoding with codec.
+func CodecOptions(codec VideoCodec) (args Args) {
 	switch codec {
 	// CPU Codecs
 	case VideoCodecLibX264:
@@ -39,6 +49,19 @@ func CodecInit(codec VideoCodec) (args Args) {
 			"-crf", "30",
 			"-b:v", "0",
 		)
+	case VideoCodecLibX265:
+		args = append(args,
+			"-pix_fmt", "yuv420p",
+			"-preset", "veryfast",
+			"-crf", "28",
+			"-x265-params", "log-level=error",
+		)
+	case VideoCodecSVTAV1:
+		args = append(args,
+			"-pix_fmt", "yuv420p",
+			"-preset", "10",
+			"-crf", "35",
+		)
 	// HW Codecs
 	case VideoCodecN264:
 		args = append(args,
@@ -76,6 +99,35 @@ func CodecInit(codec VideoCodec) (args Args) {
 		args = append(args,
 			"-qp", "20",
 		)
+	case VideoCodecN265:
+		args = append(args,
+			"-rc", "vbr",
+			"-cq", "20",
+		)
+	case VideoCodecI265:
+		args = append(args,
+			"-global_quality", "22",
+			"-preset", "faster",
+		)
+	case VideoCodecV265:
+		args = append(args,
+			"-qp", "22",
+		)
+	case VideoCodecNAV1:
+		args = append(args,
+			"-rc", "vbr",
+			"-cq", "30",
+		)
+	case VideoCodecIAV1:
+		args = append(args,
+			"-global_quality", "30",
+			"-preset", "faster",
+		)
+	case VideoCodecVAV1:
+		args = append(args,
+			"-rc_mode", "CQP",
+			"-global_quality", "120",
+		)
 	}
 
 	return args
@@ -83,6 +135,7 @@ func CodecInit(codec VideoCodec) (args Args) {
 
 var (
 	StreamTypeMP4 = StreamFormat{
+		Name:     "mp4",
 		MimeType: MimeMp4Video,
 		Args: func(codec VideoCodec, videoFilter VideoFilter, videoOnly bool) (args Args) {
 			args = CodecInit(codec)
@@ -98,6 +151,7 @@ var (
 		},
 	}
 	StreamTypeWEBM = StreamFormat{
+		Name:     "webm",
 		MimeType: MimeWebmVideo,
 		Args: func(codec VideoCodec, videoFilter VideoFilter, videoOnly bool) (args Args) {
 			args = CodecInit(codec)
@@ -111,7 +165,40 @@ var (
 			return
 		},
 	}
+	// StreamTypeRemux copies the video stream into a fragmented mp4.
+	// The audio arguments are added in makeStreamArgs.
+	StreamTypeRemux = StreamFormat{
+		Name:     "remux",
+		MimeType: MimeMp4Video,
+		Remux:    true,
+		Args: func(codec VideoCodec, videoFilter VideoFilter, videoOnly bool) (args Args) {
+			args = args.VideoCodec(VideoCodecCopy)
+			args = append(args, "-movflags", "frag_keyframe+empty_moov+default_base_moof")
+			if videoOnly {
+				args = args.SkipAudio()
+			}
+			args = args.Format(FormatMP4)
+			return
+		},
+	}
+	StreamTypeMPEGTS = StreamFormat{
+		Name:     "mpegts",
+		MimeType: MimeMpegVideo,
+		Args: func(codec VideoCodec, videoFilter VideoFilter, videoOnly bool) (args Args) {
+			args = CodecInit(codec)
+			args = args.VideoFilter(videoFilter)
+			if videoOnly {
+				args = args.SkipAudio()
+			} else {
+				args = args.AudioCodec(AudioCodecAAC)
+				args = append(args, "-ac", "2")
+			}
+			args = args.Format(FormatMpegTS)
+			return
+		},
+	}
 	StreamTypeMKV = StreamFormat{
+		Name:     "mkv",
 		MimeType: MimeMkvVideo,
 		Args: func(codec VideoCodec, videoFilter VideoFilter, videoOnly bool) (args Args) {
 			args = CodecInit(codec)
@@ -134,20 +221,31 @@ var (
 type TranscodeOptions struct {
 	StreamType StreamFormat
 	VideoFile  *models.VideoFile
+	// Hash is the hash of the scene, used to identify the transcode.
+	Hash       string
 	Resolution string
 	StartTime  float64
+	// AudioTrack is the index of the audio stream to include.
+	// If nil, ffmpeg selects the default audio stream.
+	AudioTrack *int
 }
 
 func FileGetCodec(sm *StreamManager, mimetype string) (codec VideoCodec) {
+	return fileGetCodec(sm, mimetype, sm.config.GetTranscodeHardwareAcceleration())
+}
+
+// fileGetCodec returns the codec for the given mime type.
+// A hardware codec is only returned if hardware is true.
+func fileGetCodec(sm *StreamManager, mimetype string, hardware bool) (codec VideoCodec) {
 	switch mimetype {
-	case MimeMp4Video:
+	case MimeMp4Video, MimeMpegVideo:
 		codec = VideoCodecLibX264
-		if hwcodec := sm.encoder.hwCodecMP4Compatible(); hwcodec != nil && sm.config.GetTranscodeHardwareAcceleration() {
+		if hwcodec := sm.encoder.HWCodecMP4Compatible(); hwcodec != nil && hardware {
 			codec = *hwcodec
 		}
 	case MimeWebmVideo:
 		codec = VideoCodecVP9
-		if hwcodec := sm.encoder.hwCodecWEBMCompatible(); hwcodec != nil && sm.config.GetTranscodeHardwareAcceleration() {
+		if hwcodec := sm.encoder.hwCodecWEBMCompatible(); hwcodec != nil && hardware {
 			codec = *hwcodec
 		}
 	case MimeMkvVideo:
@@ -157,20 +255,22 @@ func FileGetCodec(sm *StreamManager, mimetype string) (codec VideoCodec) {
 	return codec
 }
 
-func (o TranscodeOptions) makeStreamArgs(sm *StreamManager) Args {
-	maxTranscodeSize := sm.config.GetMaxStreamingTranscodeSize().GetMaxResolution()
+func (o TranscodeOptions) maxTranscodeSize(sm *StreamManager) int {
 	if o.Resolution != "" {
-		maxTranscodeSize = models.StreamingResolutionEnum(o.Resolution).GetMaxResolution()
+		return models.StreamingResolutionEnum(o.Resolution).GetMaxResolution()
 	}
+	return sm.config.GetMaxStreamingTranscodeSize().GetMaxResolution()
+}
+
+func (o TranscodeOptions) makeStreamArgs(sm *StreamManager, pipeline HWPipeline) Args {
+	maxTranscodeSize := o.maxTranscodeSize(sm)
 	extraInputArgs := sm.config.GetLiveTranscodeInputArgs()
 	extraOutputArgs := sm.config.GetLiveTranscodeOutputArgs()
 
 	args := Args{"-hide_banner"}
 	args = args.LogLevel(LogLevelError)
 
-	codec := FileGetCodec(sm, o.StreamType.MimeType)
-
-	args = sm.encoder.hwDeviceInit(args, codec)
+	args = pipeline.InputArgs(args)
 	args = append(args, extraInputArgs...)
 
 	if o.StartTime != 0 {
@@ -180,10 +280,25 @@ func (o TranscodeOptions) makeStreamArgs(sm *StreamManager) Args {
 	args = args.Input(o.VideoFile.Path)
 
 	videoOnly := ProbeAudioCodec(o.VideoFile.AudioCodec) == MissingUnsupported
+	if o.AudioTrack != nil && !videoOnly {
+		args = args.MapAudioTrack(*o.AudioTrack)
+	}
 
-	videoFilter := sm.encoder.hwMaxResFilter(codec, o.VideoFile.Width, o.VideoFile.Height, maxTranscodeSize)
+	// the loudness is analysed for the default audio track only
+	gain, normalise := sm.loudnessGain(o.VideoFile)
+	normalise = normalise && o.AudioTrack == nil && !videoOnly
 
-	args = append(args, o.StreamType.Args(codec, videoFilter, videoOnly)...)
+	if o.StreamType.Remux && !videoOnly {
+		args = append(args, o.remuxAudioArgs(normalise)...)
+	}
+
+	if normalise {
+		args = args.AudioFilter(VolumeFilter(gain))
+	}
+
+	videoFilter := sm.encoder.hwMaxResFilter(pipeline, o.VideoFile.Width, o.VideoFile.Height, maxTranscodeSize)
+
+	args = append(args, o.StreamType.Args(pipeline.Codec, videoFilter, videoOnly)...)
 
 	args = append(args, extraOutputArgs...)
 
@@ -192,6 +307,31 @@ func (o TranscodeOptions) makeStreamArgs(sm *StreamManager) Args {
 	return args
 }
 
+// getCodec returns the video codec of the transcode.
+// A hardware codec is only returned if hardware is true.
+func (o TranscodeOptions) getCodec(sm *StreamManager, hardware bool) VideoCodec {
+	if o.StreamType.Remux {
+		return VideoCodecCopy
+	}
+	return fileGetCodec(sm, o.StreamType.MimeType, hardware)
+}
+
+// remuxAudioArgs returns the audio arguments for a remux. The audio is
+// copied if valid for mp4, otherwise it is transcoded to aac. The codec of a
+// selected audio track is not known, so it is always transcoded. Normalised
+// audio is always transcoded to apply the gain.
+func (o TranscodeOptions) remuxAudioArgs(normalise bool) Args {
+	var args Args
+	if !normalise && o.AudioTrack == nil && IsValidAudioForContainer(ProbeAudioCodec(o.VideoFile.AudioCodec), Mp4) {
+		args = args.AudioCodec(AudioCodecCopy)
+	} else {
+		args = args.AudioCodec(AudioCodecAAC)
+		args = append(args, "-ac", "2")
+	}
+
+	return args
+}
+
 func (sm *StreamManager) ServeTranscode(w http.ResponseWriter, r *http.Request, options TranscodeOptions) {
 	streamRequestCtx := NewStreamRequestContext(w, r)
 	lockCtx := sm.lockManager.ReadLock(streamRequestCtx, options.VideoFile.Path)
@@ -200,7 +340,13 @@ func (sm *StreamManager) ServeTranscode(w http.ResponseWriter, r *http.Request,
 	// due to ERR_INCOMPLETE_CHUNKED_ENCODING
 	// We trust that the request context will be closed, so we don't need to call Cancel on the returned context here.
 
-	handler, err := sm.getTranscodeStream(lockCtx, options)
+	handler, err := sm.getTranscodeStream(lockCtx, options, clientIP(r))
+
+	if errors.Is(err, ErrTranscodeLimit) {
+		logger.Warnf("[transcode] cannot transcode video file: %v", err)
+		writeTranscodeLimitError(w)
+		return
+	}
 
 	if err != nil {
 		logger.Errorf("[transcode] error transcoding video file: %v", err)
@@ -214,32 +360,61 @@ func (sm *StreamManager) ServeTranscode(w http.ResponseWriter, r *http.Request,
 	handler(w, r)
 }
 
-func (sm *StreamManager) getTranscodeStream(ctx *fsutil.LockContext, options TranscodeOptions) (http.HandlerFunc, error) {
-	args := options.makeStreamArgs(sm)
+func (sm *StreamManager) getTranscodeStream(ctx *fsutil.LockContext, options TranscodeOptions, clientIP string) (http.HandlerFunc, error) {
+	sm.streamsMutex.Lock()
+	codec, err := sm.acquireTranscodeSlot(options.getCodec(sm, sm.config.GetTranscodeHardwareAcceleration()), options.getCodec(sm, false))
+	sm.streamsMutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	releaseSlot := func() {
+		sm.streamsMutex.Lock()
+		defer sm.streamsMutex.Unlock()
+		sm.releaseTranscodeSlot(codec)
+	}
+
+	pipeline := sm.encoder.NewHWPipeline(sm.context, codec, options.VideoFile.Path, options.VideoFile.VideoCodec)
+
+	args := options.makeStreamArgs(sm, pipeline)
 	cmd := sm.encoder.Command(ctx, args)
 
 	stdout, err := cmd.StdoutPipe()
 	if nil != err {
 		logger.Errorf("[transcode] ffmpeg stdout not available: %v", err)
+		releaseSlot()
 		return nil, err
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if nil != err {
 		logger.Errorf("[transcode] ffmpeg stderr not available: %v", err)
+		releaseSlot()
 		return nil, err
 	}
 
 	if err = cmd.Start(); err != nil {
+		releaseSlot()
 		return nil, err
 	}
 	ctx.AttachCommand(cmd)
 
+	t := &runningTranscode{
+		options:          options,
+		maxTranscodeSize: options.maxTranscodeSize(sm),
+		pid:              cmd.Process.Pid,
+		cancel:           ctx.Cancel,
+		codec:            pipeline.Codec,
+		clientIP:         clientIP,
+	}
+	sm.addTranscode(t)
+
 	// stderr must be consumed or the process deadlocks
 	go func() {
 		errStr, _ := io.ReadAll(stderr)
 
 		errCmd := cmd.Wait()
+		sm.removeTranscode(t)
 
 		var err error
 
@@ -0,0 +1,64 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranscodeOptions_remuxAudioArgs(t *testing.T) {
+	audioTrack := 1
+
+	tests := []struct {
+		name       string
+		audioCodec string
+		audioTrack *int
+		normalise  bool
+		want       Args
+	}{
+		{
+			"aac is copied",
+			string(Aac),
+			nil,
+			false,
+			Args{"-c:a", "copy"},
+		},
+		{
+			"opus is transcoded",
+			string(Opus),
+			nil,
+			false,
+			Args{"-c:a", "aac", "-ac", "2"},
+		},
+		{
+			"selected audio track is transcoded",
+			string(Aac),
+			&audioTrack,
+			false,
+			Args{"-c:a", "aac", "-ac", "2"},
+		},
+		{
+			"normalised audio is transcoded",
+			string(Aac),
+			nil,
+			true,
+			Args{"-c:a", "aac", "-ac", "2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := TranscodeOptions{
+				StreamType: StreamTypeRemux,
+				VideoFile: &models.VideoFile{
+					VideoCodec: H264,
+					AudioCodec: tt.audioCodec,
+				},
+				AudioTrack: tt.audioTrack,
+			}
+
+			assert.Equal(t, tt.want, o.remuxAudioArgs(tt.normalise))
+		})
+	}
+}
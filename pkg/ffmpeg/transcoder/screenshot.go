@@ -1,6 +1,9 @@
 package transcoder
 
-import "github.com/stashapp/stash/pkg/ffmpeg"
+import (
+	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/models"
+)
 
 type ScreenshotOptions struct {
 	OutputPath string
@@ -11,6 +14,10 @@ type ScreenshotOptions struct {
 
 	Width int
 
+	// Crop is the region of the frame to keep, before scaling. The full
+	// frame is used if nil.
+	Crop *models.VideoCrop
+
 	// Verbosity is the logging verbosity. Defaults to LogLevelError if not set.
 	Verbosity ffmpeg.LogLevel
 
@@ -67,8 +74,15 @@ func ScreenshotTime(input string, t float64, options ScreenshotOptions) ffmpeg.A
 
 	var vf ffmpeg.VideoFilter
 
+	if options.Crop != nil {
+		vf = vf.CropRect(*options.Crop)
+	}
+
 	if options.Width > 0 {
 		vf = vf.ScaleWidth(options.Width)
+	}
+
+	if vf != "" {
 		args = args.VideoFilter(vf)
 	}
 
@@ -96,6 +110,10 @@ func ScreenshotFrame(input string, frame int, options ScreenshotOptions) ffmpeg.
 	// keep only frame number options.Frame)
 	vf = vf.Select(frame)
 
+	if options.Crop != nil {
+		vf = vf.CropRect(*options.Crop)
+	}
+
 	if options.Width > 0 {
 		vf = vf.ScaleWidth(options.Width)
 	}
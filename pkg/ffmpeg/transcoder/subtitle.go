@@ -0,0 +1,23 @@
+package transcoder
+
+import (
+	"fmt"
+
+	"github.com/stashapp/stash/pkg/ffmpeg"
+)
+
+// SubtitleWebVTT returns the arguments to convert the subtitle stream with
+// the given index (among the subtitle streams of the input) to WebVTT.
+// The subtitle stream must be text based.
+func SubtitleWebVTT(input string, index int, output string) ffmpeg.Args {
+	var args ffmpeg.Args
+	args = append(args, "-hide_banner")
+	args = args.LogLevel(ffmpeg.LogLevelError)
+
+	args = args.Input(input)
+	args = append(args, "-map", fmt.Sprintf("0:s:%d", index))
+	args = args.Format(ffmpeg.FormatWebVTT)
+	args = args.Output(output)
+
+	return args
+}
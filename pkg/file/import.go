@@ -61,7 +61,7 @@ func (i *Importer) fileJSONToFile(ctx context.Context, fileJSON jsonschema.DirEn
 		if err != nil {
 			return nil, err
 		}
-		return &models.VideoFile{
+		vf := &models.VideoFile{
 			BaseFile:         baseFile,
 			Format:           ff.Format,
 			Width:            ff.Width,
@@ -73,7 +73,30 @@ func (i *Importer) fileJSONToFile(ctx context.Context, fileJSON jsonschema.DirEn
 			BitRate:          ff.BitRate,
 			Interactive:      ff.Interactive,
 			InteractiveSpeed: ff.InteractiveSpeed,
-		}, nil
+
+			IntegratedLoudness: ff.IntegratedLoudness,
+			TruePeak:           ff.TruePeak,
+		}
+
+		if ff.Crop != nil {
+			vf.Crop = &models.VideoCrop{
+				X:      ff.Crop.X,
+				Y:      ff.Crop.Y,
+				Width:  ff.Crop.Width,
+				Height: ff.Crop.Height,
+			}
+		}
+
+		if ff.Integrity != nil {
+			vf.Integrity = &models.VideoIntegrity{
+				Status:         models.IntegrityStatus(ff.Integrity.Status),
+				ErrorCount:     ff.Integrity.ErrorCount,
+				FirstErrorTime: ff.Integrity.FirstErrorTime,
+				Truncated:      ff.Integrity.Truncated,
+			}
+		}
+
+		return vf, nil
 	case *jsonschema.ImageFile:
 		baseFile, err := i.baseFileJSONToBaseFile(ctx, ff.BaseFile)
 		if err != nil {
@@ -7,12 +7,24 @@ import (
 
 	"github.com/stashapp/stash/pkg/ffmpeg"
 	"github.com/stashapp/stash/pkg/file"
+	"github.com/stashapp/stash/pkg/logger"
 	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/txn"
 )
 
+// StreamFinder finds the audio and subtitle streams recorded for a file.
+type StreamFinder interface {
+	GetStreams(ctx context.Context, fileID models.FileID) ([]*models.VideoStream, error)
+}
+
 // Decorator adds video specific fields to a File.
 type Decorator struct {
 	FFProbe ffmpeg.FFProbe
+
+	// TxnManager and StreamFinder are used to check that the streams of
+	// existing files have been recorded. The check is skipped if either is nil.
+	TxnManager   txn.Manager
+	StreamFinder StreamFinder
 }
 
 func (d *Decorator) Decorate(ctx context.Context, fs models.FS, f models.File) (models.File, error) {
@@ -54,9 +66,35 @@ func (d *Decorator) Decorate(ctx context.Context, fs models.FS, f models.File) (
 		FrameRate:   videoFile.FrameRate,
 		BitRate:     videoFile.Bitrate,
 		Interactive: interactive,
+		Streams:     getStreams(videoFile),
 	}, nil
 }
 
+// getStreams returns the audio and subtitle streams of the probed file.
+// The returned slice is never nil, so that the stored streams are replaced.
+func getStreams(videoFile *ffmpeg.VideoFile) []*models.VideoStream {
+	ret := []*models.VideoStream{}
+
+	add := func(t models.VideoStreamType, streams []*ffmpeg.FFProbeStream) {
+		for i, s := range streams {
+			ret = append(ret, &models.VideoStream{
+				Type:     t,
+				Index:    i,
+				Codec:    s.CodecName,
+				Language: s.Tags.Language,
+				Title:    s.Tags.Title,
+				Channels: s.Channels,
+				Default:  s.Disposition.Default == 1,
+			})
+		}
+	}
+
+	add(models.VideoStreamTypeAudio, videoFile.AudioStreams)
+	add(models.VideoStreamTypeSubtitle, videoFile.SubtitleStreams)
+
+	return ret
+}
+
 func (d *Decorator) IsMissingMetadata(ctx context.Context, fs models.FS, f models.File) bool {
 	const (
 		unsetString = "unset"
@@ -77,5 +115,27 @@ func (d *Decorator) IsMissingMetadata(ctx context.Context, fs models.FS, f model
 		vf.Format == unsetString || vf.Width == unsetNumber ||
 		vf.Height == unsetNumber || vf.FrameRate == unsetNumber ||
 		vf.Duration == unsetNumber ||
-		vf.BitRate == unsetNumber || interactive != vf.Interactive
+		vf.BitRate == unsetNumber || interactive != vf.Interactive ||
+		d.isMissingStreams(ctx, vf)
+}
+
+// isMissingStreams returns true if the file has audio, but no audio streams
+// have been recorded. This is the case for files scanned before streams were
+// recorded.
+func (d *Decorator) isMissingStreams(ctx context.Context, vf *models.VideoFile) bool {
+	if d.TxnManager == nil || d.StreamFinder == nil || vf.ID == 0 || vf.AudioCodec == "" {
+		return false
+	}
+
+	var streams []*models.VideoStream
+	if err := txn.WithReadTxn(ctx, d.TxnManager, func(ctx context.Context) error {
+		var err error
+		streams, err = d.StreamFinder.GetStreams(ctx, vf.ID)
+		return err
+	}); err != nil {
+		logger.Warnf("error getting streams of %s: %v", vf.Path, err)
+		return false
+	}
+
+	return len(models.FilterVideoStreams(streams, models.VideoStreamTypeAudio)) == 0
 }
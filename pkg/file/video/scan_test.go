@@ -0,0 +1,67 @@
+package video
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/models/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDecorator_isMissingStreams(t *testing.T) {
+	const (
+		withAudioID    models.FileID = 1
+		missingAudioID models.FileID = 2
+	)
+
+	audioStream := &models.VideoStream{Type: models.VideoStreamTypeAudio, Codec: "aac"}
+	subtitleStream := &models.VideoStream{Type: models.VideoStreamTypeSubtitle, Codec: "subrip"}
+
+	finder := &mocks.FileReaderWriter{}
+	finder.On("GetStreams", mock.Anything, withAudioID).Return([]*models.VideoStream{audioStream, subtitleStream}, nil)
+	finder.On("GetStreams", mock.Anything, missingAudioID).Return([]*models.VideoStream{subtitleStream}, nil)
+
+	d := &Decorator{
+		TxnManager:   &mocks.TxnManager{},
+		StreamFinder: finder,
+	}
+
+	tests := []struct {
+		name string
+		vf   *models.VideoFile
+		want bool
+	}{
+		{
+			"audio streams recorded",
+			&models.VideoFile{BaseFile: &models.BaseFile{ID: withAudioID}, AudioCodec: "aac"},
+			false,
+		},
+		{
+			"audio streams missing",
+			&models.VideoFile{BaseFile: &models.BaseFile{ID: missingAudioID}, AudioCodec: "aac"},
+			true,
+		},
+		{
+			"no audio",
+			&models.VideoFile{BaseFile: &models.BaseFile{ID: missingAudioID}},
+			false,
+		},
+		{
+			"new file",
+			&models.VideoFile{BaseFile: &models.BaseFile{}, AudioCodec: "aac"},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, d.isMissingStreams(context.Background(), tt.vf))
+		})
+	}
+
+	// the check is skipped without a stream finder
+	d = &Decorator{}
+	assert.False(t, d.isMissingStreams(context.Background(), &models.VideoFile{BaseFile: &models.BaseFile{ID: missingAudioID}, AudioCodec: "aac"}))
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"context"
+	"time"
+)
+
+type APIKeyReader interface {
+	Find(ctx context.Context, id int) (*APIKey, error)
+	// FindByKeyHash returns the API key with the given hash of its key.
+	FindByKeyHash(ctx context.Context, keyHash string) (*APIKey, error)
+	FindByUserID(ctx context.Context, userID int) ([]*APIKey, error)
+}
+
+type APIKeyWriter interface {
+	Create(ctx context.Context, newKey *APIKey) error
+	UpdateLastUsed(ctx context.Context, id int, lastUsedAt time.Time) error
+	Destroy(ctx context.Context, id int) error
+}
+
+type APIKeyReaderWriter interface {
+	APIKeyReader
+	APIKeyWriter
+}
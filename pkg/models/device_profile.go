@@ -0,0 +1,100 @@
+package models
+
+import "strings"
+
+// DeviceProfile describes the media which a client can play without
+// transcoding.
+type DeviceProfile struct {
+	Name string `json:"name"`
+	// UserAgents contains substrings of the User-Agent header which identify
+	// the client. Matching is case-insensitive.
+	UserAgents  []string `json:"user_agents"`
+	Containers  []string `json:"containers"`
+	VideoCodecs []string `json:"video_codecs"`
+	AudioCodecs []string `json:"audio_codecs"`
+	// MaxResolution is the largest resolution the client can play.
+	// Empty or ORIGINAL for no limit.
+	MaxResolution StreamingResolutionEnum `json:"max_resolution"`
+	// MaxBitrate is the highest bitrate in bits per second the client can
+	// play. 0 for no limit.
+	MaxBitrate int64 `json:"max_bitrate"`
+}
+
+func containsFold(values []string, v string) bool {
+	for _, vv := range values {
+		if strings.EqualFold(vv, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *DeviceProfile) SupportsContainer(container string) bool {
+	return containsFold(p.Containers, container)
+}
+
+func (p *DeviceProfile) SupportsVideoCodec(codec string) bool {
+	return containsFold(p.VideoCodecs, codec)
+}
+
+func (p *DeviceProfile) SupportsAudioCodec(codec string) bool {
+	return containsFold(p.AudioCodecs, codec)
+}
+
+// MaxStreamingResolution returns the smaller of the maximum resolution of
+// the profile and max.
+func (p *DeviceProfile) MaxStreamingResolution(max StreamingResolutionEnum) StreamingResolutionEnum {
+	if p.MaxResolution == "" || p.MaxResolution == StreamingResolutionEnumOriginal {
+		return max
+	}
+
+	if max == StreamingResolutionEnumOriginal || p.MaxResolution.GetMaxResolution() < max.GetMaxResolution() {
+		return p.MaxResolution
+	}
+
+	return max
+}
+
+// ExceedsLimits returns true if media of the given resolution or bitrate
+// cannot be played by the client. resolution is the smaller of the width
+// and height.
+func (p *DeviceProfile) ExceedsLimits(resolution int, bitrate int64) bool {
+	if p.MaxResolution != "" && p.MaxResolution != StreamingResolutionEnumOriginal && resolution > p.MaxResolution.GetMaxResolution() {
+		return true
+	}
+
+	return p.MaxBitrate > 0 && bitrate > p.MaxBitrate
+}
+
+type DeviceProfiles []*DeviceProfile
+
+// Find returns the profile with the given name, ignoring case.
+// Returns nil if not found.
+func (p DeviceProfiles) Find(name string) *DeviceProfile {
+	for _, pp := range p {
+		if strings.EqualFold(pp.Name, name) {
+			return pp
+		}
+	}
+
+	return nil
+}
+
+// MatchUserAgent returns the first profile with a user agent substring
+// contained in userAgent, ignoring case. Returns nil if none match.
+func (p DeviceProfiles) MatchUserAgent(userAgent string) *DeviceProfile {
+	if userAgent == "" {
+		return nil
+	}
+
+	userAgent = strings.ToLower(userAgent)
+	for _, pp := range p {
+		for _, m := range pp.UserAgents {
+			if m != "" && strings.Contains(userAgent, strings.ToLower(m)) {
+				return pp
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceProfiles_MatchUserAgent(t *testing.T) {
+	profiles := DeviceProfiles{
+		{Name: "Kodi", UserAgents: []string{"Kodi"}},
+		{Name: "Android", UserAgents: []string{"Android", "ExoPlayer"}},
+	}
+
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"kodi", "Kodi/20.2 (Windows NT 10.0.19045.3570; Win64; x64) App_Bitness/64 Version/20.2", "Kodi"},
+		{"case insensitive", "Mozilla/5.0 (Linux; android 13; Pixel 7)", "Android"},
+		{"no match", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Firefox/118.0", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := profiles.MatchUserAgent(tt.userAgent)
+			name := ""
+			if got != nil {
+				name = got.Name
+			}
+			assert.Equal(t, tt.want, name)
+		})
+	}
+}
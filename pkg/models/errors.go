@@ -10,4 +10,8 @@ var (
 	ErrConversion = errors.New("conversion error")
 
 	ErrScraperSource = errors.New("invalid ScraperSource")
+
+	// ErrRestricted signifies changes to objects which are excluded by the
+	// content restrictions of the current user
+	ErrRestricted = errors.New("restricted by content restrictions")
 )
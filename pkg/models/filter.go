@@ -128,6 +128,11 @@ type ResolutionCriterionInput struct {
 	Modifier CriterionModifier `json:"modifier"`
 }
 
+type IntegrityCriterionInput struct {
+	Value    IntegrityStatus   `json:"value"`
+	Modifier CriterionModifier `json:"modifier"`
+}
+
 type HierarchicalMultiCriterionInput struct {
 	Value    []string          `json:"value"`
 	Modifier CriterionModifier `json:"modifier"`
@@ -7,18 +7,24 @@ import (
 )
 
 type GenerateMetadataOptions struct {
-	Covers                    bool                    `json:"covers"`
-	Sprites                   bool                    `json:"sprites"`
-	Previews                  bool                    `json:"previews"`
-	ImagePreviews             bool                    `json:"imagePreviews"`
-	PreviewOptions            *GeneratePreviewOptions `json:"previewOptions"`
-	Markers                   bool                    `json:"markers"`
-	MarkerImagePreviews       bool                    `json:"markerImagePreviews"`
-	MarkerScreenshots         bool                    `json:"markerScreenshots"`
-	Transcodes                bool                    `json:"transcodes"`
-	Phashes                   bool                    `json:"phashes"`
-	InteractiveHeatmapsSpeeds bool                    `json:"interactiveHeatmapsSpeeds"`
-	ClipPreviews              bool                    `json:"clipPreviews"`
+	Covers                      bool                    `json:"covers"`
+	Sprites                     bool                    `json:"sprites"`
+	Trickplay                   bool                    `json:"trickplay"`
+	Previews                    bool                    `json:"previews"`
+	ImagePreviews               bool                    `json:"imagePreviews"`
+	PreviewOptions              *GeneratePreviewOptions `json:"previewOptions"`
+	Markers                     bool                    `json:"markers"`
+	MarkerImagePreviews         bool                    `json:"markerImagePreviews"`
+	MarkerScreenshots           bool                    `json:"markerScreenshots"`
+	Transcodes                  bool                    `json:"transcodes"`
+	Phashes                     bool                    `json:"phashes"`
+	InteractiveHeatmapsSpeeds   bool                    `json:"interactiveHeatmapsSpeeds"`
+	ClipPreviews                bool                    `json:"clipPreviews"`
+	Loudness                    bool                    `json:"loudness"`
+	SceneCuts                   bool                    `json:"sceneCuts"`
+	CropDetect                  bool                    `json:"cropDetect"`
+	IntegrityCheck              bool                    `json:"integrityCheck"`
+	IntegrityCheckKeyframesOnly bool                    `json:"integrityCheckKeyframesOnly"`
 }
 
 type GeneratePreviewOptions struct {
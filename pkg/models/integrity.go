@@ -0,0 +1,89 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type IntegrityStatus string
+
+const (
+	// IntegrityStatusUnchecked is used in filters to find files which have
+	// not been checked.
+	IntegrityStatusUnchecked IntegrityStatus = "UNCHECKED"
+	// IntegrityStatusOk indicates that the file decoded without errors.
+	IntegrityStatusOk IntegrityStatus = "OK"
+	// IntegrityStatusCorrupt indicates that errors occurred while decoding.
+	IntegrityStatusCorrupt IntegrityStatus = "CORRUPT"
+	// IntegrityStatusTruncated indicates that decoding ended before the
+	// declared duration of the file.
+	IntegrityStatusTruncated IntegrityStatus = "TRUNCATED"
+)
+
+var AllIntegrityStatus = []IntegrityStatus{
+	IntegrityStatusUnchecked,
+	IntegrityStatusOk,
+	IntegrityStatusCorrupt,
+	IntegrityStatusTruncated,
+}
+
+func (e IntegrityStatus) IsValid() bool {
+	switch e {
+	case IntegrityStatusUnchecked, IntegrityStatusOk, IntegrityStatusCorrupt, IntegrityStatusTruncated:
+		return true
+	}
+	return false
+}
+
+func (e IntegrityStatus) String() string {
+	return string(e)
+}
+
+func (e *IntegrityStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = IntegrityStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid IntegrityStatus", str)
+	}
+	return nil
+}
+
+func (e IntegrityStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+// VideoIntegrity is the result of decoding a video file to check for errors.
+type VideoIntegrity struct {
+	Status IntegrityStatus `json:"status"`
+	// ErrorCount is the number of errors logged while decoding.
+	ErrorCount int `json:"error_count"`
+	// FirstErrorTime is the approximate time in seconds of the first error.
+	FirstErrorTime *float64 `json:"first_error_time"`
+	// Truncated is true if decoding ended before the declared duration.
+	Truncated bool `json:"truncated"`
+}
+
+// NewVideoIntegrity returns a VideoIntegrity with the status set from the
+// error count and truncation.
+func NewVideoIntegrity(errorCount int, firstErrorTime *float64, truncated bool) *VideoIntegrity {
+	ret := &VideoIntegrity{
+		Status:         IntegrityStatusOk,
+		ErrorCount:     errorCount,
+		FirstErrorTime: firstErrorTime,
+		Truncated:      truncated,
+	}
+
+	switch {
+	case truncated:
+		ret.Status = IntegrityStatusTruncated
+	case errorCount > 0:
+		ret.Status = IntegrityStatusCorrupt
+	}
+
+	return ret
+}
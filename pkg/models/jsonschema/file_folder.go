@@ -88,6 +88,26 @@ type VideoFile struct {
 
 	Interactive      bool `json:"interactive,omitempty"`
 	InteractiveSpeed *int `json:"interactive_speed,omitempty"`
+
+	IntegratedLoudness *float64 `json:"integrated_loudness,omitempty"`
+	TruePeak           *float64 `json:"true_peak,omitempty"`
+
+	Crop      *VideoCrop      `json:"crop,omitempty"`
+	Integrity *VideoIntegrity `json:"integrity,omitempty"`
+}
+
+type VideoCrop struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type VideoIntegrity struct {
+	Status         string   `json:"status"`
+	ErrorCount     int      `json:"error_count,omitempty"`
+	FirstErrorTime *float64 `json:"first_error_time,omitempty"`
+	Truncated      bool     `json:"truncated,omitempty"`
 }
 
 type ImageFile struct {
@@ -38,6 +38,18 @@ type SceneMovie struct {
 	SceneIndex int    `json:"scene_index,omitempty"`
 }
 
+// SceneActivity is the activity of a user for a scene.
+type SceneActivity struct {
+	User         string          `json:"user"`
+	Rating       int             `json:"rating,omitempty"`
+	OCounter     int             `json:"o_counter,omitempty"`
+	LastPlayedAt json.JSONTime   `json:"last_played_at,omitempty"`
+	ResumeTime   float64         `json:"resume_time,omitempty"`
+	PlayCount    int             `json:"play_count,omitempty"`
+	PlayDuration float64         `json:"play_duration,omitempty"`
+	PlayHistory  []json.JSONTime `json:"play_history,omitempty"`
+}
+
 type Scene struct {
 	Title  string `json:"title,omitempty"`
 	Code   string `json:"code,omitempty"`
@@ -67,6 +79,10 @@ type Scene struct {
 	PlayCount    int              `json:"play_count,omitempty"`
 	PlayDuration float64          `json:"play_duration,omitempty"`
 	StashIDs     []models.StashID `json:"stash_ids,omitempty"`
+
+	// Activity is the activity of each user. The activity fields above are
+	// the activity recorded without a logged in user.
+	Activity []SceneActivity `json:"activity,omitempty"`
 }
 
 func (s Scene) Filename(id int, basename string, hash string) string {
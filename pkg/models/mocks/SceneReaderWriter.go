@@ -7,6 +7,8 @@ import (
 
 	models "github.com/stashapp/stash/pkg/models"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // SceneReaderWriter is an autogenerated mock type for the SceneReaderWriter type
@@ -79,6 +81,20 @@ func (_m *SceneReaderWriter) AssignFiles(ctx context.Context, sceneID int, fileI
 	return r0
 }
 
+// CopyActivityToUser provides a mock function with given fields: ctx, userID
+func (_m *SceneReaderWriter) CopyActivityToUser(ctx context.Context, userID int) error {
+	ret := _m.Called(ctx, userID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, userID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Count provides a mock function with given fields: ctx
 func (_m *SceneReaderWriter) Count(ctx context.Context) (int, error) {
 	ret := _m.Called(ctx)
@@ -593,6 +609,52 @@ func (_m *SceneReaderWriter) FindMany(ctx context.Context, ids []int) ([]*models
 	return r0, r1
 }
 
+// FindPlayHistory provides a mock function with given fields: ctx, limit
+func (_m *SceneReaderWriter) FindPlayHistory(ctx context.Context, limit int) ([]*models.ScenePlay, error) {
+	ret := _m.Called(ctx, limit)
+
+	var r0 []*models.ScenePlay
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*models.ScenePlay); ok {
+		r0 = rf(ctx, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.ScenePlay)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetActivity provides a mock function with given fields: ctx, sceneID
+func (_m *SceneReaderWriter) GetActivity(ctx context.Context, sceneID int) ([]*models.SceneActivity, error) {
+	ret := _m.Called(ctx, sceneID)
+
+	var r0 []*models.SceneActivity
+	if rf, ok := ret.Get(0).(func(context.Context, int) []*models.SceneActivity); ok {
+		r0 = rf(ctx, sceneID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.SceneActivity)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, sceneID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetCover provides a mock function with given fields: ctx, sceneID
 func (_m *SceneReaderWriter) GetCover(ctx context.Context, sceneID int) ([]byte, error) {
 	ret := _m.Called(ctx, sceneID)
@@ -731,6 +793,29 @@ func (_m *SceneReaderWriter) GetPerformerIDs(ctx context.Context, relatedID int)
 	return r0, r1
 }
 
+// GetPlayHistory provides a mock function with given fields: ctx, sceneID
+func (_m *SceneReaderWriter) GetPlayHistory(ctx context.Context, sceneID int) ([]time.Time, error) {
+	ret := _m.Called(ctx, sceneID)
+
+	var r0 []time.Time
+	if rf, ok := ret.Get(0).(func(context.Context, int) []time.Time); ok {
+		r0 = rf(ctx, sceneID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]time.Time)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, sceneID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetStashIDs provides a mock function with given fields: ctx, relatedID
 func (_m *SceneReaderWriter) GetStashIDs(ctx context.Context, relatedID int) ([]models.StashID, error) {
 	ret := _m.Called(ctx, relatedID)
@@ -1033,6 +1118,20 @@ func (_m *SceneReaderWriter) SaveActivity(ctx context.Context, sceneID int, resu
 	return r0, r1
 }
 
+// SetActivity provides a mock function with given fields: ctx, sceneID, activity
+func (_m *SceneReaderWriter) SetActivity(ctx context.Context, sceneID int, activity []*models.SceneActivity) error {
+	ret := _m.Called(ctx, sceneID, activity)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, []*models.SceneActivity) error); ok {
+		r0 = rf(ctx, sceneID, activity)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Size provides a mock function with given fields: ctx
 func (_m *SceneReaderWriter) Size(ctx context.Context) (float64, error) {
 	ret := _m.Called(ctx)
@@ -55,5 +55,7 @@ func NewTxnRepository() models.Repository {
 		Studio:         &StudioReaderWriter{},
 		Tag:            &TagReaderWriter{},
 		SavedFilter:    &SavedFilterReaderWriter{},
+		User:           &UserReaderWriter{},
+		APIKey:         &APIKeyReaderWriter{},
 	}
 }
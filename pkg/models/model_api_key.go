@@ -0,0 +1,87 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type APIKeyScope string
+
+const (
+	// APIKeyScopeRead allows queries and access to media.
+	APIKeyScopeRead APIKeyScope = "READ"
+	// APIKeyScopeWrite allows mutations which change the library content.
+	APIKeyScopeWrite APIKeyScope = "WRITE"
+	// APIKeyScopeTasks allows running and stopping tasks.
+	APIKeyScopeTasks APIKeyScope = "TASKS"
+	// APIKeyScopeAdmin allows all operations permitted by the role of the user.
+	APIKeyScopeAdmin APIKeyScope = "ADMIN"
+)
+
+var AllAPIKeyScope = []APIKeyScope{
+	APIKeyScopeRead,
+	APIKeyScopeWrite,
+	APIKeyScopeTasks,
+	APIKeyScopeAdmin,
+}
+
+func (e APIKeyScope) IsValid() bool {
+	switch e {
+	case APIKeyScopeRead, APIKeyScopeWrite, APIKeyScopeTasks, APIKeyScopeAdmin:
+		return true
+	}
+	return false
+}
+
+func (e APIKeyScope) String() string {
+	return string(e)
+}
+
+func (e *APIKeyScope) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = APIKeyScope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid APIKeyScope", str)
+	}
+	return nil
+}
+
+func (e APIKeyScope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type APIKey struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"user_id"`
+	Name   string `json:"name"`
+	// KeyHash is the SHA-256 hash of the key. The key itself is not stored.
+	KeyHash    string        `json:"-"`
+	Scopes     []APIKeyScope `json:"scopes"`
+	ExpiresAt  *time.Time    `json:"expires_at"`
+	LastUsedAt *time.Time    `json:"last_used_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// HasScope returns true if the key grants scope. The admin scope grants all
+// other scopes.
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == APIKeyScopeAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsExpired returns true if the key has expired at the given time.
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
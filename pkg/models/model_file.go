@@ -261,6 +261,83 @@ type VideoFile struct {
 
 	Interactive      bool `json:"interactive"`
 	InteractiveSpeed *int `json:"interactive_speed"`
+
+	// IntegratedLoudness is the EBU R128 integrated loudness of the default
+	// audio stream in LUFS. Nil if the loudness has not been analysed.
+	IntegratedLoudness *float64 `json:"integrated_loudness"`
+	// TruePeak is the true peak of the default audio stream in dBTP.
+	TruePeak *float64 `json:"true_peak"`
+
+	// Crop is the region of the frame containing the picture, excluding
+	// black bars. Nil if crop detection has not been run.
+	Crop *VideoCrop `json:"crop"`
+
+	// Integrity is the result of the last integrity check. Nil if the file
+	// has not been checked.
+	Integrity *VideoIntegrity `json:"integrity"`
+
+	// Streams contains the audio and subtitle streams of the file.
+	// Streams are not loaded with the file; use GetStreams to retrieve them.
+	// When updating the file, the stored streams are only replaced if
+	// Streams is not nil.
+	Streams []*VideoStream `json:"streams,omitempty"`
+}
+
+// VideoCrop is a rectangle of a video frame.
+type VideoCrop struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type VideoStreamType string
+
+const (
+	VideoStreamTypeAudio    VideoStreamType = "audio"
+	VideoStreamTypeSubtitle VideoStreamType = "subtitle"
+)
+
+// VideoStream represents an audio or subtitle stream of a video file.
+type VideoStream struct {
+	Type VideoStreamType `json:"type"`
+	// Index is the index of the stream among the streams of the same type.
+	Index    int    `json:"index"`
+	Codec    string `json:"codec"`
+	Language string `json:"language"`
+	Title    string `json:"title"`
+	// Channels is the number of audio channels. Zero for subtitle streams.
+	Channels int  `json:"channels"`
+	Default  bool `json:"default"`
+}
+
+// DisplayName returns a human readable name for the stream.
+func (s VideoStream) DisplayName() string {
+	var name string
+	switch {
+	case s.Title != "" && s.Language != "":
+		name = fmt.Sprintf("%s (%s)", s.Title, s.Language)
+	case s.Title != "":
+		name = s.Title
+	case s.Language != "":
+		name = s.Language
+	default:
+		name = fmt.Sprintf("Track %d", s.Index+1)
+	}
+
+	return name
+}
+
+// FilterVideoStreams returns the streams of the given type.
+func FilterVideoStreams(streams []*VideoStream, t VideoStreamType) []*VideoStream {
+	var ret []*VideoStream
+	for _, s := range streams {
+		if s.Type == t {
+			ret = append(ret, s)
+		}
+	}
+
+	return ret
 }
 
 func (f VideoFile) GetWidth() int {
@@ -271,6 +348,27 @@ func (f VideoFile) GetHeight() int {
 	return f.Height
 }
 
+// IsCropped returns true if black bars were detected around the picture.
+func (f VideoFile) IsCropped() bool {
+	return f.Crop != nil && (f.Crop.Width != f.Width || f.Crop.Height != f.Height)
+}
+
+// EffectiveWidth returns the width of the picture excluding black bars.
+func (f VideoFile) EffectiveWidth() int {
+	if f.IsCropped() {
+		return f.Crop.Width
+	}
+	return f.Width
+}
+
+// EffectiveHeight returns the height of the picture excluding black bars.
+func (f VideoFile) EffectiveHeight() int {
+	if f.IsCropped() {
+		return f.Crop.Height
+	}
+	return f.Height
+}
+
 func (f VideoFile) GetFormat() string {
 	return f.Format
 }
@@ -263,6 +263,25 @@ func (s Scene) GetHash(hashAlgorithm HashAlgorithm) string {
 	return ""
 }
 
+// ScenePlay represents a single play of a scene.
+type ScenePlay struct {
+	SceneID  int       `json:"scene_id"`
+	PlayedAt time.Time `json:"played_at"`
+}
+
+// SceneActivity represents the activity of a user for a scene.
+type SceneActivity struct {
+	UserID       int        `json:"user_id"`
+	Rating       *int       `json:"rating"`
+	OCounter     int        `json:"o_counter"`
+	PlayCount    int        `json:"play_count"`
+	PlayDuration float64    `json:"play_duration"`
+	ResumeTime   float64    `json:"resume_time"`
+	LastPlayedAt *time.Time `json:"last_played_at"`
+	// PlayHistory contains the times the scene was played, most recent first.
+	PlayHistory []time.Time `json:"play_history"`
+}
+
 // SceneFileType represents the file metadata for a scene.
 type SceneFileType struct {
 	Size       *string  `graphql:"size" json:"size"`
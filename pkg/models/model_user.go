@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/stashapp/stash/pkg/sliceutil/intslice"
+	"github.com/stashapp/stash/pkg/sliceutil/stringslice"
+)
+
+type UserRole string
+
+const (
+	// UserRoleAdmin can change the configuration, run tasks and manage users.
+	UserRoleAdmin UserRole = "ADMIN"
+	// UserRoleEditor can change the library content.
+	UserRoleEditor UserRole = "EDITOR"
+	// UserRoleReadOnly can only browse the library.
+	UserRoleReadOnly UserRole = "READ_ONLY"
+)
+
+var AllUserRole = []UserRole{
+	UserRoleAdmin,
+	UserRoleEditor,
+	UserRoleReadOnly,
+}
+
+func (e UserRole) IsValid() bool {
+	switch e {
+	case UserRoleAdmin, UserRoleEditor, UserRoleReadOnly:
+		return true
+	}
+	return false
+}
+
+func (e UserRole) String() string {
+	return string(e)
+}
+
+func (e *UserRole) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = UserRole(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid UserRole", str)
+	}
+	return nil
+}
+
+func (e UserRole) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e UserRole) level() int {
+	switch e {
+	case UserRoleAdmin:
+		return 2
+	case UserRoleEditor:
+		return 1
+	}
+	return 0
+}
+
+// Includes returns true if the role has the permissions of other.
+// Each role includes the permissions of the roles below it.
+func (e UserRole) Includes(other UserRole) bool {
+	return e.level() >= other.level()
+}
+
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+	// PasswordHash is the bcrypt hash of the password of the user.
+	PasswordHash string   `json:"-"`
+	Role         UserRole `json:"role"`
+	// Restrictions excludes content from the user.
+	Restrictions ContentRestrictions `json:"restrictions"`
+	CreatedAt    time.Time           `json:"created_at"`
+	UpdatedAt    time.Time           `json:"updated_at"`
+}
+
+// HasRole returns true if the user has the permissions of role.
+func (u *User) HasRole(role UserRole) bool {
+	return u.Role.Includes(role)
+}
+
+// ContentRestrictions returns the restrictions which apply to the user.
+// Returns nil if the user is not restricted. Admin users are never
+// restricted, so that tasks run by them see all of the content.
+func (u *User) ContentRestrictions() *ContentRestrictions {
+	if u.Role == UserRoleAdmin || u.Restrictions.IsEmpty() {
+		return nil
+	}
+
+	return &u.Restrictions
+}
+
+// ContentRestrictions excludes scenes, images, galleries and scene markers
+// from a user. Content is excluded if it has any of the excluded tags or
+// their child tags, has one of the excluded studios or their child studios,
+// has any of the excluded performers, or if any of its files is within one
+// of the excluded paths.
+type ContentRestrictions struct {
+	ExcludedTagIDs       []int    `json:"excluded_tag_ids"`
+	ExcludedStudioIDs    []int    `json:"excluded_studio_ids"`
+	ExcludedPerformerIDs []int    `json:"excluded_performer_ids"`
+	ExcludedPaths        []string `json:"excluded_paths"`
+}
+
+func (r ContentRestrictions) IsEmpty() bool {
+	return len(r.ExcludedTagIDs) == 0 && len(r.ExcludedStudioIDs) == 0 && len(r.ExcludedPerformerIDs) == 0 && len(r.ExcludedPaths) == 0
+}
+
+// Merge returns restrictions which exclude the content excluded by either
+// r or other.
+func (r ContentRestrictions) Merge(other ContentRestrictions) ContentRestrictions {
+	return ContentRestrictions{
+		ExcludedTagIDs:       intslice.IntAppendUniques(intslice.IntAppendUniques(nil, r.ExcludedTagIDs), other.ExcludedTagIDs),
+		ExcludedStudioIDs:    intslice.IntAppendUniques(intslice.IntAppendUniques(nil, r.ExcludedStudioIDs), other.ExcludedStudioIDs),
+		ExcludedPerformerIDs: intslice.IntAppendUniques(intslice.IntAppendUniques(nil, r.ExcludedPerformerIDs), other.ExcludedPerformerIDs),
+		ExcludedPaths:        stringslice.StrAppendUniques(stringslice.StrAppendUniques(nil, r.ExcludedPaths), other.ExcludedPaths),
+	}
+}
@@ -50,6 +50,26 @@ func (sp *scenePaths) GetSpriteVttFilePath(checksum string) string {
 	return filepath.Join(sp.Vtt, checksum+"_thumbs.vtt")
 }
 
+func (sp *scenePaths) GetTrickplayBIFPath(checksum string) string {
+	return filepath.Join(sp.Vtt, checksum+".bif")
+}
+
+// GetTrickplayDir returns the directory containing the trickplay tiles and manifest.
+func (sp *scenePaths) GetTrickplayDir(checksum string) string {
+	return filepath.Join(sp.Vtt, checksum+"_trickplay")
+}
+
+// GetSubtitlesDir returns the directory containing the WebVTT subtitles
+// extracted from the scene file, named by subtitle stream index.
+func (sp *scenePaths) GetSubtitlesDir(checksum string) string {
+	return filepath.Join(sp.Vtt, checksum+"_subtitles")
+}
+
+// GetSceneCutsPath returns the path of the json file containing the detected scene cuts.
+func (sp *scenePaths) GetSceneCutsPath(checksum string) string {
+	return filepath.Join(sp.Vtt, checksum+"_cuts.json")
+}
+
 func (sp *scenePaths) GetInteractiveHeatmapPath(checksum string) string {
 	return filepath.Join(sp.InteractiveHeatmap, checksum+".png")
 }
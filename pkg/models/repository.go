@@ -25,4 +25,6 @@ type Repository struct {
 	Studio         StudioReaderWriter
 	Tag            TagReaderWriter
 	SavedFilter    SavedFilterReaderWriter
+	User           UserReaderWriter
+	APIKey         APIKeyReaderWriter
 }
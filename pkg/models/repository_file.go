@@ -69,6 +69,7 @@ type FileReader interface {
 	FileCounter
 
 	GetCaptions(ctx context.Context, fileID FileID) ([]*VideoCaption, error)
+	GetStreams(ctx context.Context, fileID FileID) ([]*VideoStream, error)
 	IsPrimary(ctx context.Context, fileID FileID) (bool, error)
 }
 
@@ -1,6 +1,9 @@
 package models
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // SceneGetter provides methods to get scenes by ID.
 type SceneGetter interface {
@@ -90,6 +93,9 @@ type SceneReader interface {
 	PlayDuration(ctx context.Context) (float64, error)
 	GetCover(ctx context.Context, sceneID int) ([]byte, error)
 	HasCover(ctx context.Context, sceneID int) (bool, error)
+	GetPlayHistory(ctx context.Context, sceneID int) ([]time.Time, error)
+	FindPlayHistory(ctx context.Context, limit int) ([]*ScenePlay, error)
+	GetActivity(ctx context.Context, sceneID int) ([]*SceneActivity, error)
 }
 
 // SceneWriter provides all methods to modify scenes.
@@ -106,6 +112,8 @@ type SceneWriter interface {
 	ResetOCounter(ctx context.Context, id int) (int, error)
 	SaveActivity(ctx context.Context, sceneID int, resumeTime *float64, playDuration *float64) (bool, error)
 	IncrementWatchCount(ctx context.Context, sceneID int) (int, error)
+	CopyActivityToUser(ctx context.Context, userID int) error
+	SetActivity(ctx context.Context, sceneID int, activity []*SceneActivity) error
 }
 
 // SceneReaderWriter provides all scene methods.
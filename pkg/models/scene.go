@@ -79,6 +79,10 @@ type SceneFilterType struct {
 	Interactive *bool `json:"interactive"`
 	// Filter by InteractiveSpeed
 	InteractiveSpeed *IntCriterionInput `json:"interactive_speed"`
+	// Filter by integrated loudness
+	Loudness *FloatCriterionInput `json:"loudness"`
+	// Filter by integrity check status
+	Integrity *IntegrityCriterionInput `json:"integrity"`
 	// Filter by captions
 	Captions *StringCriterionInput `json:"captions"`
 	// Filter by resume time
@@ -0,0 +1,23 @@
+package models
+
+import "context"
+
+type UserReader interface {
+	Find(ctx context.Context, id int) (*User, error)
+	// FindByUsername returns the user with the given username, ignoring case.
+	FindByUsername(ctx context.Context, username string) (*User, error)
+	All(ctx context.Context) ([]*User, error)
+	Count(ctx context.Context) (int, error)
+	CountByRole(ctx context.Context, role UserRole) (int, error)
+}
+
+type UserWriter interface {
+	Create(ctx context.Context, newUser *User) error
+	Update(ctx context.Context, updatedUser *User) error
+	Destroy(ctx context.Context, id int) error
+}
+
+type UserReaderWriter interface {
+	UserReader
+	UserWriter
+}
@@ -37,6 +37,22 @@ func (d *FileDeleter) MarkGeneratedFiles(scene *models.Scene) error {
 		}
 	}
 
+	trickplayDir := d.Paths.Scene.GetTrickplayDir(sceneHash)
+	exists, _ = fsutil.DirExists(trickplayDir)
+	if exists {
+		if err := d.Dirs([]string{trickplayDir}); err != nil {
+			return err
+		}
+	}
+
+	subtitlesDir := d.Paths.Scene.GetSubtitlesDir(sceneHash)
+	exists, _ = fsutil.DirExists(subtitlesDir)
+	if exists {
+		if err := d.Dirs([]string{subtitlesDir}); err != nil {
+			return err
+		}
+	}
+
 	var files []string
 
 	streamPreviewPath := d.Paths.Scene.GetVideoPreviewPath(sceneHash)
@@ -69,6 +85,12 @@ func (d *FileDeleter) MarkGeneratedFiles(scene *models.Scene) error {
 		files = append(files, vttPath)
 	}
 
+	bifPath := d.Paths.Scene.GetTrickplayBIFPath(sceneHash)
+	exists, _ = fsutil.FileExists(bifPath)
+	if exists {
+		files = append(files, bifPath)
+	}
+
 	heatmapPath := d.Paths.Scene.GetInteractiveHeatmapPath(sceneHash)
 	exists, _ = fsutil.FileExists(heatmapPath)
 	if exists {
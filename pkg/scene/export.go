@@ -76,6 +76,51 @@ func ToBasicJSON(ctx context.Context, reader CoverGetter, scene *models.Scene) (
 	return &newSceneJSON, nil
 }
 
+type ActivityGetter interface {
+	GetActivity(ctx context.Context, sceneID int) ([]*models.SceneActivity, error)
+}
+
+// GetActivityJSON returns the activity of each user for the provided scene.
+// usernames maps user IDs to the usernames written to the JSON.
+func GetActivityJSON(ctx context.Context, reader ActivityGetter, usernames map[int]string, scene *models.Scene) ([]jsonschema.SceneActivity, error) {
+	activity, err := reader.GetActivity(ctx, scene.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting scene activity: %v", err)
+	}
+
+	var results []jsonschema.SceneActivity
+	for _, a := range activity {
+		username, ok := usernames[a.UserID]
+		if !ok {
+			continue
+		}
+
+		activityJSON := jsonschema.SceneActivity{
+			User:         username,
+			OCounter:     a.OCounter,
+			ResumeTime:   a.ResumeTime,
+			PlayCount:    a.PlayCount,
+			PlayDuration: a.PlayDuration,
+		}
+
+		if a.Rating != nil {
+			activityJSON.Rating = *a.Rating
+		}
+
+		if a.LastPlayedAt != nil {
+			activityJSON.LastPlayedAt = json.JSONTime{Time: *a.LastPlayedAt}
+		}
+
+		for _, t := range a.PlayHistory {
+			activityJSON.PlayHistory = append(activityJSON.PlayHistory, json.JSONTime{Time: t})
+		}
+
+		results = append(results, activityJSON)
+	}
+
+	return results, nil
+}
+
 // GetStudioName returns the name of the provided scene's studio. It returns an
 // empty string if there is no studio assigned to the scene.
 func GetStudioName(ctx context.Context, reader models.StudioGetter, scene *models.Scene) (string, error) {
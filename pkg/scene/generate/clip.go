@@ -0,0 +1,218 @@
+package generate
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/ffmpeg/transcoder"
+	"github.com/stashapp/stash/pkg/fsutil"
+	"github.com/stashapp/stash/pkg/logger"
+)
+
+const (
+	mkvPattern = "*.mkv"
+
+	clipAudioBitrate = "192k"
+
+	// clips joined in accurate mode are letterboxed to a common size so
+	// that they can be concatenated.
+	clipJoinWidth  = 1920
+	clipJoinHeight = 1080
+)
+
+// ClipMode is the method used to cut a clip from a video.
+type ClipMode int
+
+const (
+	// ClipModeCopy copies the streams without re-encoding. The clip starts
+	// at the keyframe before the requested start time.
+	ClipModeCopy ClipMode = iota
+	// ClipModeAccurate re-encodes the streams so that the clip starts and
+	// ends at exactly the requested times.
+	ClipModeAccurate
+)
+
+// Extension returns the file extension of clips cut using the mode.
+// Copied streams are written to matroska, which accepts any codec.
+func (m ClipMode) Extension() string {
+	if m == ClipModeCopy {
+		return ".mkv"
+	}
+	return ".mp4"
+}
+
+func (m ClipMode) format() ffmpeg.Format {
+	if m == ClipModeCopy {
+		return ffmpeg.FormatMatroska
+	}
+	return ffmpeg.FormatMP4
+}
+
+func (m ClipMode) pattern() string {
+	if m == ClipModeCopy {
+		return mkvPattern
+	}
+	return mp4Pattern
+}
+
+// ClipRange is a time range of a video file.
+type ClipRange struct {
+	Input string
+	Start float64
+	End   float64
+}
+
+func (r ClipRange) Duration() float64 {
+	return r.End - r.Start
+}
+
+// Clip cuts the given ranges and joins them into a single file at output.
+// The output is overwritten if it exists. If progress is not nil, it is
+// called after each range is cut.
+func (g Generator) Clip(ctx context.Context, output string, clips []ClipRange, mode ClipMode, progress func()) error {
+	if len(clips) == 0 {
+		return fmt.Errorf("no clips to cut")
+	}
+
+	for _, c := range clips {
+		if c.Duration() <= 0 {
+			return fmt.Errorf("invalid clip range %v-%v of %s", c.Start, c.End, c.Input)
+		}
+	}
+
+	// hold read locks on all of the inputs until the clip is complete
+	locks := make(map[string]*fsutil.LockContext)
+	for _, c := range clips {
+		if _, found := locks[c.Input]; !found {
+			lockCtx := g.LockManager.ReadLock(ctx, c.Input)
+			defer lockCtx.Cancel()
+			locks[c.Input] = lockCtx
+		}
+	}
+
+	lockCtx := locks[clips[0].Input]
+
+	if progress == nil {
+		progress = func() {}
+	}
+
+	var fn generateFn
+	if len(clips) == 1 {
+		clipFn := g.clip(clips[0], mode, false)
+		fn = func(lockCtx *fsutil.LockContext, tmpFn string) error {
+			if err := clipFn(lockCtx, tmpFn); err != nil {
+				return err
+			}
+
+			progress()
+			return nil
+		}
+	} else {
+		fn = g.joinClips(clips, mode, locks, progress)
+	}
+
+	if err := g.generateFile(lockCtx, g.ScenePaths, mode.pattern(), output, fn); err != nil {
+		return err
+	}
+
+	logger.Debugf("created clip from %d ranges: %s", len(clips), output)
+
+	return nil
+}
+
+// joinClips returns a generateFn which cuts each range into a temporary
+// file, then splices them together.
+func (g Generator) joinClips(clips []ClipRange, mode ClipMode, locks map[string]*fsutil.LockContext, progress func()) generateFn {
+	return func(lockCtx *fsutil.LockContext, tmpFn string) error {
+		var chunkFiles []string
+		defer func() {
+			removeFiles(chunkFiles)
+		}()
+
+		for i, c := range clips {
+			chunkFile, err := g.tempFile(g.ScenePaths, mode.pattern())
+			if err != nil {
+				return err
+			}
+
+			chunkFiles = append(chunkFiles, chunkFile.Name())
+
+			logger.Progressf("[generator] cutting clip %d of %d", i+1, len(clips))
+
+			if err := g.clip(c, mode, true)(locks[c.Input], chunkFile.Name()); err != nil {
+				return err
+			}
+
+			progress()
+		}
+
+		concatFile, err := g.generateConcatFile(chunkFiles)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(concatFile)
+
+		args := transcoder.Splice(concatFile, transcoder.SpliceOptions{
+			OutputPath: tmpFn,
+			Format:     mode.format(),
+		})
+
+		return g.generate(lockCtx, args)
+	}
+}
+
+// clip returns a generateFn which cuts a single range. If join is true,
+// accurate clips are scaled to a common size so they can be spliced.
+func (g Generator) clip(c ClipRange, mode ClipMode, join bool) generateFn {
+	return func(lockCtx *fsutil.LockContext, tmpFn string) error {
+		options := transcoder.TranscodeOptions{
+			OutputPath: tmpFn,
+			Format:     mode.format(),
+			StartTime:  c.Start,
+			Duration:   c.Duration(),
+		}
+
+		switch mode {
+		case ClipModeCopy:
+			options.VideoCodec = ffmpeg.VideoCodecCopy
+			options.AudioCodec = ffmpeg.AudioCodecCopy
+			// shift timestamps so that the clip starts at zero
+			options.ExtraOutputArgs = []string{"-avoid_negative_ts", "make_zero"}
+		case ClipModeAccurate:
+			var videoArgs ffmpeg.Args
+			if join {
+				var videoFilter ffmpeg.VideoFilter
+				videoFilter = videoFilter.Append(fmt.Sprintf(
+					"scale=%[1]d:%[2]d:force_original_aspect_ratio=decrease,pad=%[1]d:%[2]d:(ow-iw)/2:(oh-ih)/2,setsar=1",
+					clipJoinWidth, clipJoinHeight,
+				))
+				videoArgs = videoArgs.VideoFilter(videoFilter)
+			}
+
+			videoArgs = append(videoArgs,
+				"-pix_fmt", "yuv420p",
+				"-profile:v", "high",
+				"-preset", "fast",
+				"-crf", "21",
+				"-movflags", "+faststart",
+			)
+
+			var audioArgs ffmpeg.Args
+			audioArgs = audioArgs.AudioBitrate(clipAudioBitrate)
+
+			options.SlowSeek = true
+			options.VideoCodec = ffmpeg.VideoCodecLibX264
+			options.VideoArgs = videoArgs
+			options.AudioCodec = ffmpeg.AudioCodecAAC
+			options.AudioArgs = audioArgs
+			options.ExtraInputArgs = g.FFMpegConfig.GetTranscodeInputArgs()
+			options.ExtraOutputArgs = g.FFMpegConfig.GetTranscodeOutputArgs()
+		}
+
+		args := transcoder.Transcode(c.Input, options)
+
+		return g.generate(lockCtx, args)
+	}
+}
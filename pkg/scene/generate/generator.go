@@ -18,6 +18,9 @@ const (
 	jpgPattern  = "*.jpg"
 	txtPattern  = "*.txt"
 	vttPattern  = "*.vtt"
+	bifPattern  = "*.bif"
+	m3u8Pattern = "*.m3u8"
+	jsonPattern = "*.json"
 )
 
 type Paths interface {
@@ -41,12 +44,18 @@ type ScenePaths interface {
 	GetSpriteImageFilePath(checksum string) string
 	GetSpriteVttFilePath(checksum string) string
 
+	GetTrickplayBIFPath(checksum string) string
+	GetTrickplayDir(checksum string) string
+
 	GetTranscodePath(checksum string) string
+
+	GetSceneCutsPath(checksum string) string
 }
 
 type FFMpegConfig interface {
 	GetTranscodeInputArgs() []string
 	GetTranscodeOutputArgs() []string
+	GetTranscodeHardwareAcceleration() bool
 }
 
 type Generator struct {
@@ -13,6 +13,7 @@ import (
 	"github.com/stashapp/stash/pkg/ffmpeg/transcoder"
 	"github.com/stashapp/stash/pkg/fsutil"
 	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
 )
 
 const (
@@ -33,6 +34,11 @@ type PreviewOptions struct {
 	Preset string
 
 	Audio bool
+	// AudioGain is the gain in dB applied to the preview audio, if set.
+	AudioGain *float64
+	// Crop is the region of the frame to keep, excluding black bars.
+	// The full frame is used if nil.
+	Crop *models.VideoCrop
 }
 
 func getExcludeValue(videoDuration float64, v string) float64 {
@@ -128,6 +134,8 @@ func (g *Generator) previewVideo(input string, videoDuration float64, options Pr
 				Duration:   segmentDuration,
 				OutputPath: chunkFile.Name(),
 				Audio:      options.Audio,
+				AudioGain:  options.AudioGain,
+				Crop:       options.Crop,
 				Preset:     options.Preset,
 			}
 
@@ -157,6 +165,8 @@ func (g *Generator) previewVideoSingle(input string, videoDuration float64, opti
 			Duration:   videoDuration,
 			OutputPath: tmpFn,
 			Audio:      options.Audio,
+			AudioGain:  options.AudioGain,
+			Crop:       options.Crop,
 			Preset:     options.Preset,
 		}
 
@@ -169,11 +179,16 @@ type previewChunkOptions struct {
 	Duration   float64
 	OutputPath string
 	Audio      bool
+	AudioGain  *float64
+	Crop       *models.VideoCrop
 	Preset     string
 }
 
 func (g Generator) previewVideoChunk(lockCtx *fsutil.LockContext, fn string, options previewChunkOptions, fallback bool, useVsync2 bool) error {
 	var videoFilter ffmpeg.VideoFilter
+	if options.Crop != nil {
+		videoFilter = videoFilter.CropRect(*options.Crop)
+	}
 	videoFilter = videoFilter.ScaleWidth(scenePreviewWidth)
 
 	var videoArgs ffmpeg.Args
@@ -211,6 +226,9 @@ func (g Generator) previewVideoChunk(lockCtx *fsutil.LockContext, fn string, opt
 	if options.Audio {
 		var audioArgs ffmpeg.Args
 		audioArgs = audioArgs.AudioBitrate(scenePreviewAudioBitrate)
+		if options.AudioGain != nil {
+			audioArgs = audioArgs.AudioFilter(ffmpeg.VolumeFilter(*options.AudioGain))
+		}
 
 		trimOptions.AudioCodec = ffmpeg.AudioCodecAAC
 		trimOptions.AudioArgs = audioArgs
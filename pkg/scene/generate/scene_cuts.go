@@ -0,0 +1,76 @@
+package generate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/stashapp/stash/pkg/ffmpeg"
+	"github.com/stashapp/stash/pkg/fsutil"
+	"github.com/stashapp/stash/pkg/logger"
+)
+
+// SceneCuts detects the scene cuts of the input and writes them to the
+// scene cuts file for hash.
+func (g Generator) SceneCuts(ctx context.Context, input string, hash string) error {
+	lockCtx := g.LockManager.ReadLock(ctx, input)
+	defer lockCtx.Cancel()
+
+	output := g.ScenePaths.GetSceneCutsPath(hash)
+	if !g.Overwrite {
+		if exists, _ := fsutil.FileExists(output); exists {
+			return nil
+		}
+	}
+
+	logger.Infof("[generator] detecting scene cuts for %s", input)
+
+	if err := g.generateFile(lockCtx, g.ScenePaths, jsonPattern, output, g.sceneCuts(input)); err != nil {
+		return err
+	}
+
+	logger.Debug("created scene cuts: ", output)
+
+	return nil
+}
+
+func (g Generator) sceneCuts(input string) generateFn {
+	return func(lockCtx *fsutil.LockContext, tmpFn string) error {
+		cuts, err := g.Encoder.DetectSceneCuts(lockCtx, input, ffmpeg.SceneCutThreshold)
+		if err != nil {
+			return err
+		}
+
+		// write an empty list rather than null if there are no cuts
+		if cuts == nil {
+			cuts = ffmpeg.SceneCuts{}
+		}
+
+		data, err := json.Marshal(cuts)
+		if err != nil {
+			return fmt.Errorf("encoding scene cuts: %w", err)
+		}
+
+		return os.WriteFile(tmpFn, data, 0644)
+	}
+}
+
+// ReadSceneCuts reads the scene cuts file at path. Returns nil if the file
+// does not exist.
+func ReadSceneCuts(path string) (ffmpeg.SceneCuts, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var ret ffmpeg.SceneCuts
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return nil, fmt.Errorf("decoding scene cuts %s: %w", path, err)
+	}
+
+	return ret, nil
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stashapp/stash/pkg/ffmpeg/transcoder"
 	"github.com/stashapp/stash/pkg/fsutil"
 	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
 )
 
 const (
@@ -19,6 +20,9 @@ const (
 
 type ScreenshotOptions struct {
 	At *float64
+	// Crop is the region of the frame to keep, excluding black bars.
+	// The full frame is used if nil.
+	Crop *models.VideoCrop
 }
 
 func (g Generator) Screenshot(ctx context.Context, input string, videoWidth int, videoDuration float64, options ScreenshotOptions) ([]byte, error) {
@@ -35,6 +39,7 @@ func (g Generator) Screenshot(ctx context.Context, input string, videoWidth int,
 	ret, err := g.generateBytes(lockCtx, g.ScenePaths, jpgPattern, g.screenshot(input, screenshotOptions{
 		Time:    at,
 		Quality: screenshotQuality,
+		Crop:    options.Crop,
 		// default Width is video width
 	}))
 	if err != nil {
@@ -48,6 +53,7 @@ type screenshotOptions struct {
 	Time    float64
 	Width   int
 	Quality int
+	Crop    *models.VideoCrop
 }
 
 func (g Generator) screenshot(input string, options screenshotOptions) generateFn {
@@ -57,6 +63,7 @@ func (g Generator) screenshot(input string, options screenshotOptions) generateF
 			OutputType: transcoder.ScreenshotOutputTypeImage2,
 			Quality:    options.Quality,
 			Width:      options.Width,
+			Crop:       options.Crop,
 		}
 
 		args := transcoder.ScreenshotTime(input, options.Time, ssOptions)
@@ -15,6 +15,7 @@ import (
 	"github.com/stashapp/stash/pkg/ffmpeg"
 	"github.com/stashapp/stash/pkg/ffmpeg/transcoder"
 	"github.com/stashapp/stash/pkg/fsutil"
+	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/utils"
 )
 
@@ -26,14 +27,23 @@ const (
 	spriteChunks = spriteRows * spriteCols
 )
 
-func (g Generator) SpriteScreenshot(ctx context.Context, input string, seconds float64) (image.Image, error) {
+// SpriteScreenshot extracts a single sprite frame at the given time. The
+// frame is cropped to crop if it is not nil.
+func (g Generator) SpriteScreenshot(ctx context.Context, input string, seconds float64, crop *models.VideoCrop) (image.Image, error) {
 	lockCtx := g.LockManager.ReadLock(ctx, input)
 	defer lockCtx.Cancel()
 
+	return g.screenshotImage(lockCtx, input, seconds, spriteScreenshotWidth, crop)
+}
+
+// screenshotImage extracts a single frame at the given time, cropped to crop
+// if not nil and scaled to width.
+func (g Generator) screenshotImage(lockCtx *fsutil.LockContext, input string, seconds float64, width int, crop *models.VideoCrop) (image.Image, error) {
 	ssOptions := transcoder.ScreenshotOptions{
 		OutputPath: "-",
 		OutputType: transcoder.ScreenshotOutputTypeBMP,
-		Width:      spriteScreenshotWidth,
+		Width:      width,
+		Crop:       crop,
 	}
 
 	args := transcoder.ScreenshotTime(input, seconds, ssOptions)
@@ -41,7 +51,7 @@ func (g Generator) SpriteScreenshot(ctx context.Context, input string, seconds f
 	return g.generateImage(lockCtx, args)
 }
 
-func (g Generator) SpriteScreenshotSlow(ctx context.Context, input string, frame int) (image.Image, error) {
+func (g Generator) SpriteScreenshotSlow(ctx context.Context, input string, frame int, crop *models.VideoCrop) (image.Image, error) {
 	lockCtx := g.LockManager.ReadLock(ctx, input)
 	defer lockCtx.Cancel()
 
@@ -49,6 +59,7 @@ func (g Generator) SpriteScreenshotSlow(ctx context.Context, input string, frame
 		OutputPath: "-",
 		OutputType: transcoder.ScreenshotOutputTypeBMP,
 		Width:      spriteScreenshotWidth,
+		Crop:       crop,
 	}
 
 	args := transcoder.ScreenshotFrame(input, frame, ssOptions)
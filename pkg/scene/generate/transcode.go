@@ -7,11 +7,33 @@ import (
 	"github.com/stashapp/stash/pkg/ffmpeg/transcoder"
 	"github.com/stashapp/stash/pkg/fsutil"
 	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
 )
 
 type TranscodeOptions struct {
 	Width  int
 	Height int
+
+	// VideoCodec is the codec of the input video stream.
+	// Used to select a hardware decoder.
+	VideoCodec string
+
+	// Crop is the region of the frame to keep, excluding black bars.
+	// It is applied before scaling, and ignored if the video stream is
+	// copied.
+	Crop *models.VideoCrop
+}
+
+// videoFilter returns the filter cropping and scaling the video stream.
+func (o TranscodeOptions) videoFilter() ffmpeg.VideoFilter {
+	var videoFilter ffmpeg.VideoFilter
+	if o.Crop != nil {
+		videoFilter = videoFilter.CropRect(*o.Crop)
+	}
+	if o.Width != 0 && o.Height != 0 {
+		videoFilter = videoFilter.ScaleDimensions(o.Width, o.Height)
+	}
+	return videoFilter
 }
 
 func (g Generator) Transcode(ctx context.Context, input string, hash string, options TranscodeOptions) error {
@@ -64,30 +86,51 @@ func (g Generator) makeTranscode(lockCtx *fsutil.LockContext, hash string, gener
 	return nil
 }
 
+// transcodeCodec returns the video codec used to generate transcodes.
+// A hardware codec is used if hardware acceleration is enabled and supported.
+func (g Generator) transcodeCodec() ffmpeg.VideoCodec {
+	if g.FFMpegConfig.GetTranscodeHardwareAcceleration() {
+		if codec := g.Encoder.HWCodecMP4Compatible(); codec != nil {
+			return *codec
+		}
+	}
+
+	return ffmpeg.VideoCodecLibX264
+}
+
+// transcodeVideoArgs returns the encoder arguments for generating a transcode with codec.
+func transcodeVideoArgs(codec ffmpeg.VideoCodec) ffmpeg.Args {
+	if codec != ffmpeg.VideoCodecLibX264 {
+		// hardware codecs use the same settings as live transcoding
+		return ffmpeg.CodecOptions(codec)
+	}
+
+	return ffmpeg.Args{
+		"-pix_fmt", "yuv420p",
+		"-profile:v", "high",
+		"-level", "4.2",
+		"-preset", "superfast",
+		"-crf", "23",
+	}
+}
+
 func (g Generator) transcode(input string, options TranscodeOptions) generateFn {
 	return func(lockCtx *fsutil.LockContext, tmpFn string) error {
-		var videoArgs ffmpeg.Args
-		if options.Width != 0 && options.Height != 0 {
-			var videoFilter ffmpeg.VideoFilter
-			videoFilter = videoFilter.ScaleDimensions(options.Width, options.Height)
-			videoArgs = videoArgs.VideoFilter(videoFilter)
-		}
+		pipeline := g.Encoder.NewHWPipeline(lockCtx, g.transcodeCodec(), input, options.VideoCodec)
 
-		videoArgs = append(videoArgs,
-			"-pix_fmt", "yuv420p",
-			"-profile:v", "high",
-			"-level", "4.2",
-			"-preset", "superfast",
-			"-crf", "23",
-		)
+		videoFilter := options.videoFilter()
+
+		var videoArgs ffmpeg.Args
+		videoArgs = videoArgs.VideoFilter(pipeline.VideoFilter(videoFilter))
+		videoArgs = append(videoArgs, transcodeVideoArgs(pipeline.Codec)...)
 
 		args := transcoder.Transcode(input, transcoder.TranscodeOptions{
 			OutputPath: tmpFn,
-			VideoCodec: ffmpeg.VideoCodecLibX264,
+			VideoCodec: pipeline.Codec,
 			VideoArgs:  videoArgs,
 			AudioCodec: ffmpeg.AudioCodecAAC,
 
-			ExtraInputArgs:  g.FFMpegConfig.GetTranscodeInputArgs(),
+			ExtraInputArgs:  append(pipeline.InputArgs(nil), g.FFMpegConfig.GetTranscodeInputArgs()...),
 			ExtraOutputArgs: g.FFMpegConfig.GetTranscodeOutputArgs(),
 		})
 
@@ -97,31 +140,24 @@ func (g Generator) transcode(input string, options TranscodeOptions) generateFn
 
 func (g Generator) transcodeVideo(input string, options TranscodeOptions) generateFn {
 	return func(lockCtx *fsutil.LockContext, tmpFn string) error {
-		var videoArgs ffmpeg.Args
-		if options.Width != 0 && options.Height != 0 {
-			var videoFilter ffmpeg.VideoFilter
-			videoFilter = videoFilter.ScaleDimensions(options.Width, options.Height)
-			videoArgs = videoArgs.VideoFilter(videoFilter)
-		}
+		pipeline := g.Encoder.NewHWPipeline(lockCtx, g.transcodeCodec(), input, options.VideoCodec)
 
-		videoArgs = append(videoArgs,
-			"-pix_fmt", "yuv420p",
-			"-profile:v", "high",
-			"-level", "4.2",
-			"-preset", "superfast",
-			"-crf", "23",
-		)
+		videoFilter := options.videoFilter()
+
+		var videoArgs ffmpeg.Args
+		videoArgs = videoArgs.VideoFilter(pipeline.VideoFilter(videoFilter))
+		videoArgs = append(videoArgs, transcodeVideoArgs(pipeline.Codec)...)
 
 		var audioArgs ffmpeg.Args
 		audioArgs = audioArgs.SkipAudio()
 
 		args := transcoder.Transcode(input, transcoder.TranscodeOptions{
 			OutputPath: tmpFn,
-			VideoCodec: ffmpeg.VideoCodecLibX264,
+			VideoCodec: pipeline.Codec,
 			VideoArgs:  videoArgs,
 			AudioArgs:  audioArgs,
 
-			ExtraInputArgs:  g.FFMpegConfig.GetTranscodeInputArgs(),
+			ExtraInputArgs:  append(pipeline.InputArgs(nil), g.FFMpegConfig.GetTranscodeInputArgs()...),
 			ExtraOutputArgs: g.FFMpegConfig.GetTranscodeOutputArgs(),
 		})
 
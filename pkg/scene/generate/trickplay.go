@@ -0,0 +1,269 @@
+package generate
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+	"github.com/stashapp/stash/pkg/fsutil"
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+)
+
+const (
+	trickplayRows  = 10
+	trickplayCols  = 10
+	trickplayChunk = trickplayRows * trickplayCols
+
+	trickplayJPEGQuality = 80
+
+	// TrickplayManifest is the name of the tile playlist in the trickplay directory.
+	TrickplayManifest = "tiles.m3u8"
+)
+
+// bifMagic is the magic number of the Roku BIF format.
+var bifMagic = []byte{0x89, 'B', 'I', 'F', 0x0d, 0x0a, 0x1a, 0x0a}
+
+const (
+	bifVersion    = 0
+	bifHeaderSize = 64
+)
+
+type TrickplayOptions struct {
+	// Interval is the time between thumbnails, in seconds.
+	Interval float64
+	// Width is the width of each thumbnail.
+	Width int
+	// Crop is the region of the frame to keep, excluding black bars.
+	// The full frame is used if nil.
+	Crop *models.VideoCrop
+}
+
+// Trickplay generates a BIF file and a set of tiled thumbnail images with an
+// HLS image playlist for the given video.
+func (g Generator) Trickplay(ctx context.Context, input string, videoDuration float64, hash string, options TrickplayOptions) error {
+	if options.Interval <= 0 || options.Width <= 0 {
+		return fmt.Errorf("invalid trickplay options: interval %f, width %d", options.Interval, options.Width)
+	}
+
+	bifPath := g.ScenePaths.GetTrickplayBIFPath(hash)
+	tilesDir := g.ScenePaths.GetTrickplayDir(hash)
+	manifestPath := filepath.Join(tilesDir, TrickplayManifest)
+
+	bifRequired := true
+	tilesRequired := true
+	if !g.Overwrite {
+		if exists, _ := fsutil.FileExists(bifPath); exists {
+			bifRequired = false
+		}
+		if exists, _ := fsutil.FileExists(manifestPath); exists {
+			tilesRequired = false
+		}
+	}
+
+	if !bifRequired && !tilesRequired {
+		return nil
+	}
+
+	lockCtx := g.LockManager.ReadLock(ctx, input)
+	defer lockCtx.Cancel()
+
+	logger.Infof("[generator] generating trickplay for %s", input)
+
+	images, err := g.trickplayImages(lockCtx, input, videoDuration, options)
+	if err != nil {
+		return err
+	}
+
+	if bifRequired {
+		if err := g.generateFile(lockCtx, g.ScenePaths, bifPattern, bifPath, g.trickplayBIF(images, options.Interval)); err != nil {
+			return err
+		}
+
+		logger.Debug("created trickplay bif: ", bifPath)
+	}
+
+	if tilesRequired {
+		if err := g.trickplayTiles(lockCtx, tilesDir, images, options.Interval); err != nil {
+			return err
+		}
+
+		logger.Debug("created trickplay tiles: ", tilesDir)
+	}
+
+	return nil
+}
+
+func (g Generator) trickplayImages(lockCtx *fsutil.LockContext, input string, videoDuration float64, options TrickplayOptions) ([]image.Image, error) {
+	count := int(math.Ceil(videoDuration / options.Interval))
+	if count < 1 {
+		count = 1
+	}
+
+	var images []image.Image
+	for i := 0; i < count; i++ {
+		t := float64(i) * options.Interval
+
+		img, err := g.screenshotImage(lockCtx, input, t, options.Width, options.Crop)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, img)
+	}
+
+	return images, nil
+}
+
+func (g Generator) trickplayBIF(images []image.Image, interval float64) generateFn {
+	return func(lockCtx *fsutil.LockContext, tmpFn string) error {
+		var encoded [][]byte
+		for _, img := range images {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: trickplayJPEGQuality}); err != nil {
+				return fmt.Errorf("encoding trickplay image: %w", err)
+			}
+			encoded = append(encoded, buf.Bytes())
+		}
+
+		f, err := os.Create(tmpFn)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		return writeBIF(f, encoded, interval)
+	}
+}
+
+// writeBIF writes the given JPEG images to w in the Roku BIF format.
+// See https://developer.roku.com/docs/developer-program/media-playback/trick-mode/bif-file-creation.md
+func writeBIF(w io.Writer, images [][]byte, interval float64) error {
+	if len(images) == 0 {
+		return errors.New("no images")
+	}
+
+	header := make([]byte, bifHeaderSize)
+	copy(header, bifMagic)
+	binary.LittleEndian.PutUint32(header[8:], bifVersion)
+	binary.LittleEndian.PutUint32(header[12:], uint32(len(images)))
+	binary.LittleEndian.PutUint32(header[16:], uint32(math.Round(interval*1000)))
+
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	// one index entry per image, plus the terminating entry
+	offset := uint32(bifHeaderSize + (len(images)+1)*8)
+	index := make([]byte, 8)
+	for i, img := range images {
+		binary.LittleEndian.PutUint32(index[0:], uint32(i))
+		binary.LittleEndian.PutUint32(index[4:], offset)
+		if _, err := w.Write(index); err != nil {
+			return err
+		}
+		offset += uint32(len(img))
+	}
+
+	binary.LittleEndian.PutUint32(index[0:], math.MaxUint32)
+	binary.LittleEndian.PutUint32(index[4:], offset)
+	if _, err := w.Write(index); err != nil {
+		return err
+	}
+
+	for _, img := range images {
+		if _, err := w.Write(img); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g Generator) trickplayTiles(lockCtx *fsutil.LockContext, dir string, images []image.Image, interval float64) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("removing %s: %w", dir, err)
+	}
+	if err := fsutil.EnsureDir(dir); err != nil {
+		return fmt.Errorf("creating %s: %w", dir, err)
+	}
+
+	var tiles []int
+	for i := 0; i < len(images); i += trickplayChunk {
+		end := i + trickplayChunk
+		if end > len(images) {
+			end = len(images)
+		}
+
+		tile := combineTrickplayImages(images[i:end])
+		output := filepath.Join(dir, strconv.Itoa(len(tiles))+".jpg")
+		if err := g.generateFile(lockCtx, g.ScenePaths, jpgPattern, output, func(lockCtx *fsutil.LockContext, tmpFn string) error {
+			return imaging.Save(tile, tmpFn, imaging.JPEGQuality(trickplayJPEGQuality))
+		}); err != nil {
+			return err
+		}
+
+		tiles = append(tiles, end-i)
+	}
+
+	size := images[0].Bounds().Size()
+	manifest := trickplayManifest(tiles, size.X, size.Y, interval)
+
+	// write the manifest last, since its existence marks the tiles as complete
+	output := filepath.Join(dir, TrickplayManifest)
+	return g.generateFile(lockCtx, g.ScenePaths, m3u8Pattern, output, func(lockCtx *fsutil.LockContext, tmpFn string) error {
+		return os.WriteFile(tmpFn, []byte(manifest), 0644)
+	})
+}
+
+func combineTrickplayImages(images []image.Image) image.Image {
+	width := images[0].Bounds().Size().X
+	height := images[0].Bounds().Size().Y
+	montage := imaging.New(width*trickplayCols, height*trickplayRows, color.NRGBA{})
+	for index, img := range images {
+		x := width * (index % trickplayCols)
+		y := height * (index / trickplayCols)
+		montage = imaging.Paste(montage, img, image.Pt(x, y))
+	}
+
+	return montage
+}
+
+// trickplayManifest returns an HLS image playlist for the tiles.
+// tiles contains the number of thumbnails in each tile.
+func trickplayManifest(tiles []int, width int, height int, interval float64) string {
+	tileDuration := interval * trickplayChunk
+	intervalStr := strconv.FormatFloat(interval, 'f', -1, 64)
+
+	lines := []string{
+		"#EXTM3U",
+		fmt.Sprintf("#EXT-X-TARGETDURATION:%d", int(math.Ceil(tileDuration))),
+		"#EXT-X-VERSION:7",
+		"#EXT-X-MEDIA-SEQUENCE:0",
+		"#EXT-X-PLAYLIST-TYPE:VOD",
+		"#EXT-X-IMAGES-ONLY",
+	}
+
+	for i, count := range tiles {
+		lines = append(lines,
+			fmt.Sprintf("#EXTINF:%s,", strconv.FormatFloat(interval*float64(count), 'f', 3, 64)),
+			fmt.Sprintf("#EXT-X-TILES:RESOLUTION=%dx%d,LAYOUT=%dx%d,DURATION=%s", width, height, trickplayCols, trickplayRows, intervalStr),
+			strconv.Itoa(i)+".jpg",
+		)
+	}
+
+	lines = append(lines, "#EXT-X-ENDLIST", "")
+
+	return strings.Join(lines, "\n")
+}
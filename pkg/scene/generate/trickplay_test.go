@@ -0,0 +1,63 @@
+package generate
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBIF(t *testing.T) {
+	images := [][]byte{
+		[]byte("first"),
+		[]byte("second!"),
+	}
+
+	var buf bytes.Buffer
+	if err := writeBIF(&buf, images, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	assert.Equal(t, bifMagic, b[:8])
+	assert.Equal(t, uint32(0), binary.LittleEndian.Uint32(b[8:]))
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(b[12:]))
+	assert.Equal(t, uint32(10000), binary.LittleEndian.Uint32(b[16:]))
+
+	// index starts after the header, with an entry per image and a terminating entry
+	dataStart := uint32(bifHeaderSize + 3*8)
+	index := b[bifHeaderSize:]
+	assert.Equal(t, uint32(0), binary.LittleEndian.Uint32(index[0:]))
+	assert.Equal(t, dataStart, binary.LittleEndian.Uint32(index[4:]))
+	assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(index[8:]))
+	assert.Equal(t, dataStart+5, binary.LittleEndian.Uint32(index[12:]))
+	assert.Equal(t, uint32(0xffffffff), binary.LittleEndian.Uint32(index[16:]))
+	assert.Equal(t, dataStart+12, binary.LittleEndian.Uint32(index[20:]))
+
+	assert.Equal(t, "firstsecond!", string(b[dataStart:]))
+	assert.Len(t, b, int(dataStart+12))
+
+	assert.Error(t, writeBIF(&buf, nil, 10))
+}
+
+func TestTrickplayManifest(t *testing.T) {
+	got := trickplayManifest([]int{100, 20}, 320, 180, 10)
+
+	want := `#EXTM3U
+#EXT-X-TARGETDURATION:1000
+#EXT-X-VERSION:7
+#EXT-X-MEDIA-SEQUENCE:0
+#EXT-X-PLAYLIST-TYPE:VOD
+#EXT-X-IMAGES-ONLY
+#EXTINF:1000.000,
+#EXT-X-TILES:RESOLUTION=320x180,LAYOUT=10x10,DURATION=10
+0.jpg
+#EXTINF:200.000,
+#EXT-X-TILES:RESOLUTION=320x180,LAYOUT=10x10,DURATION=10
+1.jpg
+#EXT-X-ENDLIST
+`
+
+	assert.Equal(t, want, got)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/stashapp/stash/pkg/logger"
 	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/models/jsonschema"
 	"github.com/stashapp/stash/pkg/sliceutil/stringslice"
@@ -14,6 +15,11 @@ import (
 type ImporterReaderWriter interface {
 	models.SceneCreatorUpdater
 	FindByFileID(ctx context.Context, fileID models.FileID) ([]*models.Scene, error)
+	SetActivity(ctx context.Context, sceneID int, activity []*models.SceneActivity) error
+}
+
+type UserFinder interface {
+	FindByUsername(ctx context.Context, username string) (*models.User, error)
 }
 
 type Importer struct {
@@ -24,12 +30,14 @@ type Importer struct {
 	PerformerWriter     models.PerformerFinderCreator
 	MovieWriter         models.MovieFinderCreator
 	TagWriter           models.TagFinderCreator
+	UserFinder          UserFinder
 	Input               jsonschema.Scene
 	MissingRefBehaviour models.ImportMissingRefEnum
 	FileNamingAlgorithm models.HashAlgorithm
 
 	ID             int
 	scene          models.Scene
+	activity       []*models.SceneActivity
 	coverImageData []byte
 }
 
@@ -60,6 +68,10 @@ func (i *Importer) PreImport(ctx context.Context) error {
 		return err
 	}
 
+	if err := i.populateActivity(ctx); err != nil {
+		return err
+	}
+
 	var err error
 	if len(i.Input.Cover) > 0 {
 		i.coverImageData, err = utils.ProcessBase64Image(i.Input.Cover)
@@ -365,6 +377,53 @@ func (i *Importer) populateTags(ctx context.Context) error {
 	return nil
 }
 
+// populateActivity converts the activity of each user. Users are not
+// included in the import, so activity of users which do not exist is
+// ignored.
+func (i *Importer) populateActivity(ctx context.Context) error {
+	if i.UserFinder == nil {
+		return nil
+	}
+
+	for _, a := range i.Input.Activity {
+		u, err := i.UserFinder.FindByUsername(ctx, a.User)
+		if err != nil {
+			return fmt.Errorf("error finding user: %v", err)
+		}
+
+		if u == nil {
+			logger.Warnf("[scenes] ignoring activity of missing user %s", a.User)
+			continue
+		}
+
+		activity := &models.SceneActivity{
+			UserID:       u.ID,
+			OCounter:     a.OCounter,
+			ResumeTime:   a.ResumeTime,
+			PlayCount:    a.PlayCount,
+			PlayDuration: a.PlayDuration,
+		}
+
+		if a.Rating != 0 {
+			rating := a.Rating
+			activity.Rating = &rating
+		}
+
+		if !a.LastPlayedAt.IsZero() {
+			t := a.LastPlayedAt.GetTime()
+			activity.LastPlayedAt = &t
+		}
+
+		for _, t := range a.PlayHistory {
+			activity.PlayHistory = append(activity.PlayHistory, t.GetTime())
+		}
+
+		i.activity = append(i.activity, activity)
+	}
+
+	return nil
+}
+
 func (i *Importer) PostImport(ctx context.Context, id int) error {
 	if len(i.coverImageData) > 0 {
 		if err := i.ReaderWriter.UpdateCover(ctx, id, i.coverImageData); err != nil {
@@ -372,6 +431,12 @@ func (i *Importer) PostImport(ctx context.Context, id int) error {
 		}
 	}
 
+	if len(i.activity) > 0 {
+		if err := i.ReaderWriter.SetActivity(ctx, id, i.activity); err != nil {
+			return fmt.Errorf("error setting scene activity: %v", err)
+		}
+	}
+
 	return nil
 }
 
@@ -396,3 +396,50 @@ func TestImporterPreImportWithMissingTagCreateErr(t *testing.T) {
 	err := i.PreImport(testCtx)
 	assert.NotNil(t, err)
 }
+
+func TestImporterPreImportWithActivity(t *testing.T) {
+	userReaderWriter := &mocks.UserReaderWriter{}
+
+	const (
+		existingUserID   = 106
+		existingUserName = "existingUserName"
+		missingUserName  = "missingUserName"
+	)
+
+	i := Importer{
+		UserFinder: userReaderWriter,
+		Input: jsonschema.Scene{
+			Activity: []jsonschema.SceneActivity{
+				{
+					User:      existingUserName,
+					Rating:    60,
+					PlayCount: 1,
+				},
+				{
+					User:      missingUserName,
+					PlayCount: 2,
+				},
+			},
+		},
+	}
+
+	userReaderWriter.On("FindByUsername", testCtx, existingUserName).Return(&models.User{
+		ID:       existingUserID,
+		Username: existingUserName,
+	}, nil).Once()
+	userReaderWriter.On("FindByUsername", testCtx, missingUserName).Return(nil, nil).Once()
+
+	err := i.PreImport(testCtx)
+	assert.Nil(t, err)
+
+	rating := 60
+	assert.Equal(t, []*models.SceneActivity{
+		{
+			UserID:    existingUserID,
+			Rating:    &rating,
+			PlayCount: 1,
+		},
+	}, i.activity)
+
+	userReaderWriter.AssertExpectations(t)
+}
@@ -37,6 +37,18 @@ func MigrateHash(p *paths.Paths, oldHash string, newHash string) {
 	migrateSceneFiles(oldPath, newPath)
 	migrateVttFile(newVttPath, oldPath, newPath)
 
+	oldPath = scenePaths.GetTrickplayBIFPath(oldHash)
+	newPath = scenePaths.GetTrickplayBIFPath(newHash)
+	migrateSceneFiles(oldPath, newPath)
+
+	oldPath = scenePaths.GetTrickplayDir(oldHash)
+	newPath = scenePaths.GetTrickplayDir(newHash)
+	migrateSceneFolder(oldPath, newPath)
+
+	oldPath = scenePaths.GetSubtitlesDir(oldHash)
+	newPath = scenePaths.GetSubtitlesDir(newHash)
+	migrateSceneFolder(oldPath, newPath)
+
 	oldPath = scenePaths.GetInteractiveHeatmapPath(oldHash)
 	newPath = scenePaths.GetInteractiveHeatmapPath(newHash)
 	migrateSceneFiles(oldPath, newPath)
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+const (
+	ApiKeyHeader    = "ApiKey"
+	ApiKeyParameter = "apikey"
+)
+
+type apiKeyContextValue struct {
+	key   *models.APIKey
+	value string
+}
+
+// GetRequestAPIKey returns the API key provided in the header or query
+// parameters of the request. Returns an empty string if not present.
+func GetRequestAPIKey(r *http.Request) string {
+	apiKey := r.Header.Get(ApiKeyHeader)
+
+	// try getting the api key as a query parameter
+	if apiKey == "" {
+		apiKey = r.URL.Query().Get(ApiKeyParameter)
+	}
+
+	return apiKey
+}
+
+// SetCurrentAPIKey sets the API key which authenticated the request in the
+// context. value is the key as provided in the request.
+func SetCurrentAPIKey(ctx context.Context, key *models.APIKey, value string) context.Context {
+	return context.WithValue(ctx, contextAPIKey, apiKeyContextValue{
+		key:   key,
+		value: value,
+	})
+}
+
+// GetCurrentAPIKey returns the API key which authenticated the request.
+// Returns nil if the request was not authenticated by an API key.
+func GetCurrentAPIKey(ctx context.Context) *models.APIKey {
+	if v, ok := ctx.Value(contextAPIKey).(apiKeyContextValue); ok {
+		return v.key
+	}
+
+	return nil
+}
+
+// GetCurrentAPIKeyValue returns the API key as provided in the request.
+// Returns an empty string if the request was not authenticated by an API key.
+func GetCurrentAPIKeyValue(ctx context.Context) string {
+	if v, ok := ctx.Value(contextAPIKey).(apiKeyContextValue); ok {
+		return v.value
+	}
+
+	return ""
+}
+
+// HasScope returns true if the request was not authenticated by an API key,
+// or if the API key grants scope.
+func HasScope(ctx context.Context, scope models.APIKeyScope) bool {
+	k := GetCurrentAPIKey(ctx)
+	return k == nil || k.HasScope(scope)
+}
@@ -17,20 +17,9 @@ func (e ExternalAccessError) Error() string {
 
 func CheckAllowPublicWithoutAuth(c ExternalAccessConfig, r *http.Request) error {
 	if !c.HasCredentials() && !c.GetDangerousAllowPublicWithoutAuth() && !c.IsNewSystem() {
-		requestIPString, _, err := net.SplitHostPort(r.RemoteAddr)
+		requestIP, err := parseRemoteIP(r)
 		if err != nil {
-			return fmt.Errorf("error parsing remote host (%s): %w", r.RemoteAddr, err)
-		}
-
-		// presence of scope ID in IPv6 addresses prevents parsing. Remove if present
-		scopeIDIndex := strings.Index(requestIPString, "%")
-		if scopeIDIndex != -1 {
-			requestIPString = requestIPString[0:scopeIDIndex]
-		}
-
-		requestIP := net.ParseIP(requestIPString)
-		if requestIP == nil {
-			return fmt.Errorf("unable to parse remote host (%s)", requestIPString)
+			return err
 		}
 
 		if r.Header.Get("X-FORWARDED-FOR") != "" {
@@ -59,6 +48,28 @@ func CheckAllowPublicWithoutAuth(c ExternalAccessConfig, r *http.Request) error
 	return nil
 }
 
+// parseRemoteIP returns the IP address of the client which made the request.
+// This is the address of the proxy if the request was proxied.
+func parseRemoteIP(r *http.Request) (net.IP, error) {
+	requestIPString, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing remote host (%s): %w", r.RemoteAddr, err)
+	}
+
+	// presence of scope ID in IPv6 addresses prevents parsing. Remove if present
+	scopeIDIndex := strings.Index(requestIPString, "%")
+	if scopeIDIndex != -1 {
+		requestIPString = requestIPString[0:scopeIDIndex]
+	}
+
+	requestIP := net.ParseIP(requestIPString)
+	if requestIP == nil {
+		return nil, fmt.Errorf("unable to parse remote host (%s)", requestIPString)
+	}
+
+	return requestIP, nil
+}
+
 func CheckExternalAccessTripwire(c ExternalAccessConfig) *ExternalAccessError {
 	if !c.HasCredentials() && !c.GetDangerousAllowPublicWithoutAuth() {
 		if remoteIP := c.GetSecurityTripwireAccessedFromPublicInternet(); remoteIP != "" {
@@ -1,5 +1,11 @@
 package session
 
+import (
+	"context"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
 type ExternalAccessConfig interface {
 	HasCredentials() bool
 	GetDangerousAllowPublicWithoutAuth() bool
@@ -8,10 +14,42 @@ type ExternalAccessConfig interface {
 }
 
 type SessionConfig interface {
-	GetUsername() string
-	GetAPIKey() string
-
 	GetSessionStoreKey() []byte
 	GetMaxSessionAge() int
-	ValidateCredentials(username string, password string) bool
+	OIDCConfig
+	TrustedProxyConfig
+}
+
+// OIDCConfig configures single sign-on using an OpenID Connect provider.
+type OIDCConfig interface {
+	// GetOIDCIssuer returns an empty string if OpenID Connect is disabled.
+	GetOIDCIssuer() string
+	GetOIDCClientID() string
+	GetOIDCClientSecret() string
+	// GetOIDCUsernameClaim returns the ID token claim which contains the
+	// username of the user.
+	GetOIDCUsernameClaim() string
+}
+
+// TrustedProxyConfig configures authentication by a reverse proxy which
+// provides the username of the authenticated user in a request header.
+type TrustedProxyConfig interface {
+	// GetTrustedProxyHeader returns an empty string if disabled.
+	GetTrustedProxyHeader() string
+	// GetTrustedProxyNetworks returns the IP addresses and CIDR ranges of
+	// the proxies which may set the header.
+	GetTrustedProxyNetworks() []string
+}
+
+// UserFinder resolves the users which may log in.
+// Methods return a nil user if no matching user is found.
+type UserFinder interface {
+	FindUser(ctx context.Context, id int) (*models.User, error)
+	FindUserByCredentials(ctx context.Context, username string, password string) (*models.User, error)
+	// FindExternalUser finds the user authenticated by single sign-on,
+	// creating the user if configured to do so.
+	FindExternalUser(ctx context.Context, username string) (*models.User, error)
+	// HasUsers returns true if any users exist, in which case
+	// authentication is required.
+	HasUsers(ctx context.Context) (bool, error)
 }
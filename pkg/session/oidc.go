@@ -0,0 +1,205 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/stashapp/stash/pkg/hash"
+	"github.com/stashapp/stash/pkg/logger"
+)
+
+const (
+	oidcCookieName   = "oidc"
+	oidcStateKey     = "state"
+	oidcNonceKey     = "nonce"
+	oidcReturnURLKey = "returnURL"
+
+	// the login must be completed within this number of seconds
+	oidcCookieMaxAge = 10 * 60
+
+	oidcRandomKeyLength = 16
+)
+
+var ErrOIDCDisabled = errors.New("OpenID Connect is not configured")
+
+// oidcProviderCache holds the provider for the configured issuer, so that the
+// discovery document and keys are not retrieved on every login.
+type oidcProviderCache struct {
+	mutex    sync.Mutex
+	issuer   string
+	provider *oidc.Provider
+}
+
+func (c *oidcProviderCache) get(issuer string) (*oidc.Provider, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.provider != nil && c.issuer == issuer {
+		return c.provider, nil
+	}
+
+	// the provider retains the context to refresh the keys, so the request
+	// context cannot be used
+	p, err := oidc.NewProvider(context.Background(), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("getting OpenID Connect provider %s: %w", issuer, err)
+	}
+
+	c.issuer = issuer
+	c.provider = p
+	return p, nil
+}
+
+// OIDCEnabled returns true if single sign-on using OpenID Connect is
+// configured.
+func (s *Store) OIDCEnabled() bool {
+	return s.config.GetOIDCIssuer() != "" && s.config.GetOIDCClientID() != ""
+}
+
+func (s *Store) oidcConfig(redirectURL string) (*oidc.Provider, *oauth2.Config, error) {
+	if !s.OIDCEnabled() {
+		return nil, nil, ErrOIDCDisabled
+	}
+
+	provider, err := s.oidc.get(s.config.GetOIDCIssuer())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return provider, &oauth2.Config{
+		ClientID:     s.config.GetOIDCClientID(),
+		ClientSecret: s.config.GetOIDCClientSecret(),
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURL,
+		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+	}, nil
+}
+
+// OIDCLogin redirects to the authorization endpoint of the OpenID Connect
+// provider. redirectURL is the URL of the callback handler, which must call
+// OIDCCallback with the same redirectURL. returnURL is returned by
+// OIDCCallback once the user is logged in.
+func (s *Store) OIDCLogin(w http.ResponseWriter, r *http.Request, redirectURL string, returnURL string) error {
+	_, oauthConfig, err := s.oidcConfig(redirectURL)
+	if err != nil {
+		return err
+	}
+
+	state, err := hash.GenerateRandomKey(oidcRandomKeyLength)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := hash.GenerateRandomKey(oidcRandomKeyLength)
+	if err != nil {
+		return err
+	}
+
+	// ignore error - we want a new session regardless
+	loginSession, _ := s.sessionStore.New(r, oidcCookieName)
+	options := *s.sessionStore.Options
+	options.MaxAge = oidcCookieMaxAge
+	loginSession.Options = &options
+
+	loginSession.Values[oidcStateKey] = state
+	loginSession.Values[oidcNonceKey] = nonce
+	loginSession.Values[oidcReturnURLKey] = returnURL
+
+	if err := loginSession.Save(r, w); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, oauthConfig.AuthCodeURL(state, oidc.Nonce(nonce)), http.StatusFound)
+	return nil
+}
+
+// OIDCCallback completes the login started by OIDCLogin, using the
+// authorization code provided by the OpenID Connect provider. The user is
+// matched by the configured username claim of the ID token. Returns the
+// return URL provided to OIDCLogin.
+func (s *Store) OIDCCallback(w http.ResponseWriter, r *http.Request, redirectURL string) (string, error) {
+	ctx := r.Context()
+
+	provider, oauthConfig, err := s.oidcConfig(redirectURL)
+	if err != nil {
+		return "", err
+	}
+
+	loginSession, err := s.sessionStore.Get(r, oidcCookieName)
+	if err != nil || loginSession.IsNew {
+		return "", errors.New("login session not found or expired")
+	}
+
+	state, _ := loginSession.Values[oidcStateKey].(string)
+	nonce, _ := loginSession.Values[oidcNonceKey].(string)
+	returnURL, _ := loginSession.Values[oidcReturnURLKey].(string)
+
+	// the login session may only be used once
+	loginSession.Options.MaxAge = -1
+	if err := loginSession.Save(r, w); err != nil {
+		return "", err
+	}
+
+	q := r.URL.Query()
+	if providerErr := q.Get("error"); providerErr != "" {
+		return "", fmt.Errorf("provider returned error %s: %s", providerErr, q.Get("error_description"))
+	}
+
+	if state == "" || q.Get("state") != state {
+		return "", errors.New("state does not match")
+	}
+
+	token, err := oauthConfig.Exchange(ctx, q.Get("code"))
+	if err != nil {
+		return "", fmt.Errorf("exchanging authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return "", errors.New("token response does not contain an ID token")
+	}
+
+	verifier := provider.Verifier(&oidc.Config{ClientID: oauthConfig.ClientID})
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return "", fmt.Errorf("verifying ID token: %w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return "", errors.New("nonce does not match")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return "", fmt.Errorf("reading ID token claims: %w", err)
+	}
+
+	claim := s.config.GetOIDCUsernameClaim()
+	username, _ := claims[claim].(string)
+	if username == "" {
+		return "", fmt.Errorf("ID token does not contain the %s claim", claim)
+	}
+
+	user, err := s.users.FindExternalUser(ctx, username)
+	if err != nil {
+		return "", err
+	}
+
+	if user == nil {
+		return "", &InvalidCredentialsError{Username: username}
+	}
+
+	logger.Infof("User %s logged in using OpenID Connect", user.Username)
+
+	if err := s.saveUserSession(w, r, user); err != nil {
+		return "", err
+	}
+
+	return returnURL, nil
+}
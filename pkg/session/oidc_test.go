@@ -0,0 +1,300 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testClientID    = "stash"
+	testRedirectURL = "http://stash.local/login/oidc/callback"
+	testCode        = "code"
+	testKeyID       = "key"
+)
+
+type sessionConfig struct {
+	oidcIssuer           string
+	trustedProxyHeader   string
+	trustedProxyNetworks []string
+}
+
+func (c *sessionConfig) GetSessionStoreKey() []byte {
+	return []byte("0123456789abcdef0123456789abcdef")
+}
+
+func (c *sessionConfig) GetMaxSessionAge() int {
+	return 60
+}
+
+func (c *sessionConfig) GetOIDCIssuer() string {
+	return c.oidcIssuer
+}
+
+func (c *sessionConfig) GetOIDCClientID() string {
+	return testClientID
+}
+
+func (c *sessionConfig) GetOIDCClientSecret() string {
+	return "secret"
+}
+
+func (c *sessionConfig) GetOIDCUsernameClaim() string {
+	return "preferred_username"
+}
+
+func (c *sessionConfig) GetTrustedProxyHeader() string {
+	return c.trustedProxyHeader
+}
+
+func (c *sessionConfig) GetTrustedProxyNetworks() []string {
+	return c.trustedProxyNetworks
+}
+
+type userFinder map[string]*models.User
+
+func (f userFinder) FindUser(ctx context.Context, id int) (*models.User, error) {
+	for _, u := range f {
+		if u.ID == id {
+			return u, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (f userFinder) FindUserByCredentials(ctx context.Context, username string, password string) (*models.User, error) {
+	return nil, nil
+}
+
+func (f userFinder) FindExternalUser(ctx context.Context, username string) (*models.User, error) {
+	return f[username], nil
+}
+
+func (f userFinder) HasUsers(ctx context.Context) (bool, error) {
+	return len(f) > 0, nil
+}
+
+// mockOIDCProvider is an OpenID Connect provider which issues an ID token
+// for username for any authorization request.
+type mockOIDCProvider struct {
+	*httptest.Server
+	key      *rsa.PrivateKey
+	username string
+	nonce    string
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+
+	p := &mockOIDCProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("/keys", p.handleKeys)
+	mux.HandleFunc("/token", p.handleToken)
+	p.Server = httptest.NewServer(mux)
+
+	return p
+}
+
+func (p *mockOIDCProvider) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (p *mockOIDCProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	p.writeJSON(w, map[string]interface{}{
+		"issuer":                                p.URL,
+		"authorization_endpoint":                p.URL + "/authorize",
+		"token_endpoint":                        p.URL + "/token",
+		"jwks_uri":                              p.URL + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (p *mockOIDCProvider) handleKeys(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	p.writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"alg": "RS256",
+				"use": "sig",
+				"kid": testKeyID,
+				"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func (p *mockOIDCProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != testCode {
+		w.WriteHeader(http.StatusBadRequest)
+		p.writeJSON(w, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                p.URL,
+		"aud":                testClientID,
+		"sub":                "subject",
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"nonce":              p.nonce,
+		"preferred_username": p.username,
+	})
+	token.Header["kid"] = testKeyID
+
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	p.writeJSON(w, map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func addCookies(r *http.Request, w *httptest.ResponseRecorder) {
+	for _, c := range w.Result().Cookies() {
+		if c.MaxAge >= 0 {
+			r.AddCookie(c)
+		}
+	}
+}
+
+func TestOIDCLogin(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	defer provider.Close()
+
+	users := userFinder{
+		"user": {ID: 1, Username: "user", Role: models.UserRoleEditor},
+	}
+
+	c := &sessionConfig{oidcIssuer: provider.URL}
+	store := NewStore(c, users)
+
+	// login starts the authorization code flow and returns the state and
+	// nonce in the redirect to the provider
+	login := func(t *testing.T) (*httptest.ResponseRecorder, url.Values) {
+		t.Helper()
+
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/login/oidc", nil)
+		if err := store.OIDCLogin(w, r, testRedirectURL, "/scenes"); err != nil {
+			t.Fatalf("OIDCLogin: %v", err)
+		}
+
+		location := w.Header().Get("Location")
+		if !strings.HasPrefix(location, provider.URL+"/authorize?") {
+			t.Fatalf("unexpected redirect: %s", location)
+		}
+
+		u, _ := url.Parse(location)
+		q := u.Query()
+		assert.Equal(t, testClientID, q.Get("client_id"))
+		assert.Equal(t, testRedirectURL, q.Get("redirect_uri"))
+		assert.Equal(t, "code", q.Get("response_type"))
+
+		return w, q
+	}
+
+	callback := func(loginResponse *httptest.ResponseRecorder, state string) (*httptest.ResponseRecorder, string, error) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, testRedirectURL+"?code="+testCode+"&state="+url.QueryEscape(state), nil)
+		addCookies(r, loginResponse)
+
+		returnURL, err := store.OIDCCallback(w, r, testRedirectURL)
+		return w, returnURL, err
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		loginResponse, q := login(t)
+		provider.username = "user"
+		provider.nonce = q.Get("nonce")
+
+		w, returnURL, err := callback(loginResponse, q.Get("state"))
+		if err != nil {
+			t.Fatalf("OIDCCallback: %v", err)
+		}
+
+		assert.Equal(t, "/scenes", returnURL)
+
+		// the session cookie authenticates the user
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		addCookies(r, w)
+		u, err := store.Authenticate(httptest.NewRecorder(), r)
+		if assert.Nil(t, err) && assert.NotNil(t, u) {
+			assert.Equal(t, 1, u.ID)
+		}
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		loginResponse, q := login(t)
+		provider.username = "user"
+		provider.nonce = q.Get("nonce")
+
+		_, _, err := callback(loginResponse, "invalid")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("invalid nonce", func(t *testing.T) {
+		loginResponse, q := login(t)
+		provider.username = "user"
+		provider.nonce = "invalid"
+
+		_, _, err := callback(loginResponse, q.Get("state"))
+		assert.NotNil(t, err)
+	})
+
+	t.Run("missing login session", func(t *testing.T) {
+		provider.username = "user"
+
+		_, _, err := callback(httptest.NewRecorder(), "state")
+		assert.NotNil(t, err)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		loginResponse, q := login(t)
+		provider.username = "unknown"
+		provider.nonce = q.Get("nonce")
+
+		_, _, err := callback(loginResponse, q.Get("state"))
+		var invalidCredentialsError *InvalidCredentialsError
+		assert.True(t, errors.As(err, &invalidCredentialsError))
+	})
+}
+
+func TestOIDCDisabled(t *testing.T) {
+	store := NewStore(&sessionConfig{}, userFinder{})
+
+	assert.False(t, store.OIDCEnabled())
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/login/oidc", nil)
+	assert.ErrorIs(t, store.OIDCLogin(w, r, testRedirectURL, "/"), ErrOIDCDisabled)
+}
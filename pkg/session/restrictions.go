@@ -0,0 +1,31 @@
+package session
+
+import (
+	"context"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+// SetContentRestrictions sets the content restrictions which apply to the
+// context, replacing those of the current user. This is used where content
+// is served without a user, such as by DLNA.
+func SetContentRestrictions(ctx context.Context, r *models.ContentRestrictions) context.Context {
+	return context.WithValue(ctx, contextContentRestrictions, r)
+}
+
+// GetContentRestrictions returns the content restrictions which apply to
+// the context. Returns nil if content is not restricted.
+func GetContentRestrictions(ctx context.Context) *models.ContentRestrictions {
+	if r, ok := ctx.Value(contextContentRestrictions).(*models.ContentRestrictions); ok {
+		if r == nil || r.IsEmpty() {
+			return nil
+		}
+		return r
+	}
+
+	if u := GetCurrentUser(ctx); u != nil {
+		return u.ContentRestrictions()
+	}
+
+	return nil
+}
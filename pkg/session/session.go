@@ -8,6 +8,7 @@ import (
 	"github.com/gorilla/securecookie"
 	"github.com/gorilla/sessions"
 	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
 	"github.com/stashapp/stash/pkg/sliceutil/stringslice"
 )
 
@@ -16,16 +17,14 @@ type key int
 const (
 	contextUser key = iota
 	contextVisitedPlugins
+	contextAPIKey
+	contextContentRestrictions
 )
 
 const (
 	userIDKey         = "userID"
 	visitedPluginsKey = "visitedPlugins"
-)
-
-const (
-	ApiKeyHeader    = "ApiKey"
-	ApiKeyParameter = "apikey"
+	apiKeyScopesKey   = "apiKeyScopes"
 )
 
 const (
@@ -48,12 +47,15 @@ var ErrUnauthorized = errors.New("unauthorized")
 type Store struct {
 	sessionStore *sessions.CookieStore
 	config       SessionConfig
+	users        UserFinder
+	oidc         oidcProviderCache
 }
 
-func NewStore(c SessionConfig) *Store {
+func NewStore(c SessionConfig, users UserFinder) *Store {
 	ret := &Store{
 		sessionStore: sessions.NewCookieStore(c.GetSessionStoreKey()),
 		config:       c,
+		users:        users,
 	}
 
 	ret.sessionStore.MaxAge(c.GetMaxSessionAge())
@@ -63,28 +65,33 @@ func NewStore(c SessionConfig) *Store {
 }
 
 func (s *Store) Login(w http.ResponseWriter, r *http.Request) error {
-	// ignore error - we want a new session regardless
-	newSession, _ := s.sessionStore.Get(r, cookieName)
-
 	username := r.FormValue(usernameFormKey)
 	password := r.FormValue(passwordFormKey)
 
 	// authenticate the user
-	if !s.config.ValidateCredentials(username, password) {
+	user, err := s.users.FindUserByCredentials(r.Context(), username, password)
+	if err != nil {
+		return err
+	}
+
+	if user == nil {
 		return &InvalidCredentialsError{Username: username}
 	}
 
-	// since we only have one user, don't leak the name
-	logger.Info("User logged in")
+	logger.Infof("User %s logged in", user.Username)
 
-	newSession.Values[userIDKey] = username
+	return s.saveUserSession(w, r, user)
+}
 
-	err := newSession.Save(r, w)
-	if err != nil {
-		return err
-	}
+// saveUserSession sets the session cookie for the logged in user.
+func (s *Store) saveUserSession(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	// ignore error - we want a new session regardless
+	newSession, _ := s.sessionStore.Get(r, cookieName)
 
-	return nil
+	newSession.Values[userIDKey] = user.ID
+	delete(newSession.Values, apiKeyScopesKey)
+
+	return newSession.Save(r, w)
 }
 
 func (s *Store) Logout(w http.ResponseWriter, r *http.Request) error {
@@ -101,18 +108,19 @@ func (s *Store) Logout(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
-	// since we only have one user, don't leak the name
 	logger.Infof("User logged out")
 
 	return nil
 }
 
-func (s *Store) GetSessionUserID(w http.ResponseWriter, r *http.Request) (string, error) {
+// GetSessionUserID returns the ID of the user in the session cookie.
+// Returns nil if there is no current session.
+func (s *Store) GetSessionUserID(w http.ResponseWriter, r *http.Request) (*int, error) {
 	session, err := s.sessionStore.Get(r, cookieName)
 	// ignore errors and treat as an empty user id, so that we handle expired
 	// cookie
 	if err != nil {
-		return "", nil
+		return nil, nil
 	}
 
 	if !session.IsNew {
@@ -121,32 +129,60 @@ func (s *Store) GetSessionUserID(w http.ResponseWriter, r *http.Request) (string
 		// refresh the cookie
 		err = session.Save(r, w)
 		if err != nil {
-			return "", err
+			return nil, err
 		}
 
-		ret, _ := val.(string)
+		// cookies from older versions contain the username and are ignored
+		ret, ok := val.(int)
+		if !ok {
+			return nil, nil
+		}
 
-		return ret, nil
+		return &ret, nil
 	}
 
-	return "", nil
+	return nil, nil
 }
 
-func SetCurrentUserID(ctx context.Context, userID string) context.Context {
-	return context.WithValue(ctx, contextUser, userID)
+// SetCurrentUser sets the authenticated user in the context.
+func SetCurrentUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, contextUser, user)
 }
 
-// GetCurrentUserID gets the current user id from the provided context
-func GetCurrentUserID(ctx context.Context) *string {
+// GetCurrentUser gets the current user from the provided context.
+// Returns nil if no user is authenticated, which is the case when
+// authentication is not required.
+func GetCurrentUser(ctx context.Context) *models.User {
 	userCtxVal := ctx.Value(contextUser)
 	if userCtxVal != nil {
-		currentUser := userCtxVal.(string)
-		return &currentUser
+		return userCtxVal.(*models.User)
 	}
 
 	return nil
 }
 
+// GetCurrentUserID gets the current user id from the provided context
+func GetCurrentUserID(ctx context.Context) *int {
+	if u := GetCurrentUser(ctx); u != nil {
+		id := u.ID
+		return &id
+	}
+
+	return nil
+}
+
+// HasRole returns true if the current user has at least the given role.
+// Always returns true if authentication is not required.
+func HasRole(ctx context.Context, role models.UserRole) bool {
+	u := GetCurrentUser(ctx)
+	return u == nil || u.HasRole(role)
+}
+
+// AuthenticationRequired returns true if users exist which must log in.
+func (s *Store) AuthenticationRequired(ctx context.Context) (bool, error) {
+	return s.users.HasUsers(ctx)
+}
+
 func (s *Store) VisitedPluginHandler() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -198,6 +234,16 @@ func (s *Store) MakePluginCookie(ctx context.Context) *http.Cookie {
 
 	session.Values[visitedPluginsKey] = visitedPlugins
 
+	// plugins run by a request authenticated by an API key are limited to
+	// the scopes of the key
+	if k := GetCurrentAPIKey(ctx); k != nil {
+		scopes := make([]string, len(k.Scopes))
+		for i, scope := range k.Scopes {
+			scopes[i] = scope.String()
+		}
+		session.Values[apiKeyScopesKey] = scopes
+	}
+
 	encoded, err := securecookie.EncodeMulti(session.Name(), session.Values,
 		s.sessionStore.Codecs...)
 	if err != nil {
@@ -208,34 +254,54 @@ func (s *Store) MakePluginCookie(ctx context.Context) *http.Cookie {
 	return sessions.NewCookie(session.Name(), encoded, session.Options)
 }
 
-func (s *Store) Authenticate(w http.ResponseWriter, r *http.Request) (userID string, err error) {
-	c := s.config
+// GetSessionAPIKey returns an API key of the user with the scopes which the
+// session is limited to. This is the case for the sessions of plugins run by
+// a request authenticated by an API key. Returns nil if the session is not
+// limited to scopes.
+func (s *Store) GetSessionAPIKey(r *http.Request, user *models.User) *models.APIKey {
+	session, err := s.sessionStore.Get(r, cookieName)
+	if err != nil {
+		return nil
+	}
 
-	// translate api key into current user, if present
-	apiKey := r.Header.Get(ApiKeyHeader)
+	scopes, ok := session.Values[apiKeyScopesKey].([]string)
+	if !ok {
+		return nil
+	}
 
-	// try getting the api key as a query parameter
-	if apiKey == "" {
-		apiKey = r.URL.Query().Get(ApiKeyParameter)
+	ret := &models.APIKey{
+		UserID: user.ID,
+		Scopes: []models.APIKeyScope{},
+	}
+	for _, scope := range scopes {
+		ret.Scopes = append(ret.Scopes, models.APIKeyScope(scope))
 	}
 
-	if apiKey != "" {
-		// match against configured API and set userID to the
-		// configured username. In future, we'll want to
-		// get the username from the key.
-		if c.GetAPIKey() != apiKey {
-			return "", ErrUnauthorized
-		}
+	return ret
+}
 
-		userID = c.GetUsername()
-	} else {
-		// handle session
-		userID, err = s.GetSessionUserID(w, r)
+// Authenticate returns the user authenticated by the trusted proxy header
+// or the session cookie of the request. Returns nil if the request is not
+// authenticated.
+// API keys are handled separately. See GetRequestAPIKey.
+func (s *Store) Authenticate(w http.ResponseWriter, r *http.Request) (*models.User, error) {
+	ctx := r.Context()
+
+	// the proxy authenticates every request, so the header takes precedence
+	// over the session
+	if username := getTrustedProxyUsername(s.config, r); username != "" {
+		return s.users.FindExternalUser(ctx, username)
 	}
 
+	userID, err := s.GetSessionUserID(w, r)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	if userID == nil {
+		return nil, nil
 	}
 
-	return
+	// the user may have been deleted since the session was created
+	return s.users.FindUser(ctx, *userID)
 }
@@ -0,0 +1,59 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakePluginCookieAPIKeyScopes(t *testing.T) {
+	user := &models.User{ID: 1, Username: "user"}
+	store := NewStore(&sessionConfig{}, userFinder{user.Username: user})
+
+	makeRequest := func(ctx context.Context) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		r.AddCookie(store.MakePluginCookie(ctx))
+		return r
+	}
+
+	ctx := SetCurrentUser(context.Background(), user)
+
+	t.Run("no api key", func(t *testing.T) {
+		r := makeRequest(ctx)
+
+		authenticated, err := store.Authenticate(httptest.NewRecorder(), r)
+		if assert.Nil(t, err) && assert.NotNil(t, authenticated) {
+			assert.Equal(t, user.ID, authenticated.ID)
+		}
+		assert.Nil(t, store.GetSessionAPIKey(r, user))
+	})
+
+	t.Run("api key", func(t *testing.T) {
+		key := &models.APIKey{
+			UserID: user.ID,
+			Scopes: []models.APIKeyScope{models.APIKeyScopeRead, models.APIKeyScopeWrite},
+		}
+		r := makeRequest(SetCurrentAPIKey(ctx, key, "value"))
+
+		got := store.GetSessionAPIKey(r, user)
+		if assert.NotNil(t, got) {
+			assert.Equal(t, user.ID, got.UserID)
+			assert.True(t, got.HasScope(models.APIKeyScopeWrite))
+			assert.False(t, got.HasScope(models.APIKeyScopeAdmin))
+		}
+	})
+
+	t.Run("api key without scopes", func(t *testing.T) {
+		key := &models.APIKey{UserID: user.ID}
+		r := makeRequest(SetCurrentAPIKey(ctx, key, "value"))
+
+		got := store.GetSessionAPIKey(r, user)
+		if assert.NotNil(t, got) {
+			assert.False(t, got.HasScope(models.APIKeyScopeRead))
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/stashapp/stash/pkg/logger"
+)
+
+// getTrustedProxyUsername returns the username provided in the trusted proxy
+// header of the request. Returns an empty string if the header is not
+// configured or not present, or if the request was not made by a trusted
+// proxy.
+func getTrustedProxyUsername(c TrustedProxyConfig, r *http.Request) string {
+	header := c.GetTrustedProxyHeader()
+	if header == "" {
+		return ""
+	}
+
+	username := strings.TrimSpace(r.Header.Get(header))
+	if username == "" {
+		return ""
+	}
+
+	requestIP, err := parseRemoteIP(r)
+	if err != nil {
+		logger.Warnf("Ignoring %s header: %v", header, err)
+		return ""
+	}
+
+	if !isTrustedProxy(requestIP, c.GetTrustedProxyNetworks()) {
+		logger.Warnf("Ignoring %s header from untrusted address %s", header, requestIP)
+		return ""
+	}
+
+	return username
+}
+
+// isTrustedProxy returns true if ip matches any of the IP addresses or CIDR
+// ranges in networks.
+func isTrustedProxy(ip net.IP, networks []string) bool {
+	for _, n := range networks {
+		if !strings.Contains(n, "/") {
+			if trusted := net.ParseIP(n); trusted != nil && trusted.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(n)
+		if err != nil {
+			logger.Warnf("Invalid trusted proxy network %q: %v", n, err)
+			continue
+		}
+
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
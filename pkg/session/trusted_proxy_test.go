@@ -0,0 +1,58 @@
+package session
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthenticateTrustedProxy(t *testing.T) {
+	const header = "Remote-User"
+
+	users := userFinder{
+		"user": {ID: 1, Username: "user", Role: models.UserRoleReadOnly},
+	}
+
+	c := &sessionConfig{
+		trustedProxyHeader:   header,
+		trustedProxyNetworks: []string{"172.16.0.0/12", "192.168.1.10", "invalid"},
+	}
+	store := NewStore(c, users)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		username   string
+		wantUserID int
+	}{
+		{"trusted network", "172.18.0.2:1234", "user", 1},
+		{"trusted address", "192.168.1.10:1234", "user", 1},
+		{"untrusted address", "192.168.1.11:1234", "user", 0},
+		{"missing header", "172.18.0.2:1234", "", 0},
+		{"unknown user", "172.18.0.2:1234", "unknown", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.username != "" {
+				r.Header.Set(header, tt.username)
+			}
+
+			u, err := store.Authenticate(httptest.NewRecorder(), r)
+			if !assert.Nil(t, err) {
+				return
+			}
+
+			if tt.wantUserID == 0 {
+				assert.Nil(t, u)
+			} else if assert.NotNil(t, u) {
+				assert.Equal(t, tt.wantUserID, u.ID)
+			}
+		})
+	}
+}
@@ -47,6 +47,7 @@ func (db *Anonymiser) Anonymise(ctx context.Context) error {
 		return utils.Do([]func() error{
 			func() error { return db.deleteBlobs() },
 			func() error { return db.deleteStashIDs() },
+			func() error { return db.deleteUsers() },
 			func() error { return db.anonymiseFolders(ctx) },
 			func() error { return db.anonymiseFiles(ctx) },
 			func() error { return db.anonymiseFingerprints(ctx) },
@@ -101,6 +102,19 @@ func (db *Anonymiser) deleteStashIDs() error {
 	})
 }
 
+func (db *Anonymiser) deleteUsers() error {
+	return utils.Do([]func() error{
+		func() error { return db.truncateTable(sceneActivityTable) },
+		func() error { return db.truncateTable(scenePlayHistoryTable) },
+		func() error { return db.truncateTable(apiKeyTable) },
+		func() error { return db.truncateTable(usersExcludedTagsTable) },
+		func() error { return db.truncateTable(usersExcludedStudiosTable) },
+		func() error { return db.truncateTable(usersExcludedPerformersTable) },
+		func() error { return db.truncateTable(usersExcludedPathsTable) },
+		func() error { return db.truncateTable(userTable) },
+	})
+}
+
 func (db *Anonymiser) anonymiseFolders(ctx context.Context) error {
 	logger.Infof("Anonymising folders")
 	return txn.WithTxn(ctx, db, func(ctx context.Context) error {
@@ -0,0 +1,88 @@
+//go:build integration
+// +build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyCreateFind(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		now := time.Now()
+
+		u := models.User{
+			Username:     "apiKeyUser",
+			PasswordHash: "hash",
+			Role:         models.UserRoleEditor,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+
+		if err := db.User.Create(ctx, &u); err != nil {
+			t.Errorf("Error creating user: %s", err.Error())
+			return nil
+		}
+
+		qb := db.APIKey
+		expires := now.Add(time.Hour)
+		newKey := models.APIKey{
+			UserID:    u.ID,
+			Name:      "test",
+			KeyHash:   "keyhash",
+			Scopes:    []models.APIKeyScope{models.APIKeyScopeRead, models.APIKeyScopeTasks},
+			ExpiresAt: &expires,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		if err := qb.Create(ctx, &newKey); err != nil {
+			t.Errorf("Error creating api key: %s", err.Error())
+			return nil
+		}
+
+		found, err := qb.FindByKeyHash(ctx, "keyhash")
+		if err != nil {
+			t.Errorf("Error finding api key: %s", err.Error())
+		}
+
+		if assert.NotNil(t, found) {
+			assert.Equal(t, newKey.ID, found.ID)
+			assert.Equal(t, newKey.Scopes, found.Scopes)
+			assert.NotNil(t, found.ExpiresAt)
+			assert.Nil(t, found.LastUsedAt)
+		}
+
+		if err := qb.UpdateLastUsed(ctx, newKey.ID, now); err != nil {
+			t.Errorf("Error updating last used: %s", err.Error())
+		}
+
+		found, err = qb.Find(ctx, newKey.ID)
+		if err != nil {
+			t.Errorf("Error finding api key: %s", err.Error())
+		}
+
+		if assert.NotNil(t, found) {
+			assert.NotNil(t, found.LastUsedAt)
+		}
+
+		// keys are removed with the user
+		if err := db.User.Destroy(ctx, u.ID); err != nil {
+			t.Errorf("Error destroying user: %s", err.Error())
+		}
+
+		keys, err := qb.FindByUserID(ctx, u.ID)
+		if err != nil {
+			t.Errorf("Error finding api keys: %s", err.Error())
+		}
+
+		assert.Len(t, keys, 0)
+
+		return nil
+	})
+}
@@ -33,7 +33,7 @@ const (
 	dbConnTimeout = 30
 )
 
-var appSchemaVersion uint = 52
+var appSchemaVersion uint = 61
 
 //go:embed migrations/*.sql
 var migrationsBox embed.FS
@@ -75,6 +75,8 @@ type Database struct {
 	SceneMarker    *SceneMarkerStore
 	Performer      *PerformerStore
 	SavedFilter    *SavedFilterStore
+	User           *UserStore
+	APIKey         *APIKeyStore
 	Studio         *StudioStore
 	Tag            *TagStore
 	Movie          *MovieStore
@@ -106,6 +108,8 @@ func NewDatabase() *Database {
 		Tag:            NewTagStore(blobStore),
 		Movie:          NewMovieStore(blobStore),
 		SavedFilter:    NewSavedFilterStore(),
+		User:           NewUserStore(),
+		APIKey:         NewAPIKeyStore(),
 		lockChan:       make(chan struct{}, 1),
 	}
 
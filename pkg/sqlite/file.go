@@ -27,6 +27,15 @@ const (
 	captionCodeColumn     = "language_code"
 	captionFilenameColumn = "filename"
 	captionTypeColumn     = "caption_type"
+
+	videoStreamsTable        = "video_streams"
+	streamTypeColumn         = "stream_type"
+	streamIndexColumn        = "stream_index"
+	streamCodecColumn        = "codec"
+	streamLanguageCodeColumn = "language_code"
+	streamTitleColumn        = "title"
+	streamChannelsColumn     = "channels"
+	streamDefaultColumn      = "is_default"
 )
 
 type basicFileRow struct {
@@ -63,6 +72,19 @@ type videoFileRow struct {
 	BitRate          int64         `db:"bit_rate"`
 	Interactive      bool          `db:"interactive"`
 	InteractiveSpeed null.Int      `db:"interactive_speed"`
+
+	IntegratedLoudness null.Float `db:"integrated_loudness"`
+	TruePeak           null.Float `db:"true_peak"`
+
+	CropX      null.Int `db:"crop_x"`
+	CropY      null.Int `db:"crop_y"`
+	CropWidth  null.Int `db:"crop_width"`
+	CropHeight null.Int `db:"crop_height"`
+
+	IntegrityStatus         null.String `db:"integrity_status"`
+	IntegrityErrorCount     null.Int    `db:"integrity_error_count"`
+	IntegrityFirstErrorTime null.Float  `db:"integrity_first_error_time"`
+	IntegrityTruncated      null.Bool   `db:"integrity_truncated"`
 }
 
 func (f *videoFileRow) fromVideoFile(ff models.VideoFile) {
@@ -77,6 +99,22 @@ func (f *videoFileRow) fromVideoFile(ff models.VideoFile) {
 	f.BitRate = ff.BitRate
 	f.Interactive = ff.Interactive
 	f.InteractiveSpeed = intFromPtr(ff.InteractiveSpeed)
+	f.IntegratedLoudness = null.FloatFromPtr(ff.IntegratedLoudness)
+	f.TruePeak = null.FloatFromPtr(ff.TruePeak)
+
+	if ff.Crop != nil {
+		f.CropX = null.IntFrom(int64(ff.Crop.X))
+		f.CropY = null.IntFrom(int64(ff.Crop.Y))
+		f.CropWidth = null.IntFrom(int64(ff.Crop.Width))
+		f.CropHeight = null.IntFrom(int64(ff.Crop.Height))
+	}
+
+	if ff.Integrity != nil {
+		f.IntegrityStatus = null.StringFrom(ff.Integrity.Status.String())
+		f.IntegrityErrorCount = null.IntFrom(int64(ff.Integrity.ErrorCount))
+		f.IntegrityFirstErrorTime = null.FloatFromPtr(ff.Integrity.FirstErrorTime)
+		f.IntegrityTruncated = null.BoolFrom(ff.Integrity.Truncated)
+	}
 }
 
 type imageFileRow struct {
@@ -107,10 +145,23 @@ type videoFileQueryRow struct {
 	BitRate          null.Int    `db:"bit_rate"`
 	Interactive      null.Bool   `db:"interactive"`
 	InteractiveSpeed null.Int    `db:"interactive_speed"`
+
+	IntegratedLoudness null.Float `db:"integrated_loudness"`
+	TruePeak           null.Float `db:"true_peak"`
+
+	CropX      null.Int `db:"crop_x"`
+	CropY      null.Int `db:"crop_y"`
+	CropWidth  null.Int `db:"crop_width"`
+	CropHeight null.Int `db:"crop_height"`
+
+	IntegrityStatus         null.String `db:"integrity_status"`
+	IntegrityErrorCount     null.Int    `db:"integrity_error_count"`
+	IntegrityFirstErrorTime null.Float  `db:"integrity_first_error_time"`
+	IntegrityTruncated      null.Bool   `db:"integrity_truncated"`
 }
 
 func (f *videoFileQueryRow) resolve() *models.VideoFile {
-	return &models.VideoFile{
+	ret := &models.VideoFile{
 		Format:           f.Format.String,
 		Width:            int(f.Width.Int64),
 		Height:           int(f.Height.Int64),
@@ -121,7 +172,30 @@ func (f *videoFileQueryRow) resolve() *models.VideoFile {
 		BitRate:          f.BitRate.Int64,
 		Interactive:      f.Interactive.Bool,
 		InteractiveSpeed: nullIntPtr(f.InteractiveSpeed),
+
+		IntegratedLoudness: nullFloatPtr(f.IntegratedLoudness),
+		TruePeak:           nullFloatPtr(f.TruePeak),
 	}
+
+	if f.CropWidth.Valid && f.CropHeight.Valid {
+		ret.Crop = &models.VideoCrop{
+			X:      int(f.CropX.Int64),
+			Y:      int(f.CropY.Int64),
+			Width:  int(f.CropWidth.Int64),
+			Height: int(f.CropHeight.Int64),
+		}
+	}
+
+	if f.IntegrityStatus.Valid {
+		ret.Integrity = &models.VideoIntegrity{
+			Status:         models.IntegrityStatus(f.IntegrityStatus.String),
+			ErrorCount:     int(f.IntegrityErrorCount.Int64),
+			FirstErrorTime: nullFloatPtr(f.IntegrityFirstErrorTime),
+			Truncated:      f.IntegrityTruncated.Bool,
+		}
+	}
+
+	return ret
 }
 
 func videoFileQueryColumns() []interface{} {
@@ -138,6 +212,16 @@ func videoFileQueryColumns() []interface{} {
 		table.Col("bit_rate"),
 		table.Col("interactive"),
 		table.Col("interactive_speed"),
+		table.Col("integrated_loudness"),
+		table.Col("true_peak"),
+		table.Col("crop_x"),
+		table.Col("crop_y"),
+		table.Col("crop_width"),
+		table.Col("crop_height"),
+		table.Col("integrity_status"),
+		table.Col("integrity_error_count"),
+		table.Col("integrity_first_error_time"),
+		table.Col("integrity_truncated"),
 	}
 }
 
@@ -311,6 +395,9 @@ func (qb *FileStore) Create(ctx context.Context, f models.File) error {
 		if err := qb.createVideoFile(ctx, fileID, *ef); err != nil {
 			return err
 		}
+		if err := qb.updateStreams(ctx, fileID, ef.Streams); err != nil {
+			return err
+		}
 	case *models.ImageFile:
 		if err := qb.createImageFile(ctx, fileID, *ef); err != nil {
 			return err
@@ -348,6 +435,9 @@ func (qb *FileStore) Update(ctx context.Context, f models.File) error {
 		if err := qb.updateOrCreateVideoFile(ctx, id, *ef); err != nil {
 			return err
 		}
+		if err := qb.updateStreams(ctx, id, ef.Streams); err != nil {
+			return err
+		}
 	case *models.ImageFile:
 		if err := qb.updateOrCreateImageFile(ctx, id, *ef); err != nil {
 			return err
@@ -935,3 +1025,27 @@ func (qb *FileStore) GetCaptions(ctx context.Context, fileID models.FileID) ([]*
 func (qb *FileStore) UpdateCaptions(ctx context.Context, fileID models.FileID, captions []*models.VideoCaption) error {
 	return qb.captionRepository().replace(ctx, fileID, captions)
 }
+
+func (qb *FileStore) streamRepository() *streamRepository {
+	return &streamRepository{
+		repository: repository{
+			tx:        qb.tx,
+			tableName: videoStreamsTable,
+			idColumn:  fileIDColumn,
+		},
+	}
+}
+
+func (qb *FileStore) GetStreams(ctx context.Context, fileID models.FileID) ([]*models.VideoStream, error) {
+	return qb.streamRepository().get(ctx, fileID)
+}
+
+// updateStreams replaces the streams of the file. Streams are not loaded with
+// the file, so the existing streams are left untouched if streams is nil.
+func (qb *FileStore) updateStreams(ctx context.Context, fileID models.FileID, streams []*models.VideoStream) error {
+	if streams == nil {
+		return nil
+	}
+
+	return qb.streamRepository().replace(ctx, fileID, streams)
+}
@@ -662,3 +662,82 @@ func TestFileStore_IsPrimary(t *testing.T) {
 		})
 	}
 }
+
+func TestFileStore_Streams(t *testing.T) {
+	streams := []*models.VideoStream{
+		{
+			Type:     models.VideoStreamTypeAudio,
+			Index:    0,
+			Codec:    "aac",
+			Language: "eng",
+			Channels: 2,
+			Default:  true,
+		},
+		{
+			Type:     models.VideoStreamTypeAudio,
+			Index:    1,
+			Codec:    "ac3",
+			Language: "jpn",
+			Title:    "Commentary",
+			Channels: 6,
+		},
+		{
+			Type:     models.VideoStreamTypeSubtitle,
+			Index:    0,
+			Codec:    "subrip",
+			Language: "eng",
+		},
+	}
+
+	tests := []struct {
+		name    string
+		streams []*models.VideoStream
+		want    []*models.VideoStream
+	}{
+		{
+			"set streams",
+			streams,
+			streams,
+		},
+		{
+			"nil streams",
+			nil,
+			nil,
+		},
+		{
+			"clear streams",
+			[]*models.VideoStream{},
+			nil,
+		},
+	}
+
+	qb := db.File
+
+	for _, tt := range tests {
+		runWithRollbackTxn(t, tt.name, func(t *testing.T, ctx context.Context) {
+			assert := assert.New(t)
+
+			files, err := qb.Find(ctx, sceneFileIDs[sceneIdxWithGallery])
+			if err != nil {
+				t.Errorf("FileStore.Find() error = %v", err)
+				return
+			}
+
+			f := files[0].(*models.VideoFile)
+			f.Streams = tt.streams
+
+			if err := qb.Update(ctx, f); err != nil {
+				t.Errorf("FileStore.Update() error = %v", err)
+				return
+			}
+
+			got, err := qb.GetStreams(ctx, f.ID)
+			if err != nil {
+				t.Errorf("FileStore.GetStreams() error = %v", err)
+				return
+			}
+
+			assert.Equal(tt.want, got)
+		})
+	}
+}
@@ -799,13 +799,17 @@ type joinedMultiSumCriterionHandlerBuilder struct {
 	primaryFK     string
 	foreignFK1    string
 	foreignFK2    string
-	sum           string
+	// alias of the summed column
+	sum string
+	// expressions summed over foreignTable1 and foreignTable2
+	sum1 string
+	sum2 string
 }
 
 func (m *joinedMultiSumCriterionHandlerBuilder) handler(criterion *models.IntCriterionInput) criterionHandlerFunc {
 	return func(ctx context.Context, f *filterBuilder) {
 		if criterion != nil {
-			clause, args := getJoinedMultiSumCriterionClause(m.primaryTable, m.foreignTable1, m.joinTable1, m.foreignTable2, m.joinTable2, m.primaryFK, m.foreignFK1, m.foreignFK2, m.sum, *criterion)
+			clause, args := getJoinedMultiSumCriterionClause(m.primaryTable, m.foreignTable1, m.joinTable1, m.foreignTable2, m.joinTable2, m.primaryFK, m.foreignFK1, m.foreignFK2, m.sum, m.sum1, m.sum2, *criterion)
 
 			f.addWhere(clause, args...)
 		}
@@ -232,7 +232,7 @@ func (qb *GalleryStore) Update(ctx context.Context, updatedObject *models.Galler
 		}
 	}
 	if updatedObject.SceneIDs.Loaded() {
-		if err := galleriesScenesTableMgr.replaceJoins(ctx, updatedObject.ID, updatedObject.SceneIDs.List()); err != nil {
+		if err := sceneContentRestriction.replaceJoins(ctx, galleriesScenesTableMgr, updatedObject.ID, updatedObject.SceneIDs.List()); err != nil {
 			return err
 		}
 	}
@@ -282,7 +282,7 @@ func (qb *GalleryStore) UpdatePartial(ctx context.Context, id int, partial model
 		}
 	}
 	if partial.SceneIDs != nil {
-		if err := galleriesScenesTableMgr.modifyJoins(ctx, id, partial.SceneIDs.IDs, partial.SceneIDs.Mode); err != nil {
+		if err := sceneContentRestriction.modifyJoins(ctx, galleriesScenesTableMgr, id, partial.SceneIDs.IDs, partial.SceneIDs.Mode); err != nil {
 			return nil, err
 		}
 	}
@@ -400,6 +400,8 @@ func (qb *GalleryStore) get(ctx context.Context, q *goqu.SelectDataset) (*models
 }
 
 func (qb *GalleryStore) getMany(ctx context.Context, q *goqu.SelectDataset) ([]*models.Gallery, error) {
+	q = galleryContentRestriction.apply(ctx, q)
+
 	const single = false
 	var ret []*models.Gallery
 	var lastID int
@@ -577,6 +579,7 @@ func (qb *GalleryStore) CountByImageID(ctx context.Context, imageID int) (int, e
 	joinTable := galleriesImagesJoinTable
 
 	q := dialect.Select(goqu.COUNT("*")).From(joinTable).Where(joinTable.Col(imageIDColumn).Eq(imageID))
+	q = galleryContentRestriction.applyByID(ctx, q, joinTable.Col(galleryIDColumn))
 	return count(ctx, q)
 }
 
@@ -602,6 +605,7 @@ func (qb *GalleryStore) FindUserGalleryByTitle(ctx context.Context, title string
 
 func (qb *GalleryStore) Count(ctx context.Context) (int, error) {
 	q := dialect.Select(goqu.COUNT("*")).From(qb.table())
+	q = galleryContentRestriction.apply(ctx, q)
 	return count(ctx, q)
 }
 
@@ -773,6 +777,10 @@ func (qb *GalleryStore) makeQuery(ctx context.Context, galleryFilter *models.Gal
 		return nil, err
 	}
 
+	if err := query.addFilter(galleryContentRestriction.filter(ctx)); err != nil {
+		return nil, err
+	}
+
 	qb.setGallerySort(&query, findFilter)
 	query.sortAndPagination += getPagination(findFilter)
 
@@ -1227,5 +1235,10 @@ func (qb *GalleryStore) scenesRepository() *joinRepository {
 }
 
 func (qb *GalleryStore) GetSceneIDs(ctx context.Context, id int) ([]int, error) {
-	return qb.scenesRepository().getIDs(ctx, id)
+	ids, err := qb.scenesRepository().getIDs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return sceneContentRestriction.filterIDs(ctx, ids)
 }
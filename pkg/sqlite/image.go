@@ -224,7 +224,7 @@ func (qb *ImageStore) UpdatePartial(ctx context.Context, id int, partial models.
 	}
 
 	if partial.GalleryIDs != nil {
-		if err := imageGalleriesTableMgr.modifyJoins(ctx, id, partial.GalleryIDs.IDs, partial.GalleryIDs.Mode); err != nil {
+		if err := galleryContentRestriction.modifyJoins(ctx, imageGalleriesTableMgr, id, partial.GalleryIDs.IDs, partial.GalleryIDs.Mode); err != nil {
 			return nil, err
 		}
 	}
@@ -281,7 +281,7 @@ func (qb *ImageStore) Update(ctx context.Context, updatedObject *models.Image) e
 	}
 
 	if updatedObject.GalleryIDs.Loaded() {
-		if err := imageGalleriesTableMgr.replaceJoins(ctx, updatedObject.ID, updatedObject.GalleryIDs.List()); err != nil {
+		if err := galleryContentRestriction.replaceJoins(ctx, imageGalleriesTableMgr, updatedObject.ID, updatedObject.GalleryIDs.List()); err != nil {
 			return err
 		}
 	}
@@ -380,6 +380,8 @@ func (qb *ImageStore) get(ctx context.Context, q *goqu.SelectDataset) (*models.I
 }
 
 func (qb *ImageStore) getMany(ctx context.Context, q *goqu.SelectDataset) ([]*models.Image, error) {
+	q = imageContentRestriction.apply(ctx, q)
+
 	const single = false
 	var ret []*models.Image
 	var lastID int
@@ -523,6 +525,7 @@ func (qb *ImageStore) CountByGalleryID(ctx context.Context, galleryID int) (int,
 	joinTable := goqu.T(galleriesImagesTable)
 
 	q := dialect.Select(goqu.COUNT("*")).From(joinTable).Where(joinTable.Col("gallery_id").Eq(galleryID))
+	q = imageContentRestriction.applyByID(ctx, q, joinTable.Col(imageIDColumn))
 	return count(ctx, q)
 }
 
@@ -530,6 +533,7 @@ func (qb *ImageStore) OCountByPerformerID(ctx context.Context, performerID int)
 	table := qb.table()
 	joinTable := performersImagesJoinTable
 	q := dialect.Select(goqu.COALESCE(goqu.SUM("o_counter"), 0)).From(table).InnerJoin(joinTable, goqu.On(table.Col(idColumn).Eq(joinTable.Col(imageIDColumn)))).Where(joinTable.Col(performerIDColumn).Eq(performerID))
+	q = imageContentRestriction.apply(ctx, q)
 
 	var ret int
 	if err := querySimple(ctx, q, &ret); err != nil {
@@ -591,6 +595,7 @@ func (qb *ImageStore) FindByZipFileID(ctx context.Context, zipFileID models.File
 
 func (qb *ImageStore) Count(ctx context.Context) (int, error) {
 	q := dialect.Select(goqu.COUNT("*")).From(qb.table())
+	q = imageContentRestriction.apply(ctx, q)
 	return count(ctx, q)
 }
 
@@ -606,6 +611,8 @@ func (qb *ImageStore) Size(ctx context.Context) (float64, error) {
 		fileTable,
 		goqu.On(imagesFilesJoinTable.Col(fileIDColumn).Eq(fileTable.Col(idColumn))),
 	)
+	q = imageContentRestriction.apply(ctx, q)
+
 	var ret float64
 	if err := querySimple(ctx, q, &ret); err != nil {
 		return 0, err
@@ -764,6 +771,10 @@ func (qb *ImageStore) makeQuery(ctx context.Context, imageFilter *models.ImageFi
 		return nil, err
 	}
 
+	if err := query.addFilter(imageContentRestriction.filter(ctx)); err != nil {
+		return nil, err
+	}
+
 	qb.setImageSortAndPagination(&query, findFilter)
 
 	return &query, nil
@@ -1077,7 +1088,12 @@ func (qb *ImageStore) AddFileID(ctx context.Context, id int, fileID models.FileI
 }
 
 func (qb *ImageStore) GetGalleryIDs(ctx context.Context, imageID int) ([]int, error) {
-	return qb.galleriesRepository().getIDs(ctx, imageID)
+	ids, err := qb.galleriesRepository().getIDs(ctx, imageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return galleryContentRestriction.filterIDs(ctx, ids)
 }
 
 // func (qb *imageQueryBuilder) UpdateGalleries(ctx context.Context, imageID int, galleryIDs []int) error {
@@ -0,0 +1,12 @@
+CREATE TABLE `video_streams` (
+  `file_id` integer NOT NULL,
+  `stream_type` varchar(255) NOT NULL,
+  `stream_index` integer NOT NULL,
+  `codec` varchar(255) NOT NULL,
+  `language_code` varchar(255) NOT NULL,
+  `title` varchar(255) NOT NULL,
+  `channels` integer NOT NULL,
+  `is_default` boolean NOT NULL,
+  foreign key(`file_id`) references `video_files`(`file_id`) on delete CASCADE,
+  PRIMARY KEY(`file_id`, `stream_type`, `stream_index`)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE `video_files` ADD COLUMN `integrated_loudness` real;
+ALTER TABLE `video_files` ADD COLUMN `true_peak` real;
@@ -0,0 +1,4 @@
+ALTER TABLE `video_files` ADD COLUMN `crop_x` integer;
+ALTER TABLE `video_files` ADD COLUMN `crop_y` integer;
+ALTER TABLE `video_files` ADD COLUMN `crop_width` integer;
+ALTER TABLE `video_files` ADD COLUMN `crop_height` integer;
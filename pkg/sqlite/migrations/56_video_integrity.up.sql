@@ -0,0 +1,4 @@
+ALTER TABLE `video_files` ADD COLUMN `integrity_status` varchar(255);
+ALTER TABLE `video_files` ADD COLUMN `integrity_error_count` integer;
+ALTER TABLE `video_files` ADD COLUMN `integrity_first_error_time` real;
+ALTER TABLE `video_files` ADD COLUMN `integrity_truncated` boolean;
@@ -0,0 +1,10 @@
+CREATE TABLE `users` (
+  `id` integer not null primary key autoincrement,
+  `username` varchar(255) not null,
+  `password_hash` varchar(255) not null,
+  `role` varchar(255) not null,
+  `created_at` datetime not null,
+  `updated_at` datetime not null
+);
+
+CREATE UNIQUE INDEX `index_users_on_username` on `users` (`username` COLLATE NOCASE);
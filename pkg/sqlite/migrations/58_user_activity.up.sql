@@ -0,0 +1,32 @@
+CREATE TABLE `scenes_activity` (
+  `scene_id` integer not null,
+  `user_id` integer not null,
+  `rating` tinyint,
+  `o_counter` tinyint not null default 0,
+  `play_count` tinyint not null default 0,
+  `play_duration` float not null default 0,
+  `resume_time` float not null default 0,
+  `last_played_at` datetime default null,
+  foreign key(`scene_id`) references `scenes`(`id`) on delete CASCADE,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE,
+  PRIMARY KEY(`scene_id`, `user_id`)
+);
+
+CREATE INDEX `index_scenes_activity_on_user_id` on `scenes_activity` (`user_id`);
+
+-- user_id is null for plays recorded without a logged in user
+CREATE TABLE `scenes_play_history` (
+  `id` integer not null primary key autoincrement,
+  `scene_id` integer not null,
+  `user_id` integer,
+  `played_at` datetime not null,
+  foreign key(`scene_id`) references `scenes`(`id`) on delete CASCADE,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE
+);
+
+CREATE INDEX `index_scenes_play_history_on_scene_id` on `scenes_play_history` (`scene_id`);
+CREATE INDEX `index_scenes_play_history_on_user_id_played_at` on `scenes_play_history` (`user_id`, `played_at`);
+
+-- only the most recent play was previously recorded
+INSERT INTO `scenes_play_history` (`scene_id`, `played_at`)
+  SELECT `id`, `last_played_at` FROM `scenes` WHERE `last_played_at` IS NOT NULL;
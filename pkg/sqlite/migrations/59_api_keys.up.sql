@@ -0,0 +1,15 @@
+CREATE TABLE `api_keys` (
+  `id` integer not null primary key autoincrement,
+  `user_id` integer not null,
+  `name` varchar(255) not null,
+  `key_hash` varchar(255) not null,
+  `scopes` varchar(255) not null,
+  `expires_at` datetime,
+  `last_used_at` datetime,
+  `created_at` datetime not null,
+  `updated_at` datetime not null,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE
+);
+
+CREATE UNIQUE INDEX `index_api_keys_on_key_hash` on `api_keys` (`key_hash`);
+CREATE INDEX `index_api_keys_on_user_id` on `api_keys` (`user_id`);
@@ -0,0 +1,30 @@
+CREATE TABLE `users_excluded_tags` (
+  `user_id` integer not null,
+  `tag_id` integer not null,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE,
+  foreign key(`tag_id`) references `tags`(`id`) on delete CASCADE,
+  PRIMARY KEY(`user_id`, `tag_id`)
+);
+
+CREATE TABLE `users_excluded_studios` (
+  `user_id` integer not null,
+  `studio_id` integer not null,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE,
+  foreign key(`studio_id`) references `studios`(`id`) on delete CASCADE,
+  PRIMARY KEY(`user_id`, `studio_id`)
+);
+
+CREATE TABLE `users_excluded_performers` (
+  `user_id` integer not null,
+  `performer_id` integer not null,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE,
+  foreign key(`performer_id`) references `performers`(`id`) on delete CASCADE,
+  PRIMARY KEY(`user_id`, `performer_id`)
+);
+
+CREATE TABLE `users_excluded_paths` (
+  `user_id` integer not null,
+  `path` varchar(255) not null,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE,
+  PRIMARY KEY(`user_id`, `path`)
+);
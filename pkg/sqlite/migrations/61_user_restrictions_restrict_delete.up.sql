@@ -0,0 +1,46 @@
+PRAGMA foreign_keys=OFF;
+
+-- deleting an excluded tag, studio or performer must not remove the restriction
+CREATE TABLE `users_excluded_tags_new` (
+  `user_id` integer not null,
+  `tag_id` integer not null,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE,
+  foreign key(`tag_id`) references `tags`(`id`) on delete RESTRICT,
+  PRIMARY KEY(`user_id`, `tag_id`)
+);
+
+INSERT INTO `users_excluded_tags_new` (`user_id`, `tag_id`)
+  SELECT `user_id`, `tag_id` FROM `users_excluded_tags`;
+
+DROP TABLE `users_excluded_tags`;
+ALTER TABLE `users_excluded_tags_new` rename to `users_excluded_tags`;
+
+CREATE TABLE `users_excluded_studios_new` (
+  `user_id` integer not null,
+  `studio_id` integer not null,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE,
+  foreign key(`studio_id`) references `studios`(`id`) on delete RESTRICT,
+  PRIMARY KEY(`user_id`, `studio_id`)
+);
+
+INSERT INTO `users_excluded_studios_new` (`user_id`, `studio_id`)
+  SELECT `user_id`, `studio_id` FROM `users_excluded_studios`;
+
+DROP TABLE `users_excluded_studios`;
+ALTER TABLE `users_excluded_studios_new` rename to `users_excluded_studios`;
+
+CREATE TABLE `users_excluded_performers_new` (
+  `user_id` integer not null,
+  `performer_id` integer not null,
+  foreign key(`user_id`) references `users`(`id`) on delete CASCADE,
+  foreign key(`performer_id`) references `performers`(`id`) on delete RESTRICT,
+  PRIMARY KEY(`user_id`, `performer_id`)
+);
+
+INSERT INTO `users_excluded_performers_new` (`user_id`, `performer_id`)
+  SELECT `user_id`, `performer_id` FROM `users_excluded_performers`;
+
+DROP TABLE `users_excluded_performers`;
+ALTER TABLE `users_excluded_performers_new` rename to `users_excluded_performers`;
+
+PRAGMA foreign_keys=ON;
@@ -307,6 +307,14 @@ func (qb *PerformerStore) Update(ctx context.Context, updatedObject *models.Perf
 }
 
 func (qb *PerformerStore) Destroy(ctx context.Context, id int) error {
+	if err := checkPerformersNotRestricted(ctx, []int{id}); err != nil {
+		return err
+	}
+
+	if err := checkNotExcludedByUsers(ctx, usersExcludedPerformersTableMgr, id, "performer"); err != nil {
+		return err
+	}
+
 	// must handle image checksums manually
 	if err := qb.destroyImage(ctx, id); err != nil {
 		return err
@@ -701,7 +709,7 @@ func (qb *PerformerStore) makeQuery(ctx context.Context, performerFilter *models
 		return nil, err
 	}
 
-	query.sortAndPagination = qb.getPerformerSort(findFilter) + getPagination(findFilter)
+	query.sortAndPagination = qb.getPerformerSort(ctx, findFilter) + getPagination(findFilter)
 
 	return &query, nil
 }
@@ -814,49 +822,59 @@ func performerTagCountCriterionHandler(qb *PerformerStore, count *models.IntCrit
 }
 
 func performerSceneCountCriterionHandler(qb *PerformerStore, count *models.IntCriterionInput) criterionHandlerFunc {
-	h := countCriterionHandlerBuilder{
-		primaryTable: performerTable,
-		joinTable:    performersScenesTable,
-		primaryFK:    performerIDColumn,
-	}
+	return func(ctx context.Context, f *filterBuilder) {
+		h := countCriterionHandlerBuilder{
+			primaryTable: performerTable,
+			joinTable:    sceneContentRestriction.visibleJoinTable(ctx, performersScenesTable, sceneIDColumn),
+			primaryFK:    performerIDColumn,
+		}
 
-	return h.handler(count)
+		h.handler(count)(ctx, f)
+	}
 }
 
 func performerImageCountCriterionHandler(qb *PerformerStore, count *models.IntCriterionInput) criterionHandlerFunc {
-	h := countCriterionHandlerBuilder{
-		primaryTable: performerTable,
-		joinTable:    performersImagesTable,
-		primaryFK:    performerIDColumn,
-	}
+	return func(ctx context.Context, f *filterBuilder) {
+		h := countCriterionHandlerBuilder{
+			primaryTable: performerTable,
+			joinTable:    imageContentRestriction.visibleJoinTable(ctx, performersImagesTable, imageIDColumn),
+			primaryFK:    performerIDColumn,
+		}
 
-	return h.handler(count)
+		h.handler(count)(ctx, f)
+	}
 }
 
 func performerGalleryCountCriterionHandler(qb *PerformerStore, count *models.IntCriterionInput) criterionHandlerFunc {
-	h := countCriterionHandlerBuilder{
-		primaryTable: performerTable,
-		joinTable:    performersGalleriesTable,
-		primaryFK:    performerIDColumn,
-	}
+	return func(ctx context.Context, f *filterBuilder) {
+		h := countCriterionHandlerBuilder{
+			primaryTable: performerTable,
+			joinTable:    galleryContentRestriction.visibleJoinTable(ctx, performersGalleriesTable, galleryIDColumn),
+			primaryFK:    performerIDColumn,
+		}
 
-	return h.handler(count)
+		h.handler(count)(ctx, f)
+	}
 }
 
 func performerOCounterCriterionHandler(qb *PerformerStore, count *models.IntCriterionInput) criterionHandlerFunc {
-	h := joinedMultiSumCriterionHandlerBuilder{
-		primaryTable:  performerTable,
-		foreignTable1: sceneTable,
-		joinTable1:    performersScenesTable,
-		foreignTable2: imageTable,
-		joinTable2:    performersImagesTable,
-		primaryFK:     performerIDColumn,
-		foreignFK1:    sceneIDColumn,
-		foreignFK2:    imageIDColumn,
-		sum:           "o_counter",
-	}
+	return func(ctx context.Context, f *filterBuilder) {
+		h := joinedMultiSumCriterionHandlerBuilder{
+			primaryTable:  performerTable,
+			foreignTable1: sceneTable,
+			joinTable1:    sceneContentRestriction.visibleJoinTable(ctx, performersScenesTable, sceneIDColumn),
+			foreignTable2: imageTable,
+			joinTable2:    imageContentRestriction.visibleJoinTable(ctx, performersImagesTable, imageIDColumn),
+			primaryFK:     performerIDColumn,
+			foreignFK1:    sceneIDColumn,
+			foreignFK2:    imageIDColumn,
+			sum:           "o_counter",
+			sum1:          sceneActivityColumn(ctx, "o_counter"),
+			sum2:          "images.o_counter",
+		}
 
-	return h.handler(count)
+		h.handler(count)(ctx, f)
+	}
 }
 
 func performerStudiosCriterionHandler(qb *PerformerStore, studios *models.HierarchicalMultiCriterionInput) criterionHandlerFunc {
@@ -994,7 +1012,7 @@ func performerAppearsWithCriterionHandler(qb *PerformerStore, performers *models
 	}
 }
 
-func (qb *PerformerStore) getPerformerSort(findFilter *models.FindFilterType) string {
+func (qb *PerformerStore) getPerformerSort(ctx context.Context, findFilter *models.FindFilterType) string {
 	var sort string
 	var direction string
 	if findFilter == nil {
@@ -1010,16 +1028,18 @@ func (qb *PerformerStore) getPerformerSort(findFilter *models.FindFilterType) st
 	case "tag_count":
 		sortQuery += getCountSort(performerTable, performersTagsTable, performerIDColumn, direction)
 	case "scenes_count":
-		sortQuery += getCountSort(performerTable, performersScenesTable, performerIDColumn, direction)
+		sortQuery += getCountSort(performerTable, sceneContentRestriction.visibleJoinTable(ctx, performersScenesTable, sceneIDColumn), performerIDColumn, direction)
 	case "images_count":
-		sortQuery += getCountSort(performerTable, performersImagesTable, performerIDColumn, direction)
+		sortQuery += getCountSort(performerTable, imageContentRestriction.visibleJoinTable(ctx, performersImagesTable, imageIDColumn), performerIDColumn, direction)
 	case "galleries_count":
-		sortQuery += getCountSort(performerTable, performersGalleriesTable, performerIDColumn, direction)
+		sortQuery += getCountSort(performerTable, galleryContentRestriction.visibleJoinTable(ctx, performersGalleriesTable, galleryIDColumn), performerIDColumn, direction)
 	default:
 		sortQuery += getSort(sort, direction, "performers")
 	}
 	if sort == "o_counter" {
-		return getMultiSumSort("o_counter", performerTable, sceneTable, performersScenesTable, imageTable, performersImagesTable, performerIDColumn, sceneIDColumn, imageIDColumn, direction)
+		scenesJoinTable := sceneContentRestriction.visibleJoinTable(ctx, performersScenesTable, sceneIDColumn)
+		imagesJoinTable := imageContentRestriction.visibleJoinTable(ctx, performersImagesTable, imageIDColumn)
+		return getMultiSumSort("o_counter", sceneActivityColumn(ctx, "o_counter"), "images.o_counter", performerTable, sceneTable, scenesJoinTable, imageTable, imagesJoinTable, performerIDColumn, sceneIDColumn, imageIDColumn, direction)
 	}
 
 	// Whatever the sorting, always use name/id as a final sort
@@ -377,6 +377,48 @@ func (r *captionRepository) replace(ctx context.Context, id models.FileID, capti
 	return nil
 }
 
+type streamRepository struct {
+	repository
+}
+
+func (r *streamRepository) get(ctx context.Context, id models.FileID) ([]*models.VideoStream, error) {
+	query := fmt.Sprintf("SELECT %s, %s, %s, %s, %s, %s, %s from %s WHERE %s = ? ORDER BY %[1]s, %[2]s",
+		streamTypeColumn, streamIndexColumn, streamCodecColumn, streamLanguageCodeColumn, streamTitleColumn, streamChannelsColumn, streamDefaultColumn,
+		r.tableName, r.idColumn)
+	var ret []*models.VideoStream
+	err := r.queryFunc(ctx, query, []interface{}{id}, false, func(rows *sqlx.Rows) error {
+		var stream models.VideoStream
+
+		if err := rows.Scan(&stream.Type, &stream.Index, &stream.Codec, &stream.Language, &stream.Title, &stream.Channels, &stream.Default); err != nil {
+			return err
+		}
+
+		ret = append(ret, &stream)
+		return nil
+	})
+	return ret, err
+}
+
+func (r *streamRepository) insert(ctx context.Context, id models.FileID, stream *models.VideoStream) (sql.Result, error) {
+	stmt := fmt.Sprintf("INSERT INTO %s (%s, %s, %s, %s, %s, %s, %s, %s) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		r.tableName, r.idColumn, streamTypeColumn, streamIndexColumn, streamCodecColumn, streamLanguageCodeColumn, streamTitleColumn, streamChannelsColumn, streamDefaultColumn)
+	return r.tx.Exec(ctx, stmt, id, stream.Type, stream.Index, stream.Codec, stream.Language, stream.Title, stream.Channels, stream.Default)
+}
+
+func (r *streamRepository) replace(ctx context.Context, id models.FileID, streams []*models.VideoStream) error {
+	if err := r.destroy(ctx, []int{int(id)}); err != nil {
+		return err
+	}
+
+	for _, stream := range streams {
+		if _, err := r.insert(ctx, id, stream); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 type stringRepository struct {
 	repository
 	stringColumn string
@@ -0,0 +1,390 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"github.com/stashapp/stash/pkg/logger"
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+	"github.com/stashapp/stash/pkg/sliceutil/intslice"
+)
+
+// contentRestriction excludes the rows of a table which are restricted by
+// the content restrictions of the context.
+type contentRestriction struct {
+	table string
+	// visible returns the where clause, and its arguments, which is true for
+	// the rows of table which are not excluded by r
+	visible func(r *models.ContentRestrictions) (string, []interface{})
+}
+
+var (
+	sceneContentRestriction = contentRestriction{
+		table: sceneTable,
+		visible: restrictedObject{
+			table:               sceneTable,
+			fkColumn:            sceneIDColumn,
+			tagsJoinTable:       scenesTagsTable,
+			performersJoinTable: performersScenesTable,
+			filesJoinTable:      scenesFilesTable,
+		}.visibleClause,
+	}
+
+	imageContentRestriction = contentRestriction{
+		table: imageTable,
+		visible: restrictedObject{
+			table:               imageTable,
+			fkColumn:            imageIDColumn,
+			tagsJoinTable:       imagesTagsTable,
+			performersJoinTable: performersImagesTable,
+			filesJoinTable:      imagesFilesTable,
+		}.visibleClause,
+	}
+
+	galleryContentRestriction = contentRestriction{
+		table: galleryTable,
+		visible: restrictedObject{
+			table:               galleryTable,
+			fkColumn:            galleryIDColumn,
+			tagsJoinTable:       galleriesTagsTable,
+			performersJoinTable: performersGalleriesTable,
+			filesJoinTable:      galleriesFilesTable,
+			folderColumn:        "folder_id",
+		}.visibleClause,
+	}
+
+	sceneMarkerContentRestriction = contentRestriction{
+		table:   sceneMarkerTable,
+		visible: sceneMarkerVisibleClause,
+	}
+)
+
+// clause returns the where clause, and its arguments, which excludes the
+// restricted rows. Returns an empty clause if content is not restricted.
+func (c contentRestriction) clause(ctx context.Context) (string, []interface{}) {
+	r := session.GetContentRestrictions(ctx)
+	if r == nil {
+		return "", nil
+	}
+
+	return c.visible(r)
+}
+
+// filter returns a filter which excludes the restricted rows. The filter is
+// empty if content is not restricted.
+func (c contentRestriction) filter(ctx context.Context) *filterBuilder {
+	f := &filterBuilder{}
+	if clause, args := c.clause(ctx); clause != "" {
+		f.addWhere(clause, args...)
+	}
+
+	return f
+}
+
+// apply excludes the restricted rows from q, which must select from the table.
+func (c contentRestriction) apply(ctx context.Context, q *goqu.SelectDataset) *goqu.SelectDataset {
+	if clause, args := c.clause(ctx); clause != "" {
+		return q.Where(goqu.L(clause, args...))
+	}
+
+	return q
+}
+
+// applyByID excludes the rows of q where idColumn references a restricted row
+// of the table.
+func (c contentRestriction) applyByID(ctx context.Context, q *goqu.SelectDataset, idColumn exp.IdentifierExpression) *goqu.SelectDataset {
+	if clause, args := c.clause(ctx); clause != "" {
+		sql := fmt.Sprintf("? IN (SELECT %[1]s.id FROM %[1]s WHERE %[2]s)", c.table, clause)
+		return q.Where(goqu.L(sql, append([]interface{}{idColumn}, args...)...))
+	}
+
+	return q
+}
+
+// visibleJoinTable returns a subquery selecting the rows of joinTable where
+// fkColumn references rows of the table which are not restricted, or
+// joinTable if content is not restricted. The arguments of the restriction
+// are interpolated, so that the subquery may be used in place of joinTable
+// where arguments are not supported, such as in sort clauses.
+func (c contentRestriction) visibleJoinTable(ctx context.Context, joinTable string, fkColumn string) string {
+	clause, args := c.clause(ctx)
+	if clause == "" {
+		return joinTable
+	}
+
+	table := goqu.T(c.table)
+	visible, _, err := dialect.From(table).Select(table.Col(idColumn)).Where(goqu.L(clause, args...)).ToSQL()
+	if err != nil {
+		// exclude all rows rather than including restricted rows
+		logger.Errorf("error building content restriction for %s: %v", c.table, err)
+		return fmt.Sprintf("(SELECT * FROM %s WHERE 0)", joinTable)
+	}
+
+	return fmt.Sprintf("(SELECT * FROM %[1]s WHERE %[1]s.%[2]s IN (%[3]s))", joinTable, fkColumn, visible)
+}
+
+// filterIDs returns the ids which do not reference restricted rows of the
+// table, in their original order.
+func (c contentRestriction) filterIDs(ctx context.Context, ids []int) ([]int, error) {
+	if session.GetContentRestrictions(ctx) == nil || len(ids) == 0 {
+		return ids, nil
+	}
+
+	table := goqu.T(c.table)
+	q := dialect.From(table).Select(table.Col(idColumn)).Where(table.Col(idColumn).In(ids))
+	q = c.apply(ctx, q)
+
+	visible := make(map[int]bool)
+	const single = false
+	if err := queryFunc(ctx, q, single, func(rows *sqlx.Rows) error {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+
+		visible[id] = true
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("filtering restricted %s: %w", c.table, err)
+	}
+
+	ret := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if visible[id] {
+			ret = append(ret, id)
+		}
+	}
+
+	return ret, nil
+}
+
+// replaceJoins replaces the joins of id in t, where the foreign ids reference
+// the table. Existing joins to restricted rows are kept, since they cannot be
+// seen, and thus cannot be included in foreignIDs.
+func (c contentRestriction) replaceJoins(ctx context.Context, t *joinTable, id int, foreignIDs []int) error {
+	if session.GetContentRestrictions(ctx) != nil {
+		existing, err := t.get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		visible, err := c.filterIDs(ctx, existing)
+		if err != nil {
+			return err
+		}
+
+		foreignIDs = intslice.IntAppendUniques(foreignIDs, intslice.IntExclude(existing, visible))
+	}
+
+	return t.replaceJoins(ctx, id, foreignIDs)
+}
+
+func (c contentRestriction) modifyJoins(ctx context.Context, t *joinTable, id int, foreignIDs []int, mode models.RelationshipUpdateMode) error {
+	if mode == models.RelationshipUpdateModeSet {
+		return c.replaceJoins(ctx, t, id, foreignIDs)
+	}
+
+	return t.modifyJoins(ctx, id, foreignIDs, mode)
+}
+
+// restrictedObject describes how the rows of a table relate to the tags,
+// studios, performers and files which may be excluded.
+type restrictedObject struct {
+	table string
+	// fkColumn is the column of the join tables which references the table
+	fkColumn            string
+	tagsJoinTable       string
+	performersJoinTable string
+	filesJoinTable      string
+	// folderColumn is the optional column referencing the folder of the row
+	folderColumn string
+}
+
+func (o restrictedObject) visibleClause(r *models.ContentRestrictions) (string, []interface{}) {
+	var excluded []string
+	var args []interface{}
+
+	if len(r.ExcludedTagIDs) > 0 {
+		tagsQuery, tagArgs := excludedTagsQuery(r.ExcludedTagIDs)
+		excluded = append(excluded, fmt.Sprintf("EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.%[2]s = %[3]s.id AND %[1]s.tag_id IN (%[4]s))", o.tagsJoinTable, o.fkColumn, o.table, tagsQuery))
+		args = append(args, tagArgs...)
+	}
+
+	if len(r.ExcludedStudioIDs) > 0 {
+		studiosQuery, studioArgs := excludedStudiosQuery(r.ExcludedStudioIDs)
+		// studio_id is checked for null so that the clause is never null
+		excluded = append(excluded, fmt.Sprintf("(%[1]s.studio_id IS NOT NULL AND %[1]s.studio_id IN (%[2]s))", o.table, studiosQuery))
+		args = append(args, studioArgs...)
+	}
+
+	if len(r.ExcludedPerformerIDs) > 0 {
+		excluded = append(excluded, fmt.Sprintf("EXISTS (SELECT 1 FROM %[1]s WHERE %[1]s.%[2]s = %[3]s.id AND %[1]s.performer_id IN %[4]s)", o.performersJoinTable, o.fkColumn, o.table, getInBinding(len(r.ExcludedPerformerIDs))))
+		args = append(args, intsToArgs(r.ExcludedPerformerIDs)...)
+	}
+
+	if len(r.ExcludedPaths) > 0 {
+		pathClause, pathArgs := excludedPathsClause(r.ExcludedPaths)
+		excluded = append(excluded, fmt.Sprintf("EXISTS (SELECT 1 FROM %[1]s INNER JOIN files ON files.id = %[1]s.file_id INNER JOIN folders ON folders.id = files.parent_folder_id WHERE %[1]s.%[2]s = %[3]s.id AND %[4]s)", o.filesJoinTable, o.fkColumn, o.table, pathClause))
+		args = append(args, pathArgs...)
+
+		if o.folderColumn != "" {
+			excluded = append(excluded, fmt.Sprintf("(%[1]s.%[2]s IS NOT NULL AND %[1]s.%[2]s IN (SELECT folders.id FROM folders WHERE %[3]s))", o.table, o.folderColumn, pathClause))
+			args = append(args, pathArgs...)
+		}
+	}
+
+	if len(excluded) == 0 {
+		return "1 = 1", nil
+	}
+
+	return "NOT (" + strings.Join(excluded, " OR ") + ")", args
+}
+
+// sceneMarkerVisibleClause excludes markers of restricted scenes, and markers
+// with excluded tags.
+func sceneMarkerVisibleClause(r *models.ContentRestrictions) (string, []interface{}) {
+	sceneClause, args := sceneContentRestriction.visible(r)
+	clauses := []string{
+		fmt.Sprintf("scene_markers.scene_id IN (SELECT scenes.id FROM scenes WHERE %s)", sceneClause),
+	}
+
+	if len(r.ExcludedTagIDs) > 0 {
+		tagsQuery, tagArgs := excludedTagsQuery(r.ExcludedTagIDs)
+		clauses = append(clauses,
+			fmt.Sprintf("scene_markers.primary_tag_id NOT IN (%s)", tagsQuery),
+			fmt.Sprintf("NOT EXISTS (SELECT 1 FROM scene_markers_tags WHERE scene_markers_tags.scene_marker_id = scene_markers.id AND scene_markers_tags.tag_id IN (%s))", tagsQuery),
+		)
+		args = append(args, tagArgs...)
+		args = append(args, tagArgs...)
+	}
+
+	return strings.Join(clauses, " AND "), args
+}
+
+// checkTagsNotRestricted returns models.ErrRestricted if any of the tags are
+// excluded by the content restrictions of the context, or are descendants
+// of excluded tags.
+func checkTagsNotRestricted(ctx context.Context, ids []int) error {
+	r := session.GetContentRestrictions(ctx)
+	if r == nil || len(r.ExcludedTagIDs) == 0 || len(ids) == 0 {
+		return nil
+	}
+
+	query, args := excludedTagsQuery(r.ExcludedTagIDs)
+	return checkNotRestricted(ctx, query, args, ids)
+}
+
+// checkStudiosNotRestricted returns models.ErrRestricted if any of the
+// studios are excluded by the content restrictions of the context, or are
+// descendants of excluded studios.
+func checkStudiosNotRestricted(ctx context.Context, ids []int) error {
+	r := session.GetContentRestrictions(ctx)
+	if r == nil || len(r.ExcludedStudioIDs) == 0 || len(ids) == 0 {
+		return nil
+	}
+
+	query, args := excludedStudiosQuery(r.ExcludedStudioIDs)
+	return checkNotRestricted(ctx, query, args, ids)
+}
+
+// checkPerformersNotRestricted returns models.ErrRestricted if any of the
+// performers are excluded by the content restrictions of the context.
+func checkPerformersNotRestricted(ctx context.Context, ids []int) error {
+	r := session.GetContentRestrictions(ctx)
+	if r == nil {
+		return nil
+	}
+
+	for _, id := range ids {
+		if intslice.IntInclude(r.ExcludedPerformerIDs, id) {
+			return models.ErrRestricted
+		}
+	}
+
+	return nil
+}
+
+// checkNotExcludedByUsers returns an error if the object with the given id
+// is excluded by the content restrictions of any user. t is the table of the
+// excluded objects of users.
+func checkNotExcludedByUsers(ctx context.Context, t *joinTable, id int, name string) error {
+	userIDs, err := t.invert().get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(userIDs) > 0 {
+		return fmt.Errorf("cannot delete %s excluded by the content restrictions of users", name)
+	}
+
+	return nil
+}
+
+// checkNotRestricted returns models.ErrRestricted if any of the ids are
+// selected by excludedQuery.
+func checkNotRestricted(ctx context.Context, excludedQuery string, args []interface{}, ids []int) error {
+	query := fmt.Sprintf("SELECT COUNT(*) as count FROM (%s) AS excluded WHERE excluded.id IN %s", excludedQuery, getInBinding(len(ids)))
+	args = append(args, intsToArgs(ids)...)
+
+	var result struct {
+		Count int `db:"count"`
+	}
+	var tx dbWrapper
+	if err := tx.Get(ctx, &result, query, args...); err != nil {
+		return fmt.Errorf("checking content restrictions: %w", err)
+	}
+
+	if result.Count > 0 {
+		return models.ErrRestricted
+	}
+
+	return nil
+}
+
+// excludedTagsQuery returns a query selecting the ids of the tags and
+// their descendants.
+func excludedTagsQuery(ids []int) (string, []interface{}) {
+	return `WITH RECURSIVE excluded_tags(id) AS (
+SELECT tags.id FROM tags WHERE tags.id IN ` + getInBinding(len(ids)) + `
+UNION SELECT tags_relations.child_id FROM tags_relations INNER JOIN excluded_tags ON tags_relations.parent_id = excluded_tags.id
+) SELECT id FROM excluded_tags`, intsToArgs(ids)
+}
+
+// excludedStudiosQuery returns a query selecting the ids of the studios and
+// their descendants.
+func excludedStudiosQuery(ids []int) (string, []interface{}) {
+	return `WITH RECURSIVE excluded_studios(id) AS (
+SELECT studios.id FROM studios WHERE studios.id IN ` + getInBinding(len(ids)) + `
+UNION SELECT studios.id FROM studios INNER JOIN excluded_studios ON studios.parent_id = excluded_studios.id
+) SELECT id FROM excluded_studios`, intsToArgs(ids)
+}
+
+// excludedPathsClause returns a clause which is true if folders.path is one
+// of the paths or is within one of them.
+func excludedPathsClause(paths []string) (string, []interface{}) {
+	var clauses []string
+	var args []interface{}
+	for _, p := range paths {
+		p = strings.TrimSuffix(filepath.Clean(p), string(filepath.Separator))
+		prefix := p + string(filepath.Separator)
+		clauses = append(clauses, "folders.path = ? OR substr(folders.path, 1, length(?)) = ?")
+		args = append(args, p, prefix, prefix)
+	}
+
+	return "(" + strings.Join(clauses, " OR ") + ")", args
+}
+
+func intsToArgs(ids []int) []interface{} {
+	ret := make([]interface{}, len(ids))
+	for i, id := range ids {
+		ret[i] = id
+	}
+
+	return ret
+}
@@ -0,0 +1,506 @@
+//go:build integration
+// +build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+	"github.com/stashapp/stash/pkg/sliceutil/intslice"
+	"github.com/stretchr/testify/assert"
+)
+
+func restrictedContext(ctx context.Context, role models.UserRole, r models.ContentRestrictions) context.Context {
+	return session.SetCurrentUser(ctx, &models.User{
+		ID:           -1,
+		Username:     "restricted",
+		Role:         role,
+		Restrictions: r,
+	})
+}
+
+func querySceneIDs(ctx context.Context, t *testing.T) []int {
+	perPage := -1
+	result, err := db.Scene.Query(ctx, models.SceneQueryOptions{
+		QueryOptions: models.QueryOptions{
+			FindFilter: &models.FindFilterType{
+				PerPage: &perPage,
+			},
+		},
+	})
+	if err != nil {
+		t.Errorf("SceneStore.Query() error = %v", err)
+		return nil
+	}
+
+	return result.IDs
+}
+
+func TestSceneContentRestrictions(t *testing.T) {
+	tests := []struct {
+		name         string
+		restrictions models.ContentRestrictions
+		sceneIdx     int
+	}{
+		{
+			"tag",
+			models.ContentRestrictions{ExcludedTagIDs: []int{tagIDs[tagIdxWithScene]}},
+			sceneIdxWithTag,
+		},
+		{
+			"studio",
+			models.ContentRestrictions{ExcludedStudioIDs: []int{studioIDs[studioIdxWithScene]}},
+			sceneIdxWithStudio,
+		},
+		{
+			"performer",
+			models.ContentRestrictions{ExcludedPerformerIDs: []int{performerIDs[performerIdxWithScene]}},
+			sceneIdxWithPerformer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withTxn(func(ctx context.Context) error {
+				assert := assert.New(t)
+				qb := db.Scene
+				sceneID := sceneIDs[tt.sceneIdx]
+
+				total, err := qb.Count(ctx)
+				if err != nil {
+					t.Errorf("SceneStore.Count() error = %v", err)
+					return nil
+				}
+
+				restrictedCtx := restrictedContext(ctx, models.UserRoleReadOnly, tt.restrictions)
+
+				scene, err := qb.Find(restrictedCtx, sceneID)
+				if err != nil {
+					t.Errorf("SceneStore.Find() error = %v", err)
+					return nil
+				}
+				assert.Nil(scene)
+
+				ids := querySceneIDs(restrictedCtx, t)
+				assert.NotContains(ids, sceneID)
+				assert.Len(ids, total-1)
+
+				count, err := qb.Count(restrictedCtx)
+				if err != nil {
+					t.Errorf("SceneStore.Count() error = %v", err)
+					return nil
+				}
+				assert.Equal(total-1, count)
+
+				// restrictions are not applied to admins
+				adminCtx := restrictedContext(ctx, models.UserRoleAdmin, tt.restrictions)
+				scene, err = qb.Find(adminCtx, sceneID)
+				if err != nil {
+					t.Errorf("SceneStore.Find() error = %v", err)
+					return nil
+				}
+				assert.NotNil(scene)
+
+				return nil
+			})
+		})
+	}
+}
+
+func TestSceneContentRestrictionsChildTag(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		qb := db.Scene
+		sceneID := sceneIDs[sceneIdxWithMovie]
+
+		if _, err := qb.UpdatePartial(ctx, sceneID, models.ScenePartial{
+			TagIDs: &models.UpdateIDs{
+				IDs:  []int{tagIDs[tagIdxWithParentTag]},
+				Mode: models.RelationshipUpdateModeAdd,
+			},
+			UpdatedAt: models.NewOptionalTime(time.Now()),
+		}); err != nil {
+			t.Errorf("SceneStore.UpdatePartial() error = %v", err)
+			return nil
+		}
+
+		// excluding the parent tag excludes scenes with its child tags
+		restrictedCtx := restrictedContext(ctx, models.UserRoleReadOnly, models.ContentRestrictions{
+			ExcludedTagIDs: []int{tagIDs[tagIdxWithChildTag]},
+		})
+
+		scene, err := qb.Find(restrictedCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+		assert.Nil(t, scene)
+
+		return nil
+	})
+}
+
+func TestSceneContentRestrictionsPath(t *testing.T) {
+	withTxn(func(ctx context.Context) error {
+		qb := db.Scene
+		sceneID := sceneIDs[sceneIdxWithTag]
+
+		scene, err := qb.Find(ctx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+
+		dir := filepath.Dir(scene.Path)
+
+		// a path which is a prefix of the folder name does not exclude it
+		restrictedCtx := restrictedContext(ctx, models.UserRoleReadOnly, models.ContentRestrictions{
+			ExcludedPaths: []string{dir[:len(dir)-1]},
+		})
+
+		scene, err = qb.Find(restrictedCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+		assert.NotNil(t, scene)
+
+		restrictedCtx = restrictedContext(ctx, models.UserRoleReadOnly, models.ContentRestrictions{
+			ExcludedPaths: []string{dir},
+		})
+
+		scene, err = qb.Find(restrictedCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+		assert.Nil(t, scene)
+
+		assert.NotContains(t, querySceneIDs(restrictedCtx, t), sceneID)
+
+		return nil
+	})
+}
+
+func TestSceneMarkerContentRestrictions(t *testing.T) {
+	withTxn(func(ctx context.Context) error {
+		qb := db.SceneMarker
+		assert := assert.New(t)
+
+		restrictedCtx := restrictedContext(ctx, models.UserRoleReadOnly, models.ContentRestrictions{
+			ExcludedTagIDs: []int{tagIDs[tagIdxWithMarkers]},
+		})
+
+		markers, err := qb.FindBySceneID(restrictedCtx, sceneIDs[sceneIdxWithMarkers])
+		if err != nil {
+			t.Errorf("SceneMarkerStore.FindBySceneID() error = %v", err)
+			return nil
+		}
+
+		// markers with the excluded tag are excluded
+		assert.Len(markers, 2)
+		for _, m := range markers {
+			markerTagIDs, err := qb.GetTagIDs(ctx, m.ID)
+			if err != nil {
+				t.Errorf("SceneMarkerStore.GetTagIDs() error = %v", err)
+				return nil
+			}
+			assert.NotContains(markerTagIDs, tagIDs[tagIdxWithMarkers])
+		}
+
+		count, err := qb.CountByTagID(restrictedCtx, tagIDs[tagIdxWithMarkers])
+		if err != nil {
+			t.Errorf("SceneMarkerStore.CountByTagID() error = %v", err)
+			return nil
+		}
+		assert.Zero(count)
+
+		// markers of excluded scenes are excluded
+		restrictedCtx = restrictedContext(ctx, models.UserRoleReadOnly, models.ContentRestrictions{
+			ExcludedTagIDs: []int{tagIDs[tagIdx3WithScene]},
+		})
+
+		markers, err = qb.FindBySceneID(restrictedCtx, sceneIDs[sceneIdxWithMarkerAndTag])
+		if err != nil {
+			t.Errorf("SceneMarkerStore.FindBySceneID() error = %v", err)
+			return nil
+		}
+		assert.Len(markers, 0)
+
+		return nil
+	})
+}
+
+func TestImageContentRestrictions(t *testing.T) {
+	withTxn(func(ctx context.Context) error {
+		qb := db.Image
+		imageID := imageIDs[imageIdxWithTag]
+
+		restrictedCtx := restrictedContext(ctx, models.UserRoleReadOnly, models.ContentRestrictions{
+			ExcludedTagIDs: []int{tagIDs[tagIdxWithImage]},
+		})
+
+		image, err := qb.Find(restrictedCtx, imageID)
+		if err != nil {
+			t.Errorf("ImageStore.Find() error = %v", err)
+			return nil
+		}
+		assert.Nil(t, image)
+
+		return nil
+	})
+}
+
+func TestGalleryContentRestrictions(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		assert := assert.New(t)
+		galleryID := galleryIDs[galleryIdxWithTag]
+
+		restrictedCtx := session.SetContentRestrictions(ctx, &models.ContentRestrictions{
+			ExcludedTagIDs: []int{tagIDs[tagIdxWithGallery]},
+		})
+
+		gallery, err := db.Gallery.Find(restrictedCtx, galleryID)
+		if err != nil {
+			t.Errorf("GalleryStore.Find() error = %v", err)
+			return nil
+		}
+		assert.Nil(gallery)
+
+		// link the restricted gallery to a scene
+		sceneID := sceneIDs[sceneIdxWithGallery]
+		if err := db.Scene.AddGalleryIDs(ctx, sceneID, []int{galleryID}); err != nil {
+			t.Errorf("SceneStore.AddGalleryIDs() error = %v", err)
+			return nil
+		}
+
+		ids, err := db.Scene.GetGalleryIDs(restrictedCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.GetGalleryIDs() error = %v", err)
+			return nil
+		}
+		assert.Equal([]int{galleryIDs[galleryIdxWithScene]}, ids)
+
+		// setting the galleries keeps the restricted gallery
+		if _, err := db.Scene.UpdatePartial(restrictedCtx, sceneID, models.ScenePartial{
+			GalleryIDs: &models.UpdateIDs{
+				Mode: models.RelationshipUpdateModeSet,
+			},
+			UpdatedAt: models.NewOptionalTime(time.Now()),
+		}); err != nil {
+			t.Errorf("SceneStore.UpdatePartial() error = %v", err)
+			return nil
+		}
+
+		ids, err = db.Scene.GetGalleryIDs(ctx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.GetGalleryIDs() error = %v", err)
+			return nil
+		}
+		assert.True(intslice.IntInclude(ids, galleryID))
+		assert.False(intslice.IntInclude(ids, galleryIDs[galleryIdxWithScene]))
+
+		return nil
+	})
+}
+
+func TestCountContentRestrictions(t *testing.T) {
+	withTxn(func(ctx context.Context) error {
+		assert := assert.New(t)
+
+		scene, err := db.Scene.Find(ctx, sceneIDs[sceneIdxWithPerformer])
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+
+		// excludes all scenes
+		restrictedCtx := session.SetContentRestrictions(ctx, &models.ContentRestrictions{
+			ExcludedPaths: []string{filepath.Dir(scene.Path)},
+		})
+
+		hasScenes := &models.IntCriterionInput{
+			Value:    0,
+			Modifier: models.CriterionModifierGreaterThan,
+		}
+
+		for _, c := range []context.Context{ctx, restrictedCtx} {
+			restricted := c == restrictedCtx
+
+			performers, _, err := db.Performer.Query(c, &models.PerformerFilterType{SceneCount: hasScenes}, nil)
+			if err != nil {
+				t.Errorf("PerformerStore.Query() error = %v", err)
+				return nil
+			}
+			var ids []int
+			for _, p := range performers {
+				ids = append(ids, p.ID)
+			}
+			assert.Equal(!restricted, intslice.IntInclude(ids, performerIDs[performerIdxWithScene]))
+
+			studios, _, err := db.Studio.Query(c, &models.StudioFilterType{SceneCount: hasScenes}, nil)
+			if err != nil {
+				t.Errorf("StudioStore.Query() error = %v", err)
+				return nil
+			}
+			assert.Equal(!restricted, len(studios) > 0)
+
+			tags, _, err := db.Tag.Query(c, &models.TagFilterType{SceneCount: hasScenes}, nil)
+			if err != nil {
+				t.Errorf("TagStore.Query() error = %v", err)
+				return nil
+			}
+			assert.Equal(!restricted, len(tags) > 0)
+		}
+
+		// the sort subqueries must be valid when restricted
+		for _, sort := range []string{"scenes_count", "images_count", "galleries_count", "o_counter"} {
+			sort := sort
+			if _, _, err := db.Performer.Query(restrictedCtx, nil, &models.FindFilterType{Sort: &sort}); err != nil {
+				t.Errorf("PerformerStore.Query() sort %s error = %v", sort, err)
+			}
+		}
+		for _, sort := range []string{"scenes_count", "scene_markers_count", "images_count", "galleries_count"} {
+			sort := sort
+			if _, _, err := db.Tag.Query(restrictedCtx, nil, &models.FindFilterType{Sort: &sort}); err != nil {
+				t.Errorf("TagStore.Query() sort %s error = %v", sort, err)
+			}
+		}
+		for _, sort := range []string{"scenes_count", "images_count", "galleries_count"} {
+			sort := sort
+			if _, _, err := db.Studio.Query(restrictedCtx, nil, &models.FindFilterType{Sort: &sort}); err != nil {
+				t.Errorf("StudioStore.Query() sort %s error = %v", sort, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func TestTagContentRestrictionsModify(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		assert := assert.New(t)
+		qb := db.Tag
+
+		parentID := tagIDs[tagIdxWithChildTag]
+		childID := tagIDs[tagIdxWithParentTag]
+
+		restrictedCtx := restrictedContext(ctx, models.UserRoleEditor, models.ContentRestrictions{
+			ExcludedTagIDs: []int{parentID},
+		})
+
+		// descendants of excluded tags are also restricted
+		assert.ErrorIs(qb.Destroy(restrictedCtx, childID), models.ErrRestricted)
+		assert.ErrorIs(qb.UpdateParentTags(restrictedCtx, childID, nil), models.ErrRestricted)
+		assert.ErrorIs(qb.UpdateChildTags(restrictedCtx, parentID, nil), models.ErrRestricted)
+		assert.ErrorIs(qb.Merge(restrictedCtx, []int{childID}, tagIDs[tagIdxWithScene]), models.ErrRestricted)
+
+		// unchanged relations may be saved
+		assert.Nil(qb.UpdateParentTags(restrictedCtx, childID, []int{parentID}))
+
+		return nil
+	})
+}
+
+func TestStudioContentRestrictionsModify(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		assert := assert.New(t)
+		qb := db.Studio
+
+		parentID := studioIDs[studioIdxWithChildStudio]
+		childID := studioIDs[studioIdxWithParentStudio]
+
+		restrictedCtx := restrictedContext(ctx, models.UserRoleEditor, models.ContentRestrictions{
+			ExcludedStudioIDs: []int{parentID},
+		})
+
+		assert.ErrorIs(qb.Destroy(restrictedCtx, childID), models.ErrRestricted)
+
+		_, err := qb.UpdatePartial(restrictedCtx, models.StudioPartial{
+			ID:       childID,
+			ParentID: models.NewOptionalIntPtr(nil),
+		})
+		assert.ErrorIs(err, models.ErrRestricted)
+
+		// unchanged parent may be saved
+		_, err = qb.UpdatePartial(restrictedCtx, models.StudioPartial{
+			ID:       childID,
+			ParentID: models.NewOptionalInt(parentID),
+		})
+		assert.Nil(err)
+
+		return nil
+	})
+}
+
+func TestPerformerContentRestrictionsModify(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		performerID := performerIDs[performerIdxWithScene]
+
+		restrictedCtx := restrictedContext(ctx, models.UserRoleEditor, models.ContentRestrictions{
+			ExcludedPerformerIDs: []int{performerID},
+		})
+
+		assert.ErrorIs(t, db.Performer.Destroy(restrictedCtx, performerID), models.ErrRestricted)
+
+		return nil
+	})
+}
+
+func TestDestroyExcludedByUser(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		assert := assert.New(t)
+
+		now := time.Now()
+		newTag := func(name string) int {
+			tag := &models.Tag{Name: name, CreatedAt: now, UpdatedAt: now}
+			if err := db.Tag.Create(ctx, tag); err != nil {
+				t.Fatalf("TagStore.Create() error = %v", err)
+			}
+			return tag.ID
+		}
+
+		sourceID := newTag("excluded source")
+		destinationID := newTag("excluded destination")
+
+		u := &models.User{
+			Username:     "excluded",
+			PasswordHash: "hash",
+			Role:         models.UserRoleReadOnly,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+			Restrictions: models.ContentRestrictions{
+				ExcludedTagIDs:       []int{sourceID},
+				ExcludedStudioIDs:    []int{studioIDs[studioIdxWithScene]},
+				ExcludedPerformerIDs: []int{performerIDs[performerIdxWithScene]},
+			},
+		}
+		if err := db.User.Create(ctx, u); err != nil {
+			t.Errorf("UserStore.Create() error = %v", err)
+			return nil
+		}
+
+		assert.NotNil(db.Tag.Destroy(ctx, sourceID))
+		assert.NotNil(db.Studio.Destroy(ctx, studioIDs[studioIdxWithScene]))
+		assert.NotNil(db.Performer.Destroy(ctx, performerIDs[performerIdxWithScene]))
+
+		// merging moves the restriction to the destination tag
+		if err := db.Tag.Merge(ctx, []int{sourceID}, destinationID); err != nil {
+			t.Errorf("TagStore.Merge() error = %v", err)
+			return nil
+		}
+
+		found, err := db.User.Find(ctx, u.ID)
+		if err != nil {
+			t.Errorf("UserStore.Find() error = %v", err)
+			return nil
+		}
+		assert.Equal([]int{destinationID}, found.Restrictions.ExcludedTagIDs)
+
+		return nil
+	})
+}
@@ -9,7 +9,6 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
@@ -113,6 +112,16 @@ func (r *sceneRow) fromScene(o models.Scene) {
 	r.PlayCount = o.PlayCount
 }
 
+// clearActivity resets the fields which are stored per user.
+func (r *sceneRow) clearActivity() {
+	r.Rating = null.Int{}
+	r.OCounter = 0
+	r.LastPlayedAt = NullTimestamp{}
+	r.ResumeTime = 0
+	r.PlayDuration = 0
+	r.PlayCount = 0
+}
+
 type sceneQueryRow struct {
 	sceneRow
 	PrimaryFileID         null.Int    `db:"primary_file_id"`
@@ -253,11 +262,25 @@ func (qb *SceneStore) Create(ctx context.Context, newObject *models.Scene, fileI
 	var r sceneRow
 	r.fromScene(*newObject)
 
+	userID := activityUserID(ctx)
+	if userID != nil {
+		// activity is stored against the user
+		r.clearActivity()
+	}
+
 	id, err := qb.tableMgr.insertID(ctx, r)
 	if err != nil {
 		return err
 	}
 
+	if userID != nil {
+		activity := *newObject
+		activity.ID = id
+		if err := qb.createActivity(ctx, *userID, activity); err != nil {
+			return err
+		}
+	}
+
 	if len(fileIDs) > 0 {
 		const firstPrimary = true
 		if err := scenesFilesTableMgr.insertJoins(ctx, id, firstPrimary, fileIDs); err != nil {
@@ -320,6 +343,14 @@ func (qb *SceneStore) UpdatePartial(ctx context.Context, id int, partial models.
 
 	r.fromPartial(partial)
 
+	if userID := activityUserID(ctx); userID != nil {
+		if activity := splitSceneActivity(r.Record); len(activity) > 0 {
+			if err := qb.updateActivity(ctx, id, *userID, activity); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	if len(r.Record) > 0 {
 		if err := qb.tableMgr.updateByID(ctx, id, r.Record); err != nil {
 			return nil, err
@@ -342,7 +373,7 @@ func (qb *SceneStore) UpdatePartial(ctx context.Context, id int, partial models.
 		}
 	}
 	if partial.GalleryIDs != nil {
-		if err := scenesGalleriesTableMgr.modifyJoins(ctx, id, partial.GalleryIDs.IDs, partial.GalleryIDs.Mode); err != nil {
+		if err := galleryContentRestriction.modifyJoins(ctx, scenesGalleriesTableMgr, id, partial.GalleryIDs.IDs, partial.GalleryIDs.Mode); err != nil {
 			return nil, err
 		}
 	}
@@ -369,8 +400,14 @@ func (qb *SceneStore) Update(ctx context.Context, updatedObject *models.Scene) e
 	var r sceneRow
 	r.fromScene(*updatedObject)
 
-	if err := qb.tableMgr.updateByID(ctx, updatedObject.ID, r); err != nil {
-		return err
+	if userID := activityUserID(ctx); userID != nil {
+		if err := qb.updateWithActivity(ctx, *userID, r); err != nil {
+			return err
+		}
+	} else {
+		if err := qb.tableMgr.updateByID(ctx, updatedObject.ID, r); err != nil {
+			return err
+		}
 	}
 
 	if updatedObject.URLs.Loaded() {
@@ -392,7 +429,7 @@ func (qb *SceneStore) Update(ctx context.Context, updatedObject *models.Scene) e
 	}
 
 	if updatedObject.GalleryIDs.Loaded() {
-		if err := scenesGalleriesTableMgr.replaceJoins(ctx, updatedObject.ID, updatedObject.GalleryIDs.List()); err != nil {
+		if err := galleryContentRestriction.replaceJoins(ctx, scenesGalleriesTableMgr, updatedObject.ID, updatedObject.GalleryIDs.List()); err != nil {
 			return err
 		}
 	}
@@ -513,6 +550,8 @@ func (qb *SceneStore) get(ctx context.Context, q *goqu.SelectDataset) (*models.S
 }
 
 func (qb *SceneStore) getMany(ctx context.Context, q *goqu.SelectDataset) ([]*models.Scene, error) {
+	q = sceneContentRestriction.apply(ctx, q)
+
 	const single = false
 	var ret []*models.Scene
 	var lastID int
@@ -534,6 +573,12 @@ func (qb *SceneStore) getMany(ctx context.Context, q *goqu.SelectDataset) ([]*mo
 		return nil, err
 	}
 
+	if userID := activityUserID(ctx); userID != nil {
+		if err := qb.loadActivity(ctx, *userID, ret); err != nil {
+			return nil, err
+		}
+	}
+
 	return ret, nil
 }
 
@@ -704,6 +749,7 @@ func (qb *SceneStore) CountByPerformerID(ctx context.Context, performerID int) (
 	joinTable := scenesPerformersJoinTable
 
 	q := dialect.Select(goqu.COUNT("*")).From(joinTable).Where(joinTable.Col(performerIDColumn).Eq(performerID))
+	q = sceneContentRestriction.applyByID(ctx, q, joinTable.Col(sceneIDColumn))
 	return count(ctx, q)
 }
 
@@ -711,7 +757,11 @@ func (qb *SceneStore) OCountByPerformerID(ctx context.Context, performerID int)
 	table := qb.table()
 	joinTable := scenesPerformersJoinTable
 
-	q := dialect.Select(goqu.COALESCE(goqu.SUM("o_counter"), 0)).From(table).InnerJoin(joinTable, goqu.On(table.Col(idColumn).Eq(joinTable.Col(sceneIDColumn)))).Where(joinTable.Col(performerIDColumn).Eq(performerID))
+	oCounter := goqu.L(sceneActivityColumn(ctx, "o_counter"))
+
+	q := dialect.Select(goqu.COALESCE(goqu.SUM(oCounter), 0)).From(table).InnerJoin(joinTable, goqu.On(table.Col(idColumn).Eq(joinTable.Col(sceneIDColumn)))).Where(joinTable.Col(performerIDColumn).Eq(performerID))
+	q = sceneContentRestriction.apply(ctx, q)
+
 	var ret int
 	if err := querySimple(ctx, q, &ret); err != nil {
 		return 0, err
@@ -723,7 +773,11 @@ func (qb *SceneStore) OCountByPerformerID(ctx context.Context, performerID int)
 func (qb *SceneStore) OCount(ctx context.Context) (int, error) {
 	table := qb.table()
 
-	q := dialect.Select(goqu.COALESCE(goqu.SUM("o_counter"), 0)).From(table)
+	oCounter := goqu.L(sceneActivityColumn(ctx, "o_counter"))
+
+	q := dialect.Select(goqu.COALESCE(goqu.SUM(oCounter), 0)).From(table)
+	q = sceneContentRestriction.apply(ctx, q)
+
 	var ret int
 	if err := querySimple(ctx, q, &ret); err != nil {
 		return 0, err
@@ -749,16 +803,21 @@ func (qb *SceneStore) CountByMovieID(ctx context.Context, movieID int) (int, err
 	joinTable := scenesMoviesJoinTable
 
 	q := dialect.Select(goqu.COUNT("*")).From(joinTable).Where(joinTable.Col(movieIDColumn).Eq(movieID))
+	q = sceneContentRestriction.applyByID(ctx, q, joinTable.Col(sceneIDColumn))
 	return count(ctx, q)
 }
 
 func (qb *SceneStore) Count(ctx context.Context) (int, error) {
 	q := dialect.Select(goqu.COUNT("*")).From(qb.table())
+	q = sceneContentRestriction.apply(ctx, q)
 	return count(ctx, q)
 }
 
 func (qb *SceneStore) PlayCount(ctx context.Context) (int, error) {
-	q := dialect.Select(goqu.COALESCE(goqu.SUM("play_count"), 0)).From(qb.table())
+	playCount := goqu.L(sceneActivityColumn(ctx, "play_count"))
+
+	q := dialect.Select(goqu.COALESCE(goqu.SUM(playCount), 0)).From(qb.table())
+	q = sceneContentRestriction.apply(ctx, q)
 
 	var ret int
 	if err := querySimple(ctx, q, &ret); err != nil {
@@ -770,7 +829,10 @@ func (qb *SceneStore) PlayCount(ctx context.Context) (int, error) {
 
 func (qb *SceneStore) UniqueScenePlayCount(ctx context.Context) (int, error) {
 	table := qb.table()
-	q := dialect.Select(goqu.COUNT("*")).From(table).Where(table.Col("play_count").Gt(0))
+	playCount := goqu.L(sceneActivityColumn(ctx, "play_count"))
+
+	q := dialect.Select(goqu.COUNT("*")).From(table).Where(playCount.Gt(0))
+	q = sceneContentRestriction.apply(ctx, q)
 
 	return count(ctx, q)
 }
@@ -787,6 +849,8 @@ func (qb *SceneStore) Size(ctx context.Context) (float64, error) {
 		fileTable,
 		goqu.On(scenesFilesJoinTable.Col(fileIDColumn).Eq(fileTable.Col(idColumn))),
 	)
+	q = sceneContentRestriction.apply(ctx, q)
+
 	var ret float64
 	if err := querySimple(ctx, q, &ret); err != nil {
 		return 0, err
@@ -807,6 +871,7 @@ func (qb *SceneStore) Duration(ctx context.Context) (float64, error) {
 		videoFileTable,
 		goqu.On(videoFileTable.Col("file_id").Eq(scenesFilesJoinTable.Col("file_id"))),
 	)
+	q = sceneContentRestriction.apply(ctx, q)
 
 	var ret float64
 	if err := querySimple(ctx, q, &ret); err != nil {
@@ -819,7 +884,10 @@ func (qb *SceneStore) Duration(ctx context.Context) (float64, error) {
 func (qb *SceneStore) PlayDuration(ctx context.Context) (float64, error) {
 	table := qb.table()
 
-	q := dialect.Select(goqu.COALESCE(goqu.SUM("play_duration"), 0)).From(table)
+	playDuration := goqu.L(sceneActivityColumn(ctx, "play_duration"))
+
+	q := dialect.Select(goqu.COALESCE(goqu.SUM(playDuration), 0)).From(table)
+	q = sceneContentRestriction.apply(ctx, q)
 
 	var ret float64
 	if err := querySimple(ctx, q, &ret); err != nil {
@@ -833,6 +901,7 @@ func (qb *SceneStore) CountByStudioID(ctx context.Context, studioID int) (int, e
 	table := qb.table()
 
 	q := dialect.Select(goqu.COUNT("*")).From(table).Where(table.Col(studioIDColumn).Eq(studioID))
+	q = sceneContentRestriction.apply(ctx, q)
 	return count(ctx, q)
 }
 
@@ -840,6 +909,7 @@ func (qb *SceneStore) CountByTagID(ctx context.Context, tagID int) (int, error)
 	joinTable := scenesTagsJoinTable
 
 	q := dialect.Select(goqu.COUNT("*")).From(joinTable).Where(joinTable.Col(tagIDColumn).Eq(tagID))
+	q = sceneContentRestriction.applyByID(ctx, q, joinTable.Col(sceneIDColumn))
 	return count(ctx, q)
 }
 
@@ -975,14 +1045,14 @@ func (qb *SceneStore) makeFilter(ctx context.Context, sceneFilter *models.SceneF
 
 	query.handleCriterion(ctx, scenePhashDistanceCriterionHandler(qb, sceneFilter.PhashDistance))
 
-	query.handleCriterion(ctx, intCriterionHandler(sceneFilter.Rating100, "scenes.rating", nil))
+	query.handleCriterion(ctx, intCriterionHandler(sceneFilter.Rating100, sceneActivityColumn(ctx, "rating"), nil))
 	// legacy rating handler
-	query.handleCriterion(ctx, rating5CriterionHandler(sceneFilter.Rating, "scenes.rating", nil))
-	query.handleCriterion(ctx, intCriterionHandler(sceneFilter.OCounter, "scenes.o_counter", nil))
+	query.handleCriterion(ctx, rating5CriterionHandler(sceneFilter.Rating, sceneActivityColumn(ctx, "rating"), nil))
+	query.handleCriterion(ctx, intCriterionHandler(sceneFilter.OCounter, sceneActivityColumn(ctx, "o_counter"), nil))
 	query.handleCriterion(ctx, boolCriterionHandler(sceneFilter.Organized, "scenes.organized", nil))
 
 	query.handleCriterion(ctx, floatIntCriterionHandler(sceneFilter.Duration, "video_files.duration", qb.addVideoFilesTable))
-	query.handleCriterion(ctx, resolutionCriterionHandler(sceneFilter.Resolution, "video_files.height", "video_files.width", qb.addVideoFilesTable))
+	query.handleCriterion(ctx, resolutionCriterionHandler(sceneFilter.Resolution, "COALESCE(video_files.crop_height, video_files.height)", "COALESCE(video_files.crop_width, video_files.width)", qb.addVideoFilesTable))
 
 	query.handleCriterion(ctx, codecCriterionHandler(sceneFilter.VideoCodec, "video_files.video_codec", qb.addVideoFilesTable))
 	query.handleCriterion(ctx, codecCriterionHandler(sceneFilter.AudioCodec, "video_files.audio_codec", qb.addVideoFilesTable))
@@ -1006,12 +1076,14 @@ func (qb *SceneStore) makeFilter(ctx context.Context, sceneFilter *models.SceneF
 
 	query.handleCriterion(ctx, boolCriterionHandler(sceneFilter.Interactive, "video_files.interactive", qb.addVideoFilesTable))
 	query.handleCriterion(ctx, intCriterionHandler(sceneFilter.InteractiveSpeed, "video_files.interactive_speed", qb.addVideoFilesTable))
+	query.handleCriterion(ctx, floatCriterionHandler(sceneFilter.Loudness, "video_files.integrated_loudness", qb.addVideoFilesTable))
+	query.handleCriterion(ctx, integrityCriterionHandler(sceneFilter.Integrity, "video_files.integrity_status", qb.addVideoFilesTable))
 
 	query.handleCriterion(ctx, sceneCaptionCriterionHandler(qb, sceneFilter.Captions))
 
-	query.handleCriterion(ctx, floatIntCriterionHandler(sceneFilter.ResumeTime, "scenes.resume_time", nil))
-	query.handleCriterion(ctx, floatIntCriterionHandler(sceneFilter.PlayDuration, "scenes.play_duration", nil))
-	query.handleCriterion(ctx, intCriterionHandler(sceneFilter.PlayCount, "scenes.play_count", nil))
+	query.handleCriterion(ctx, floatIntCriterionHandler(sceneFilter.ResumeTime, sceneActivityColumn(ctx, "resume_time"), nil))
+	query.handleCriterion(ctx, floatIntCriterionHandler(sceneFilter.PlayDuration, sceneActivityColumn(ctx, "play_duration"), nil))
+	query.handleCriterion(ctx, intCriterionHandler(sceneFilter.PlayCount, sceneActivityColumn(ctx, "play_count"), nil))
 
 	query.handleCriterion(ctx, sceneTagsCriterionHandler(qb, sceneFilter.Tags))
 	query.handleCriterion(ctx, sceneTagCountCriterionHandler(qb, sceneFilter.TagCount))
@@ -1098,7 +1170,11 @@ func (qb *SceneStore) makeQuery(ctx context.Context, sceneFilter *models.SceneFi
 		return nil, err
 	}
 
-	qb.setSceneSort(&query, findFilter)
+	if err := query.addFilter(sceneContentRestriction.filter(ctx)); err != nil {
+		return nil, err
+	}
+
+	qb.setSceneSort(ctx, &query, findFilter)
 	query.sortAndPagination += getPagination(findFilter)
 
 	return &query, nil
@@ -1262,6 +1338,34 @@ func resolutionCriterionHandler(resolution *models.ResolutionCriterionInput, hei
 	}
 }
 
+func integrityCriterionHandler(integrity *models.IntegrityCriterionInput, statusColumn string, addJoinFn func(f *filterBuilder)) criterionHandlerFunc {
+	return func(ctx context.Context, f *filterBuilder) {
+		if integrity != nil && integrity.Value.IsValid() {
+			if addJoinFn != nil {
+				addJoinFn(f)
+			}
+
+			var clause string
+			var args []interface{}
+			if integrity.Value == models.IntegrityStatusUnchecked {
+				clause = statusColumn + " IS NULL"
+			} else {
+				clause = "COALESCE(" + statusColumn + ", '') = ?"
+				args = append(args, integrity.Value.String())
+			}
+
+			switch integrity.Modifier {
+			case models.CriterionModifierEquals:
+				f.addWhere(clause, args...)
+			case models.CriterionModifierNotEquals:
+				f.addWhere("NOT ("+clause+")", args...)
+			default:
+				f.setError(fmt.Errorf("invalid integrity modifier: %s", integrity.Modifier))
+			}
+		}
+	}
+}
+
 func codecCriterionHandler(codec *models.StringCriterionInput, codecColumn string, addJoinFn func(f *filterBuilder)) criterionHandlerFunc {
 	return func(ctx context.Context, f *filterBuilder) {
 		if codec != nil {
@@ -1508,7 +1612,7 @@ func scenePhashDistanceCriterionHandler(qb *SceneStore, phashDistance *models.Ph
 	}
 }
 
-func (qb *SceneStore) setSceneSort(query *queryBuilder, findFilter *models.FindFilterType) {
+func (qb *SceneStore) setSceneSort(ctx context.Context, query *queryBuilder, findFilter *models.FindFilterType) {
 	if findFilter == nil || findFilter.Sort == nil || *findFilter.Sort == "" {
 		return
 	}
@@ -1599,9 +1703,10 @@ func (qb *SceneStore) setSceneSort(query *queryBuilder, findFilter *models.FindF
 		addFileTable()
 		addFolderTable()
 		query.sortAndPagination += " ORDER BY COALESCE(scenes.title, files.basename) COLLATE NATURAL_CI " + direction + ", folders.path COLLATE NATURAL_CI " + direction
-	case "play_count":
+	case "play_count", "rating", "o_counter", "play_duration", "resume_time", "last_played_at":
 		// handle here since getSort has special handling for _count suffix
-		query.sortAndPagination += " ORDER BY scenes.play_count " + direction
+		// and activity is stored per user
+		query.sortAndPagination += " ORDER BY " + sceneActivityColumn(ctx, sort) + " " + getSortDirection(direction)
 	default:
 		query.sortAndPagination += getSort(sort, direction, "scenes")
 	}
@@ -1610,62 +1715,6 @@ func (qb *SceneStore) setSceneSort(query *queryBuilder, findFilter *models.FindF
 	query.sortAndPagination += ", COALESCE(scenes.title, scenes.id) COLLATE NATURAL_CI ASC"
 }
 
-func (qb *SceneStore) getPlayCount(ctx context.Context, id int) (int, error) {
-	q := dialect.From(qb.tableMgr.table).Select("play_count").Where(goqu.Ex{"id": id})
-
-	const single = true
-	var ret int
-	if err := queryFunc(ctx, q, single, func(rows *sqlx.Rows) error {
-		if err := rows.Scan(&ret); err != nil {
-			return err
-		}
-		return nil
-	}); err != nil {
-		return 0, err
-	}
-
-	return ret, nil
-}
-
-func (qb *SceneStore) SaveActivity(ctx context.Context, id int, resumeTime *float64, playDuration *float64) (bool, error) {
-	if err := qb.tableMgr.checkIDExists(ctx, id); err != nil {
-		return false, err
-	}
-
-	record := goqu.Record{}
-
-	if resumeTime != nil {
-		record["resume_time"] = resumeTime
-	}
-
-	if playDuration != nil {
-		record["play_duration"] = goqu.L("play_duration + ?", playDuration)
-	}
-
-	if len(record) > 0 {
-		if err := qb.tableMgr.updateByID(ctx, id, record); err != nil {
-			return false, err
-		}
-	}
-
-	return true, nil
-}
-
-func (qb *SceneStore) IncrementWatchCount(ctx context.Context, id int) (int, error) {
-	if err := qb.tableMgr.checkIDExists(ctx, id); err != nil {
-		return 0, err
-	}
-
-	if err := qb.tableMgr.updateByID(ctx, id, goqu.Record{
-		"play_count":     goqu.L("play_count + 1"),
-		"last_played_at": time.Now(),
-	}); err != nil {
-		return 0, err
-	}
-
-	return qb.getPlayCount(ctx, id)
-}
-
 func (qb *SceneStore) GetURLs(ctx context.Context, sceneID int) ([]string, error) {
 	return scenesURLsTableMgr.get(ctx, sceneID)
 }
@@ -1787,7 +1836,12 @@ func (qb *SceneStore) galleriesRepository() *joinRepository {
 }
 
 func (qb *SceneStore) GetGalleryIDs(ctx context.Context, id int) ([]int, error) {
-	return qb.galleriesRepository().getIDs(ctx, id)
+	ids, err := qb.galleriesRepository().getIDs(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return galleryContentRestriction.filterIDs(ctx, ids)
 }
 
 func (qb *SceneStore) AddGalleryIDs(ctx context.Context, sceneID int, galleryIDs []int) error {
@@ -1852,6 +1906,15 @@ func (qb *SceneStore) FindDuplicates(ctx context.Context, distance int, duration
 
 	var duplicates [][]*models.Scene
 	for _, sceneIds := range dupeIds {
+		sceneIds, err := sceneContentRestriction.filterIDs(ctx, sceneIds)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(sceneIds) < 2 {
+			continue
+		}
+
 		if scenes, err := qb.FindMany(ctx, sceneIds); err == nil {
 			duplicates = append(duplicates, scenes)
 		}
@@ -0,0 +1,644 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	"gopkg.in/guregu/null.v4"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+)
+
+const (
+	sceneActivityTable    = "scenes_activity"
+	scenePlayHistoryTable = "scenes_play_history"
+	userIDColumn          = "user_id"
+)
+
+// sceneActivityColumns are the columns of the scenes table which are stored
+// per user in the scenes_activity table.
+var sceneActivityColumns = []string{
+	"rating",
+	"o_counter",
+	"play_count",
+	"play_duration",
+	"resume_time",
+	"last_played_at",
+}
+
+// activityUserID returns the ID of the user whose activity is read and
+// written. If there is no current user, the activity columns of the scenes
+// table are used instead.
+//
+// Since the activity of users is stored in the scenes_activity table, the
+// activity columns of the scenes table only hold the activity recorded
+// without a logged in user: while authentication is not required, by DLNA
+// renderers when no DLNA activity user is configured, and by imports of
+// the top level activity fields. They are copied to the first user created.
+func activityUserID(ctx context.Context) *int {
+	return session.GetCurrentUserID(ctx)
+}
+
+// sceneActivityColumn returns an SQL expression for the activity column of
+// the scenes table for the current user.
+func sceneActivityColumn(ctx context.Context, column string) string {
+	userID := activityUserID(ctx)
+	if userID == nil {
+		return sceneTable + "." + column
+	}
+
+	expr := fmt.Sprintf("(SELECT %[1]s.%[2]s FROM %[1]s WHERE %[1]s.scene_id = %[3]s.id AND %[1]s.user_id = %[4]d)", sceneActivityTable, column, sceneTable, *userID)
+
+	switch column {
+	case "rating", "last_played_at":
+		return expr
+	default:
+		return "COALESCE(" + expr + ", 0)"
+	}
+}
+
+// splitSceneActivity removes the activity columns from r and returns them.
+func splitSceneActivity(r exp.Record) exp.Record {
+	ret := exp.Record{}
+	for _, c := range sceneActivityColumns {
+		if v, ok := r[c]; ok {
+			ret[c] = v
+			delete(r, c)
+		}
+	}
+
+	return ret
+}
+
+type sceneActivityRow struct {
+	SceneID      int           `db:"scene_id"`
+	Rating       null.Int      `db:"rating"`
+	OCounter     int           `db:"o_counter"`
+	PlayCount    int           `db:"play_count"`
+	PlayDuration float64       `db:"play_duration"`
+	ResumeTime   float64       `db:"resume_time"`
+	LastPlayedAt NullTimestamp `db:"last_played_at"`
+}
+
+func (r *sceneActivityRow) fromScene(o models.Scene) {
+	r.SceneID = o.ID
+	r.Rating = intFromPtr(o.Rating)
+	r.OCounter = o.OCounter
+	r.PlayCount = o.PlayCount
+	r.PlayDuration = o.PlayDuration
+	r.ResumeTime = o.ResumeTime
+	r.LastPlayedAt = NullTimestampFromTimePtr(o.LastPlayedAt)
+}
+
+func (r *sceneActivityRow) isEmpty() bool {
+	return !r.Rating.Valid && r.OCounter == 0 && r.PlayCount == 0 && r.PlayDuration == 0 && r.ResumeTime == 0 && !r.LastPlayedAt.Valid
+}
+
+func (r *sceneActivityRow) apply(s *models.Scene) {
+	s.Rating = nullIntPtr(r.Rating)
+	s.OCounter = r.OCounter
+	s.PlayCount = r.PlayCount
+	s.PlayDuration = r.PlayDuration
+	s.ResumeTime = r.ResumeTime
+	s.LastPlayedAt = r.LastPlayedAt.TimePtr()
+}
+
+func (r *sceneActivityRow) record() exp.Record {
+	return exp.Record{
+		"rating":         r.Rating,
+		"o_counter":      r.OCounter,
+		"play_count":     r.PlayCount,
+		"play_duration":  r.PlayDuration,
+		"resume_time":    r.ResumeTime,
+		"last_played_at": r.LastPlayedAt,
+	}
+}
+
+func (qb *SceneStore) byActivityUser(sceneID int, userID int) exp.Expression {
+	return goqu.And(
+		sceneActivityJoinTable.Col(sceneIDColumn).Eq(sceneID),
+		sceneActivityJoinTable.Col(userIDColumn).Eq(userID),
+	)
+}
+
+// updateActivity sets the activity of the user for the scene, creating it
+// if it does not exist. where adds further conditions to the update.
+func (qb *SceneStore) updateActivity(ctx context.Context, sceneID int, userID int, record exp.Record, where ...exp.Expression) error {
+	table := sceneActivityJoinTable
+
+	q := dialect.Insert(table).Prepared(true).Rows(goqu.Record{
+		sceneIDColumn: sceneID,
+		userIDColumn:  userID,
+	}).OnConflict(goqu.DoNothing())
+
+	if _, err := exec(ctx, q); err != nil {
+		return fmt.Errorf("inserting into %s: %w", table.GetTable(), err)
+	}
+
+	if len(record) == 0 {
+		return nil
+	}
+
+	where = append(where, qb.byActivityUser(sceneID, userID))
+	uq := dialect.Update(table).Prepared(true).Set(record).Where(where...)
+
+	if _, err := exec(ctx, uq); err != nil {
+		return fmt.Errorf("updating %s: %w", table.GetTable(), err)
+	}
+
+	return nil
+}
+
+func (qb *SceneStore) getActivityInt(ctx context.Context, sceneID int, userID int, column string) (int, error) {
+	q := dialect.From(sceneActivityJoinTable).Select(column).Where(qb.byActivityUser(sceneID, userID))
+
+	const single = true
+	var ret int
+	if err := queryFunc(ctx, q, single, func(rows *sqlx.Rows) error {
+		return rows.Scan(&ret)
+	}); err != nil {
+		return 0, err
+	}
+
+	return ret, nil
+}
+
+// loadActivity replaces the activity fields of the scenes with the activity
+// of the user.
+func (qb *SceneStore) loadActivity(ctx context.Context, userID int, scenes []*models.Scene) error {
+	if len(scenes) == 0 {
+		return nil
+	}
+
+	ids := make([]int, len(scenes))
+	for i, s := range scenes {
+		ids[i] = s.ID
+	}
+
+	activity := make(map[int]sceneActivityRow)
+	table := sceneActivityJoinTable
+	if err := batchExec(ids, defaultBatchSize, func(batch []int) error {
+		q := dialect.From(table).Prepared(true).Select(
+			table.Col(sceneIDColumn),
+			table.Col("rating"),
+			table.Col("o_counter"),
+			table.Col("play_count"),
+			table.Col("play_duration"),
+			table.Col("resume_time"),
+			table.Col("last_played_at"),
+		).Where(
+			table.Col(userIDColumn).Eq(userID),
+			table.Col(sceneIDColumn).In(batch),
+		)
+
+		const single = false
+		return queryFunc(ctx, q, single, func(rows *sqlx.Rows) error {
+			var r sceneActivityRow
+			if err := rows.StructScan(&r); err != nil {
+				return err
+			}
+
+			activity[r.SceneID] = r
+			return nil
+		})
+	}); err != nil {
+		return fmt.Errorf("loading scene activity: %w", err)
+	}
+
+	for _, s := range scenes {
+		// scenes without activity have the zero values
+		r := activity[s.ID]
+		r.apply(s)
+	}
+
+	return nil
+}
+
+// createActivity sets the activity of the current user for a new scene.
+func (qb *SceneStore) createActivity(ctx context.Context, userID int, newObject models.Scene) error {
+	var r sceneActivityRow
+	r.fromScene(newObject)
+
+	if r.isEmpty() {
+		return nil
+	}
+
+	return qb.updateActivity(ctx, newObject.ID, userID, r.record())
+}
+
+// updateWithActivity updates the scene, storing the activity fields against
+// the user.
+func (qb *SceneStore) updateWithActivity(ctx context.Context, userID int, r sceneRow) error {
+	record, err := exp.NewRecordFromStruct(r, false, true)
+	if err != nil {
+		return err
+	}
+
+	activity := splitSceneActivity(record)
+
+	if err := qb.tableMgr.updateByID(ctx, r.ID, record); err != nil {
+		return err
+	}
+
+	return qb.updateActivity(ctx, r.ID, userID, activity)
+}
+
+func (qb *SceneStore) IncrementOCounter(ctx context.Context, id int) (int, error) {
+	userID := activityUserID(ctx)
+	if userID == nil {
+		return qb.oCounterManager.IncrementOCounter(ctx, id)
+	}
+
+	if err := qb.tableMgr.checkIDExists(ctx, id); err != nil {
+		return 0, err
+	}
+
+	if err := qb.updateActivity(ctx, id, *userID, goqu.Record{
+		"o_counter": goqu.L("o_counter + 1"),
+	}); err != nil {
+		return 0, err
+	}
+
+	return qb.getActivityInt(ctx, id, *userID, "o_counter")
+}
+
+func (qb *SceneStore) DecrementOCounter(ctx context.Context, id int) (int, error) {
+	userID := activityUserID(ctx)
+	if userID == nil {
+		return qb.oCounterManager.DecrementOCounter(ctx, id)
+	}
+
+	if err := qb.tableMgr.checkIDExists(ctx, id); err != nil {
+		return 0, err
+	}
+
+	if err := qb.updateActivity(ctx, id, *userID, goqu.Record{
+		"o_counter": goqu.L("o_counter - 1"),
+	}, goqu.L("o_counter > 0")); err != nil {
+		return 0, err
+	}
+
+	return qb.getActivityInt(ctx, id, *userID, "o_counter")
+}
+
+func (qb *SceneStore) ResetOCounter(ctx context.Context, id int) (int, error) {
+	userID := activityUserID(ctx)
+	if userID == nil {
+		return qb.oCounterManager.ResetOCounter(ctx, id)
+	}
+
+	if err := qb.tableMgr.checkIDExists(ctx, id); err != nil {
+		return 0, err
+	}
+
+	if err := qb.updateActivity(ctx, id, *userID, goqu.Record{
+		"o_counter": 0,
+	}); err != nil {
+		return 0, err
+	}
+
+	return qb.getActivityInt(ctx, id, *userID, "o_counter")
+}
+
+func (qb *SceneStore) getPlayCount(ctx context.Context, id int) (int, error) {
+	if userID := activityUserID(ctx); userID != nil {
+		return qb.getActivityInt(ctx, id, *userID, "play_count")
+	}
+
+	q := dialect.From(qb.tableMgr.table).Select("play_count").Where(goqu.Ex{"id": id})
+
+	const single = true
+	var ret int
+	if err := queryFunc(ctx, q, single, func(rows *sqlx.Rows) error {
+		if err := rows.Scan(&ret); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+
+	return ret, nil
+}
+
+func (qb *SceneStore) SaveActivity(ctx context.Context, id int, resumeTime *float64, playDuration *float64) (bool, error) {
+	if err := qb.tableMgr.checkIDExists(ctx, id); err != nil {
+		return false, err
+	}
+
+	record := goqu.Record{}
+
+	if resumeTime != nil {
+		record["resume_time"] = resumeTime
+	}
+
+	if playDuration != nil {
+		record["play_duration"] = goqu.L("play_duration + ?", playDuration)
+	}
+
+	if len(record) == 0 {
+		return true, nil
+	}
+
+	if userID := activityUserID(ctx); userID != nil {
+		if err := qb.updateActivity(ctx, id, *userID, record); err != nil {
+			return false, err
+		}
+	} else {
+		if err := qb.tableMgr.updateByID(ctx, id, record); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (qb *SceneStore) IncrementWatchCount(ctx context.Context, id int) (int, error) {
+	if err := qb.tableMgr.checkIDExists(ctx, id); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	record := goqu.Record{
+		"play_count":     goqu.L("play_count + 1"),
+		"last_played_at": NullTimestampFromTimePtr(&now),
+	}
+
+	userID := activityUserID(ctx)
+	if userID != nil {
+		if err := qb.updateActivity(ctx, id, *userID, record); err != nil {
+			return 0, err
+		}
+	} else {
+		if err := qb.tableMgr.updateByID(ctx, id, record); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := qb.addPlayHistory(ctx, id, userID, now); err != nil {
+		return 0, err
+	}
+
+	return qb.getPlayCount(ctx, id)
+}
+
+func (qb *SceneStore) addPlayHistory(ctx context.Context, sceneID int, userID *int, playedAt time.Time) error {
+	table := scenePlayHistoryJoinTable
+	q := dialect.Insert(table).Prepared(true).Rows(goqu.Record{
+		sceneIDColumn: sceneID,
+		userIDColumn:  intFromPtr(userID),
+		"played_at":   Timestamp{Timestamp: playedAt},
+	})
+
+	if _, err := exec(ctx, q); err != nil {
+		return fmt.Errorf("inserting into %s: %w", table.GetTable(), err)
+	}
+
+	return nil
+}
+
+// playHistoryUserWhere returns the condition selecting the play history of
+// the current user.
+func playHistoryUserWhere(ctx context.Context) exp.Expression {
+	col := scenePlayHistoryJoinTable.Col(userIDColumn)
+	if userID := activityUserID(ctx); userID != nil {
+		return col.Eq(*userID)
+	}
+
+	return col.IsNull()
+}
+
+// GetPlayHistory returns the times the scene was played by the current
+// user, most recent first. The history of a restricted scene is empty.
+func (qb *SceneStore) GetPlayHistory(ctx context.Context, sceneID int) ([]time.Time, error) {
+	table := scenePlayHistoryJoinTable
+	q := dialect.From(table).Select(table.Col("played_at")).Where(
+		table.Col(sceneIDColumn).Eq(sceneID),
+		playHistoryUserWhere(ctx),
+	).Order(table.Col("played_at").Desc(), table.Col(idColumn).Desc())
+	q = sceneContentRestriction.applyByID(ctx, q, table.Col(sceneIDColumn))
+
+	const single = false
+	var ret []time.Time
+	if err := queryFunc(ctx, q, single, func(rows *sqlx.Rows) error {
+		var t Timestamp
+		if err := rows.Scan(&t); err != nil {
+			return err
+		}
+
+		ret = append(ret, t.Timestamp)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("getting play history: %w", err)
+	}
+
+	return ret, nil
+}
+
+// FindPlayHistory returns the most recent plays by the current user, most
+// recent first. Plays of restricted scenes are excluded.
+func (qb *SceneStore) FindPlayHistory(ctx context.Context, limit int) ([]*models.ScenePlay, error) {
+	table := scenePlayHistoryJoinTable
+	q := dialect.From(table).Select(
+		table.Col(sceneIDColumn),
+		table.Col("played_at"),
+	).Where(
+		playHistoryUserWhere(ctx),
+	).Order(table.Col("played_at").Desc(), table.Col(idColumn).Desc()).Limit(uint(limit))
+	q = sceneContentRestriction.applyByID(ctx, q, table.Col(sceneIDColumn))
+
+	const single = false
+	var ret []*models.ScenePlay
+	if err := queryFunc(ctx, q, single, func(rows *sqlx.Rows) error {
+		var sceneID int
+		var t Timestamp
+		if err := rows.Scan(&sceneID, &t); err != nil {
+			return err
+		}
+
+		ret = append(ret, &models.ScenePlay{
+			SceneID:  sceneID,
+			PlayedAt: t.Timestamp,
+		})
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("finding play history: %w", err)
+	}
+
+	return ret, nil
+}
+
+// GetActivity returns the activity of each user for the scene, ordered by
+// user ID. The activity recorded without a user is not included, and the
+// activity of a restricted scene is empty.
+func (qb *SceneStore) GetActivity(ctx context.Context, sceneID int) ([]*models.SceneActivity, error) {
+	table := sceneActivityJoinTable
+	q := dialect.From(table).Select(
+		table.Col(userIDColumn),
+		table.Col("rating"),
+		table.Col("o_counter"),
+		table.Col("play_count"),
+		table.Col("play_duration"),
+		table.Col("resume_time"),
+		table.Col("last_played_at"),
+	).Where(table.Col(sceneIDColumn).Eq(sceneID))
+	q = sceneContentRestriction.applyByID(ctx, q, table.Col(sceneIDColumn))
+
+	byUser := make(map[int]*models.SceneActivity)
+	var ret []*models.SceneActivity
+	get := func(userID int) *models.SceneActivity {
+		a := byUser[userID]
+		if a == nil {
+			a = &models.SceneActivity{UserID: userID}
+			byUser[userID] = a
+			ret = append(ret, a)
+		}
+		return a
+	}
+
+	const single = false
+	if err := queryFunc(ctx, q, single, func(rows *sqlx.Rows) error {
+		var r struct {
+			UserID int `db:"user_id"`
+			sceneActivityRow
+		}
+		if err := rows.StructScan(&r); err != nil {
+			return err
+		}
+
+		var s models.Scene
+		r.apply(&s)
+
+		a := get(r.UserID)
+		a.Rating = s.Rating
+		a.OCounter = s.OCounter
+		a.PlayCount = s.PlayCount
+		a.PlayDuration = s.PlayDuration
+		a.ResumeTime = s.ResumeTime
+		a.LastPlayedAt = s.LastPlayedAt
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("getting scene activity: %w", err)
+	}
+
+	historyTable := scenePlayHistoryJoinTable
+	hq := dialect.From(historyTable).Select(
+		historyTable.Col(userIDColumn),
+		historyTable.Col("played_at"),
+	).Where(
+		historyTable.Col(sceneIDColumn).Eq(sceneID),
+		historyTable.Col(userIDColumn).IsNotNull(),
+	).Order(historyTable.Col("played_at").Desc(), historyTable.Col(idColumn).Desc())
+	hq = sceneContentRestriction.applyByID(ctx, hq, historyTable.Col(sceneIDColumn))
+
+	if err := queryFunc(ctx, hq, single, func(rows *sqlx.Rows) error {
+		var userID int
+		var t Timestamp
+		if err := rows.Scan(&userID, &t); err != nil {
+			return err
+		}
+
+		a := get(userID)
+		a.PlayHistory = append(a.PlayHistory, t.Timestamp)
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("getting scene play history: %w", err)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].UserID < ret[j].UserID
+	})
+
+	return ret, nil
+}
+
+// SetActivity replaces the activity and play history of the scene for each
+// of the users in activity. The activity of other users is unchanged.
+func (qb *SceneStore) SetActivity(ctx context.Context, sceneID int, activity []*models.SceneActivity) error {
+	if err := qb.tableMgr.checkIDExists(ctx, sceneID); err != nil {
+		return err
+	}
+
+	historyTable := scenePlayHistoryJoinTable
+	for _, a := range activity {
+		dq := dialect.Delete(historyTable).Where(
+			historyTable.Col(sceneIDColumn).Eq(sceneID),
+			historyTable.Col(userIDColumn).Eq(a.UserID),
+		)
+		if _, err := exec(ctx, dq); err != nil {
+			return fmt.Errorf("deleting from %s: %w", historyTable.GetTable(), err)
+		}
+
+		var r sceneActivityRow
+		r.fromScene(models.Scene{
+			Rating:       a.Rating,
+			OCounter:     a.OCounter,
+			PlayCount:    a.PlayCount,
+			PlayDuration: a.PlayDuration,
+			ResumeTime:   a.ResumeTime,
+			LastPlayedAt: a.LastPlayedAt,
+		})
+
+		if err := qb.updateActivity(ctx, sceneID, a.UserID, r.record()); err != nil {
+			return err
+		}
+
+		// the history is most recent first, so insert it in reverse
+		userID := a.UserID
+		for i := len(a.PlayHistory) - 1; i >= 0; i-- {
+			if err := qb.addPlayHistory(ctx, sceneID, &userID, a.PlayHistory[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// CopyActivityToUser copies the activity recorded without a user to the
+// user with the given ID.
+func (qb *SceneStore) CopyActivityToUser(ctx context.Context, userID int) error {
+	table := qb.table()
+	activityTable := sceneActivityJoinTable
+
+	cols := []interface{}{sceneIDColumn, userIDColumn}
+	selectCols := []interface{}{table.Col(idColumn), goqu.V(userID)}
+	for _, c := range sceneActivityColumns {
+		cols = append(cols, c)
+		selectCols = append(selectCols, table.Col(c))
+	}
+
+	sq := dialect.From(table).Select(selectCols...).Where(goqu.Or(
+		table.Col("rating").IsNotNull(),
+		table.Col("o_counter").Gt(0),
+		table.Col("play_count").Gt(0),
+		table.Col("play_duration").Gt(0),
+		table.Col("resume_time").Gt(0),
+		table.Col("last_played_at").IsNotNull(),
+	))
+
+	q := dialect.Insert(activityTable).Cols(cols...).FromQuery(sq)
+	if _, err := exec(ctx, q); err != nil {
+		return fmt.Errorf("copying scene activity: %w", err)
+	}
+
+	historyTable := scenePlayHistoryJoinTable
+	hq := dialect.Insert(historyTable).Cols(sceneIDColumn, userIDColumn, "played_at").FromQuery(
+		dialect.From(historyTable).Select(
+			historyTable.Col(sceneIDColumn),
+			goqu.V(userID),
+			historyTable.Col("played_at"),
+		).Where(historyTable.Col(userIDColumn).IsNull()),
+	)
+	if _, err := exec(ctx, hq); err != nil {
+		return fmt.Errorf("copying scene play history: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,321 @@
+//go:build integration
+// +build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/session"
+	"github.com/stretchr/testify/assert"
+)
+
+func createActivityUser(ctx context.Context, t *testing.T, username string) context.Context {
+	now := time.Now()
+	u := &models.User{
+		Username:     username,
+		PasswordHash: "hash",
+		Role:         models.UserRoleReadOnly,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if err := db.User.Create(ctx, u); err != nil {
+		t.Fatalf("Error creating user: %s", err.Error())
+	}
+
+	return session.SetCurrentUser(ctx, u)
+}
+
+func TestSceneActivityPerUser(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		qb := db.Scene
+		assert := assert.New(t)
+
+		sceneIdx := sceneIdx1WithPerformer
+		sceneID := sceneIDs[sceneIdx]
+
+		aliceCtx := createActivityUser(ctx, t, "alice")
+		bobCtx := createActivityUser(ctx, t, "bob")
+
+		count, err := qb.IncrementWatchCount(aliceCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.IncrementWatchCount() error = %v", err)
+			return nil
+		}
+		assert.Equal(1, count)
+
+		oCount, err := qb.IncrementOCounter(aliceCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.IncrementOCounter() error = %v", err)
+			return nil
+		}
+		assert.Equal(1, oCount)
+
+		resumeTime := 12.5
+		if _, err := qb.SaveActivity(aliceCtx, sceneID, &resumeTime, nil); err != nil {
+			t.Errorf("SceneStore.SaveActivity() error = %v", err)
+			return nil
+		}
+
+		rating := 80
+		if _, err := qb.UpdatePartial(aliceCtx, sceneID, models.ScenePartial{
+			Rating: models.NewOptionalInt(rating),
+		}); err != nil {
+			t.Errorf("SceneStore.UpdatePartial() error = %v", err)
+			return nil
+		}
+
+		// alice sees her own activity
+		s, err := qb.Find(aliceCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+		assert.Equal(1, s.PlayCount)
+		assert.Equal(1, s.OCounter)
+		assert.Equal(resumeTime, s.ResumeTime)
+		assert.Equal(&rating, s.Rating)
+		assert.NotNil(s.LastPlayedAt)
+
+		// bob has no activity
+		s, err = qb.Find(bobCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+		assert.Equal(0, s.PlayCount)
+		assert.Equal(0, s.OCounter)
+		assert.Nil(s.Rating)
+		assert.Nil(s.LastPlayedAt)
+
+		// activity without a user is unchanged
+		s, err = qb.Find(ctx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+		assert.Equal(getScenePlayCount(sceneIdx), s.PlayCount)
+
+		// filters use the activity of the user
+		playCount := &models.IntCriterionInput{
+			Value:    0,
+			Modifier: models.CriterionModifierGreaterThan,
+		}
+		scenes := queryScene(ctx, t, qb, &models.SceneFilterType{PlayCount: playCount}, nil)
+		assert.Greater(len(scenes), 1)
+
+		scenes = queryScene(aliceCtx, t, qb, &models.SceneFilterType{PlayCount: playCount}, nil)
+		if assert.Len(scenes, 1) {
+			assert.Equal(sceneID, scenes[0].ID)
+		}
+
+		scenes = queryScene(bobCtx, t, qb, &models.SceneFilterType{PlayCount: playCount}, nil)
+		assert.Len(scenes, 0)
+
+		// sorting uses the activity of the user
+		sort := "play_count"
+		direction := models.SortDirectionEnumDesc
+		perPage := 1
+		scenes = queryScene(aliceCtx, t, qb, nil, &models.FindFilterType{
+			Sort:      &sort,
+			Direction: &direction,
+			PerPage:   &perPage,
+		})
+		if assert.Len(scenes, 1) {
+			assert.Equal(sceneID, scenes[0].ID)
+		}
+
+		// play history
+		history, err := qb.GetPlayHistory(aliceCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.GetPlayHistory() error = %v", err)
+			return nil
+		}
+		assert.Len(history, 1)
+
+		plays, err := qb.FindPlayHistory(aliceCtx, 10)
+		if err != nil {
+			t.Errorf("SceneStore.FindPlayHistory() error = %v", err)
+			return nil
+		}
+		if assert.Len(plays, 1) {
+			assert.Equal(sceneID, plays[0].SceneID)
+		}
+
+		plays, err = qb.FindPlayHistory(bobCtx, 10)
+		if err != nil {
+			t.Errorf("SceneStore.FindPlayHistory() error = %v", err)
+			return nil
+		}
+		assert.Len(plays, 0)
+
+		return nil
+	})
+}
+
+func TestSceneFindPlayHistoryRestricted(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		qb := db.Scene
+		assert := assert.New(t)
+
+		restrictedSceneID := sceneIDs[sceneIdxWithTag]
+		sceneID := sceneIDs[sceneIdx1WithPerformer]
+
+		userCtx := createActivityUser(ctx, t, "restricted")
+		for _, id := range []int{restrictedSceneID, sceneID} {
+			if _, err := qb.IncrementWatchCount(userCtx, id); err != nil {
+				t.Errorf("SceneStore.IncrementWatchCount() error = %v", err)
+				return nil
+			}
+		}
+
+		// the scene is excluded after it was played
+		restrictedCtx := session.SetContentRestrictions(userCtx, &models.ContentRestrictions{
+			ExcludedTagIDs: []int{tagIDs[tagIdxWithScene]},
+		})
+
+		plays, err := qb.FindPlayHistory(restrictedCtx, 10)
+		if err != nil {
+			t.Errorf("SceneStore.FindPlayHistory() error = %v", err)
+			return nil
+		}
+		if !assert.Len(plays, 1) {
+			return nil
+		}
+		assert.Equal(sceneID, plays[0].SceneID)
+
+		scenes, err := qb.FindMany(restrictedCtx, []int{plays[0].SceneID})
+		if err != nil {
+			t.Errorf("SceneStore.FindMany() error = %v", err)
+			return nil
+		}
+		assert.Len(scenes, 1)
+
+		history, err := qb.GetPlayHistory(restrictedCtx, restrictedSceneID)
+		if err != nil {
+			t.Errorf("SceneStore.GetPlayHistory() error = %v", err)
+			return nil
+		}
+		assert.Len(history, 0)
+
+		activity, err := qb.GetActivity(restrictedCtx, restrictedSceneID)
+		if err != nil {
+			t.Errorf("SceneStore.GetActivity() error = %v", err)
+			return nil
+		}
+		assert.Len(activity, 0)
+
+		activity, err = qb.GetActivity(restrictedCtx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.GetActivity() error = %v", err)
+			return nil
+		}
+		assert.Len(activity, 1)
+
+		return nil
+	})
+}
+
+func TestSceneCopyActivityToUser(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		qb := db.Scene
+		assert := assert.New(t)
+
+		userCtx := createActivityUser(ctx, t, "carol")
+		userID := session.GetCurrentUserID(userCtx)
+
+		if err := qb.CopyActivityToUser(ctx, *userID); err != nil {
+			t.Errorf("SceneStore.CopyActivityToUser() error = %v", err)
+			return nil
+		}
+
+		sceneIdx := sceneIdx1WithPerformer
+		s, err := qb.Find(userCtx, sceneIDs[sceneIdx])
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+
+		assert.Equal(getScenePlayCount(sceneIdx), s.PlayCount)
+		assert.Equal(getScenePlayDuration(sceneIdx), s.PlayDuration)
+		assert.Equal(getSceneResumeTime(sceneIdx), s.ResumeTime)
+
+		return nil
+	})
+}
+
+func TestSceneSetActivity(t *testing.T) {
+	withRollbackTxn(func(ctx context.Context) error {
+		qb := db.Scene
+		assert := assert.New(t)
+
+		sceneIdx := sceneIdxWithGallery
+		sceneID := sceneIDs[sceneIdx]
+
+		userCtx := createActivityUser(ctx, t, "dave")
+		userID := session.GetCurrentUserID(userCtx)
+
+		rating := 60
+		lastPlayed := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+		earlier := lastPlayed.Add(-time.Hour)
+		activity := &models.SceneActivity{
+			UserID:       *userID,
+			Rating:       &rating,
+			OCounter:     2,
+			PlayCount:    2,
+			PlayDuration: 100,
+			ResumeTime:   50,
+			LastPlayedAt: &lastPlayed,
+			PlayHistory:  []time.Time{lastPlayed, earlier},
+		}
+
+		if err := qb.SetActivity(ctx, sceneID, []*models.SceneActivity{activity}); err != nil {
+			t.Errorf("SceneStore.SetActivity() error = %v", err)
+			return nil
+		}
+
+		// setting the activity again replaces it
+		if err := qb.SetActivity(ctx, sceneID, []*models.SceneActivity{activity}); err != nil {
+			t.Errorf("SceneStore.SetActivity() error = %v", err)
+			return nil
+		}
+
+		got, err := qb.GetActivity(ctx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.GetActivity() error = %v", err)
+			return nil
+		}
+
+		if assert.Len(got, 1) {
+			a := got[0]
+			assert.Equal(*userID, a.UserID)
+			assert.Equal(&rating, a.Rating)
+			assert.Equal(2, a.OCounter)
+			assert.Equal(2, a.PlayCount)
+			assert.Equal(100.0, a.PlayDuration)
+			assert.Equal(50.0, a.ResumeTime)
+			if assert.NotNil(a.LastPlayedAt) {
+				assert.True(lastPlayed.Equal(*a.LastPlayedAt))
+			}
+			if assert.Len(a.PlayHistory, 2) {
+				assert.True(lastPlayed.Equal(a.PlayHistory[0]))
+				assert.True(earlier.Equal(a.PlayHistory[1]))
+			}
+		}
+
+		// the activity recorded without a user is unchanged
+		s, err := qb.Find(ctx, sceneID)
+		if err != nil {
+			t.Errorf("SceneStore.Find() error = %v", err)
+			return nil
+		}
+
+		assert.Equal(getScenePlayCount(sceneIdx), s.PlayCount)
+
+		return nil
+	})
+}
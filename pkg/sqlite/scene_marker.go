@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/doug-martin/goqu/v9"
 	"github.com/doug-martin/goqu/v9/exp"
@@ -19,8 +20,7 @@ const sceneMarkerTable = "scene_markers"
 const countSceneMarkersForTagQuery = `
 SELECT scene_markers.id FROM scene_markers
 LEFT JOIN scene_markers_tags as tags_join on tags_join.scene_marker_id = scene_markers.id
-WHERE tags_join.tag_id = ? OR scene_markers.primary_tag_id = ?
-GROUP BY scene_markers.id
+WHERE (tags_join.tag_id = ? OR scene_markers.primary_tag_id = ?)
 `
 
 type sceneMarkerRow struct {
@@ -211,6 +211,8 @@ func (qb *SceneMarkerStore) get(ctx context.Context, q *goqu.SelectDataset) (*mo
 }
 
 func (qb *SceneMarkerStore) getMany(ctx context.Context, q *goqu.SelectDataset) ([]*models.SceneMarker, error) {
+	q = sceneMarkerContentRestriction.apply(ctx, q)
+
 	const single = false
 	var ret []*models.SceneMarker
 	if err := queryFunc(ctx, q, single, func(r *sqlx.Rows) error {
@@ -231,25 +233,36 @@ func (qb *SceneMarkerStore) getMany(ctx context.Context, q *goqu.SelectDataset)
 }
 
 func (qb *SceneMarkerStore) FindBySceneID(ctx context.Context, sceneID int) ([]*models.SceneMarker, error) {
-	query := `
-		SELECT scene_markers.* FROM scene_markers
-		WHERE scene_markers.scene_id = ?
-		GROUP BY scene_markers.id
-		ORDER BY scene_markers.seconds ASC
-	`
-	args := []interface{}{sceneID}
-	return qb.querySceneMarkers(ctx, query, args)
+	table := qb.table()
+	q := qb.selectDataset().Where(table.Col(sceneIDColumn).Eq(sceneID)).Order(table.Col("seconds").Asc())
+	return qb.getMany(ctx, q)
 }
 
 func (qb *SceneMarkerStore) CountByTagID(ctx context.Context, tagID int) (int, error) {
+	query := countSceneMarkersForTagQuery
 	args := []interface{}{tagID, tagID}
-	return qb.runCountQuery(ctx, qb.buildCountQuery(countSceneMarkersForTagQuery), args)
+	if clause, restrictionArgs := sceneMarkerContentRestriction.clause(ctx); clause != "" {
+		query += " AND " + clause
+		args = append(args, restrictionArgs...)
+	}
+	query += " GROUP BY scene_markers.id"
+
+	return qb.runCountQuery(ctx, qb.buildCountQuery(query), args)
 }
 
 func (qb *SceneMarkerStore) GetMarkerStrings(ctx context.Context, q *string, sort *string) ([]*models.MarkerStringsResultType, error) {
 	query := "SELECT count(*) as `count`, scene_markers.id as id, scene_markers.title as title FROM scene_markers"
+	var where []string
+	var args []interface{}
 	if q != nil {
-		query += " WHERE title LIKE '%" + *q + "%'"
+		where = append(where, "title LIKE '%"+*q+"%'")
+	}
+	if clause, restrictionArgs := sceneMarkerContentRestriction.clause(ctx); clause != "" {
+		where = append(where, clause)
+		args = append(args, restrictionArgs...)
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
 	}
 	query += " GROUP BY title"
 	if sort != nil && *sort == "count" {
@@ -257,7 +270,6 @@ func (qb *SceneMarkerStore) GetMarkerStrings(ctx context.Context, q *string, sor
 	} else {
 		query += " ORDER BY title ASC"
 	}
-	var args []interface{}
 	return qb.queryMarkerStringsResultType(ctx, query, args)
 }
 
@@ -309,6 +321,10 @@ func (qb *SceneMarkerStore) makeQuery(ctx context.Context, sceneMarkerFilter *mo
 		return nil, err
 	}
 
+	if err := query.addFilter(sceneMarkerContentRestriction.filter(ctx)); err != nil {
+		return nil, err
+	}
+
 	query.sortAndPagination = qb.getSceneMarkerSort(&query, findFilter) + getPagination(findFilter)
 
 	return &query, nil
@@ -486,26 +502,6 @@ func (qb *SceneMarkerStore) getSceneMarkerSort(query *queryBuilder, findFilter *
 	return getSort(sort, direction, tableName) + additional
 }
 
-func (qb *SceneMarkerStore) querySceneMarkers(ctx context.Context, query string, args []interface{}) ([]*models.SceneMarker, error) {
-	const single = false
-	var ret []*models.SceneMarker
-	if err := qb.queryFunc(ctx, query, args, single, func(r *sqlx.Rows) error {
-		var f sceneMarkerRow
-		if err := r.StructScan(&f); err != nil {
-			return err
-		}
-
-		s := f.resolve()
-
-		ret = append(ret, s)
-		return nil
-	}); err != nil {
-		return nil, err
-	}
-
-	return ret, nil
-}
-
 func (qb *SceneMarkerStore) queryMarkerStringsResultType(ctx context.Context, query string, args []interface{}) ([]*models.MarkerStringsResultType, error) {
 	rows, err := qb.tx.Queryx(ctx, query, args...)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
@@ -551,6 +547,7 @@ func (qb *SceneMarkerStore) UpdateTags(ctx context.Context, id int, tagIDs []int
 
 func (qb *SceneMarkerStore) Count(ctx context.Context) (int, error) {
 	q := dialect.Select(goqu.COUNT("*")).From(qb.table())
+	q = sceneMarkerContentRestriction.apply(ctx, q)
 	return count(ctx, q)
 }
 
@@ -103,7 +103,9 @@ func getCountSort(primaryTable, joinTable, primaryFK, direction string) string {
 	return fmt.Sprintf(" ORDER BY (SELECT COUNT(*) FROM %s WHERE %s = %s.id) %s", joinTable, primaryFK, primaryTable, getSortDirection(direction))
 }
 
-func getMultiSumSort(sum string, primaryTable, foreignTable1, joinTable1, foreignTable2, joinTable2, primaryFK, foreignFK1, foreignFK2, direction string) string {
+// getMultiSumSort sorts by the sum of sum1 over foreignTable1 and sum2 over
+// foreignTable2. sum is used as the alias of the sums.
+func getMultiSumSort(sum, sum1, sum2 string, primaryTable, foreignTable1, joinTable1, foreignTable2, joinTable2, primaryFK, foreignFK1, foreignFK2, direction string) string {
 	return fmt.Sprintf(" ORDER BY (SELECT SUM(%s) "+
 		"FROM ("+
 		"SELECT SUM(%s) as %s from %s s "+
@@ -115,10 +117,10 @@ func getMultiSumSort(sum string, primaryTable, foreignTable1, joinTable1, foreig
 		"WHERE s.%s = %s.id "+
 		")) %s",
 		sum,
-		sum, sum, joinTable1,
+		sum1, sum, joinTable1,
 		foreignTable1, foreignTable1, foreignFK1,
 		primaryFK, primaryTable,
-		sum, sum, joinTable2,
+		sum2, sum, joinTable2,
 		foreignTable2, foreignTable2, foreignFK2,
 		primaryFK, primaryTable,
 		getSortDirection(direction))
@@ -342,7 +344,7 @@ func getCountCriterionClause(primaryTable, joinTable, primaryFK string, criterio
 	return getIntCriterionWhereClause(lhs, criterion)
 }
 
-func getJoinedMultiSumCriterionClause(primaryTable, foreignTable1, joinTable1, foreignTable2, joinTable2, primaryFK string, foreignFK1 string, foreignFK2 string, sum string, criterion models.IntCriterionInput) (string, []interface{}) {
+func getJoinedMultiSumCriterionClause(primaryTable, foreignTable1, joinTable1, foreignTable2, joinTable2, primaryFK string, foreignFK1 string, foreignFK2 string, sum, sum1, sum2 string, criterion models.IntCriterionInput) (string, []interface{}) {
 	lhs := fmt.Sprintf("(SELECT SUM(%s) "+
 		"FROM ("+
 		"SELECT SUM(%s) as %s from %s s "+
@@ -354,10 +356,10 @@ func getJoinedMultiSumCriterionClause(primaryTable, foreignTable1, joinTable1, f
 		"WHERE s.%s = %s.id "+
 		"))",
 		sum,
-		sum, sum, joinTable1,
+		sum1, sum, joinTable1,
 		foreignTable1, foreignTable1, foreignFK1,
 		primaryFK, primaryTable,
-		sum, sum, joinTable2,
+		sum2, sum, joinTable2,
 		foreignTable2, foreignTable2, foreignFK2,
 		primaryFK, primaryTable,
 	)
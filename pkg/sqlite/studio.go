@@ -161,6 +161,12 @@ func (qb *StudioStore) UpdatePartial(ctx context.Context, input models.StudioPar
 
 	r.fromPartial(input)
 
+	if input.ParentID.Set {
+		if err := qb.checkParentNotRestricted(ctx, input.ID, input.ParentID.Ptr()); err != nil {
+			return nil, err
+		}
+	}
+
 	if len(r.Record) > 0 {
 		if err := qb.tableMgr.updateByID(ctx, input.ID, r.Record); err != nil {
 			return nil, err
@@ -210,7 +216,37 @@ func (qb *StudioStore) Update(ctx context.Context, updatedObject *models.Studio)
 	return nil
 }
 
+// checkParentNotRestricted returns models.ErrRestricted if the studio is
+// restricted and its parent is changed. Changing the parent of a restricted
+// studio could otherwise make restricted content visible.
+func (qb *StudioStore) checkParentNotRestricted(ctx context.Context, id int, parentID *int) error {
+	if err := checkStudiosNotRestricted(ctx, []int{id}); !errors.Is(err, models.ErrRestricted) {
+		return err
+	}
+
+	existing, err := qb.find(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	changed := (existing.ParentID == nil) != (parentID == nil) ||
+		(parentID != nil && *existing.ParentID != *parentID)
+	if changed {
+		return models.ErrRestricted
+	}
+
+	return nil
+}
+
 func (qb *StudioStore) Destroy(ctx context.Context, id int) error {
+	if err := checkStudiosNotRestricted(ctx, []int{id}); err != nil {
+		return err
+	}
+
+	if err := checkNotExcludedByUsers(ctx, usersExcludedStudiosTableMgr, id, "studio"); err != nil {
+		return err
+	}
+
 	// must handle image checksums manually
 	if err := qb.destroyImage(ctx, id); err != nil {
 		return err
@@ -551,7 +587,7 @@ func (qb *StudioStore) makeQuery(ctx context.Context, studioFilter *models.Studi
 		return nil, err
 	}
 
-	query.sortAndPagination = qb.getStudioSort(findFilter) + getPagination(findFilter)
+	query.sortAndPagination = qb.getStudioSort(ctx, findFilter) + getPagination(findFilter)
 
 	return &query, nil
 }
@@ -594,7 +630,7 @@ func studioIsMissingCriterionHandler(qb *StudioStore, isMissing *string) criteri
 func studioSceneCountCriterionHandler(qb *StudioStore, sceneCount *models.IntCriterionInput) criterionHandlerFunc {
 	return func(ctx context.Context, f *filterBuilder) {
 		if sceneCount != nil {
-			f.addLeftJoin("scenes", "", "scenes.studio_id = studios.id")
+			f.addLeftJoin(sceneContentRestriction.visibleJoinTable(ctx, sceneTable, idColumn), "scenes", "scenes.studio_id = studios.id")
 			clause, args := getIntCriterionWhereClause("count(distinct scenes.id)", *sceneCount)
 
 			f.addHaving(clause, args...)
@@ -605,7 +641,7 @@ func studioSceneCountCriterionHandler(qb *StudioStore, sceneCount *models.IntCri
 func studioImageCountCriterionHandler(qb *StudioStore, imageCount *models.IntCriterionInput) criterionHandlerFunc {
 	return func(ctx context.Context, f *filterBuilder) {
 		if imageCount != nil {
-			f.addLeftJoin("images", "", "images.studio_id = studios.id")
+			f.addLeftJoin(imageContentRestriction.visibleJoinTable(ctx, imageTable, idColumn), "images", "images.studio_id = studios.id")
 			clause, args := getIntCriterionWhereClause("count(distinct images.id)", *imageCount)
 
 			f.addHaving(clause, args...)
@@ -616,7 +652,7 @@ func studioImageCountCriterionHandler(qb *StudioStore, imageCount *models.IntCri
 func studioGalleryCountCriterionHandler(qb *StudioStore, galleryCount *models.IntCriterionInput) criterionHandlerFunc {
 	return func(ctx context.Context, f *filterBuilder) {
 		if galleryCount != nil {
-			f.addLeftJoin("galleries", "", "galleries.studio_id = studios.id")
+			f.addLeftJoin(galleryContentRestriction.visibleJoinTable(ctx, galleryTable, idColumn), "galleries", "galleries.studio_id = studios.id")
 			clause, args := getIntCriterionWhereClause("count(distinct galleries.id)", *galleryCount)
 
 			f.addHaving(clause, args...)
@@ -651,7 +687,7 @@ func studioAliasCriterionHandler(qb *StudioStore, alias *models.StringCriterionI
 	return h.handler(alias)
 }
 
-func (qb *StudioStore) getStudioSort(findFilter *models.FindFilterType) string {
+func (qb *StudioStore) getStudioSort(ctx context.Context, findFilter *models.FindFilterType) string {
 	var sort string
 	var direction string
 	if findFilter == nil {
@@ -665,11 +701,11 @@ func (qb *StudioStore) getStudioSort(findFilter *models.FindFilterType) string {
 	sortQuery := ""
 	switch sort {
 	case "scenes_count":
-		sortQuery += getCountSort(studioTable, sceneTable, studioIDColumn, direction)
+		sortQuery += getCountSort(studioTable, sceneContentRestriction.visibleJoinTable(ctx, sceneTable, idColumn), studioIDColumn, direction)
 	case "images_count":
-		sortQuery += getCountSort(studioTable, imageTable, studioIDColumn, direction)
+		sortQuery += getCountSort(studioTable, imageContentRestriction.visibleJoinTable(ctx, imageTable, idColumn), studioIDColumn, direction)
 	case "galleries_count":
-		sortQuery += getCountSort(studioTable, galleryTable, studioIDColumn, direction)
+		sortQuery += getCountSort(studioTable, galleryContentRestriction.visibleJoinTable(ctx, galleryTable, idColumn), studioIDColumn, direction)
 	default:
 		sortQuery += getSort(sort, direction, "studios")
 	}
@@ -28,12 +28,20 @@ var (
 	scenesMoviesJoinTable     = goqu.T(moviesScenesTable)
 	scenesURLsJoinTable       = goqu.T(scenesURLsTable)
 
+	sceneActivityJoinTable    = goqu.T(sceneActivityTable)
+	scenePlayHistoryJoinTable = goqu.T(scenePlayHistoryTable)
+
 	performersAliasesJoinTable  = goqu.T(performersAliasesTable)
 	performersTagsJoinTable     = goqu.T(performersTagsTable)
 	performersStashIDsJoinTable = goqu.T("performer_stash_ids")
 
 	studiosAliasesJoinTable  = goqu.T(studioAliasesTable)
 	studiosStashIDsJoinTable = goqu.T("studio_stash_ids")
+
+	usersExcludedTagsJoinTable       = goqu.T(usersExcludedTagsTable)
+	usersExcludedStudiosJoinTable    = goqu.T(usersExcludedStudiosTable)
+	usersExcludedPerformersJoinTable = goqu.T(usersExcludedPerformersTable)
+	usersExcludedPathsJoinTable      = goqu.T(usersExcludedPathsTable)
 )
 
 var (
@@ -298,3 +306,47 @@ var (
 		idColumn: goqu.T(savedFilterTable).Col(idColumn),
 	}
 )
+
+var (
+	userTableMgr = &table{
+		table:    goqu.T(userTable),
+		idColumn: goqu.T(userTable).Col(idColumn),
+	}
+
+	usersExcludedTagsTableMgr = &joinTable{
+		table: table{
+			table:    usersExcludedTagsJoinTable,
+			idColumn: usersExcludedTagsJoinTable.Col(userIDColumn),
+		},
+		fkColumn: usersExcludedTagsJoinTable.Col(tagIDColumn),
+	}
+
+	usersExcludedStudiosTableMgr = &joinTable{
+		table: table{
+			table:    usersExcludedStudiosJoinTable,
+			idColumn: usersExcludedStudiosJoinTable.Col(userIDColumn),
+		},
+		fkColumn: usersExcludedStudiosJoinTable.Col(studioIDColumn),
+	}
+
+	usersExcludedPerformersTableMgr = &joinTable{
+		table: table{
+			table:    usersExcludedPerformersJoinTable,
+			idColumn: usersExcludedPerformersJoinTable.Col(userIDColumn),
+		},
+		fkColumn: usersExcludedPerformersJoinTable.Col(performerIDColumn),
+	}
+
+	usersExcludedPathsTableMgr = &stringTable{
+		table: table{
+			table:    usersExcludedPathsJoinTable,
+			idColumn: usersExcludedPathsJoinTable.Col(userIDColumn),
+		},
+		stringColumn: usersExcludedPathsJoinTable.Col("path"),
+	}
+
+	apiKeyTableMgr = &table{
+		table:    goqu.T(apiKeyTable),
+		idColumn: goqu.T(apiKeyTable).Col(idColumn),
+	}
+)
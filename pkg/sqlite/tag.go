@@ -164,6 +164,14 @@ func (qb *TagStore) Update(ctx context.Context, updatedObject *models.Tag) error
 }
 
 func (qb *TagStore) Destroy(ctx context.Context, id int) error {
+	if err := checkTagsNotRestricted(ctx, []int{id}); err != nil {
+		return err
+	}
+
+	if err := checkNotExcludedByUsers(ctx, usersExcludedTagsTableMgr, id, "tag"); err != nil {
+		return err
+	}
+
 	// must handle image checksums manually
 	if err := qb.destroyImage(ctx, id); err != nil {
 		return err
@@ -543,7 +551,7 @@ func (qb *TagStore) Query(ctx context.Context, tagFilter *models.TagFilterType,
 		return nil, 0, err
 	}
 
-	query.sortAndPagination = qb.getTagSort(&query, findFilter) + getPagination(findFilter)
+	query.sortAndPagination = qb.getTagSort(ctx, &query, findFilter) + getPagination(findFilter)
 	idsResult, countResult, err := query.executeFind(ctx)
 	if err != nil {
 		return nil, 0, err
@@ -585,7 +593,7 @@ func tagIsMissingCriterionHandler(qb *TagStore, isMissing *string) criterionHand
 func tagSceneCountCriterionHandler(qb *TagStore, sceneCount *models.IntCriterionInput) criterionHandlerFunc {
 	return func(ctx context.Context, f *filterBuilder) {
 		if sceneCount != nil {
-			f.addLeftJoin("scenes_tags", "", "scenes_tags.tag_id = tags.id")
+			f.addLeftJoin(sceneContentRestriction.visibleJoinTable(ctx, scenesTagsTable, sceneIDColumn), "scenes_tags", "scenes_tags.tag_id = tags.id")
 			clause, args := getIntCriterionWhereClause("count(distinct scenes_tags.scene_id)", *sceneCount)
 
 			f.addHaving(clause, args...)
@@ -596,7 +604,7 @@ func tagSceneCountCriterionHandler(qb *TagStore, sceneCount *models.IntCriterion
 func tagImageCountCriterionHandler(qb *TagStore, imageCount *models.IntCriterionInput) criterionHandlerFunc {
 	return func(ctx context.Context, f *filterBuilder) {
 		if imageCount != nil {
-			f.addLeftJoin("images_tags", "", "images_tags.tag_id = tags.id")
+			f.addLeftJoin(imageContentRestriction.visibleJoinTable(ctx, imagesTagsTable, imageIDColumn), "images_tags", "images_tags.tag_id = tags.id")
 			clause, args := getIntCriterionWhereClause("count(distinct images_tags.image_id)", *imageCount)
 
 			f.addHaving(clause, args...)
@@ -607,7 +615,7 @@ func tagImageCountCriterionHandler(qb *TagStore, imageCount *models.IntCriterion
 func tagGalleryCountCriterionHandler(qb *TagStore, galleryCount *models.IntCriterionInput) criterionHandlerFunc {
 	return func(ctx context.Context, f *filterBuilder) {
 		if galleryCount != nil {
-			f.addLeftJoin("galleries_tags", "", "galleries_tags.tag_id = tags.id")
+			f.addLeftJoin(galleryContentRestriction.visibleJoinTable(ctx, galleriesTagsTable, galleryIDColumn), "galleries_tags", "galleries_tags.tag_id = tags.id")
 			clause, args := getIntCriterionWhereClause("count(distinct galleries_tags.gallery_id)", *galleryCount)
 
 			f.addHaving(clause, args...)
@@ -630,7 +638,7 @@ func tagMarkerCountCriterionHandler(qb *TagStore, markerCount *models.IntCriteri
 	return func(ctx context.Context, f *filterBuilder) {
 		if markerCount != nil {
 			f.addLeftJoin("scene_markers_tags", "", "scene_markers_tags.tag_id = tags.id")
-			f.addLeftJoin("scene_markers", "", "scene_markers_tags.scene_marker_id = scene_markers.id OR scene_markers.primary_tag_id = tags.id")
+			f.addLeftJoin(sceneMarkerContentRestriction.visibleJoinTable(ctx, sceneMarkerTable, idColumn), "scene_markers", "scene_markers_tags.scene_marker_id = scene_markers.id OR scene_markers.primary_tag_id = tags.id")
 			clause, args := getIntCriterionWhereClause("count(distinct scene_markers.id)", *markerCount)
 
 			f.addHaving(clause, args...)
@@ -852,7 +860,7 @@ func (qb *TagStore) getDefaultTagSort() string {
 	return getSort("name", "ASC", "tags")
 }
 
-func (qb *TagStore) getTagSort(query *queryBuilder, findFilter *models.FindFilterType) string {
+func (qb *TagStore) getTagSort(ctx context.Context, query *queryBuilder, findFilter *models.FindFilterType) string {
 	var sort string
 	var direction string
 	if findFilter == nil {
@@ -866,13 +874,15 @@ func (qb *TagStore) getTagSort(query *queryBuilder, findFilter *models.FindFilte
 	sortQuery := ""
 	switch sort {
 	case "scenes_count":
-		sortQuery += getCountSort(tagTable, scenesTagsTable, tagIDColumn, direction)
+		sortQuery += getCountSort(tagTable, sceneContentRestriction.visibleJoinTable(ctx, scenesTagsTable, sceneIDColumn), tagIDColumn, direction)
 	case "scene_markers_count":
-		sortQuery += fmt.Sprintf(" ORDER BY (SELECT COUNT(*) FROM scene_markers_tags WHERE tags.id = scene_markers_tags.tag_id)+(SELECT COUNT(*) FROM scene_markers WHERE tags.id = scene_markers.primary_tag_id) %s", getSortDirection(direction))
+		markersTagsTable := sceneMarkerContentRestriction.visibleJoinTable(ctx, "scene_markers_tags", "scene_marker_id")
+		markersTable := sceneMarkerContentRestriction.visibleJoinTable(ctx, sceneMarkerTable, idColumn)
+		sortQuery += fmt.Sprintf(" ORDER BY (SELECT COUNT(*) FROM %s AS scene_markers_tags WHERE tags.id = scene_markers_tags.tag_id)+(SELECT COUNT(*) FROM %s AS scene_markers WHERE tags.id = scene_markers.primary_tag_id) %s", markersTagsTable, markersTable, getSortDirection(direction))
 	case "images_count":
-		sortQuery += getCountSort(tagTable, imagesTagsTable, tagIDColumn, direction)
+		sortQuery += getCountSort(tagTable, imageContentRestriction.visibleJoinTable(ctx, imagesTagsTable, imageIDColumn), tagIDColumn, direction)
 	case "galleries_count":
-		sortQuery += getCountSort(tagTable, galleriesTagsTable, tagIDColumn, direction)
+		sortQuery += getCountSort(tagTable, galleryContentRestriction.visibleJoinTable(ctx, galleriesTagsTable, galleryIDColumn), tagIDColumn, direction)
 	case "performers_count":
 		sortQuery += getCountSort(tagTable, performersTagsTable, tagIDColumn, direction)
 	default:
@@ -977,12 +987,18 @@ func (qb *TagStore) Merge(ctx context.Context, source []int, destination int) er
 
 	args = append(args, srcArgs...)
 
+	if err := checkTagsNotRestricted(ctx, append([]int{destination}, source...)); err != nil {
+		return err
+	}
+
+	// content restrictions of the source tags are moved to the destination
 	tagTables := map[string]string{
-		scenesTagsTable:      sceneIDColumn,
-		"scene_markers_tags": "scene_marker_id",
-		galleriesTagsTable:   galleryIDColumn,
-		imagesTagsTable:      imageIDColumn,
-		"performers_tags":    "performer_id",
+		scenesTagsTable:        sceneIDColumn,
+		"scene_markers_tags":   "scene_marker_id",
+		galleriesTagsTable:     galleryIDColumn,
+		imagesTagsTable:        imageIDColumn,
+		"performers_tags":      "performer_id",
+		usersExcludedTagsTable: userIDColumn,
 	}
 
 	args = append(args, destination)
@@ -1028,7 +1044,32 @@ AND NOT EXISTS(SELECT 1 FROM `+table+` o WHERE o.`+idColumn+` = `+table+`.`+idCo
 	return nil
 }
 
+// checkRelationsNotRestricted returns models.ErrRestricted if the tag is
+// restricted and its relations are changed to relatedIDs. relatedQuery
+// selects the existing related ids of the tag. Changing the relations of
+// restricted tags could otherwise make restricted content visible.
+func (qb *TagStore) checkRelationsNotRestricted(ctx context.Context, tagID int, relatedIDs []int, relatedQuery string) error {
+	if err := checkTagsNotRestricted(ctx, []int{tagID}); !errors.Is(err, models.ErrRestricted) {
+		return err
+	}
+
+	existing, err := qb.runIdsQuery(ctx, relatedQuery, []interface{}{tagID})
+	if err != nil {
+		return err
+	}
+
+	if len(intslice.IntExclude(existing, relatedIDs)) > 0 || len(intslice.IntExclude(relatedIDs, existing)) > 0 {
+		return models.ErrRestricted
+	}
+
+	return nil
+}
+
 func (qb *TagStore) UpdateParentTags(ctx context.Context, tagID int, parentIDs []int) error {
+	if err := qb.checkRelationsNotRestricted(ctx, tagID, parentIDs, "SELECT parent_id as id FROM tags_relations WHERE child_id = ?"); err != nil {
+		return err
+	}
+
 	tx := qb.tx
 	if _, err := tx.Exec(ctx, "DELETE FROM tags_relations WHERE child_id = ?", tagID); err != nil {
 		return err
@@ -1052,6 +1093,10 @@ func (qb *TagStore) UpdateParentTags(ctx context.Context, tagID int, parentIDs [
 }
 
 func (qb *TagStore) UpdateChildTags(ctx context.Context, tagID int, childIDs []int) error {
+	if err := qb.checkRelationsNotRestricted(ctx, tagID, childIDs, "SELECT child_id as id FROM tags_relations WHERE parent_id = ?"); err != nil {
+		return err
+	}
+
 	tx := qb.tx
 	if _, err := tx.Exec(ctx, "DELETE FROM tags_relations WHERE parent_id = ?", tagID); err != nil {
 		return err
@@ -138,5 +138,7 @@ func (db *Database) TxnRepository() models.Repository {
 		Studio:         db.Studio,
 		Tag:            db.Tag,
 		SavedFilter:    db.SavedFilter,
+		User:           db.User,
+		APIKey:         db.APIKey,
 	}
 }
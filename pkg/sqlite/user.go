@@ -0,0 +1,237 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+const (
+	userTable = "users"
+
+	usersExcludedTagsTable       = "users_excluded_tags"
+	usersExcludedStudiosTable    = "users_excluded_studios"
+	usersExcludedPerformersTable = "users_excluded_performers"
+	usersExcludedPathsTable      = "users_excluded_paths"
+	userUsernameColumn           = "username"
+	userRoleColumn               = "role"
+)
+
+type userRow struct {
+	ID           int             `db:"id" goqu:"skipinsert"`
+	Username     string          `db:"username"`
+	PasswordHash string          `db:"password_hash"`
+	Role         models.UserRole `db:"role"`
+	CreatedAt    Timestamp       `db:"created_at"`
+	UpdatedAt    Timestamp       `db:"updated_at"`
+}
+
+func (r *userRow) fromUser(o models.User) {
+	r.ID = o.ID
+	r.Username = o.Username
+	r.PasswordHash = o.PasswordHash
+	r.Role = o.Role
+	r.CreatedAt = Timestamp{Timestamp: o.CreatedAt}
+	r.UpdatedAt = Timestamp{Timestamp: o.UpdatedAt}
+}
+
+func (r *userRow) resolve() *models.User {
+	return &models.User{
+		ID:           r.ID,
+		Username:     r.Username,
+		PasswordHash: r.PasswordHash,
+		Role:         r.Role,
+		CreatedAt:    r.CreatedAt.Timestamp,
+		UpdatedAt:    r.UpdatedAt.Timestamp,
+	}
+}
+
+type UserStore struct {
+	repository
+	tableMgr *table
+}
+
+func NewUserStore() *UserStore {
+	return &UserStore{
+		repository: repository{
+			tableName: userTable,
+			idColumn:  idColumn,
+		},
+		tableMgr: userTableMgr,
+	}
+}
+
+func (qb *UserStore) table() exp.IdentifierExpression {
+	return qb.tableMgr.table
+}
+
+func (qb *UserStore) selectDataset() *goqu.SelectDataset {
+	return dialect.From(qb.table()).Select(qb.table().All())
+}
+
+func (qb *UserStore) Create(ctx context.Context, newObject *models.User) error {
+	var r userRow
+	r.fromUser(*newObject)
+
+	id, err := qb.tableMgr.insertID(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	if err := qb.saveRestrictions(ctx, id, newObject.Restrictions); err != nil {
+		return err
+	}
+
+	updated, err := qb.find(ctx, id)
+	if err != nil {
+		return fmt.Errorf("finding after create: %w", err)
+	}
+
+	*newObject = *updated
+
+	return nil
+}
+
+func (qb *UserStore) Update(ctx context.Context, updatedObject *models.User) error {
+	var r userRow
+	r.fromUser(*updatedObject)
+
+	if err := qb.tableMgr.updateByID(ctx, updatedObject.ID, r); err != nil {
+		return err
+	}
+
+	return qb.saveRestrictions(ctx, updatedObject.ID, updatedObject.Restrictions)
+}
+
+func (qb *UserStore) saveRestrictions(ctx context.Context, id int, r models.ContentRestrictions) error {
+	if err := usersExcludedTagsTableMgr.replaceJoins(ctx, id, r.ExcludedTagIDs); err != nil {
+		return err
+	}
+
+	if err := usersExcludedStudiosTableMgr.replaceJoins(ctx, id, r.ExcludedStudioIDs); err != nil {
+		return err
+	}
+
+	if err := usersExcludedPerformersTableMgr.replaceJoins(ctx, id, r.ExcludedPerformerIDs); err != nil {
+		return err
+	}
+
+	return usersExcludedPathsTableMgr.replaceJoins(ctx, id, r.ExcludedPaths)
+}
+
+func (qb *UserStore) loadRestrictions(ctx context.Context, u *models.User) error {
+	var err error
+	r := &u.Restrictions
+
+	if r.ExcludedTagIDs, err = usersExcludedTagsTableMgr.get(ctx, u.ID); err != nil {
+		return err
+	}
+
+	if r.ExcludedStudioIDs, err = usersExcludedStudiosTableMgr.get(ctx, u.ID); err != nil {
+		return err
+	}
+
+	if r.ExcludedPerformerIDs, err = usersExcludedPerformersTableMgr.get(ctx, u.ID); err != nil {
+		return err
+	}
+
+	if r.ExcludedPaths, err = usersExcludedPathsTableMgr.get(ctx, u.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (qb *UserStore) Destroy(ctx context.Context, id int) error {
+	return qb.destroyExisting(ctx, []int{id})
+}
+
+// returns nil, nil if not found
+func (qb *UserStore) Find(ctx context.Context, id int) (*models.User, error) {
+	ret, err := qb.find(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return ret, err
+}
+
+// returns nil, sql.ErrNoRows if not found
+func (qb *UserStore) find(ctx context.Context, id int) (*models.User, error) {
+	q := qb.selectDataset().Where(qb.tableMgr.byID(id))
+
+	return qb.get(ctx, q)
+}
+
+// returns nil, nil if not found
+func (qb *UserStore) FindByUsername(ctx context.Context, username string) (*models.User, error) {
+	// username has a case-insensitive unique index
+	q := qb.selectDataset().Prepared(true).Where(
+		goqu.L("? COLLATE NOCASE", qb.table().Col(userUsernameColumn)).Eq(username),
+	)
+
+	ret, err := qb.get(ctx, q)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return ret, err
+}
+
+func (qb *UserStore) get(ctx context.Context, q *goqu.SelectDataset) (*models.User, error) {
+	ret, err := qb.getMany(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ret) == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return ret[0], nil
+}
+
+func (qb *UserStore) getMany(ctx context.Context, q *goqu.SelectDataset) ([]*models.User, error) {
+	const single = false
+	var ret []*models.User
+	if err := queryFunc(ctx, q, single, func(r *sqlx.Rows) error {
+		var f userRow
+		if err := r.StructScan(&f); err != nil {
+			return err
+		}
+
+		ret = append(ret, f.resolve())
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, u := range ret {
+		if err := qb.loadRestrictions(ctx, u); err != nil {
+			return nil, fmt.Errorf("loading restrictions for user %d: %w", u.ID, err)
+		}
+	}
+
+	return ret, nil
+}
+
+func (qb *UserStore) All(ctx context.Context) ([]*models.User, error) {
+	table := qb.table()
+	return qb.getMany(ctx, qb.selectDataset().Order(table.Col(userUsernameColumn).Asc()))
+}
+
+func (qb *UserStore) Count(ctx context.Context) (int, error) {
+	q := dialect.Select(goqu.COUNT("*")).From(qb.table())
+	return count(ctx, q)
+}
+
+func (qb *UserStore) CountByRole(ctx context.Context, role models.UserRole) (int, error) {
+	table := qb.table()
+	q := dialect.Select(goqu.COUNT("*")).From(table).Where(table.Col(userRoleColumn).Eq(role))
+	return count(ctx, q)
+}
@@ -0,0 +1,93 @@
+//go:build integration
+// +build integration
+
+package sqlite_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserCreateFind(t *testing.T) {
+	withTxn(func(ctx context.Context) error {
+		qb := db.User
+		now := time.Now()
+
+		newUser := models.User{
+			Username:     "UserToFind",
+			PasswordHash: "hash",
+			Role:         models.UserRoleEditor,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+
+		if err := qb.Create(ctx, &newUser); err != nil {
+			t.Errorf("Error creating user: %s", err.Error())
+			return nil
+		}
+
+		found, err := qb.Find(ctx, newUser.ID)
+		if err != nil {
+			t.Errorf("Error finding user: %s", err.Error())
+		}
+
+		assert.Equal(t, newUser.Username, found.Username)
+		assert.Equal(t, newUser.PasswordHash, found.PasswordHash)
+		assert.Equal(t, newUser.Role, found.Role)
+
+		// username is case-insensitive
+		found, err = qb.FindByUsername(ctx, "usertofind")
+		if err != nil {
+			t.Errorf("Error finding user by username: %s", err.Error())
+		}
+
+		if assert.NotNil(t, found) {
+			assert.Equal(t, newUser.ID, found.ID)
+		}
+
+		duplicate := newUser
+		duplicate.ID = 0
+		duplicate.Username = "USERTOFIND"
+		assert.NotNil(t, qb.Create(ctx, &duplicate))
+
+		return qb.Destroy(ctx, newUser.ID)
+	})
+}
+
+func TestUserCountByRole(t *testing.T) {
+	withTxn(func(ctx context.Context) error {
+		qb := db.User
+		now := time.Now()
+
+		before, err := qb.CountByRole(ctx, models.UserRoleAdmin)
+		if err != nil {
+			t.Errorf("Error counting users: %s", err.Error())
+		}
+
+		newUser := models.User{
+			Username:     "adminToCount",
+			PasswordHash: "hash",
+			Role:         models.UserRoleAdmin,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		}
+
+		if err := qb.Create(ctx, &newUser); err != nil {
+			t.Errorf("Error creating user: %s", err.Error())
+			return nil
+		}
+
+		after, err := qb.CountByRole(ctx, models.UserRoleAdmin)
+		if err != nil {
+			t.Errorf("Error counting users: %s", err.Error())
+		}
+
+		assert.Equal(t, before+1, after)
+
+		return qb.Destroy(ctx, newUser.ID)
+	})
+}
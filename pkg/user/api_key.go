@@ -0,0 +1,113 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stashapp/stash/pkg/hash"
+	"github.com/stashapp/stash/pkg/models"
+)
+
+const (
+	apiKeyLength = 32
+
+	// lastUsedInterval is the minimum interval between updates of the last
+	// used time of an API key.
+	lastUsedInterval = time.Minute
+)
+
+var (
+	ErrBlankAPIKeyName = errors.New("API key name cannot be blank")
+	ErrNoAPIKeyScopes  = errors.New("API key must have at least one scope")
+	ErrAPIKeyExpiry    = errors.New("API key expiry must be in the future")
+
+	// ErrInvalidAPIKey is returned if an API key does not exist or has
+	// expired.
+	ErrInvalidAPIKey = errors.New("invalid API key")
+)
+
+// HashAPIKey returns the hash of the API key which is stored in the
+// database.
+func HashAPIKey(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(h[:])
+}
+
+// GenerateAPIKey returns a new random API key.
+func GenerateAPIKey() (string, error) {
+	return hash.GenerateRandomKey(apiKeyLength)
+}
+
+// NewAPIKey generates a new API key for the user. The returned key is not
+// stored, and must be returned to the user.
+func NewAPIKey(userID int, name string, scopes []models.APIKeyScope, expiresAt *time.Time, now time.Time) (string, *models.APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, ErrBlankAPIKeyName
+	}
+
+	if len(scopes) == 0 {
+		return "", nil, ErrNoAPIKeyScopes
+	}
+
+	for _, s := range scopes {
+		if !s.IsValid() {
+			return "", nil, fmt.Errorf("invalid scope: %s", s)
+		}
+	}
+
+	if expiresAt != nil && !expiresAt.After(now) {
+		return "", nil, ErrAPIKeyExpiry
+	}
+
+	key, err := GenerateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	return key, &models.APIKey{
+		UserID:    userID,
+		Name:      name,
+		KeyHash:   HashAPIKey(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+// AuthenticateAPIKey returns the user and API key for the provided key.
+// Returns ErrInvalidAPIKey if the key does not exist or has expired.
+func AuthenticateAPIKey(ctx context.Context, key string, keys models.APIKeyReader, users models.UserReader, now time.Time) (*models.User, *models.APIKey, error) {
+	apiKey, err := keys.FindByKeyHash(ctx, HashAPIKey(key))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if apiKey == nil || apiKey.IsExpired(now) {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	u, err := users.Find(ctx, apiKey.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if u == nil {
+		return nil, nil, ErrInvalidAPIKey
+	}
+
+	return u, apiKey, nil
+}
+
+// ShouldUpdateLastUsed returns true if the last used time of the API key
+// should be updated. The time is only updated periodically, to avoid writing
+// to the database on every request.
+func ShouldUpdateLastUsed(k *models.APIKey, now time.Time) bool {
+	return k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= lastUsedInterval
+}
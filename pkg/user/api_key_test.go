@@ -0,0 +1,96 @@
+package user
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/models/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+	read := []models.APIKeyScope{models.APIKeyScopeRead}
+
+	tests := []struct {
+		name      string
+		keyName   string
+		scopes    []models.APIKeyScope
+		expiresAt *time.Time
+		wantErr   error
+	}{
+		{"valid", "scripts", read, &future, nil},
+		{"no expiry", "scripts", read, nil, nil},
+		{"blank name", " ", read, nil, ErrBlankAPIKeyName},
+		{"no scopes", "scripts", nil, nil, ErrNoAPIKeyScopes},
+		{"expired", "scripts", read, &past, ErrAPIKeyExpiry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, apiKey, err := NewAPIKey(1, tt.keyName, tt.scopes, tt.expiresAt, now)
+			assert.Equal(t, tt.wantErr, err)
+			if err != nil {
+				return
+			}
+
+			assert.NotEmpty(t, key)
+			assert.Equal(t, HashAPIKey(key), apiKey.KeyHash)
+			assert.NotEqual(t, key, apiKey.KeyHash)
+			assert.Equal(t, tt.scopes, apiKey.Scopes)
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	past := now.Add(-time.Hour)
+
+	const (
+		validKey   = "valid"
+		expiredKey = "expired"
+		unknownKey = "unknown"
+		orphanKey  = "orphan"
+		userID     = 2
+		deletedID  = 3
+	)
+
+	u := &models.User{ID: userID, Username: "alice"}
+	valid := &models.APIKey{ID: 1, UserID: userID}
+
+	db := mocks.NewTxnRepository()
+	keys := db.APIKey.(*mocks.APIKeyReaderWriter)
+	users := db.User.(*mocks.UserReaderWriter)
+
+	keys.On("FindByKeyHash", ctx, HashAPIKey(validKey)).Return(valid, nil)
+	keys.On("FindByKeyHash", ctx, HashAPIKey(expiredKey)).Return(&models.APIKey{ID: 2, UserID: userID, ExpiresAt: &past}, nil)
+	keys.On("FindByKeyHash", ctx, HashAPIKey(orphanKey)).Return(&models.APIKey{ID: 3, UserID: deletedID}, nil)
+	keys.On("FindByKeyHash", ctx, HashAPIKey(unknownKey)).Return(nil, nil)
+	users.On("Find", ctx, userID).Return(u, nil)
+	users.On("Find", ctx, deletedID).Return(nil, nil)
+
+	gotUser, gotKey, err := AuthenticateAPIKey(ctx, validKey, keys, users, now)
+	assert.Nil(t, err)
+	assert.Equal(t, u, gotUser)
+	assert.Equal(t, valid, gotKey)
+
+	for _, k := range []string{expiredKey, unknownKey, orphanKey} {
+		_, _, err = AuthenticateAPIKey(ctx, k, keys, users, now)
+		assert.Equal(t, ErrInvalidAPIKey, err, k)
+	}
+}
+
+func TestShouldUpdateLastUsed(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-time.Second)
+	old := now.Add(-time.Hour)
+
+	assert.True(t, ShouldUpdateLastUsed(&models.APIKey{}, now))
+	assert.True(t, ShouldUpdateLastUsed(&models.APIKey{LastUsedAt: &old}, now))
+	assert.False(t, ShouldUpdateLastUsed(&models.APIKey{LastUsedAt: &recent}, now))
+}
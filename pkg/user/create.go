@@ -0,0 +1,34 @@
+package user
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+// ActivityCopier copies the activity recorded without a user to a user.
+type ActivityCopier interface {
+	CopyActivityToUser(ctx context.Context, userID int) error
+}
+
+// Create creates a new user. If there are no existing users, then the
+// activity recorded before users were created is copied to the new user.
+func Create(ctx context.Context, newUser *models.User, qb models.UserReaderWriter, activity ActivityCopier) error {
+	n, err := qb.Count(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := qb.Create(ctx, newUser); err != nil {
+		return err
+	}
+
+	if n == 0 {
+		if err := activity.CopyActivityToUser(ctx, newUser.ID); err != nil {
+			return fmt.Errorf("copying activity to user: %w", err)
+		}
+	}
+
+	return nil
+}
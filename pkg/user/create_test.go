@@ -0,0 +1,49 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/models/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreate(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		userCount int
+		wantCopy  bool
+	}{
+		{"first user", 0, true},
+		{"existing users", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := mocks.NewTxnRepository()
+			qb := db.User.(*mocks.UserReaderWriter)
+			sqb := db.Scene.(*mocks.SceneReaderWriter)
+
+			const userID = 2
+			qb.On("Count", ctx).Return(tt.userCount, nil)
+			qb.On("Create", ctx, mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+				args.Get(1).(*models.User).ID = userID
+			}).Return(nil)
+			sqb.On("CopyActivityToUser", ctx, userID).Return(nil)
+
+			u := &models.User{Username: "alice", Role: models.UserRoleAdmin}
+			assert.Nil(t, Create(ctx, u, qb, sqb))
+			assert.Equal(t, userID, u.ID)
+
+			if tt.wantCopy {
+				sqb.AssertCalled(t, "CopyActivityToUser", ctx, userID)
+			} else {
+				sqb.AssertNotCalled(t, "CopyActivityToUser", ctx, userID)
+			}
+		})
+	}
+}
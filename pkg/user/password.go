@@ -0,0 +1,34 @@
+package user
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+var ErrBlankPassword = errors.New("password cannot be blank")
+
+// HashPassword returns the bcrypt hash of password.
+func HashPassword(password string) (string, error) {
+	if password == "" {
+		return "", ErrBlankPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+// CheckPassword returns true if password matches the password of u.
+func CheckPassword(u *models.User, password string) bool {
+	if u.PasswordHash == "" {
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
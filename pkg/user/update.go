@@ -0,0 +1,61 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/stashapp/stash/pkg/models"
+)
+
+var (
+	ErrBlankUsername = errors.New("username cannot be blank")
+	ErrLastAdmin     = errors.New("at least one admin user is required")
+)
+
+type UsernameExistsError struct {
+	Username string
+}
+
+func (e *UsernameExistsError) Error() string {
+	return fmt.Sprintf("user with username '%s' already exists", e.Username)
+}
+
+// EnsureUsernameUnique returns an error if the username is blank or is used
+// by a user other than the user with the given id.
+func EnsureUsernameUnique(ctx context.Context, id int, username string, qb models.UserReader) error {
+	if strings.TrimSpace(username) == "" {
+		return ErrBlankUsername
+	}
+
+	existing, err := qb.FindByUsername(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil && existing.ID != id {
+		return &UsernameExistsError{Username: username}
+	}
+
+	return nil
+}
+
+// EnsureAdminRemains returns ErrLastAdmin if u is the only admin user, and
+// so cannot be destroyed or given another role.
+func EnsureAdminRemains(ctx context.Context, u *models.User, qb models.UserReader) error {
+	if u.Role != models.UserRoleAdmin {
+		return nil
+	}
+
+	n, err := qb.CountByRole(ctx, models.UserRoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	if n <= 1 {
+		return ErrLastAdmin
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stashapp/stash/pkg/models"
+	"github.com/stashapp/stash/pkg/models/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsureUsernameUnique(t *testing.T) {
+	ctx := context.Background()
+	existing := &models.User{ID: 1, Username: "alice"}
+
+	db := mocks.NewTxnRepository()
+	qb := db.User.(*mocks.UserReaderWriter)
+	qb.On("FindByUsername", ctx, "alice").Return(existing, nil)
+	qb.On("FindByUsername", ctx, "bob").Return(nil, nil)
+
+	assert.Nil(t, EnsureUsernameUnique(ctx, 0, "bob", qb))
+	assert.Nil(t, EnsureUsernameUnique(ctx, 1, "alice", qb))
+	assert.Equal(t, ErrBlankUsername, EnsureUsernameUnique(ctx, 0, " ", qb))
+
+	var existsErr *UsernameExistsError
+	assert.ErrorAs(t, EnsureUsernameUnique(ctx, 2, "alice", qb), &existsErr)
+}
+
+func TestEnsureAdminRemains(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		role       models.UserRole
+		adminCount int
+		wantErr    error
+	}{
+		{"last admin", models.UserRoleAdmin, 1, ErrLastAdmin},
+		{"other admins", models.UserRoleAdmin, 2, nil},
+		{"editor", models.UserRoleEditor, 1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := &mocks.UserReaderWriter{}
+			qb.On("CountByRole", ctx, models.UserRoleAdmin).Return(tt.adminCount, nil)
+
+			err := EnsureAdminRemains(ctx, &models.User{ID: 1, Role: tt.role}, qb)
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := HashPassword("secret")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+
+	u := &models.User{PasswordHash: hash}
+	assert.True(t, CheckPassword(u, "secret"))
+	assert.False(t, CheckPassword(u, "wrong"))
+	assert.False(t, CheckPassword(&models.User{}, ""))
+
+	_, err = HashPassword("")
+	assert.Equal(t, ErrBlankPassword, err)
+}